@@ -222,6 +222,10 @@ const components = {
             'internal/authentication/postgresql',
         ],
     },
+    'configuration.file': {
+        conformance: true,
+        sourcePkg: ['configuration/file'],
+    },
     'configuration.postgresql.docker': {
         conformance: true,
         conformanceSetup: 'docker-compose.sh postgresql',
@@ -257,6 +261,14 @@ const components = {
         certification: true,
         sourcePkg: ['configuration/redis', 'configuration/redis/internal'],
     },
+    'configuration.sqlite': {
+        conformance: true,
+        sourcePkg: [
+            'configuration/sqlite',
+            'internal/authentication/sqlite',
+            'internal/component/sql',
+        ],
+    },
     'crypto.azure.keyvault': {
         conformance: true,
         requiredSecrets: [
@@ -17,7 +17,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,18 +28,32 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
 	cron "github.com/dapr/kit/cron"
 	"github.com/dapr/kit/logger"
 	kitmd "github.com/dapr/kit/metadata"
 )
 
+const (
+	// Prefix for one-shot schedules, followed by a RFC3339 timestamp.
+	atSchedulePrefix = "@at "
+
+	defaultCatchUpMaxRuns = 1
+)
+
 // Binding represents Cron input binding.
 type Binding struct {
 	logger   logger.Logger
 	name     string
 	schedule string
+	metadata metadata
+	sched    cron.Schedule
+	location *time.Location
 	parser   cron.Parser
 	clk      clock.Clock
+	store    state.Store
+	lastRun  time.Time
+	lock     sync.Mutex
 	closed   atomic.Bool
 	closeCh  chan struct{}
 	wg       sync.WaitGroup
@@ -45,6 +61,17 @@ type Binding struct {
 
 type metadata struct {
 	Schedule string
+	// Time zone in which the schedule is interpreted, as an IANA name such as "Europe/Berlin".
+	// Defaults to the local time zone of the process. A "CRON_TZ=" prefix in the schedule takes precedence.
+	Timezone string `mapstructure:"timezone"`
+	// Maximum random delay added to each scheduled run.
+	Jitter time.Duration `mapstructure:"jitter"`
+	// If true, the time of the last run is persisted in the state store and runs missed while the binding was not running are replayed.
+	CatchUp bool `mapstructure:"catchUp"`
+	// Name of the state store used for catch-up.
+	StateStore string `mapstructure:"stateStore"`
+	// Maximum number of missed runs to replay; the most recent ones are replayed.
+	CatchUpMaxRuns int `mapstructure:"catchUpMaxRuns"`
 }
 
 // NewCron returns a new Cron event input binding.
@@ -63,14 +90,24 @@ func NewCronWithClock(logger logger.Logger, clk clock.Clock) bindings.InputBindi
 	}
 }
 
+// SetStateStore sets the state store named in the stateStore metadata property, used to persist the time of the last run when catch-up is enabled.
+// It implements state.StoreConsumer.
+func (b *Binding) SetStateStore(store state.Store) {
+	b.store = store
+}
+
 // Init initializes the Cron binding
 // Examples from https://godoc.org/github.com/robfig/cron:
 //
 //	"15 * * * * *" - Every 15 sec
 //	"0 30 * * * *" - Every 30 min
+//	"CRON_TZ=Europe/Berlin 0 0 9 * * *" - Every day at 09:00 in Berlin
+//	"@at 2023-12-31T23:59:59Z" - Once, at the given time
 func (b *Binding) Init(ctx context.Context, meta bindings.Metadata) error {
 	b.name = meta.Name
-	m := metadata{}
+	m := metadata{
+		CatchUpMaxRuns: defaultCatchUpMaxRuns,
+	}
 	err := kitmd.DecodeMetadata(meta.Properties, &m)
 	if err != nil {
 		return err
@@ -78,36 +115,74 @@ func (b *Binding) Init(ctx context.Context, meta bindings.Metadata) error {
 	if m.Schedule == "" {
 		return fmt.Errorf("schedule not set")
 	}
-	_, err = b.parser.Parse(m.Schedule)
+	b.sched, err = b.parseSchedule(m.Schedule)
 	if err != nil {
 		return fmt.Errorf("invalid schedule format '%s': %w", m.Schedule, err)
 	}
 	b.schedule = m.Schedule
 
+	b.location = time.Local
+	if m.Timezone != "" {
+		b.location, err = time.LoadLocation(m.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone '%s': %w", m.Timezone, err)
+		}
+	}
+	if m.Jitter < 0 {
+		return errors.New("invalid jitter: must not be negative")
+	}
+	if m.CatchUp && m.CatchUpMaxRuns < 1 {
+		return errors.New("invalid catchUpMaxRuns: must be greater than zero")
+	}
+	if m.CatchUp && m.StateStore == "" {
+		return errors.New("metadata property catchUp requires the stateStore property")
+	}
+	b.metadata = m
+
 	return nil
 }
 
+// parseSchedule parses a cron spec, or a one-shot schedule with the "@at" prefix.
+func (b *Binding) parseSchedule(spec string) (cron.Schedule, error) {
+	if at, ok := strings.CutPrefix(spec, atSchedulePrefix); ok {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(at))
+		if err != nil {
+			return nil, err
+		}
+		return onceSchedule{at: t}, nil
+	}
+	return b.parser.Parse(spec)
+}
+
 // Read triggers the Cron scheduler.
 func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
 	if b.closed.Load() {
 		return errors.New("binding is closed")
 	}
 
-	c := cron.New(cron.WithParser(b.parser), cron.WithClock(b.clk))
-	id, err := c.AddFunc(b.schedule, func() {
-		b.logger.Debugf("name: %s, schedule fired: %v", b.name, time.Now())
-		handler(ctx, &bindings.ReadResponse{
-			Metadata: map[string]string{
-				"timeZone":    c.Location().String(),
-				"readTimeUTC": time.Now().UTC().String(),
-			},
-		})
-	})
-	if err != nil {
-		return fmt.Errorf("name: %s, error scheduling %s: %w", b.name, b.schedule, err)
+	var missed []time.Time
+	if b.metadata.CatchUp {
+		if b.store == nil {
+			return fmt.Errorf("name: %s, catch-up is enabled but state store '%s' was not set", b.name, b.metadata.StateStore)
+		}
+		var err error
+		missed, err = b.missedRuns(ctx)
+		if err != nil {
+			return fmt.Errorf("name: %s, error loading last run: %w", b.name, err)
+		}
 	}
+
+	c := cron.New(cron.WithParser(b.parser), cron.WithClock(b.clk), cron.WithLocation(b.location))
+	id := c.Schedule(b.sched, cron.FuncJob(func() {
+		now := b.clk.Now()
+		b.logger.Debugf("name: %s, schedule fired: %v", b.name, now)
+		if !b.waitJitter(ctx) {
+			return
+		}
+		b.fire(ctx, handler, c.Location(), now, nil)
+	}))
 	c.Start()
-	b.logger.Debugf("name: %s, next run: %v", b.name, time.Until(c.Entry(id).Next))
+	b.logger.Debugf("name: %s, next run: %v", b.name, c.Entry(id).Next.Sub(b.clk.Now()))
 
 	b.wg.Add(1)
 	go func() {
@@ -121,6 +196,117 @@ func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
 		c.Stop()
 	}()
 
+	if len(missed) > 0 {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			for _, t := range missed {
+				if ctx.Err() != nil || b.closed.Load() {
+					return
+				}
+				b.logger.Debugf("name: %s, replaying missed run: %v", b.name, t)
+				b.fire(ctx, handler, c.Location(), t, map[string]string{
+					"catchUp":          "true",
+					"scheduledTimeUTC": t.UTC().String(),
+				})
+			}
+		}()
+	}
+
+	return nil
+}
+
+// fire invokes the handler and, if catch-up is enabled, records the run.
+func (b *Binding) fire(ctx context.Context, handler bindings.Handler, loc *time.Location, scheduled time.Time, extra map[string]string) {
+	md := map[string]string{
+		"timeZone":    loc.String(),
+		"readTimeUTC": b.clk.Now().UTC().String(),
+	}
+	for k, v := range extra {
+		md[k] = v
+	}
+	handler(ctx, &bindings.ReadResponse{
+		Metadata: md,
+	})
+
+	if b.metadata.CatchUp {
+		err := b.saveLastRun(ctx, scheduled)
+		if err != nil {
+			b.logger.Errorf("name: %s, error saving last run: %v", b.name, err)
+		}
+	}
+}
+
+// waitJitter waits for a random duration up to the configured jitter.
+// It returns false if the binding was closed while waiting.
+func (b *Binding) waitJitter(ctx context.Context) bool {
+	if b.metadata.Jitter <= 0 {
+		return true
+	}
+	//nolint:gosec
+	delay := time.Duration(rand.Int63n(int64(b.metadata.Jitter)))
+	t := b.clk.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return true
+	case <-ctx.Done():
+		return false
+	case <-b.closeCh:
+		return false
+	}
+}
+
+func (b *Binding) stateKey() string {
+	return b.name + "||lastRun"
+}
+
+// missedRuns returns the scheduled times that were missed since the last persisted run, up to CatchUpMaxRuns.
+func (b *Binding) missedRuns(ctx context.Context) ([]time.Time, error) {
+	res, err := b.store.Get(ctx, &state.GetRequest{Key: b.stateKey()})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || len(res.Data) == 0 {
+		return nil, nil
+	}
+	last, err := time.Parse(time.RFC3339Nano, strings.Trim(string(res.Data), `"`))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for last run: %w", err)
+	}
+
+	b.lock.Lock()
+	b.lastRun = last
+	b.lock.Unlock()
+
+	now := b.clk.Now()
+	missed := make([]time.Time, 0, b.metadata.CatchUpMaxRuns)
+	for t := b.sched.Next(last.In(b.location)); !t.IsZero() && !t.After(now); t = b.sched.Next(t) {
+		// Keep only the most recent runs
+		if len(missed) == b.metadata.CatchUpMaxRuns {
+			missed = append(missed[:0], missed[1:]...)
+		}
+		missed = append(missed, t)
+	}
+	return missed, nil
+}
+
+// saveLastRun persists the time of the last run, if it is more recent than the one already saved.
+func (b *Binding) saveLastRun(ctx context.Context, t time.Time) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !t.After(b.lastRun) {
+		return nil
+	}
+	err := b.store.Set(ctx, &state.SetRequest{
+		Key:   b.stateKey(),
+		Value: []byte(t.UTC().Format(time.RFC3339Nano)),
+	})
+	if err != nil {
+		return err
+	}
+	b.lastRun = t
 	return nil
 }
 
@@ -138,3 +324,19 @@ func (b *Binding) GetComponentMetadata() (metadataInfo contribMetadata.MetadataM
 	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.BindingType)
 	return
 }
+
+// onceSchedule is a schedule that fires only once, at the given time.
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next implements cron.Schedule.
+// Returning the zero time indicates there are no more runs.
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+var _ state.StoreConsumer = (*Binding)(nil)
@@ -22,10 +22,13 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/utils/clock"
 	clocktesting "k8s.io/utils/clock/testing"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
 	"github.com/dapr/kit/logger"
 )
 
@@ -155,3 +158,223 @@ func TestCronReadWithContextCancellation(t *testing.T) {
 	assert.NoErrorf(t, err, "error on read")
 	assert.NoError(t, c.Close())
 }
+
+func TestCronInitOptions(t *testing.T) {
+	initTests := []struct {
+		name          string
+		properties    map[string]string
+		errorExpected bool
+	}{
+		{
+			name:       "timezone",
+			properties: map[string]string{"schedule": "0 0 9 * * *", "timezone": "Europe/Berlin"},
+		},
+		{
+			name:          "invalid timezone",
+			properties:    map[string]string{"schedule": "0 0 9 * * *", "timezone": "Mars/Olympus_Mons"},
+			errorExpected: true,
+		},
+		{
+			name:       "CRON_TZ prefix",
+			properties: map[string]string{"schedule": "CRON_TZ=America/New_York 0 0 9 * * *"},
+		},
+		{
+			name:       "one-shot",
+			properties: map[string]string{"schedule": "@at 2030-01-01T09:00:00+01:00"},
+		},
+		{
+			name:          "invalid one-shot",
+			properties:    map[string]string{"schedule": "@at tomorrow"},
+			errorExpected: true,
+		},
+		{
+			name:       "jitter",
+			properties: map[string]string{"schedule": "@every 1m", "jitter": "10s"},
+		},
+		{
+			name:          "negative jitter",
+			properties:    map[string]string{"schedule": "@every 1m", "jitter": "-10s"},
+			errorExpected: true,
+		},
+		{
+			name:          "invalid catchUpMaxRuns",
+			properties:    map[string]string{"schedule": "@every 1m", "catchUp": "true", "stateStore": "statestore", "catchUpMaxRuns": "0"},
+			errorExpected: true,
+		},
+	}
+
+	for _, test := range initTests {
+		t.Run(test.name, func(t *testing.T) {
+			c := getNewCron()
+			m := bindings.Metadata{}
+			m.Properties = test.properties
+			err := c.Init(context.Background(), m)
+			if test.errorExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCronReadTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// 08:59:59 in Berlin
+	clk := clocktesting.NewFakeClock(time.Date(2023, 6, 1, 8, 59, 59, 0, loc))
+	c := getNewCronWithClock(clk)
+	m := getTestMetadata("0 0 9 * * *")
+	m.Properties["timezone"] = "Europe/Berlin"
+	require.NoError(t, c.Init(context.Background(), m))
+
+	resCh := make(chan *bindings.ReadResponse, 1)
+	require.NoError(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		resCh <- res
+		return nil, nil
+	}))
+	defer c.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	clk.Step(time.Second)
+
+	select {
+	case res := <-resCh:
+		assert.Equal(t, "Europe/Berlin", res.Metadata["timeZone"])
+	case <-time.After(time.Second):
+		t.Fatal("Cron did not trigger")
+	}
+}
+
+func TestCronReadOneShot(t *testing.T) {
+	start := time.Now()
+	clk := clocktesting.NewFakeClock(start)
+	c := getNewCronWithClock(clk)
+	schedule := "@at " + start.Add(2*time.Second).Format(time.RFC3339)
+	require.NoError(t, c.Init(context.Background(), getTestMetadata(schedule)))
+
+	var observedCount atomic.Int32
+	require.NoError(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		observedCount.Add(1)
+		return nil, nil
+	}))
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		clk.Step(time.Second)
+		runtime.Gosched()
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Eventually(t, func() bool {
+		return observedCount.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), observedCount.Load())
+}
+
+func TestCronReadJitter(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	c := getNewCronWithClock(clk)
+	m := getTestMetadata("@every 1s")
+	m.Properties["jitter"] = "10s"
+	require.NoError(t, c.Init(context.Background(), m))
+
+	var observedCount atomic.Int32
+	require.NoError(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		observedCount.Add(1)
+		return nil, nil
+	}))
+
+	time.Sleep(100 * time.Millisecond)
+	clk.Step(time.Second)
+	// The run is delayed by up to the jitter
+	time.Sleep(100 * time.Millisecond)
+	clk.Step(10 * time.Second)
+	assert.Eventually(t, func() bool {
+		return observedCount.Load() >= 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Closing the binding aborts pending jittered runs
+	assert.NoError(t, c.Close())
+}
+
+func TestCronCatchUp(t *testing.T) {
+	store := inmemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+
+	start := time.Date(2023, 6, 1, 10, 0, 30, 0, time.UTC)
+	newBinding := func(clk clock.Clock, maxRuns string) *Binding {
+		c := getNewCronWithClock(clk)
+		c.SetStateStore(store)
+		m := getTestMetadata("0 * * * * *") // Every minute
+		m.Name = "mycron"
+		m.Properties["timezone"] = "UTC"
+		m.Properties["catchUp"] = "true"
+		m.Properties["stateStore"] = "statestore"
+		m.Properties["catchUpMaxRuns"] = maxRuns
+		require.NoError(t, c.Init(context.Background(), m))
+		return c
+	}
+
+	t.Run("catch-up requires a state store", func(t *testing.T) {
+		c := getNewCron()
+		m := getTestMetadata("@every 1s")
+		m.Properties["catchUp"] = "true"
+		require.ErrorContains(t, c.Init(context.Background(), m), "requires the stateStore property")
+
+		m.Properties["stateStore"] = "statestore"
+		require.NoError(t, c.Init(context.Background(), m))
+		require.Error(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			return nil, nil
+		}))
+	})
+
+	t.Run("first run is persisted", func(t *testing.T) {
+		clk := clocktesting.NewFakeClock(start)
+		c := newBinding(clk, "3")
+		var observedCount atomic.Int32
+		require.NoError(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			assert.Empty(t, res.Metadata["catchUp"])
+			observedCount.Add(1)
+			return nil, nil
+		}))
+
+		time.Sleep(100 * time.Millisecond)
+		clk.Step(30 * time.Second)
+		assert.Eventually(t, func() bool {
+			return observedCount.Load() == 1
+		}, time.Second, 10*time.Millisecond)
+		require.NoError(t, c.Close())
+
+		res, err := store.Get(context.Background(), &state.GetRequest{Key: "mycron||lastRun"})
+		require.NoError(t, err)
+		assert.Equal(t, "2023-06-01T10:01:00Z", string(res.Data))
+	})
+
+	t.Run("missed runs are replayed", func(t *testing.T) {
+		// Binding was down for 5 minutes, so it missed 10:02 to 10:06; only the last 3 are replayed
+		clk := clocktesting.NewFakeClock(start.Add(6 * time.Minute))
+		c := newBinding(clk, "3")
+		resCh := make(chan *bindings.ReadResponse, 10)
+		require.NoError(t, c.Read(context.Background(), func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+			resCh <- res
+			return nil, nil
+		}))
+
+		for _, expect := range []string{"10:04:00", "10:05:00", "10:06:00"} {
+			select {
+			case res := <-resCh:
+				assert.Equal(t, "true", res.Metadata["catchUp"])
+				assert.Contains(t, res.Metadata["scheduledTimeUTC"], expect)
+			case <-time.After(time.Second):
+				t.Fatal("missed run was not replayed")
+			}
+		}
+		require.NoError(t, c.Close())
+
+		res, err := store.Get(context.Background(), &state.GetRequest{Key: "mycron||lastRun"})
+		require.NoError(t, err)
+		assert.Equal(t, "2023-06-01T10:06:00Z", string(res.Data))
+	})
+}
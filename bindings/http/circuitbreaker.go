@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// ErrCircuitOpen is returned by Invoke when the circuit breaker is open and requests are not sent.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker is a consecutive-failures circuit breaker.
+// After `threshold` consecutive failures the circuit opens and requests fail fast for `timeout`.
+// Once the timeout has elapsed, a single trial request is allowed through (half-open): if it succeeds the circuit closes, otherwise it opens again.
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+	clock     clock.Clock
+
+	lock     sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(threshold int, timeout time.Duration, clk clock.Clock) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		timeout:   timeout,
+		clock:     clk,
+	}
+}
+
+// Allow returns ErrCircuitOpen if the request must not be sent.
+func (cb *circuitBreaker) Allow() error {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if cb.clock.Since(cb.openedAt) < cb.timeout {
+			return ErrCircuitOpen
+		}
+		// Let one trial request through
+		cb.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// A trial request is already in-flight
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// Record records the outcome of a request that was allowed through.
+func (cb *circuitBreaker) Record(success bool) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if success {
+		cb.state = circuitClosed
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.state == circuitHalfOpen || cb.failures >= cb.threshold {
+		cb.state = circuitOpen
+		cb.openedAt = cb.clock.Now()
+	}
+}
@@ -30,6 +30,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+	"k8s.io/utils/clock"
+
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
@@ -48,6 +51,13 @@ const (
 	securityToken                   = "securityToken"
 	securityTokenHeader             = "securityTokenHeader"
 	defaultMaxResponseBodySizeBytes = 100 << 20 // 100 MB
+	defaultRetryInitialInterval     = 500 * time.Millisecond
+	defaultRetryMaxInterval         = 30 * time.Second
+	defaultRetryStatusCodes         = "429,502,503,504"
+	defaultCircuitBreakerTimeout    = 30 * time.Second
+
+	// Response metadata key with the number of retries performed.
+	RetryAttemptsMetadataKey = "retryAttempts"
 )
 
 // HTTPSource is a binding for an http url endpoint invocation
@@ -58,6 +68,10 @@ type HTTPSource struct {
 	client        *http.Client
 	errorIfNot2XX bool
 	logger        logger.Logger
+	clock         clock.Clock
+
+	retryStatusCodes map[int]struct{}
+	circuitBreaker   *circuitBreaker
 }
 
 type httpMetadata struct {
@@ -74,21 +88,44 @@ type httpMetadata struct {
 	// A value <= 0 means no limit.
 	// Default: 100MB
 	MaxResponseBodySize kitmd.ByteSize `mapstructure:"maxResponseBodySize"`
+	// Maximum number of retries for requests with idempotent methods.
+	// Default: 0 (retries disabled)
+	MaxRetries int `mapstructure:"maxRetries"`
+	// Initial and maximum delay for the exponential backoff between retries.
+	RetryInitialInterval time.Duration `mapstructure:"retryInitialInterval"`
+	RetryMaxInterval     time.Duration `mapstructure:"retryMaxInterval"`
+	// Comma-separated list of response status codes that are retried.
+	RetryStatusCodes string `mapstructure:"retryStatusCodes"`
+	// Number of consecutive failures after which the circuit breaker opens.
+	// Default: 0 (circuit breaker disabled)
+	CircuitBreakerFailureThreshold int `mapstructure:"circuitBreakerFailureThreshold"`
+	// Duration the circuit breaker stays open before allowing a trial request.
+	CircuitBreakerTimeout time.Duration `mapstructure:"circuitBreakerTimeout"`
 
 	maxResponseBodySizeBytes int64
 }
 
 // NewHTTP returns a new HTTPSource.
 func NewHTTP(logger logger.Logger) bindings.OutputBinding {
+	return NewHTTPWithClock(logger, clock.RealClock{})
+}
+
+// NewHTTPWithClock returns a new HTTPSource that uses the given clock.
+func NewHTTPWithClock(logger logger.Logger, clk clock.Clock) bindings.OutputBinding {
 	return &HTTPSource{
 		logger: logger,
+		clock:  clk,
 	}
 }
 
 // Init performs metadata parsing.
 func (h *HTTPSource) Init(_ context.Context, meta bindings.Metadata) error {
 	h.metadata = httpMetadata{
-		MaxResponseBodySize: kitmd.NewByteSize(defaultMaxResponseBodySizeBytes),
+		MaxResponseBodySize:   kitmd.NewByteSize(defaultMaxResponseBodySizeBytes),
+		RetryInitialInterval:  defaultRetryInitialInterval,
+		RetryMaxInterval:      defaultRetryMaxInterval,
+		RetryStatusCodes:      defaultRetryStatusCodes,
+		CircuitBreakerTimeout: defaultCircuitBreakerTimeout,
 	}
 	err := kitmd.DecodeMetadata(meta.Properties, &h.metadata)
 	if err != nil {
@@ -117,6 +154,26 @@ func (h *HTTPSource) Init(_ context.Context, meta bindings.Metadata) error {
 		return fmt.Errorf("invalid value for maxResponseBodySize: %w", err)
 	}
 
+	if h.metadata.MaxRetries < 0 {
+		return errors.New("invalid value for maxRetries: must not be negative")
+	}
+	h.retryStatusCodes = make(map[int]struct{})
+	for _, v := range strings.Split(h.metadata.RetryStatusCodes, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		code, err := strconv.Atoi(v)
+		if err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("invalid status code in retryStatusCodes: %q", v)
+		}
+		h.retryStatusCodes[code] = struct{}{}
+	}
+
+	if h.metadata.CircuitBreakerFailureThreshold > 0 {
+		h.circuitBreaker = newCircuitBreaker(h.metadata.CircuitBreakerFailureThreshold, h.metadata.CircuitBreakerTimeout, h.clock)
+	}
+
 	// See guidance on proper HTTP client settings here:
 	// https://medium.com/@nate510/don-t-use-go-s-default-http-client-4804cb19f779
 	dialer := &net.Dialer{
@@ -255,20 +312,110 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 		errorIfNot2XX = utils.IsTruthy(req.Metadata["errorIfNot2XX"])
 	}
 
-	var body io.Reader
 	method := strings.ToUpper(string(req.Operation))
 	// For backward compatibility
 	if method == "CREATE" {
 		method = "POST"
 	}
 	switch method {
-	case "PUT", "POST", "PATCH":
-		body = bytes.NewBuffer(req.Data)
-	case "GET", "HEAD", "DELETE", "OPTIONS", "TRACE":
+	case "PUT", "POST", "PATCH", "GET", "HEAD", "DELETE", "OPTIONS", "TRACE":
 	default:
 		return nil, fmt.Errorf("invalid operation: %s", req.Operation)
 	}
 
+	// Only requests with idempotent methods are retried
+	var bo backoff.BackOff
+	if h.metadata.MaxRetries > 0 && isIdempotentMethod(method) {
+		bo = h.newBackOff()
+	}
+
+	var (
+		res     *httpResult
+		err     error
+		retries int
+	)
+	for {
+		if h.circuitBreaker != nil {
+			err = h.circuitBreaker.Allow()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		res, err = h.doRequest(parentCtx, method, u, req)
+		if h.circuitBreaker != nil {
+			h.circuitBreaker.Record(!h.isFailure(res, err))
+		}
+
+		if bo == nil || parentCtx.Err() != nil || !h.isRetriable(res, err) {
+			break
+		}
+		delay := bo.NextBackOff()
+		if delay == backoff.Stop {
+			break
+		}
+		if ra, ok := retryAfter(res, h.clock.Now()); ok {
+			delay = ra
+			if delay > h.metadata.RetryMaxInterval {
+				delay = h.metadata.RetryMaxInterval
+			}
+		}
+
+		h.logger.Debugf("Retrying HTTP request to %s in %v (attempt %d of %d)", u, delay, retries+1, h.metadata.MaxRetries)
+		t := h.clock.NewTimer(delay)
+		select {
+		case <-parentCtx.Done():
+			t.Stop()
+			return nil, parentCtx.Err()
+		case <-t.C():
+		}
+		retries++
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string, len(res.header)+3)
+	// Include status code & desc
+	metadata["statusCode"] = strconv.Itoa(res.statusCode)
+	metadata["status"] = res.status
+	if h.metadata.MaxRetries > 0 {
+		metadata[RetryAttemptsMetadataKey] = strconv.Itoa(retries)
+	}
+
+	// Response headers are mapped from `map[string][]string` to `map[string]string`
+	// where headers with multiple values are delimited with ", ".
+	for key, values := range res.header {
+		metadata[key] = strings.Join(values, ", ")
+	}
+
+	// Create an error for non-200 status codes unless suppressed.
+	if errorIfNot2XX && res.statusCode/100 != 2 {
+		err = fmt.Errorf("received status code %d", res.statusCode)
+	}
+
+	return &bindings.InvokeResponse{
+		Data:     res.body,
+		Metadata: metadata,
+	}, err
+}
+
+// httpResult contains the result of a single HTTP request, with the body already read.
+type httpResult struct {
+	statusCode int
+	status     string
+	header     http.Header
+	body       []byte
+}
+
+// doRequest performs a single HTTP request and reads the response.
+func (h *HTTPSource) doRequest(parentCtx context.Context, method string, u string, req *bindings.InvokeRequest) (*httpResult, error) {
+	var body io.Reader
+	switch method {
+	case "PUT", "POST", "PATCH":
+		body = bytes.NewReader(req.Data)
+	}
+
 	ctx := parentCtx
 	if h.metadata.ResponseTimeout != nil {
 		var cancel context.CancelFunc
@@ -343,26 +490,70 @@ func (h *HTTPSource) Invoke(parentCtx context.Context, req *bindings.InvokeReque
 		return nil, err
 	}
 
-	metadata := make(map[string]string, len(resp.Header)+2)
-	// Include status code & desc
-	metadata["statusCode"] = strconv.Itoa(resp.StatusCode)
-	metadata["status"] = resp.Status
+	return &httpResult{
+		statusCode: resp.StatusCode,
+		status:     resp.Status,
+		header:     resp.Header,
+		body:       b,
+	}, nil
+}
 
-	// Response headers are mapped from `map[string][]string` to `map[string]string`
-	// where headers with multiple values are delimited with ", ".
-	for key, values := range resp.Header {
-		metadata[key] = strings.Join(values, ", ")
+// isIdempotentMethod returns true if requests with the method can be safely retried.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS", "TRACE":
+		return true
+	default:
+		return false
 	}
+}
 
-	// Create an error for non-200 status codes unless suppressed.
-	if errorIfNot2XX && resp.StatusCode/100 != 2 {
-		err = fmt.Errorf("received status code %d", resp.StatusCode)
+// isRetriable returns true if the result of a request should be retried.
+func (h *HTTPSource) isRetriable(res *httpResult, err error) bool {
+	if err != nil {
+		return true
 	}
+	_, ok := h.retryStatusCodes[res.statusCode]
+	return ok
+}
 
-	return &bindings.InvokeResponse{
-		Data:     b,
-		Metadata: metadata,
-	}, err
+// isFailure returns true if the result of a request counts as a failure for the circuit breaker.
+func (h *HTTPSource) isFailure(res *httpResult, err error) bool {
+	return err != nil || res.statusCode >= 500 || h.isRetriable(res, nil)
+}
+
+// newBackOff returns an exponential backoff with jitter for retrying requests.
+func (h *HTTPSource) newBackOff() backoff.BackOff {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = h.metadata.RetryInitialInterval
+	bo.MaxInterval = h.metadata.RetryMaxInterval
+	bo.MaxElapsedTime = 0
+	bo.Clock = h.clock
+	bo.Reset()
+	return backoff.WithMaxRetries(bo, uint64(h.metadata.MaxRetries))
+}
+
+// retryAfter returns the delay requested by the server in the Retry-After header, if any.
+// The header can contain either a number of seconds or an HTTP date.
+func retryAfter(res *httpResult, now time.Time) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	val := res.header.Get("Retry-After")
+	if val == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(val); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
 }
 
 // GetComponentMetadata returns the metadata of the component.
@@ -25,11 +25,13 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/metadata"
@@ -761,3 +763,147 @@ func TestMaxBodySizeHonored(t *testing.T) {
 	// Should have only read 1KB
 	assert.Len(t, response.Data, 1<<10)
 }
+
+func TestRetries(t *testing.T) {
+	var attempts atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := attempts.Add(1)
+		if r.Header.Get("X-Retry-After") != "" {
+			w.Header().Set("Retry-After", r.Header.Get("X-Retry-After"))
+		}
+		if int(n) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, map[string]string{
+		"maxRetries":           "3",
+		"retryInitialInterval": "1ms",
+		"retryMaxInterval":     "10ms",
+	})
+	require.NoError(t, err)
+
+	t.Run("idempotent method is retried", func(t *testing.T) {
+		attempts.Store(0)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		require.NoError(t, err)
+		assert.Equal(t, "200", res.Metadata["statusCode"])
+		assert.Equal(t, "2", res.Metadata[RetryAttemptsMetadataKey])
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("non-idempotent method is not retried", func(t *testing.T) {
+		attempts.Store(0)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "post", Data: []byte("{}")})
+		require.Error(t, err)
+		assert.Equal(t, "503", res.Metadata["statusCode"])
+		assert.Equal(t, "0", res.Metadata[RetryAttemptsMetadataKey])
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("retry-after is capped at max interval", func(t *testing.T) {
+		attempts.Store(0)
+		start := time.Now()
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: "get",
+			Metadata:  map[string]string{"X-Retry-After": "60"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "2", res.Metadata[RetryAttemptsMetadataKey])
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("max retries exhausted", func(t *testing.T) {
+		hs, err := InitBinding(s, map[string]string{
+			"maxRetries":           "1",
+			"retryInitialInterval": "1ms",
+		})
+		require.NoError(t, err)
+
+		attempts.Store(0)
+		res, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		require.Error(t, err)
+		assert.Equal(t, "503", res.Metadata["statusCode"])
+		assert.Equal(t, "1", res.Metadata[RetryAttemptsMetadataKey])
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("invalid status codes", func(t *testing.T) {
+		_, err := InitBinding(s, map[string]string{"retryStatusCodes": "503,foo"})
+		require.Error(t, err)
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := retryAfter(&httpResult{header: http.Header{"Retry-After": []string{"5"}}}, now)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, d)
+
+	d, ok = retryAfter(&httpResult{header: http.Header{"Retry-After": []string{now.Add(10 * time.Second).Format(http.TimeFormat)}}}, now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+
+	_, ok = retryAfter(&httpResult{header: http.Header{}}, now)
+	assert.False(t, ok)
+
+	_, ok = retryAfter(nil, now)
+	assert.False(t, ok)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		attempts atomic.Int32
+		healthy  atomic.Bool
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	clk := clocktesting.NewFakeClock(time.Now())
+	hs := NewHTTPWithClock(logger.NewLogger("test"), clk)
+	err := hs.Init(context.Background(), bindings.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"url":                            s.URL,
+			"circuitBreakerFailureThreshold": "2",
+			"circuitBreakerTimeout":          "10s",
+		},
+	}})
+	require.NoError(t, err)
+
+	invoke := func() error {
+		_, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+		return err
+	}
+
+	// Two consecutive failures open the circuit
+	require.Error(t, invoke())
+	require.Error(t, invoke())
+	require.ErrorIs(t, invoke(), ErrCircuitOpen)
+	assert.Equal(t, int32(2), attempts.Load())
+
+	// After the timeout a trial request is sent; it fails so the circuit re-opens
+	clk.Step(11 * time.Second)
+	err = invoke()
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrCircuitOpen)
+	require.ErrorIs(t, invoke(), ErrCircuitOpen)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// A successful trial request closes the circuit
+	healthy.Store(true)
+	clk.Step(11 * time.Second)
+	require.NoError(t, invoke())
+	require.NoError(t, invoke())
+	assert.Equal(t, int32(5), attempts.Load())
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+// evalRequest is the body of a request for the eval operation.
+type evalRequest struct {
+	Keys []string `json:"keys"`
+	Args []any    `json:"args"`
+}
+
+// streamMessage is a message returned by the xrange operation.
+type streamMessage struct {
+	ID     string         `json:"id"`
+	Values map[string]any `json:"values"`
+}
+
+func (r *Redis) invokeHash(ctx context.Context, req *bindings.InvokeRequest, key string) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case HSetOperation:
+		args := []any{"HSET", key}
+		if field := req.Metadata["field"]; field != "" {
+			// Set a single field to the raw request data
+			args = append(args, field, req.Data)
+		} else {
+			fields, err := parseFields(req.Data)
+			if err != nil {
+				return nil, err
+			}
+			for f, v := range fields {
+				args = append(args, f, v)
+			}
+		}
+		err := r.client.DoWrite(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+		return nil, r.expireKeyIfRequested(ctx, req.Metadata, key)
+	case HGetOperation:
+		field := req.Metadata["field"]
+		if field == "" {
+			return nil, errors.New("redis binding: missing field in request metadata")
+		}
+		res, err := r.client.DoRead(ctx, "HGET", key, field)
+		if err != nil {
+			if isNil(err) {
+				return &bindings.InvokeResponse{}, nil
+			}
+			return nil, err
+		}
+		return &bindings.InvokeResponse{Data: []byte(fmt.Sprint(res))}, nil
+	default: // HGetAllOperation
+		res, err := r.client.HGetAllResult(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResponse(res)
+	}
+}
+
+func (r *Redis) invokeList(ctx context.Context, req *bindings.InvokeRequest, key string) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case LPushOperation:
+		err := r.client.DoWrite(ctx, "LPUSH", key, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, r.expireKeyIfRequested(ctx, req.Metadata, key)
+	case RPopOperation:
+		res, err := r.client.DoRead(ctx, "RPOP", key)
+		if err != nil {
+			if isNil(err) {
+				return &bindings.InvokeResponse{}, nil
+			}
+			return nil, err
+		}
+		return &bindings.InvokeResponse{Data: []byte(fmt.Sprint(res))}, nil
+	default: // LRangeOperation
+		start, err := parseInt(req.Metadata, "start", 0)
+		if err != nil {
+			return nil, err
+		}
+		stop, err := parseInt(req.Metadata, "stop", -1)
+		if err != nil {
+			return nil, err
+		}
+		res, err := r.client.LRangeResult(ctx, key, start, stop)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResponse(res)
+	}
+}
+
+func (r *Redis) invokeStream(ctx context.Context, req *bindings.InvokeRequest, key string) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case XAddOperation:
+		fields, err := parseFields(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return nil, errors.New("redis binding: xadd requires at least one field")
+		}
+		maxLen, err := parseInt(req.Metadata, "maxLenApprox", 0)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]any, len(fields))
+		for f, v := range fields {
+			values[f] = v
+		}
+		id, err := r.client.XAdd(ctx, key, maxLen, values)
+		if err != nil {
+			return nil, err
+		}
+		err = r.expireKeyIfRequested(ctx, req.Metadata, key)
+		if err != nil {
+			return nil, err
+		}
+		return &bindings.InvokeResponse{
+			Data:     []byte(id),
+			Metadata: map[string]string{"id": id},
+		}, nil
+	default: // XRangeOperation
+		start := req.Metadata["start"]
+		if start == "" {
+			start = "-"
+		}
+		end := req.Metadata["end"]
+		if end == "" {
+			end = "+"
+		}
+		count, err := parseInt(req.Metadata, "count", 0)
+		if err != nil {
+			return nil, err
+		}
+		res, err := r.client.XRangeResult(ctx, key, start, end, count)
+		if err != nil {
+			return nil, err
+		}
+		messages := make([]streamMessage, len(res))
+		for i, m := range res {
+			messages[i] = streamMessage{ID: m.ID, Values: m.Values}
+		}
+		return jsonResponse(messages)
+	}
+}
+
+func (r *Redis) invokeSet(ctx context.Context, req *bindings.InvokeRequest, key string) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case SAddOperation:
+		var members []any
+		err := json.Unmarshal(req.Data, &members)
+		if err != nil || len(members) == 0 {
+			return nil, errors.New("redis binding: sadd requires a non-empty JSON array of members in the request data")
+		}
+		args := []any{"SADD", key}
+		for _, m := range members {
+			args = append(args, stringValue(m))
+		}
+		err = r.client.DoWrite(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+		return nil, r.expireKeyIfRequested(ctx, req.Metadata, key)
+	default: // SMembersOperation
+		res, err := r.client.SMembersResult(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return jsonResponse(res)
+	}
+}
+
+func (r *Redis) eval(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	name := req.Metadata["script"]
+	script, ok := r.luaScripts[name]
+	if !ok {
+		return nil, fmt.Errorf("redis binding: script '%s' is not configured", name)
+	}
+
+	var evalReq evalRequest
+	if len(req.Data) > 0 {
+		err := json.Unmarshal(req.Data, &evalReq)
+		if err != nil {
+			return nil, fmt.Errorf("redis binding: invalid eval request: %w", err)
+		}
+	}
+	args := make([]any, len(evalReq.Args))
+	for i, a := range evalReq.Args {
+		args[i] = stringValue(a)
+	}
+
+	res, err := r.client.EvalResult(ctx, script, evalReq.Keys, args...)
+	if err != nil {
+		if isNil(err) {
+			return &bindings.InvokeResponse{}, nil
+		}
+		return nil, err
+	}
+	return jsonResponse(normalizeResult(res))
+}
+
+// parseFields parses a JSON object from the request data into field/value pairs.
+func parseFields(data []byte) (map[string]string, error) {
+	var obj map[string]any
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, errors.New("redis binding: request data must be a JSON object of fields and values")
+	}
+	fields := make(map[string]string, len(obj))
+	for f, v := range obj {
+		fields[f] = stringValue(v)
+	}
+	return fields, nil
+}
+
+// stringValue returns strings as-is and other JSON values in their JSON encoding.
+func stringValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// normalizeResult converts a result returned by the client into a value that can be encoded as JSON.
+// With RESP3, maps can be returned with non-string keys.
+func normalizeResult(v any) any {
+	switch t := v.(type) {
+	case []any:
+		res := make([]any, len(t))
+		for i, e := range t {
+			res[i] = normalizeResult(e)
+		}
+		return res
+	case map[any]any:
+		res := make(map[string]any, len(t))
+		for k, e := range t {
+			res[fmt.Sprint(k)] = normalizeResult(e)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func parseInt(md map[string]string, key string, defaultValue int64) (int64, error) {
+	val := md[key]
+	if val == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("redis binding: invalid value for %s: %w", key, err)
+	}
+	return i, nil
+}
+
+func jsonResponse(v any) (*bindings.InvokeResponse, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	contentType := "application/json"
+	return &bindings.InvokeResponse{
+		Data:        b,
+		ContentType: &contentType,
+	}, nil
+}
+
+func isNil(err error) bool {
+	return err != nil && err.Error() == "redis: nil"
+}
@@ -15,6 +15,7 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -29,12 +30,31 @@ import (
 type Redis struct {
 	client         rediscomponent.RedisClient
 	clientSettings *rediscomponent.Settings
+	luaScripts     map[string]string
 	logger         logger.Logger
 }
 
 const (
 	// IncrementOperation is the operation to increment a key.
 	IncrementOperation bindings.OperationKind = "increment"
+	// Operations on hashes.
+	HSetOperation    bindings.OperationKind = "hset"
+	HGetOperation    bindings.OperationKind = "hget"
+	HGetAllOperation bindings.OperationKind = "hgetall"
+	// Operations on lists.
+	LPushOperation  bindings.OperationKind = "lpush"
+	RPopOperation   bindings.OperationKind = "rpop"
+	LRangeOperation bindings.OperationKind = "lrange"
+	// Operations on streams.
+	XAddOperation   bindings.OperationKind = "xadd"
+	XRangeOperation bindings.OperationKind = "xrange"
+	// Operations on sets.
+	SAddOperation     bindings.OperationKind = "sadd"
+	SMembersOperation bindings.OperationKind = "smembers"
+	// EvalOperation runs one of the Lua scripts configured in the component.
+	EvalOperation bindings.OperationKind = "eval"
+
+	luaScriptsKey = "luaScripts"
 )
 
 // NewRedis returns a new redis bindings instance.
@@ -44,6 +64,13 @@ func NewRedis(logger logger.Logger) bindings.OutputBinding {
 
 // Init performs metadata parsing and connection creation.
 func (r *Redis) Init(ctx context.Context, meta bindings.Metadata) (err error) {
+	if val := meta.Properties[luaScriptsKey]; val != "" {
+		err = json.Unmarshal([]byte(val), &r.luaScripts)
+		if err != nil {
+			return fmt.Errorf("redis binding: invalid value for %s, must be a JSON object mapping script names to scripts: %w", luaScriptsKey, err)
+		}
+	}
+
 	r.client, r.clientSettings, err = rediscomponent.ParseClientFromProperties(meta.Properties, metadata.BindingType)
 	if err != nil {
 		return err
@@ -71,6 +98,17 @@ func (r *Redis) Operations() []bindings.OperationKind {
 		bindings.DeleteOperation,
 		bindings.GetOperation,
 		IncrementOperation,
+		HSetOperation,
+		HGetOperation,
+		HGetAllOperation,
+		LPushOperation,
+		RPopOperation,
+		LRangeOperation,
+		XAddOperation,
+		XRangeOperation,
+		SAddOperation,
+		SMembersOperation,
+		EvalOperation,
 	}
 }
 
@@ -90,6 +128,11 @@ func (r *Redis) expireKeyIfRequested(ctx context.Context, requestMetadata map[st
 }
 
 func (r *Redis) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	// Scripts declare the keys they access in the request body
+	if req.Operation == EvalOperation {
+		return r.eval(ctx, req)
+	}
+
 	if key, ok := req.Metadata["key"]; ok && key != "" {
 		switch req.Operation {
 		case bindings.DeleteOperation:
@@ -132,6 +175,14 @@ func (r *Redis) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 			if err != nil {
 				return nil, err
 			}
+		case HSetOperation, HGetOperation, HGetAllOperation:
+			return r.invokeHash(ctx, req, key)
+		case LPushOperation, RPopOperation, LRangeOperation:
+			return r.invokeList(ctx, req, key)
+		case XAddOperation, XRangeOperation:
+			return r.invokeStream(ctx, req, key)
+		case SAddOperation, SMembersOperation:
+			return r.invokeSet(ctx, req, key)
 		default:
 			return nil, fmt.Errorf("invalid operation type: %s", req.Operation)
 		}
@@ -15,12 +15,15 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
 	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	internalredis "github.com/dapr/components-contrib/internal/component/redis"
@@ -255,3 +258,214 @@ func setupMiniredis() (*miniredis.Miniredis, internalredis.RedisClient) {
 
 	return s, internalredis.ClientFromV8Client(redis.NewClient(opts))
 }
+
+func TestHashOperations(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	bind := &Redis{
+		client: c,
+		logger: logger.NewLogger("test"),
+	}
+
+	_, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte(`{"name":"dapr","stars":1000}`),
+		Metadata:  map[string]string{"key": "hash"},
+		Operation: HSetOperation,
+	})
+	require.NoError(t, err)
+
+	_, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte("cncf"),
+		Metadata:  map[string]string{"key": "hash", "field": "org"},
+		Operation: HSetOperation,
+	})
+	require.NoError(t, err)
+
+	res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "hash", "field": "stars"},
+		Operation: HGetOperation,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1000", string(res.Data))
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "hash", "field": "missing"},
+		Operation: HGetOperation,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, res.Data)
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "hash"},
+		Operation: HGetAllOperation,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"dapr","stars":"1000","org":"cncf"}`, string(res.Data))
+
+	_, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte(`not json`),
+		Metadata:  map[string]string{"key": "hash"},
+		Operation: HSetOperation,
+	})
+	require.Error(t, err)
+}
+
+func TestListOperations(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	bind := &Redis{
+		client: c,
+		logger: logger.NewLogger("test"),
+	}
+
+	for _, v := range []string{"a", "b", "c"} {
+		_, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Data:      []byte(v),
+			Metadata:  map[string]string{"key": "list"},
+			Operation: LPushOperation,
+		})
+		require.NoError(t, err)
+	}
+
+	res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "list"},
+		Operation: LRangeOperation,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["c","b","a"]`, string(res.Data))
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "list", "start": "0", "stop": "0"},
+		Operation: LRangeOperation,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["c"]`, string(res.Data))
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "list"},
+		Operation: RPopOperation,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(res.Data))
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "empty"},
+		Operation: RPopOperation,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, res.Data)
+}
+
+func TestStreamOperations(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	bind := &Redis{
+		client: c,
+		logger: logger.NewLogger("test"),
+	}
+
+	ids := make([]string, 3)
+	for i := range ids {
+		res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Data:      []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			Metadata:  map[string]string{"key": "stream"},
+			Operation: XAddOperation,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, res.Data)
+		assert.Equal(t, string(res.Data), res.Metadata["id"])
+		ids[i] = string(res.Data)
+	}
+
+	res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "stream"},
+		Operation: XRangeOperation,
+	})
+	require.NoError(t, err)
+	var messages []streamMessage
+	require.NoError(t, json.Unmarshal(res.Data, &messages))
+	require.Len(t, messages, 3)
+	assert.Equal(t, ids[0], messages[0].ID)
+	assert.Equal(t, "0", messages[0].Values["n"])
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "stream", "start": ids[1], "count": "1"},
+		Operation: XRangeOperation,
+	})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(res.Data, &messages))
+	require.Len(t, messages, 1)
+	assert.Equal(t, ids[1], messages[0].ID)
+}
+
+func TestSetOperations(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	bind := &Redis{
+		client: c,
+		logger: logger.NewLogger("test"),
+	}
+
+	_, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte(`["a","b","a"]`),
+		Metadata:  map[string]string{"key": "set"},
+		Operation: SAddOperation,
+	})
+	require.NoError(t, err)
+
+	res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"key": "set"},
+		Operation: SMembersOperation,
+	})
+	require.NoError(t, err)
+	var members []string
+	require.NoError(t, json.Unmarshal(res.Data, &members))
+	assert.ElementsMatch(t, []string{"a", "b"}, members)
+
+	_, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte(`[]`),
+		Metadata:  map[string]string{"key": "set"},
+		Operation: SAddOperation,
+	})
+	require.Error(t, err)
+}
+
+func TestEvalOperation(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	bind := &Redis{
+		client: c,
+		logger: logger.NewLogger("test"),
+		luaScripts: map[string]string{
+			"incrBy": "return redis.call('INCRBY', KEYS[1], ARGV[1])",
+			"pair":   "return {KEYS[1], ARGV[1]}",
+		},
+	}
+
+	res, err := bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte(`{"keys":["counter"],"args":[5]}`),
+		Metadata:  map[string]string{"script": "incrBy"},
+		Operation: EvalOperation,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "5", string(res.Data))
+
+	res, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Data:      []byte(`{"keys":["k"],"args":["v"]}`),
+		Metadata:  map[string]string{"script": "pair"},
+		Operation: EvalOperation,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["k","v"]`, string(res.Data))
+
+	_, err = bind.Invoke(context.TODO(), &bindings.InvokeRequest{
+		Metadata:  map[string]string{"script": "unknown"},
+		Operation: EvalOperation,
+	})
+	require.Error(t, err)
+}
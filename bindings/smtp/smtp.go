@@ -14,13 +14,21 @@ limitations under the License.
 package smtp
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net"
+	"net/smtp"
 	"reflect"
 	"strconv"
 	"strings"
+	texttemplate "text/template"
+	"time"
 
 	"gopkg.in/gomail.v2"
 
@@ -35,12 +43,25 @@ const (
 	lowestPriority  = 1
 	highestPriority = 5
 	mailSeparator   = ";"
+
+	// TLS modes.
+	// "auto" uses implicit TLS on port 465 and STARTTLS when the server supports it otherwise.
+	tlsModeAuto     = "auto"
+	tlsModeStartTLS = "starttls"
+	tlsModeImplicit = "implicit"
+
+	// Formats of the request body.
+	// "html" sends the body as-is as a HTML email; "json" parses the body as an emailRequest.
+	bodyFormatHTML = "html"
+	bodyFormatJSON = "json"
 )
 
 // Mailer allows sending of emails using the Simple Mail Transfer Protocol.
 type Mailer struct {
-	metadata Metadata
-	logger   logger.Logger
+	metadata     Metadata
+	htmlTemplate *htmltemplate.Template
+	textTemplate *texttemplate.Template
+	logger       logger.Logger
 }
 
 // Metadata holds standard email properties.
@@ -56,6 +77,32 @@ type Metadata struct {
 	EmailBCC      string `mapstructure:"emailBCC"`
 	Subject       string `mapstructure:"subject"`
 	Priority      int    `mapstructure:"priority"`
+	TLSMode       string `mapstructure:"tlsMode"`
+	BodyFormat    string `mapstructure:"bodyFormat"`
+	HTMLTemplate  string `mapstructure:"htmlTemplate"`
+	TextTemplate  string `mapstructure:"textTemplate"`
+}
+
+// emailRequest is the body of a request with the "json" body format.
+type emailRequest struct {
+	// Plain text and HTML bodies. If both are set, the email is sent as multipart/alternative.
+	Text string `json:"text"`
+	HTML string `json:"html"`
+	// Data used to render the templates configured in the component, when Text or HTML are empty.
+	Data any `json:"data"`
+	// Files attached to the email.
+	Attachments []emailFile `json:"attachments"`
+	// Images embedded in the email, referenced in the HTML body as "cid:<contentId>".
+	InlineImages []emailFile `json:"inlineImages"`
+}
+
+// emailFile is an attachment or inline image.
+type emailFile struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	ContentID   string `json:"contentId"`
+	// Content is base64-encoded in JSON.
+	Content []byte `json:"content"`
 }
 
 // NewSMTP returns a new smtp binding instance.
@@ -72,6 +119,19 @@ func (s *Mailer) Init(_ context.Context, metadata bindings.Metadata) error {
 	}
 	s.metadata = meta
 
+	if meta.HTMLTemplate != "" {
+		s.htmlTemplate, err = htmltemplate.New("html").Parse(meta.HTMLTemplate)
+		if err != nil {
+			return fmt.Errorf("smtp binding error: invalid htmlTemplate: %w", err)
+		}
+	}
+	if meta.TextTemplate != "" {
+		s.textTemplate, err = texttemplate.New("text").Parse(meta.TextTemplate)
+		if err != nil {
+			return fmt.Errorf("smtp binding error: invalid textTemplate: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -111,24 +171,21 @@ func (s *Mailer) Invoke(_ context.Context, req *bindings.InvokeRequest) (*bindin
 	msg.SetHeader("Subject", metadata.Subject)
 	msg.SetHeader("X-priority", strconv.Itoa(metadata.Priority))
 
-	body, err := strconv.Unquote(string(req.Data))
-
-	if err != nil {
-		// When data arrives over gRPC it's not quoted. Unquoting the original data will result in an error.
-		// Instead of unquoting it we'll just use the raw string as that one's already in the right format.
-
-		msg.SetBody("text/html", string(req.Data))
-	} else {
-		msg.SetBody("text/html", body)
+	switch metadata.BodyFormat {
+	case "", bodyFormatHTML:
+		msg.SetBody("text/html", string(requestBody(req.Data)))
+	case bodyFormatJSON:
+		err = s.setJSONBody(msg, requestBody(req.Data))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("smtp binding error: invalid bodyFormat '%s'", metadata.BodyFormat)
 	}
 
 	// Send message
-	dialer := gomail.NewDialer(metadata.Host, metadata.Port, metadata.User, metadata.Password)
-	if metadata.SkipTLSVerify {
-		/* #nosec */
-		dialer.TLSConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-	if err := dialer.DialAndSend(msg); err != nil {
+	err = metadata.send(msg)
+	if err != nil {
 		return nil, fmt.Errorf("error from smtp binding, sending email failed: %+v", err)
 	}
 
@@ -167,6 +224,15 @@ func (s *Mailer) parseMetadata(meta bindings.Metadata) (Metadata, error) {
 		s.logger.Warn("smtp binding warning: Skip TLS Verification is enabled. This is insecure and is NOT recommended for production scenarios.")
 	}
 
+	switch strings.ToLower(smtpMeta.TLSMode) {
+	case "":
+		smtpMeta.TLSMode = tlsModeAuto
+	case tlsModeAuto, tlsModeStartTLS, tlsModeImplicit:
+		smtpMeta.TLSMode = strings.ToLower(smtpMeta.TLSMode)
+	default:
+		return smtpMeta, fmt.Errorf("smtp binding error: invalid tlsMode '%s'", smtpMeta.TLSMode)
+	}
+
 	err = smtpMeta.parsePriority(meta.Properties["priority"])
 
 	if err != nil {
@@ -200,6 +266,10 @@ func (metadata Metadata) mergeWithRequestMetadata(req *bindings.InvokeRequest) (
 		merged.Subject = subject
 	}
 
+	if bodyFormat := req.Metadata["bodyFormat"]; bodyFormat != "" {
+		merged.BodyFormat = bodyFormat
+	}
+
 	if priority := req.Metadata["priority"]; priority != "" {
 		err := merged.parsePriority(priority)
 		if err != nil {
@@ -227,6 +297,171 @@ func (metadata *Metadata) parsePriority(req string) error {
 	return nil
 }
 
+// requestBody returns the body of the request, unquoted if needed.
+func requestBody(data []byte) []byte {
+	body, err := strconv.Unquote(string(data))
+	if err != nil {
+		// When data arrives over gRPC it's not quoted. Unquoting the original data will result in an error.
+		// Instead of unquoting it we'll just use the raw string as that one's already in the right format.
+		return data
+	}
+	return []byte(body)
+}
+
+// setJSONBody sets the bodies, attachments and inline images of the message from a JSON emailRequest.
+func (s *Mailer) setJSONBody(msg *gomail.Message, data []byte) error {
+	var req emailRequest
+	err := json.Unmarshal(data, &req)
+	if err != nil {
+		return fmt.Errorf("smtp binding error: invalid JSON request body: %w", err)
+	}
+
+	if req.Text == "" && s.textTemplate != nil {
+		var buf bytes.Buffer
+		err = s.textTemplate.Execute(&buf, req.Data)
+		if err != nil {
+			return fmt.Errorf("smtp binding error: failed to render textTemplate: %w", err)
+		}
+		req.Text = buf.String()
+	}
+	if req.HTML == "" && s.htmlTemplate != nil {
+		var buf bytes.Buffer
+		err = s.htmlTemplate.Execute(&buf, req.Data)
+		if err != nil {
+			return fmt.Errorf("smtp binding error: failed to render htmlTemplate: %w", err)
+		}
+		req.HTML = buf.String()
+	}
+
+	switch {
+	case req.Text != "" && req.HTML != "":
+		msg.SetBody("text/plain", req.Text)
+		msg.AddAlternative("text/html", req.HTML)
+	case req.HTML != "":
+		msg.SetBody("text/html", req.HTML)
+	case req.Text != "":
+		msg.SetBody("text/plain", req.Text)
+	default:
+		return errors.New("smtp binding error: request has no text or html body")
+	}
+
+	for _, f := range req.Attachments {
+		if f.Filename == "" {
+			return errors.New("smtp binding error: attachment filename is required")
+		}
+		msg.Attach(f.Filename, f.settings(false)...)
+	}
+	for _, f := range req.InlineImages {
+		if f.Filename == "" || f.ContentID == "" {
+			return errors.New("smtp binding error: inline image filename and contentId are required")
+		}
+		msg.Embed(f.Filename, f.settings(true)...)
+	}
+
+	return nil
+}
+
+// settings returns the gomail settings to attach or embed the file from memory.
+func (f emailFile) settings(inline bool) []gomail.FileSetting {
+	header := map[string][]string{}
+	if f.ContentType != "" {
+		header["Content-Type"] = []string{f.ContentType}
+	}
+	if inline {
+		header["Content-ID"] = []string{"<" + f.ContentID + ">"}
+	}
+	content := f.Content
+	return []gomail.FileSetting{
+		gomail.SetHeader(header),
+		gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		}),
+	}
+}
+
+// send sends the message using the configured TLS mode.
+func (metadata Metadata) send(msg *gomail.Message) error {
+	if metadata.TLSMode == tlsModeStartTLS {
+		return metadata.sendWithStartTLS(msg)
+	}
+
+	dialer := gomail.NewDialer(metadata.Host, metadata.Port, metadata.User, metadata.Password)
+	if metadata.TLSMode == tlsModeImplicit {
+		dialer.SSL = true
+	}
+	if metadata.SkipTLSVerify {
+		/* #nosec */
+		dialer.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return dialer.DialAndSend(msg)
+}
+
+// sendWithStartTLS sends the message, failing if the server does not support STARTTLS.
+// gomail only uses STARTTLS opportunistically, so the SMTP session is handled here.
+func (metadata Metadata) sendWithStartTLS(msg *gomail.Message) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(metadata.Host, strconv.Itoa(metadata.Port)), 10*time.Second)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, metadata.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return errors.New("server does not support STARTTLS")
+	}
+	tlsConfig := &tls.Config{
+		ServerName: metadata.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if metadata.SkipTLSVerify {
+		/* #nosec */
+		tlsConfig.InsecureSkipVerify = true
+	}
+	err = c.StartTLS(tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	if metadata.User != "" {
+		err = c.Auth(smtp.PlainAuth("", metadata.User, metadata.Password, metadata.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = gomail.Send(gomail.SendFunc(func(from string, to []string, m io.WriterTo) error {
+		err := c.Mail(from)
+		if err != nil {
+			return err
+		}
+		for _, addr := range to {
+			err = c.Rcpt(addr)
+			if err != nil {
+				return err
+			}
+		}
+		w, err := c.Data()
+		if err != nil {
+			return err
+		}
+		_, err = m.WriteTo(w)
+		if err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}), msg)
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
 func (metadata Metadata) parseAddresses(addresses string) []string {
 	return strings.Split(addresses, mailSeparator)
 }
@@ -14,9 +14,30 @@ limitations under the License.
 package smtp
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/kit/logger"
@@ -320,3 +341,310 @@ func TestMergeWithRequestMetadata_invalidPriorityNotNumber(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 }
+
+// testSMTPServer is a minimal SMTP server that records the messages it receives.
+type testSMTPServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	startTLS  bool
+	messages  chan string
+}
+
+func newTestSMTPServer(t *testing.T, implicitTLS bool, startTLS bool) *testSMTPServer {
+	t.Helper()
+
+	cert := generateTestCertificate(t)
+	srv := &testSMTPServer{
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		startTLS:  startTLS,
+		messages:  make(chan string, 10),
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if implicitTLS {
+		ln = tls.NewListener(ln, srv.tlsConfig)
+	}
+	srv.ln = ln
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.handle(conn)
+		}
+	}()
+
+	return srv
+}
+
+func (srv *testSMTPServer) port() string {
+	_, port, _ := net.SplitHostPort(srv.ln.Addr().String())
+	return port
+}
+
+func (srv *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			if srv.startTLS {
+				tp.PrintfLine("250-localhost")
+				tp.PrintfLine("250 STARTTLS")
+			} else {
+				tp.PrintfLine("250 localhost")
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, srv.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			srv.messages <- string(data)
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func generateTestCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func initTestMailer(t *testing.T, srv *testSMTPServer, props map[string]string) bindings.OutputBinding {
+	t.Helper()
+
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"host":          "127.0.0.1",
+		"port":          srv.port(),
+		"skipTLSVerify": "true",
+		"emailFrom":     "from@dapr.io",
+		"emailTo":       "to@dapr.io",
+		"subject":       "Test email",
+	}
+	for k, v := range props {
+		m.Properties[k] = v
+	}
+	mailer := NewSMTP(logger.NewLogger("test"))
+	require.NoError(t, mailer.Init(context.Background(), m))
+	return mailer
+}
+
+func receiveTestMessage(t *testing.T, srv *testSMTPServer) *mail.Message {
+	t.Helper()
+
+	select {
+	case data := <-srv.messages:
+		msg, err := mail.ReadMessage(strings.NewReader(data))
+		require.NoError(t, err)
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	t.Run("html body", func(t *testing.T) {
+		srv := newTestSMTPServer(t, false, false)
+		mailer := initTestMailer(t, srv, nil)
+
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data: []byte(`"<b>Hello</b>"`),
+		})
+		require.NoError(t, err)
+
+		msg := receiveTestMessage(t, srv)
+		assert.Equal(t, "Test email", msg.Header.Get("Subject"))
+		assert.Contains(t, msg.Header.Get("Content-Type"), "text/html")
+		body, _ := io.ReadAll(msg.Body)
+		assert.Contains(t, string(body), "<b>Hello</b>")
+	})
+
+	t.Run("json body with alternatives, attachments and inline images", func(t *testing.T) {
+		srv := newTestSMTPServer(t, false, false)
+		mailer := initTestMailer(t, srv, nil)
+
+		req, _ := json.Marshal(emailRequest{
+			Text: "Hello",
+			HTML: `<p>Hello <img src="cid:logo"></p>`,
+			Attachments: []emailFile{
+				{Filename: "invoice.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4")},
+			},
+			InlineImages: []emailFile{
+				{Filename: "logo.png", ContentType: "image/png", ContentID: "logo", Content: []byte("PNG")},
+			},
+		})
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data:     req,
+			Metadata: map[string]string{"bodyFormat": "json"},
+		})
+		require.NoError(t, err)
+
+		msg := receiveTestMessage(t, srv)
+		parts := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+		assert.Contains(t, parts, "text/plain")
+		assert.Contains(t, parts, "text/html")
+		assert.Equal(t, "%PDF-1.4", parts["application/pdf"].content)
+		assert.Contains(t, parts["application/pdf"].header.Get("Content-Disposition"), `filename="invoice.pdf"`)
+		assert.Equal(t, "PNG", parts["image/png"].content)
+		assert.Equal(t, "<logo>", parts["image/png"].header.Get("Content-ID"))
+	})
+
+	t.Run("json body with templates", func(t *testing.T) {
+		srv := newTestSMTPServer(t, false, false)
+		mailer := initTestMailer(t, srv, map[string]string{
+			"bodyFormat":   "json",
+			"textTemplate": "Hello {{ .name }}",
+			"htmlTemplate": "<p>Hello {{ .name }}</p>",
+		})
+
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data: []byte(`{"data":{"name":"<Dapr>"}}`),
+		})
+		require.NoError(t, err)
+
+		msg := receiveTestMessage(t, srv)
+		parts := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+		assert.Equal(t, "Hello <Dapr>", parts["text/plain"].content)
+		// html/template escapes the data
+		assert.Equal(t, "<p>Hello &lt;Dapr&gt;</p>", parts["text/html"].content)
+	})
+
+	t.Run("json body without content", func(t *testing.T) {
+		srv := newTestSMTPServer(t, false, false)
+		mailer := initTestMailer(t, srv, map[string]string{"bodyFormat": "json"})
+
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data: []byte(`{}`),
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("starttls required", func(t *testing.T) {
+		srv := newTestSMTPServer(t, false, true)
+		mailer := initTestMailer(t, srv, map[string]string{"tlsMode": "starttls"})
+
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data: []byte("Hello"),
+		})
+		require.NoError(t, err)
+		receiveTestMessage(t, srv)
+	})
+
+	t.Run("starttls not supported by server", func(t *testing.T) {
+		srv := newTestSMTPServer(t, false, false)
+		mailer := initTestMailer(t, srv, map[string]string{"tlsMode": "starttls"})
+
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data: []byte("Hello"),
+		})
+		require.ErrorContains(t, err, "STARTTLS")
+	})
+
+	t.Run("implicit tls", func(t *testing.T) {
+		srv := newTestSMTPServer(t, true, false)
+		mailer := initTestMailer(t, srv, map[string]string{"tlsMode": "implicit"})
+
+		_, err := mailer.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data: []byte("Hello"),
+		})
+		require.NoError(t, err)
+		receiveTestMessage(t, srv)
+	})
+
+	t.Run("invalid tls mode", func(t *testing.T) {
+		r := Mailer{logger: logger.NewLogger("test")}
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"host":    "mailserver.dapr.io",
+			"port":    "25",
+			"tlsMode": "foo",
+		}
+		_, err := r.parseMetadata(m)
+		require.Error(t, err)
+	})
+}
+
+type testPart struct {
+	header  textproto.MIMEHeader
+	content string
+}
+
+// readParts reads all leaf parts of a multipart message, indexed by media type.
+func readParts(t *testing.T, contentType string, body io.Reader) map[string]testPart {
+	t.Helper()
+
+	res := map[string]testPart{}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(mediaType, "multipart/"), "expected multipart message, got %s", mediaType)
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		partType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		if strings.HasPrefix(partType, "multipart/") {
+			for k, v := range readParts(t, p.Header.Get("Content-Type"), p) {
+				res[k] = v
+			}
+			continue
+		}
+
+		var r io.Reader = p
+		switch p.Header.Get("Content-Transfer-Encoding") {
+		case "base64":
+			r = base64.NewDecoder(base64.StdEncoding, p)
+		case "quoted-printable":
+			r = quotedprintable.NewReader(p)
+		}
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		res[partType] = testPart{header: p.Header, content: string(content)}
+	}
+	return res
+}
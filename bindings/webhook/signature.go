@@ -0,0 +1,253 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	signatureSchemeNone   = "none"
+	signatureSchemeHMAC   = "hmac"
+	signatureSchemeGitHub = "github"
+	signatureSchemeStripe = "stripe"
+
+	gitHubSignatureHeader = "X-Hub-Signature-256"
+	stripeSignatureHeader = "Stripe-Signature"
+)
+
+var (
+	errMissingSignature = errors.New("missing signature")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+// verifier verifies the signature of incoming requests.
+type verifier interface {
+	// Verify returns an error if the request is not authentic.
+	// If the request is authentic, it returns a key that uniquely identifies the request, used to detect replays.
+	Verify(header http.Header, body []byte, now time.Time) (replayKey string, err error)
+}
+
+func newVerifier(md webhookMetadata) (verifier, error) {
+	scheme := strings.ToLower(md.SignatureScheme)
+	if scheme != signatureSchemeNone && md.Secret == "" {
+		return nil, fmt.Errorf("secret is required for signature scheme '%s'", scheme)
+	}
+
+	switch scheme {
+	case signatureSchemeNone:
+		return noneVerifier{}, nil
+	case signatureSchemeHMAC:
+		return &hmacVerifier{
+			secret:          []byte(md.Secret),
+			signatureHeader: md.SignatureHeader,
+			timestampHeader: md.TimestampHeader,
+			tolerance:       md.TimestampTolerance,
+		}, nil
+	case signatureSchemeGitHub:
+		return &gitHubVerifier{
+			secret: []byte(md.Secret),
+		}, nil
+	case signatureSchemeStripe:
+		return &stripeVerifier{
+			secret:    []byte(md.Secret),
+			tolerance: md.TimestampTolerance,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid signature scheme: %s", md.SignatureScheme)
+	}
+}
+
+// noneVerifier accepts all requests.
+type noneVerifier struct{}
+
+func (noneVerifier) Verify(http.Header, []byte, time.Time) (string, error) {
+	return "", nil
+}
+
+// hmacVerifier verifies a generic HMAC-SHA256 signature, encoded as hex, of the payload "<timestamp>.<body>".
+// The timestamp is in UNIX seconds.
+type hmacVerifier struct {
+	secret          []byte
+	signatureHeader string
+	timestampHeader string
+	tolerance       time.Duration
+}
+
+func (v *hmacVerifier) Verify(header http.Header, body []byte, now time.Time) (string, error) {
+	sig := strings.TrimPrefix(header.Get(v.signatureHeader), "sha256=")
+	if sig == "" {
+		return "", errMissingSignature
+	}
+	ts := header.Get(v.timestampHeader)
+	err := checkTimestamp(ts, now, v.tolerance)
+	if err != nil {
+		return "", err
+	}
+
+	mac, ok := verifySignature(v.secret, sig, []byte(ts), []byte{'.'}, body)
+	if !ok {
+		return "", errInvalidSignature
+	}
+	return mac, nil
+}
+
+// gitHubVerifier verifies signatures of GitHub webhooks.
+// GitHub does not sign a timestamp or the delivery ID, so replays are detected using the signature, and only within the retention window of the replay cache.
+type gitHubVerifier struct {
+	secret []byte
+}
+
+func (v *gitHubVerifier) Verify(header http.Header, body []byte, _ time.Time) (string, error) {
+	sig, ok := strings.CutPrefix(header.Get(gitHubSignatureHeader), "sha256=")
+	if !ok || sig == "" {
+		return "", errMissingSignature
+	}
+	mac, ok := verifySignature(v.secret, sig, body)
+	if !ok {
+		return "", errInvalidSignature
+	}
+	return mac, nil
+}
+
+// stripeVerifier verifies signatures of Stripe webhooks.
+// The header has the format "t=<timestamp>,v1=<signature>[,v1=<signature>...]".
+type stripeVerifier struct {
+	secret    []byte
+	tolerance time.Duration
+}
+
+func (v *stripeVerifier) Verify(header http.Header, body []byte, now time.Time) (string, error) {
+	var (
+		ts   string
+		sigs []string
+	)
+	for _, part := range strings.Split(header.Get(stripeSignatureHeader), ",") {
+		k, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = val
+		case "v1":
+			sigs = append(sigs, val)
+		}
+	}
+	if len(sigs) == 0 {
+		return "", errMissingSignature
+	}
+	err := checkTimestamp(ts, now, v.tolerance)
+	if err != nil {
+		return "", err
+	}
+
+	for _, sig := range sigs {
+		if mac, ok := verifySignature(v.secret, sig, []byte(ts), []byte{'.'}, body); ok {
+			return ts + "." + mac, nil
+		}
+	}
+	return "", errInvalidSignature
+}
+
+// verifySignature returns true if sig is the hex-encoded HMAC-SHA256 of the concatenated parts.
+// It also returns the MAC in canonical (lower-case hex) encoding, which is used as replay key, since signatures are accepted in any case.
+func verifySignature(secret []byte, sig string, parts ...[]byte) (string, bool) {
+	expect, err := hex.DecodeString(sig)
+	if err != nil {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, secret)
+	for _, p := range parts {
+		mac.Write(p)
+	}
+	sum := mac.Sum(nil)
+	if !hmac.Equal(sum, expect) {
+		return "", false
+	}
+	return hex.EncodeToString(sum), true
+}
+
+// checkTimestamp returns an error if the UNIX timestamp ts is not within tolerance of now.
+func checkTimestamp(ts string, now time.Time, tolerance time.Duration) error {
+	if ts == "" {
+		return errors.New("missing timestamp")
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	diff := now.Sub(time.Unix(sec, 0))
+	if diff > tolerance || diff < -tolerance {
+		return errors.New("timestamp is outside of the tolerance window")
+	}
+	return nil
+}
+
+// replayCache records the keys of requests that were received recently.
+type replayCache struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	lock      sync.Mutex
+	seen      map[string]time.Time
+	nextSweep time.Time
+}
+
+func newReplayCache(tolerance time.Duration, clk clock.Clock) *replayCache {
+	return &replayCache{
+		// Timestamps are accepted within tolerance in either direction, so keys must be retained for twice as long
+		ttl:   2 * tolerance,
+		clock: clk,
+		seen:  make(map[string]time.Time),
+	}
+}
+
+// Add records the key and returns false if it had already been seen.
+func (c *replayCache) Add(key string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock.Now()
+	if now.After(c.nextSweep) {
+		for k, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, k)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+
+	if exp, ok := c.seen[key]; ok && !now.After(exp) {
+		return false
+	}
+	c.seen[key] = now.Add(c.ttl)
+	return true
+}
+
+// Remove forgets the key, so the request can be received again.
+func (c *replayCache) Remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.seen, key)
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/components-contrib/bindings"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+const (
+	defaultAddress                 = ":8080"
+	defaultPath                    = "/"
+	defaultSignatureHeader         = "X-Signature"
+	defaultTimestampHeader         = "X-Timestamp"
+	defaultTimestampTolerance      = 5 * time.Minute
+	defaultMaxRequestBodySizeBytes = 4 << 20 // 4 MB
+)
+
+// Binding is an input binding that receives webhooks over HTTP.
+type Binding struct {
+	metadata webhookMetadata
+	verifier verifier
+	replays  *replayCache
+	logger   logger.Logger
+	clk      clock.Clock
+
+	listener net.Listener
+	closed   atomic.Bool
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+}
+
+type webhookMetadata struct {
+	// Address to listen on, in the format "host:port".
+	Address string `mapstructure:"address"`
+	// Path to receive webhooks on. Requests on other paths are rejected with 404.
+	Path string `mapstructure:"path"`
+	// Shared secret used to verify signatures.
+	Secret string `mapstructure:"secret"`
+	// Signature scheme: "hmac" (default), "github", "stripe" or "none".
+	SignatureScheme string `mapstructure:"signatureScheme"`
+	// Names of the headers with the signature and timestamp for the "hmac" scheme.
+	SignatureHeader string `mapstructure:"signatureHeader"`
+	TimestampHeader string `mapstructure:"timestampHeader"`
+	// Maximum allowed difference between the request's timestamp and the current time.
+	TimestampTolerance time.Duration `mapstructure:"timestampTolerance"`
+	// Maximum size of the request body.
+	// This can either be an integer which is interpreted in bytes, or a string with an added unit such as Mi.
+	MaxRequestBodySize kitmd.ByteSize `mapstructure:"maxRequestBodySize"`
+
+	maxRequestBodySizeBytes int64
+}
+
+// NewWebhook returns a new webhook input binding.
+func NewWebhook(logger logger.Logger) bindings.InputBinding {
+	return NewWebhookWithClock(logger, clock.RealClock{})
+}
+
+// NewWebhookWithClock returns a new webhook input binding that uses the given clock.
+func NewWebhookWithClock(logger logger.Logger, clk clock.Clock) bindings.InputBinding {
+	return &Binding{
+		logger:  logger,
+		clk:     clk,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Init performs metadata parsing.
+func (b *Binding) Init(_ context.Context, meta bindings.Metadata) (err error) {
+	b.metadata = webhookMetadata{
+		Address:            defaultAddress,
+		Path:               defaultPath,
+		SignatureScheme:    signatureSchemeHMAC,
+		SignatureHeader:    defaultSignatureHeader,
+		TimestampHeader:    defaultTimestampHeader,
+		TimestampTolerance: defaultTimestampTolerance,
+		MaxRequestBodySize: kitmd.NewByteSize(defaultMaxRequestBodySizeBytes),
+	}
+	err = kitmd.DecodeMetadata(meta.Properties, &b.metadata)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(b.metadata.Path, "/") {
+		b.metadata.Path = "/" + b.metadata.Path
+	}
+	if b.metadata.TimestampTolerance <= 0 {
+		return errors.New("invalid value for timestampTolerance: must be greater than zero")
+	}
+	b.metadata.maxRequestBodySizeBytes, err = b.metadata.MaxRequestBodySize.GetBytes()
+	if err != nil {
+		return fmt.Errorf("invalid value for maxRequestBodySize: %w", err)
+	}
+
+	b.verifier, err = newVerifier(b.metadata)
+	if err != nil {
+		return err
+	}
+	b.replays = newReplayCache(b.metadata.TimestampTolerance, b.clk)
+
+	return nil
+}
+
+// Read starts the HTTP server and invokes the handler for each webhook received.
+func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
+	if b.closed.Load() {
+		return errors.New("binding is closed")
+	}
+
+	ln, err := net.Listen("tcp", b.metadata.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", b.metadata.Address, err)
+	}
+	b.listener = ln
+
+	mux := http.NewServeMux()
+	mux.Handle(b.metadata.Path, b.requestHandler(ctx, handler))
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Run the server in background
+	b.wg.Add(2)
+	go func() {
+		defer b.wg.Done()
+		b.logger.Infof("Listening for webhooks at http://%s%s", ln.Addr().String(), b.metadata.Path)
+		srvErr := srv.Serve(ln)
+		if srvErr != nil && !errors.Is(srvErr, http.ErrServerClosed) {
+			b.logger.Errorf("Error starting server: %v", srvErr)
+		}
+	}()
+	// Close the server when context is canceled or binding closed.
+	go func() {
+		defer b.wg.Done()
+		select {
+		case <-ctx.Done():
+		case <-b.closeCh:
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srvErr := srv.Shutdown(shutdownCtx)
+		if srvErr != nil {
+			b.logger.Errorf("Error shutting down server: %v", srvErr)
+		}
+	}()
+
+	return nil
+}
+
+// Returns the handler for the HTTP server.
+func (b *Binding) requestHandler(ctx context.Context, handler bindings.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != b.metadata.Path {
+			http.Error(w, "404 Not found", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if b.metadata.maxRequestBodySizeBytes > 0 {
+			body = http.MaxBytesReader(w, r.Body, b.metadata.maxRequestBodySizeBytes)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		// Verify the signature, then reject requests that have already been received
+		replayKey, err := b.verifier.Verify(r.Header, data, b.clk.Now())
+		if err != nil {
+			b.logger.Debugf("Rejected webhook: %v", err)
+			http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if replayKey != "" && !b.replays.Add(replayKey) {
+			b.logger.Debugf("Rejected replayed webhook")
+			http.Error(w, "409 Conflict", http.StatusConflict)
+			return
+		}
+
+		// Headers are mapped from `map[string][]string` to `map[string]string`
+		// where headers with multiple values are delimited with ", ".
+		metadata := make(map[string]string, len(r.Header)+3)
+		for key, values := range r.Header {
+			metadata[key] = strings.Join(values, ", ")
+		}
+		metadata["method"] = r.Method
+		metadata["path"] = r.URL.Path
+		metadata["query"] = r.URL.RawQuery
+
+		readResponse := &bindings.ReadResponse{
+			Data:     data,
+			Metadata: metadata,
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			readResponse.ContentType = &ct
+		}
+
+		res, err := handler(ctx, readResponse)
+		if err != nil {
+			// Allow the sender to retry the request
+			if replayKey != "" {
+				b.replays.Remove(replayKey)
+			}
+			b.logger.Errorf("Error from app handling webhook: %v", err)
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(res) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(res)
+		if err != nil {
+			b.logger.Errorf("Error writing response: %v", err)
+		}
+	})
+}
+
+func (b *Binding) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		close(b.closeCh)
+	}
+	b.wg.Wait()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (b *Binding) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := webhookMetadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.BindingType)
+	return
+}
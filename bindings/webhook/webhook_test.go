@@ -0,0 +1,289 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const testSecret = "s3cr3t"
+
+func sign(parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	for _, p := range parts {
+		mac.Write([]byte(p))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func startBinding(t *testing.T, clk *clocktesting.FakeClock, props map[string]string, handler bindings.Handler) string {
+	t.Helper()
+
+	m := bindings.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"address": "127.0.0.1:0",
+			"path":    "/hook",
+			"secret":  testSecret,
+		},
+	}}
+	for k, v := range props {
+		m.Properties[k] = v
+	}
+
+	b := NewWebhookWithClock(logger.NewLogger("test"), clk)
+	require.NoError(t, b.Init(context.Background(), m))
+	require.NoError(t, b.Read(context.Background(), handler))
+	t.Cleanup(func() {
+		require.NoError(t, b.Close())
+	})
+
+	return "http://" + b.(*Binding).listener.Addr().String()
+}
+
+func post(t *testing.T, url string, body string, header map[string]string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	resBody, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(resBody)
+}
+
+func TestInit(t *testing.T) {
+	tests := map[string]struct {
+		props map[string]string
+		err   string
+	}{
+		"defaults":       {props: map[string]string{"secret": testSecret}},
+		"missing secret": {props: map[string]string{}, err: "secret is required"},
+		"no signature":   {props: map[string]string{"signatureScheme": "none"}},
+		"invalid scheme": {props: map[string]string{"secret": testSecret, "signatureScheme": "foo"}, err: "invalid signature scheme"},
+		"invalid tolerance": {
+			props: map[string]string{"secret": testSecret, "timestampTolerance": "0"},
+			err:   "invalid value for timestampTolerance",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := NewWebhook(logger.NewLogger("test"))
+			err := b.Init(context.Background(), bindings.Metadata{Base: metadata.Base{Properties: tc.props}})
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestHMACWebhook(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	var received *bindings.ReadResponse
+	url := startBinding(t, clk, map[string]string{"maxRequestBodySize": "1Ki"}, func(_ context.Context, rr *bindings.ReadResponse) ([]byte, error) {
+		received = rr
+		if string(rr.Data) == "fail" {
+			return nil, errors.New("app error")
+		}
+		if string(rr.Data) == "empty" {
+			return nil, nil
+		}
+		return []byte("ok"), nil
+	})
+
+	signed := func(body string, ts time.Time) map[string]string {
+		tsStr := strconv.FormatInt(ts.Unix(), 10)
+		return map[string]string{
+			"X-Timestamp":  tsStr,
+			"X-Signature":  "sha256=" + sign(tsStr, ".", body),
+			"Content-Type": "application/json",
+			"X-Custom":     "foo",
+		}
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		status, body := post(t, url+"/hook?a=b", `{"hello":"world"}`, signed(`{"hello":"world"}`, clk.Now()))
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "ok", body)
+		require.NotNil(t, received)
+		assert.Equal(t, `{"hello":"world"}`, string(received.Data))
+		assert.Equal(t, "foo", received.Metadata["X-Custom"])
+		assert.Equal(t, "a=b", received.Metadata["query"])
+		assert.Equal(t, "/hook", received.Metadata["path"])
+		require.NotNil(t, received.ContentType)
+		assert.Equal(t, "application/json", *received.ContentType)
+	})
+
+	t.Run("replay is rejected", func(t *testing.T) {
+		h := signed("replay", clk.Now())
+		status, _ := post(t, url+"/hook", "replay", h)
+		assert.Equal(t, http.StatusOK, status)
+		status, _ = post(t, url+"/hook", "replay", h)
+		assert.Equal(t, http.StatusConflict, status)
+
+		// Changing the case of the signature doesn't bypass replay detection
+		h["X-Signature"] = "sha256=" + strings.ToUpper(strings.TrimPrefix(h["X-Signature"], "sha256="))
+		status, _ = post(t, url+"/hook", "replay", h)
+		assert.Equal(t, http.StatusConflict, status)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		h := signed("foo", clk.Now())
+		status, _ := post(t, url+"/hook", "bar", h)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		status, _ := post(t, url+"/hook", "foo", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("expired timestamp", func(t *testing.T) {
+		status, _ := post(t, url+"/hook", "foo", signed("foo", clk.Now().Add(-10*time.Minute)))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("app error", func(t *testing.T) {
+		h := signed("fail", clk.Now())
+		status, _ := post(t, url+"/hook", "fail", h)
+		assert.Equal(t, http.StatusInternalServerError, status)
+
+		// Retries of failed requests are not rejected as replays
+		status, _ = post(t, url+"/hook", "fail", h)
+		assert.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("empty app response", func(t *testing.T) {
+		status, _ := post(t, url+"/hook", "empty", signed("empty", clk.Now()))
+		assert.Equal(t, http.StatusNoContent, status)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		large := string(bytes.Repeat([]byte{'a'}, 2048))
+		status, _ := post(t, url+"/hook", large, signed(large, clk.Now()))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	})
+
+	t.Run("wrong path", func(t *testing.T) {
+		status, _ := post(t, url+"/other", "foo", signed("foo", clk.Now()))
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		res, err := http.Get(url + "/hook")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	url := startBinding(t, clk, map[string]string{"signatureScheme": "github"}, func(context.Context, *bindings.ReadResponse) ([]byte, error) {
+		return nil, nil
+	})
+
+	h := map[string]string{
+		"X-Hub-Signature-256": "sha256=" + sign("payload"),
+		"X-GitHub-Delivery":   "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+	}
+	status, _ := post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// The delivery ID is not signed, so changing it doesn't bypass replay detection
+	h["X-GitHub-Delivery"] = "a7c9a7d0-cc78-11e3-81ab-4c9367dc0958"
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Neither does changing the case of the signature
+	h["X-Hub-Signature-256"] = "sha256=" + strings.ToUpper(sign("payload"))
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Replays are detected only within the retention window of the replay cache
+	clk.Step(11 * time.Minute)
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	h["X-Hub-Signature-256"] = "sha256=" + sign("other")
+	h["X-GitHub-Delivery"] = "other"
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestStripeWebhook(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	url := startBinding(t, clk, map[string]string{"signatureScheme": "stripe"}, func(context.Context, *bindings.ReadResponse) ([]byte, error) {
+		return nil, nil
+	})
+
+	ts := strconv.FormatInt(clk.Now().Unix(), 10)
+	h := map[string]string{
+		"Stripe-Signature": "t=" + ts + ",v1=" + sign("nope") + ",v1=" + sign(ts, ".", "payload"),
+	}
+	status, _ := post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	// Replays are rejected, even if the case of the signature is changed
+	h["Stripe-Signature"] = "t=" + ts + ",v1=" + strings.ToUpper(sign(ts, ".", "payload"))
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusConflict, status)
+
+	h["Stripe-Signature"] = "t=" + ts + ",v0=" + sign(ts, ".", "payload")
+	status, _ = post(t, url+"/hook", "payload", h)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestReplayCache(t *testing.T) {
+	clk := clocktesting.NewFakeClock(time.Now())
+	c := newReplayCache(time.Minute, clk)
+
+	assert.True(t, c.Add("a"))
+	assert.False(t, c.Add("a"))
+	assert.True(t, c.Add("b"))
+
+	c.Remove("b")
+	assert.True(t, c.Add("b"))
+
+	clk.Step(3 * time.Minute)
+	assert.True(t, c.Add("a"))
+	assert.Len(t, c.seen, 1)
+}
@@ -0,0 +1,433 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/dapr/components-contrib/configuration"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// Delay before reloading the file after a change, so files that are written in multiple steps are read once complete.
+const reloadDelay = 100 * time.Millisecond
+
+// ConfigurationStore is a configuration store that reads items from a local YAML or JSON file.
+// The file is watched for changes, and subscribers are notified of the items that were added, modified, or removed.
+//
+// The file contains an object where each property is a configuration item.
+// Items can be set to a scalar value, or to an object with the "value", "version", and "metadata" properties:
+//
+//	logLevel: debug
+//	featureFlag:
+//	  value: "true"
+//	  version: "2"
+//	  metadata:
+//	    owner: team-a
+type ConfigurationStore struct {
+	metadata      metadata
+	logger        logger.Logger
+	items         map[string]*configuration.Item
+	lock          sync.RWMutex
+	reloadLock    sync.Mutex
+	subscriptions map[string]*subscription
+	closed        atomic.Bool
+	closeCh       chan struct{}
+	wg            sync.WaitGroup
+}
+
+type subscription struct {
+	ctx     context.Context
+	keys    []string
+	handler configuration.UpdateHandler
+}
+
+// Item as stored in the file when it's an object.
+type fileItem struct {
+	Value    string            `json:"value" yaml:"value"`
+	Version  string            `json:"version" yaml:"version"`
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+}
+
+// NewFileConfigurationStore returns a new configuration store backed by a local file.
+func NewFileConfigurationStore(logger logger.Logger) configuration.Store {
+	return &ConfigurationStore{
+		logger:        logger,
+		subscriptions: make(map[string]*subscription),
+		closeCh:       make(chan struct{}),
+	}
+}
+
+// Init loads the configuration file and starts watching it for changes.
+func (s *ConfigurationStore) Init(_ context.Context, meta configuration.Metadata) error {
+	if s.closed.Load() {
+		return errors.New("component is closed")
+	}
+
+	err := s.metadata.InitWithMetadata(meta.Properties)
+	if err != nil {
+		return err
+	}
+
+	s.items, err = readConfigurationFile(s.metadata.Path)
+	if err != nil {
+		return err
+	}
+
+	// Watch the directory rather than the file, so changes are detected when the file is replaced
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	err = watcher.Add(filepath.Dir(s.metadata.Path))
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch configuration file: %w", err)
+	}
+
+	s.wg.Add(1)
+	go s.watchFile(watcher)
+
+	return nil
+}
+
+// Get returns the requested items, or all items if no key is specified.
+// The file is read on each request, so changes are visible before subscribers are notified.
+func (s *ConfigurationStore) Get(_ context.Context, req *configuration.GetRequest) (*configuration.GetResponse, error) {
+	all, err := readConfigurationFile(s.metadata.Path)
+	if err != nil {
+		// The file may be in the process of being replaced: use the last version that was loaded
+		s.logger.Warnf("Failed to read configuration file, using the last loaded items: %v", err)
+		s.lock.RLock()
+		all = s.items
+		s.lock.RUnlock()
+	}
+
+	items := make(map[string]*configuration.Item, len(req.Keys))
+	if len(req.Keys) == 0 {
+		for key, item := range all {
+			items[key] = cloneItem(item)
+		}
+	} else {
+		for _, key := range req.Keys {
+			if item, ok := all[key]; ok {
+				items[key] = cloneItem(item)
+			}
+		}
+	}
+
+	return &configuration.GetResponse{
+		Items: items,
+	}, nil
+}
+
+// Subscribe registers a handler that is invoked when the subscribed items change in the file.
+// If the list of keys is empty, the handler is invoked for changes to any item.
+func (s *ConfigurationStore) Subscribe(ctx context.Context, req *configuration.SubscribeRequest, handler configuration.UpdateHandler) (string, error) {
+	if s.closed.Load() {
+		return "", errors.New("component is closed")
+	}
+
+	subscribeUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate subscription id - %w", err)
+	}
+	subscribeID := subscribeUID.String()
+
+	// Load the latest version of the file first, so the new subscriber is notified only of changes made after subscribing
+	err = s.reload()
+	if err != nil {
+		s.logger.Warnf("Failed to reload configuration file: %v", err)
+	}
+
+	s.lock.Lock()
+	s.subscriptions[subscribeID] = &subscription{
+		ctx:     ctx,
+		keys:    req.Keys,
+		handler: handler,
+	}
+	s.lock.Unlock()
+
+	return subscribeID, nil
+}
+
+// Unsubscribe removes a subscription.
+func (s *ConfigurationStore) Unsubscribe(_ context.Context, req *configuration.UnsubscribeRequest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.subscriptions[req.ID]; !ok {
+		return fmt.Errorf("subscription with id %s does not exist", req.ID)
+	}
+	delete(s.subscriptions, req.ID)
+	return nil
+}
+
+// watchFile reloads the file when it changes, and notifies subscribers.
+// Should be invoked in a background goroutine.
+func (s *ConfigurationStore) watchFile(watcher *fsnotify.Watcher) {
+	defer s.wg.Done()
+	defer watcher.Close()
+
+	path := filepath.Clean(s.metadata.Path)
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-s.closeCh:
+			return
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Warnf("Error watching configuration file: %v", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == path && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) {
+				reload = time.After(reloadDelay)
+			}
+		case <-reload:
+			reload = nil
+			err := s.reload()
+			if err != nil {
+				// The file may be replaced in multiple steps: wait for the next event
+				s.logger.Warnf("Failed to reload configuration file: %v", err)
+			}
+		}
+	}
+}
+
+// reload reads the file again and notifies subscribers of the items that changed.
+func (s *ConfigurationStore) reload() error {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	items, err := readConfigurationFile(s.metadata.Path)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	changed := diffItems(s.items, items)
+	s.items = items
+	subs := make(map[string]*subscription, len(s.subscriptions))
+	for id, sub := range s.subscriptions {
+		subs[id] = sub
+	}
+	s.lock.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	for id, sub := range subs {
+		if sub.ctx.Err() != nil {
+			// The subscription's context was canceled
+			s.lock.Lock()
+			delete(s.subscriptions, id)
+			s.lock.Unlock()
+			continue
+		}
+
+		e := &configuration.UpdateEvent{
+			ID:    id,
+			Items: make(map[string]*configuration.Item, len(changed)),
+		}
+		for key, item := range changed {
+			if len(sub.keys) == 0 || slices.Contains(sub.keys, key) {
+				e.Items[key] = cloneItem(item)
+			}
+		}
+		if len(e.Items) == 0 {
+			continue
+		}
+		err = sub.handler(sub.ctx, e)
+		if err != nil {
+			s.logger.Errorf("Failed to call handler to notify event for configuration update subscribe: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close stops watching the configuration file.
+func (s *ConfigurationStore) Close() error {
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.closeCh)
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (s *ConfigurationStore) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := metadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.ConfigurationStoreType)
+	return
+}
+
+// diffItems returns the items that were added or modified in current, and an empty item for each item that was removed.
+func diffItems(previous, current map[string]*configuration.Item) map[string]*configuration.Item {
+	changed := make(map[string]*configuration.Item)
+	for key, item := range current {
+		if !reflect.DeepEqual(previous[key], item) {
+			changed[key] = item
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			changed[key] = &configuration.Item{}
+		}
+	}
+	return changed
+}
+
+// readConfigurationFile reads the items from the file, parsing it as JSON if it has the ".json" extension, or as YAML otherwise.
+func readConfigurationFile(path string) (map[string]*configuration.Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	var items map[string]*configuration.Item
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		items, err = parseJSON(data)
+	} else {
+		items, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+	}
+	return items, nil
+}
+
+func parseJSON(data []byte) (map[string]*configuration.Item, error) {
+	items := map[string]*configuration.Item{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return items, nil
+	}
+
+	var doc map[string]json.RawMessage
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	for key, raw := range doc {
+		raw = bytes.TrimSpace(raw)
+		switch {
+		case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+			continue
+		case raw[0] == '{':
+			var fi fileItem
+			err = json.Unmarshal(raw, &fi)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item '%s': %w", key, err)
+			}
+			items[key] = fi.toItem()
+		case raw[0] == '"':
+			var value string
+			err = json.Unmarshal(raw, &value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item '%s': %w", key, err)
+			}
+			items[key] = &configuration.Item{Value: value, Metadata: map[string]string{}}
+		case raw[0] == '[':
+			return nil, fmt.Errorf("invalid item '%s': arrays are not supported", key)
+		default:
+			// Numbers and booleans
+			items[key] = &configuration.Item{Value: string(raw), Metadata: map[string]string{}}
+		}
+	}
+	return items, nil
+}
+
+func parseYAML(data []byte) (map[string]*configuration.Item, error) {
+	items := map[string]*configuration.Item{}
+
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		// Empty document
+		return items, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("the document must be an object")
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		node := root.Content[i+1]
+		switch {
+		case node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+			continue
+		case node.Kind == yaml.ScalarNode:
+			items[key] = &configuration.Item{Value: node.Value, Metadata: map[string]string{}}
+		case node.Kind == yaml.MappingNode:
+			var fi fileItem
+			err = node.Decode(&fi)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item '%s': %w", key, err)
+			}
+			items[key] = fi.toItem()
+		default:
+			return nil, fmt.Errorf("invalid item '%s': must be a scalar value or an object", key)
+		}
+	}
+	return items, nil
+}
+
+func (fi fileItem) toItem() *configuration.Item {
+	if fi.Metadata == nil {
+		fi.Metadata = map[string]string{}
+	}
+	return &configuration.Item{
+		Value:    fi.Value,
+		Version:  fi.Version,
+		Metadata: fi.Metadata,
+	}
+}
+
+func cloneItem(item *configuration.Item) *configuration.Item {
+	res := &configuration.Item{
+		Value:   item.Value,
+		Version: item.Version,
+	}
+	if item.Metadata != nil {
+		res.Metadata = make(map[string]string, len(item.Metadata))
+		for k, v := range item.Metadata {
+			res.Metadata[k] = v
+		}
+	}
+	return res
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/configuration"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func newStore(t *testing.T, path string) *ConfigurationStore {
+	t.Helper()
+	s := NewFileConfigurationStore(logger.NewLogger("test")).(*ConfigurationStore)
+	t.Cleanup(func() {
+		s.Close()
+	})
+	err := s.Init(context.Background(), configuration.Metadata{Base: contribMetadata.Base{
+		Properties: map[string]string{"path": path},
+	}})
+	require.NoError(t, err)
+	return s
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+	// Write the file atomically, so it's never read while partially written
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(data), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{
+			name: "YAML",
+			file: "config.yaml",
+			data: `
+logLevel: debug
+maxConns: 10
+featureFlag:
+  value: "true"
+  version: "2"
+  metadata:
+    owner: team-a
+`,
+		},
+		{
+			name: "JSON",
+			file: "config.json",
+			data: `{
+	"logLevel": "debug",
+	"maxConns": 10,
+	"featureFlag": {"value": "true", "version": "2", "metadata": {"owner": "team-a"}}
+}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeFile(t, path, tt.data)
+			s := newStore(t, path)
+
+			res, err := s.Get(context.Background(), &configuration.GetRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]*configuration.Item{
+				"logLevel":    {Value: "debug", Metadata: map[string]string{}},
+				"maxConns":    {Value: "10", Metadata: map[string]string{}},
+				"featureFlag": {Value: "true", Version: "2", Metadata: map[string]string{"owner": "team-a"}},
+			}, res.Items)
+
+			res, err = s.Get(context.Background(), &configuration.GetRequest{Keys: []string{"logLevel", "notfound"}})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]*configuration.Item{
+				"logLevel": {Value: "debug", Metadata: map[string]string{}},
+			}, res.Items)
+		})
+	}
+
+	t.Run("invalid file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeFile(t, path, "- a\n- b\n")
+		s := NewFileConfigurationStore(logger.NewLogger("test"))
+		err := s.Init(context.Background(), configuration.Metadata{Base: contribMetadata.Base{
+			Properties: map[string]string{"path": path},
+		}})
+		require.ErrorContains(t, err, "the document must be an object")
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		s := NewFileConfigurationStore(logger.NewLogger("test"))
+		err := s.Init(context.Background(), configuration.Metadata{Base: contribMetadata.Base{
+			Properties: map[string]string{},
+		}})
+		require.ErrorContains(t, err, "missing path")
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "a: 1\nb: 1\nc: 1\n")
+	s := newStore(t, path)
+
+	subscribe := func(keys ...string) (string, chan *configuration.UpdateEvent) {
+		ch := make(chan *configuration.UpdateEvent, 10)
+		id, err := s.Subscribe(context.Background(), &configuration.SubscribeRequest{Keys: keys}, func(ctx context.Context, e *configuration.UpdateEvent) error {
+			ch <- e
+			return nil
+		})
+		require.NoError(t, err)
+		return id, ch
+	}
+	receive := func(ch chan *configuration.UpdateEvent) *configuration.UpdateEvent {
+		select {
+		case e := <-ch:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update event")
+			return nil
+		}
+	}
+
+	idA, chA := subscribe("a")
+	idAll, chAll := subscribe()
+
+	// Modify a, remove b, add d; c is unchanged
+	writeFile(t, path, "a: 2\nc: 1\nd: 1\n")
+
+	e := receive(chA)
+	assert.Equal(t, idA, e.ID)
+	assert.Equal(t, map[string]*configuration.Item{
+		"a": {Value: "2", Metadata: map[string]string{}},
+	}, e.Items)
+
+	e = receive(chAll)
+	assert.Equal(t, idAll, e.ID)
+	assert.Equal(t, map[string]*configuration.Item{
+		"a": {Value: "2", Metadata: map[string]string{}},
+		"b": {},
+		"d": {Value: "1", Metadata: map[string]string{}},
+	}, e.Items)
+
+	res, err := s.Get(context.Background(), &configuration.GetRequest{Keys: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, "2", res.Items["a"].Value)
+
+	// Changes to other keys are not sent to subscriber A
+	require.NoError(t, s.Unsubscribe(context.Background(), &configuration.UnsubscribeRequest{ID: idAll}))
+	writeFile(t, path, "a: 2\nc: 2\nd: 1\n")
+	writeFile(t, path, "a: 3\nc: 2\nd: 1\n")
+	e = receive(chA)
+	assert.Equal(t, "3", e.Items["a"].Value)
+	assert.Empty(t, chAll)
+
+	require.Error(t, s.Unsubscribe(context.Background(), &configuration.UnsubscribeRequest{ID: idAll}))
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"errors"
+
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+type metadata struct {
+	// Path of the YAML or JSON file containing the configuration items.
+	Path string `mapstructure:"path"`
+}
+
+func (m *metadata) InitWithMetadata(meta map[string]string) error {
+	// Reset the object
+	m.Path = ""
+
+	err := kitmd.DecodeMetadata(meta, &m)
+	if err != nil {
+		return err
+	}
+
+	if m.Path == "" {
+		return errors.New("missing path of the configuration file")
+	}
+
+	return nil
+}
@@ -31,6 +31,7 @@ import (
 	"golang.org/x/exp/slices"
 
 	"github.com/dapr/components-contrib/configuration"
+	pginterfaces "github.com/dapr/components-contrib/internal/component/postgresql/interfaces"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
@@ -65,6 +66,8 @@ var (
 	allowedTableNameChars = regexp.MustCompile(`^[a-z0-9./_]*$`)
 )
 
+var _ configuration.StoreWriter = (*ConfigurationStore)(nil)
+
 func NewPostgresConfigurationStore(logger logger.Logger) configuration.Store {
 	return &ConfigurationStore{
 		logger:               logger,
@@ -375,6 +378,176 @@ func (p *ConfigurationStore) subscribeToChannel(ctx context.Context, pgNotifyCha
 	return subscribeID, nil
 }
 
+// Set creates or updates configuration items in a transaction.
+// The latest version of each item is updated in place, and new items are inserted.
+// Subscribers are notified by the triggers on the table.
+func (p *ConfigurationStore) Set(ctx context.Context, req *configuration.SetRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+	keys := req.SortedKeys()
+	err = validateInput(keys)
+	if err != nil {
+		return err
+	}
+	return p.executeInTransaction(ctx, func(tx pgx.Tx) error {
+		return setItems(ctx, tx, p.metadata.ConfigTable, keys, req)
+	})
+}
+
+// Delete removes all versions of configuration items in a transaction.
+// Subscribers are notified by the triggers on the table.
+func (p *ConfigurationStore) Delete(ctx context.Context, req *configuration.DeleteRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+	keys := req.SortedKeys()
+	err = validateInput(keys)
+	if err != nil {
+		return err
+	}
+	return p.executeInTransaction(ctx, func(tx pgx.Tx) error {
+		return deleteItems(ctx, tx, p.metadata.ConfigTable, keys, req.ExpectedVersions)
+	})
+}
+
+func (p *ConfigurationStore) executeInTransaction(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := p.client.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		rollbackErr := tx.Rollback(ctx)
+		if rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			p.logger.Errorf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func setItems(ctx context.Context, tx pginterfaces.DBQuerier, configTable string, keys []string, req *configuration.SetRequest) error {
+	for _, key := range keys {
+		item := req.Items[key]
+		if item == nil {
+			return fmt.Errorf("missing item for key '%s'", key)
+		}
+
+		current, exists, err := lockItem(ctx, tx, configTable, key)
+		if err != nil {
+			return err
+		}
+		if expected, ok := req.ExpectedVersions[key]; ok && !versionMatches(expected, current, exists) {
+			return fmt.Errorf("%w: key '%s'", configuration.ErrVersionMismatch, key)
+		}
+
+		version := item.Version
+		if version == "" {
+			version, err = nextVersion(current, exists)
+			if err != nil {
+				return fmt.Errorf("cannot set key '%s': %w", key, err)
+			}
+		}
+		metadata := item.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+
+		if exists {
+			_, err = tx.Exec(ctx, "UPDATE "+configTable+" SET VALUE = $1, VERSION = $2, METADATA = $3 WHERE KEY = $4 AND VERSION = $5", item.Value, version, metadata, key, current)
+		} else {
+			_, err = tx.Exec(ctx, "INSERT INTO "+configTable+" (KEY, VALUE, VERSION, METADATA) VALUES ($1, $2, $3, $4)", key, item.Value, version, metadata)
+		}
+		if err != nil {
+			return fmt.Errorf("error writing key '%s' to configuration store: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func deleteItems(ctx context.Context, tx pginterfaces.DBQuerier, configTable string, keys []string, expectedVersions map[string]string) error {
+	for _, key := range keys {
+		current, exists, err := lockItem(ctx, tx, configTable, key)
+		if err != nil {
+			return err
+		}
+		if expected, ok := expectedVersions[key]; ok && !versionMatches(expected, current, exists) {
+			return fmt.Errorf("%w: key '%s'", configuration.ErrVersionMismatch, key)
+		}
+		if !exists {
+			continue
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM "+configTable+" WHERE KEY = $1", key)
+		if err != nil {
+			return fmt.Errorf("error deleting key '%s' from configuration store: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// lockItem acquires a lock on the key for the duration of the transaction, then returns the current version of the item, and whether it exists.
+// An advisory lock is used so that concurrent transactions cannot insert the same key, which locking rows would not prevent.
+func lockItem(ctx context.Context, tx pginterfaces.DBQuerier, configTable string, key string) (string, bool, error) {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", configTable+"/"+key)
+	if err != nil {
+		return "", false, fmt.Errorf("error locking key '%s': %w", key, err)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT VERSION FROM "+configTable+" WHERE KEY = $1", key)
+	if err != nil {
+		return "", false, fmt.Errorf("error in querying configuration store: %w", err)
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", false, fmt.Errorf("error in reading data from configuration store: %w", err)
+	}
+	if len(versions) == 0 {
+		return "", false, nil
+	}
+
+	// Select the latest version like getUniqueItemPerKey does
+	latest := versions[0]
+	for _, v := range versions[1:] {
+		if getNumericVersion(v) > getNumericVersion(latest) {
+			latest = v
+		}
+	}
+	return latest, true, nil
+}
+
+// versionMatches returns true if the current version matches the expected one.
+// An empty expected version matches items that do not exist.
+func versionMatches(expected string, current string, exists bool) bool {
+	if expected == "" {
+		return !exists
+	}
+	return exists && expected == current
+}
+
+// nextVersion returns the version that follows the current one, which must be numeric.
+func nextVersion(current string, exists bool) (string, error) {
+	if !exists || current == "" {
+		return "1", nil
+	}
+	num, err := strconv.Atoi(current)
+	if err != nil {
+		return "", fmt.Errorf("current version '%s' is not numeric: the new version must be set explicitly", current)
+	}
+	return strconv.Itoa(num + 1), nil
+}
+
 // GetComponentMetadata returns the metadata of the component.
 func (p *ConfigurationStore) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
 	metadataStruct := metadata{}
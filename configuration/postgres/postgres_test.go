@@ -18,6 +18,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -116,3 +117,104 @@ func TestValidateInput(t *testing.T) {
 	keys3 := []string{"Name 1=1"}
 	assert.Error(t, validateInput(keys3), "invalid key : 'Name 1=1'")
 }
+
+func TestSetItems(t *testing.T) {
+	const lockQuery = "SELECT pg_advisory_xact_lock(hashtext($1))"
+	const versionQuery = "SELECT VERSION FROM cfgtbl WHERE KEY = $1"
+
+	newTx := func(t *testing.T) (pgxmock.PgxPoolIface, pgx.Tx) {
+		mock, err := pgxmock.NewPool()
+		require.NoError(t, err)
+		t.Cleanup(mock.Close)
+		mock.ExpectBegin()
+		tx, err := mock.Begin(context.Background())
+		require.NoError(t, err)
+		return mock, tx
+	}
+
+	t.Run("insert and update with automatic versions", func(t *testing.T) {
+		mock, tx := newTx(t)
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("cfgtbl/key1").
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WithArgs("key1").
+			WillReturnRows(pgxmock.NewRows([]string{"version"}).AddRow("1").AddRow("3").AddRow("2"))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cfgtbl SET VALUE = $1, VERSION = $2, METADATA = $3 WHERE KEY = $4 AND VERSION = $5")).
+			WithArgs("val1", "4", map[string]string{}, "key1", "3").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("cfgtbl/key2").
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WithArgs("key2").
+			WillReturnRows(pgxmock.NewRows([]string{"version"}))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO cfgtbl (KEY, VALUE, VERSION, METADATA) VALUES ($1, $2, $3, $4)")).
+			WithArgs("key2", "val2", "1", map[string]string{"a": "b"}).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		keys := []string{"key1", "key2"}
+		req := &configuration.SetRequest{
+			Items: map[string]*configuration.Item{
+				"key1": {Value: "val1"},
+				"key2": {Value: "val2", Metadata: map[string]string{"a": "b"}},
+			},
+			ExpectedVersions: map[string]string{
+				"key1": "3",
+				"key2": "",
+			},
+		}
+		require.NoError(t, req.Validate())
+		require.NoError(t, setItems(context.Background(), tx, "cfgtbl", keys, req))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		mock, tx := newTx(t)
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("cfgtbl/key1").
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WithArgs("key1").
+			WillReturnRows(pgxmock.NewRows([]string{"version"}).AddRow("2"))
+
+		err := setItems(context.Background(), tx, "cfgtbl", []string{"key1"}, &configuration.SetRequest{
+			Items:            map[string]*configuration.Item{"key1": {Value: "val1", Version: "5"}},
+			ExpectedVersions: map[string]string{"key1": "1"},
+		})
+		require.ErrorIs(t, err, configuration.ErrVersionMismatch)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("non-numeric version cannot be incremented", func(t *testing.T) {
+		mock, tx := newTx(t)
+		mock.ExpectExec(regexp.QuoteMeta(lockQuery)).WithArgs("cfgtbl/key1").
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WithArgs("key1").
+			WillReturnRows(pgxmock.NewRows([]string{"version"}).AddRow("v1"))
+
+		err := setItems(context.Background(), tx, "cfgtbl", []string{"key1"}, &configuration.SetRequest{
+			Items: map[string]*configuration.Item{"key1": {Value: "val1"}},
+		})
+		require.ErrorContains(t, err, "is not numeric")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteItems(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	mock.ExpectBegin()
+	tx, err := mock.Begin(context.Background())
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext($1))")).WithArgs("cfgtbl/key1").
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT VERSION FROM cfgtbl WHERE KEY = $1")).WithArgs("key1").
+		WillReturnRows(pgxmock.NewRows([]string{"version"}).AddRow("1"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM cfgtbl WHERE KEY = $1")).WithArgs("key1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext($1))")).WithArgs("cfgtbl/key2").
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT VERSION FROM cfgtbl WHERE KEY = $1")).WithArgs("key2").
+		WillReturnRows(pgxmock.NewRows([]string{"version"}))
+
+	err = deleteItems(context.Background(), tx, "cfgtbl", []string{"key1", "key2"}, map[string]string{"key1": "1", "key2": "1"})
+	require.ErrorIs(t, err, configuration.ErrVersionMismatch)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -38,8 +38,72 @@ const (
 	defaultBase               = 10
 	defaultBitSize            = 0
 	redisWrongTypeIdentifyStr = "WRONGTYPE"
+	separator                 = "||"
+
+	// Errors returned by the scripts
+	versionMismatchError = "VERSION_MISMATCH"
+	invalidVersionError  = "INVALID_VERSION"
+
+	// Returns the version from a value in the "value||version" format, like internal.GetRedisValueAndVersion.
+	currentVersionFunction = `local function currentVersion(raw)
+		local s = string.find(raw, "||", 1, true)
+		if s == nil then return "" end
+		local v = string.sub(raw, s + 2)
+		local e = string.find(v, "||", 1, true)
+		if e ~= nil then v = string.sub(v, 1, e - 1) end
+		return v
+	end
+	`
+
+	// KEYS: keys of the items to set
+	// ARGV: for each key, 4 values: "1" if the version must be checked, the expected version, the value, and the new version ("" to increment the current one)
+	setScript = currentVersionFunction + `local values = {}
+	for i, key in ipairs(KEYS) do
+		local base = (i - 1) * 4
+		local raw = redis.call("GET", key)
+		local version = nil
+		if raw then version = currentVersion(raw) end
+		if ARGV[base + 1] == "1" then
+			local expected = ARGV[base + 2]
+			if (expected == "" and version ~= nil) or (expected ~= "" and version ~= expected) then
+				return redis.error_reply("` + versionMismatchError + ` " .. key)
+			end
+		end
+		local newVersion = ARGV[base + 4]
+		if newVersion == "" then
+			if version == nil or version == "" then
+				newVersion = "1"
+			else
+				local n = tonumber(version)
+				if n == nil or n ~= math.floor(n) then
+					return redis.error_reply("` + invalidVersionError + ` " .. key)
+				end
+				newVersion = string.format("%d", n + 1)
+			end
+		end
+		table.insert(values, key)
+		table.insert(values, ARGV[base + 3] .. "||" .. newVersion)
+	end
+	redis.call("MSET", unpack(values))
+	return #KEYS`
+
+	// KEYS: keys of the items to delete
+	// ARGV: for each key, 2 values: "1" if the version must be checked, and the expected version
+	deleteScript = currentVersionFunction + `for i, key in ipairs(KEYS) do
+		local base = (i - 1) * 2
+		if ARGV[base + 1] == "1" then
+			local expected = ARGV[base + 2]
+			local raw = redis.call("GET", key)
+			if (expected == "" and raw) or (expected ~= "" and (not raw or currentVersion(raw) ~= expected)) then
+				return redis.error_reply("` + versionMismatchError + ` " .. key)
+			end
+		end
+	end
+	return redis.call("DEL", unpack(KEYS))`
 )
 
+var _ configuration.StoreWriter = (*ConfigurationStore)(nil)
+
 // ConfigurationStore is a Redis configuration store.
 type ConfigurationStore struct {
 	client               rediscomponent.RedisClient
@@ -244,6 +308,74 @@ func (r *ConfigurationStore) handleSubscribedChange(ctx context.Context, req *co
 	}
 }
 
+// Set creates or updates configuration items atomically, with a script.
+// Metadata of items is not stored.
+// Subscribers are notified by keyspace notifications.
+// When using Redis Cluster, all keys in the request must map to the same hash slot.
+func (r *ConfigurationStore) Set(ctx context.Context, req *configuration.SetRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+
+	keys := req.SortedKeys()
+	args := make([]interface{}, 0, 4*len(keys))
+	for _, key := range keys {
+		item := req.Items[key]
+		if strings.Contains(item.Value, separator) || strings.Contains(item.Version, separator) {
+			return fmt.Errorf("value and version of key '%s' cannot contain '%s'", key, separator)
+		}
+		expected, check := req.ExpectedVersions[key]
+		args = append(args, checkFlag(check), expected, item.Value, item.Version)
+	}
+
+	_, err = r.client.EvalResult(ctx, setScript, keys, args...)
+	return parseScriptError(err)
+}
+
+// Delete removes configuration items atomically, with a script.
+// Subscribers are notified by keyspace notifications.
+// When using Redis Cluster, all keys in the request must map to the same hash slot.
+func (r *ConfigurationStore) Delete(ctx context.Context, req *configuration.DeleteRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
+	}
+
+	keys := req.SortedKeys()
+	args := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		expected, check := req.ExpectedVersions[key]
+		args = append(args, checkFlag(check), expected)
+	}
+
+	_, err = r.client.EvalResult(ctx, deleteScript, keys, args...)
+	return parseScriptError(err)
+}
+
+func checkFlag(check bool) string {
+	if check {
+		return "1"
+	}
+	return "0"
+}
+
+// parseScriptError converts the errors returned by the scripts.
+func parseScriptError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, versionMismatchError+" "):
+		return fmt.Errorf("%w: key '%s'", configuration.ErrVersionMismatch, msg[len(versionMismatchError)+1:])
+	case strings.HasPrefix(msg, invalidVersionError+" "):
+		return fmt.Errorf("cannot set key '%s': current version is not numeric: the new version must be set explicitly", msg[len(invalidVersionError)+1:])
+	default:
+		return fmt.Errorf("error writing to configuration store: %w", err)
+	}
+}
+
 // GetComponentMetadata returns the metadata of the component.
 func (r *ConfigurationStore) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
 	metadataStruct := rediscomponent.Settings{}
@@ -21,6 +21,7 @@ import (
 	"github.com/alicebob/miniredis/v2"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	redisComponent "github.com/dapr/components-contrib/internal/component/redis"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
@@ -319,6 +320,112 @@ func Test_parseRedisMetadata(t *testing.T) {
 	}
 }
 
+func TestConfigurationStore_Write(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+	store := &ConfigurationStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+	}
+	ctx := context.Background()
+
+	t.Run("set with automatic versions", func(t *testing.T) {
+		require.NoError(t, s.Set("existing", "value||3"))
+		err := store.Set(ctx, &configuration.SetRequest{
+			Items: map[string]*configuration.Item{
+				"existing": {Value: "newValue"},
+				"new":      {Value: "value"},
+				"explicit": {Value: "value", Version: "v2"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "newValue||4", mustGet(t, s, "existing"))
+		assert.Equal(t, "value||1", mustGet(t, s, "new"))
+		assert.Equal(t, "value||v2", mustGet(t, s, "explicit"))
+	})
+
+	t.Run("set with expected versions", func(t *testing.T) {
+		err := store.Set(ctx, &configuration.SetRequest{
+			Items: map[string]*configuration.Item{
+				"existing": {Value: "value5"},
+				"new2":     {Value: "value"},
+			},
+			ExpectedVersions: map[string]string{
+				"existing": "4",
+				"new2":     "",
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "value5||5", mustGet(t, s, "existing"))
+		assert.Equal(t, "value||1", mustGet(t, s, "new2"))
+	})
+
+	t.Run("set with version mismatch does not modify any item", func(t *testing.T) {
+		err := store.Set(ctx, &configuration.SetRequest{
+			Items: map[string]*configuration.Item{
+				"existing": {Value: "value6"},
+				"new2":     {Value: "value2"},
+			},
+			ExpectedVersions: map[string]string{
+				"new2": "",
+			},
+		})
+		require.ErrorIs(t, err, configuration.ErrVersionMismatch)
+		require.ErrorContains(t, err, "new2")
+		assert.Equal(t, "value5||5", mustGet(t, s, "existing"))
+		assert.Equal(t, "value||1", mustGet(t, s, "new2"))
+	})
+
+	t.Run("non-numeric version cannot be incremented", func(t *testing.T) {
+		err := store.Set(ctx, &configuration.SetRequest{
+			Items: map[string]*configuration.Item{
+				"explicit": {Value: "value"},
+			},
+		})
+		require.ErrorContains(t, err, "not numeric")
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		err := store.Set(ctx, &configuration.SetRequest{})
+		require.Error(t, err)
+		err = store.Set(ctx, &configuration.SetRequest{
+			Items: map[string]*configuration.Item{"key": {Value: "a||b"}},
+		})
+		require.ErrorContains(t, err, "cannot contain")
+		err = store.Delete(ctx, &configuration.DeleteRequest{
+			Keys:             []string{"new"},
+			ExpectedVersions: map[string]string{"other": "1"},
+		})
+		require.ErrorContains(t, err, "not in the request")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := store.Delete(ctx, &configuration.DeleteRequest{
+			Keys:             []string{"existing", "new"},
+			ExpectedVersions: map[string]string{"existing": "1"},
+		})
+		require.ErrorIs(t, err, configuration.ErrVersionMismatch)
+		assert.True(t, s.Exists("existing"))
+		assert.True(t, s.Exists("new"))
+
+		err = store.Delete(ctx, &configuration.DeleteRequest{
+			Keys:             []string{"existing", "new", "notfound"},
+			ExpectedVersions: map[string]string{"existing": "5", "notfound": ""},
+		})
+		require.NoError(t, err)
+		assert.False(t, s.Exists("existing"))
+		assert.False(t, s.Exists("new"))
+	})
+}
+
+func mustGet(t *testing.T, s *miniredis.Miniredis, key string) string {
+	t.Helper()
+	val, err := s.Get(key)
+	require.NoError(t, err)
+	return val
+}
+
 func setupMiniredis() (*miniredis.Miniredis, redisComponent.RedisClient) {
 	s, err := miniredis.Run()
 	if err != nil {
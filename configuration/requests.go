@@ -13,6 +13,12 @@ limitations under the License.
 
 package configuration
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
 // Item represents a configuration item with name, content and other information.
 type Item struct {
 	Value    string            `json:"value,omitempty"`
@@ -26,6 +32,83 @@ type GetRequest struct {
 	Metadata map[string]string `json:"metadata"`
 }
 
+// SetRequest is the object describing a request to create or update configuration items.
+// The items are written atomically: if the version check fails for any item, no item is modified.
+type SetRequest struct {
+	// Items to write.
+	// If the version of an item is empty, the store increments the current version, which must be numeric, or sets it to "1" for new items.
+	Items map[string]*Item `json:"items"`
+	// Expected current version of items, for optimistic concurrency control.
+	// Items listed here are written only if their current version matches; an empty value means that the item must not exist.
+	// Items that are not listed are written regardless of their current version.
+	ExpectedVersions map[string]string `json:"expectedVersions,omitempty"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// Validate returns an error if the request has no items, an empty key, a nil item, or an expected version for a key that is not in the request.
+// Items with an empty value are valid.
+// StoreWriter implementations invoke it before writing.
+func (r *SetRequest) Validate() error {
+	for key, item := range r.Items {
+		if item == nil {
+			return fmt.Errorf("missing item for key '%s'", key)
+		}
+	}
+	return validateWriteKeys(r.SortedKeys(), r.ExpectedVersions)
+}
+
+// SortedKeys returns the keys of the items, sorted, so stores can always acquire locks in the same order.
+func (r *SetRequest) SortedKeys() []string {
+	keys := make([]string, 0, len(r.Items))
+	for k := range r.Items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DeleteRequest is the object describing a request to delete configuration items.
+// The items are deleted atomically: if the version check fails for any item, no item is deleted.
+type DeleteRequest struct {
+	Keys []string `json:"keys"`
+	// Expected current version of items, for optimistic concurrency control.
+	// Items listed here are deleted only if their current version matches.
+	ExpectedVersions map[string]string `json:"expectedVersions,omitempty"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// Validate returns an error if the request has no keys, an empty key, or an expected version for a key that is not in the request.
+// StoreWriter implementations invoke it before deleting.
+func (r *DeleteRequest) Validate() error {
+	return validateWriteKeys(r.SortedKeys(), r.ExpectedVersions)
+}
+
+// SortedKeys returns a sorted copy of the keys, so stores can always acquire locks in the same order.
+func (r *DeleteRequest) SortedKeys() []string {
+	keys := append([]string(nil), r.Keys...)
+	sort.Strings(keys)
+	return keys
+}
+
+// Validates the sorted keys of a write request, and the keys of its expected versions.
+func validateWriteKeys(keys []string, expectedVersions map[string]string) error {
+	if len(keys) == 0 {
+		return errors.New("no keys in request")
+	}
+	for _, key := range keys {
+		if key == "" {
+			return errors.New("empty key in request")
+		}
+	}
+	for key := range expectedVersions {
+		i := sort.SearchStrings(keys, key)
+		if i == len(keys) || keys[i] != key {
+			return fmt.Errorf("expected version set for key '%s' which is not in the request", key)
+		}
+	}
+	return nil
+}
+
 // SubscribeRequest is the object describing a request to subscribe configuration.
 type SubscribeRequest struct {
 	Keys     []string          `json:"keys"`
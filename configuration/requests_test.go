@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRequestValidate(t *testing.T) {
+	req := &SetRequest{
+		Items:            map[string]*Item{"b": {Value: "2"}, "a": {Value: "1"}},
+		ExpectedVersions: map[string]string{"a": "1"},
+	}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []string{"a", "b"}, req.SortedKeys())
+
+	require.ErrorContains(t, (&SetRequest{}).Validate(), "no keys")
+	require.ErrorContains(t, (&SetRequest{Items: map[string]*Item{"": {}}}).Validate(), "empty key")
+	require.ErrorContains(t, (&SetRequest{Items: map[string]*Item{"a": nil}}).Validate(), "missing item")
+	require.ErrorContains(t, (&SetRequest{
+		Items:            map[string]*Item{"a": {}},
+		ExpectedVersions: map[string]string{"c": "1"},
+	}).Validate(), "not in the request")
+}
+
+func TestDeleteRequestValidate(t *testing.T) {
+	req := &DeleteRequest{
+		Keys:             []string{"b", "a"},
+		ExpectedVersions: map[string]string{"b": "1"},
+	}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []string{"a", "b"}, req.SortedKeys())
+	// The keys in the request are not modified
+	assert.Equal(t, []string{"b", "a"}, req.Keys)
+
+	require.ErrorContains(t, (&DeleteRequest{}).Validate(), "no keys")
+	require.ErrorContains(t, (&DeleteRequest{Keys: []string{""}}).Validate(), "empty key")
+	require.ErrorContains(t, (&DeleteRequest{
+		Keys:             []string{"a"},
+		ExpectedVersions: map[string]string{"c": "1"},
+	}).Validate(), "not in the request")
+}
@@ -0,0 +1,454 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+
+	"github.com/dapr/components-contrib/configuration"
+	internalsql "github.com/dapr/components-contrib/internal/component/sql"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// ConfigurationStore is a configuration store backed by a SQLite database.
+//
+// Items are stored in a table with the same layout as the one used by the PostgreSQL configuration store.
+// Triggers on the table record changes in a separate table, which is polled to notify subscribers.
+type ConfigurationStore struct {
+	logger        logger.Logger
+	metadata      sqliteMetadata
+	db            *sql.DB
+	gc            internalsql.GarbageCollector
+	lastChangeID  int64
+	subscriptions map[string]*subscription
+	lock          sync.Mutex
+	closed        atomic.Bool
+	closeCh       chan struct{}
+	wg            sync.WaitGroup
+}
+
+type subscription struct {
+	ctx     context.Context
+	keys    []string
+	handler configuration.UpdateHandler
+	// ID of the last change that was recorded when the subscription was created
+	afterID int64
+}
+
+type sqliteResponse struct {
+	key  string
+	item *configuration.Item
+}
+
+type change struct {
+	id  int64
+	key string
+}
+
+// NewSQLiteConfigurationStore returns a new configuration store backed by SQLite.
+func NewSQLiteConfigurationStore(logger logger.Logger) configuration.Store {
+	return &ConfigurationStore{
+		logger:        logger,
+		subscriptions: make(map[string]*subscription),
+		closeCh:       make(chan struct{}),
+	}
+}
+
+// Init sets up the database connection, performs migrations, and starts polling for changes.
+func (s *ConfigurationStore) Init(ctx context.Context, md configuration.Metadata) error {
+	if s.closed.Load() {
+		return errors.New("component is closed")
+	}
+
+	err := s.metadata.InitWithMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	connString, err := s.metadata.GetConnectionString(s.logger)
+	if err != nil {
+		// Already logged
+		return err
+	}
+
+	s.db, err = sql.Open("sqlite", connString)
+	if err != nil {
+		return fmt.Errorf("failed to create connection: %w", err)
+	}
+
+	// Performs migrations
+	err = performMigrations(ctx, s.db, s.logger, migrationOptions{
+		ConfigTableName:   s.metadata.TableName,
+		ChangesTableName:  s.metadata.ChangesTableName(),
+		MetadataTableName: s.metadata.MetadataTableName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to perform migrations: %w", err)
+	}
+
+	// Init the background GC
+	err = s.initGC()
+	if err != nil {
+		return err
+	}
+
+	// Changes that were recorded before the component was initialized are ignored
+	s.lastChangeID, err = s.latestChangeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.pollChanges()
+
+	return nil
+}
+
+func (s *ConfigurationStore) initGC() (err error) {
+	s.gc, err = internalsql.ScheduleGarbageCollector(internalsql.GCOptions{
+		Logger: s.logger,
+		UpdateLastCleanupQuery: func(arg any) (string, any) {
+			return fmt.Sprintf(`INSERT INTO %s (key, value)
+				VALUES ('config-last-cleanup', CURRENT_TIMESTAMP)
+				ON CONFLICT (key)
+				DO UPDATE SET value = CURRENT_TIMESTAMP
+					WHERE (unixepoch(CURRENT_TIMESTAMP) - unixepoch(value)) * 1000 > ?;`,
+				s.metadata.MetadataTableName,
+			), arg
+		},
+		// Changes are kept for one cleanup interval, so they can be read by all instances polling the database
+		DeleteExpiredValuesQuery: fmt.Sprintf(
+			`DELETE FROM %s WHERE change_time < unixepoch(CURRENT_TIMESTAMP) - %d`,
+			s.metadata.ChangesTableName(),
+			int(s.metadata.CleanupInterval.Seconds()),
+		),
+		CleanupInterval: s.metadata.CleanupInterval,
+		DB:              internalsql.AdaptDatabaseSQLConn(s.db),
+	})
+	return err
+}
+
+// Get returns the latest version of the requested items, or of all items if no key is specified.
+func (s *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequest) (*configuration.GetResponse, error) {
+	items, err := s.getItems(ctx, req.Keys)
+	if err != nil {
+		return nil, err
+	}
+	return &configuration.GetResponse{
+		Items: items,
+	}, nil
+}
+
+func (s *ConfigurationStore) getItems(parentCtx context.Context, keys []string) (map[string]*configuration.Item, error) {
+	// Concatenation is required for table name because sql.DB does not substitute parameters for table names
+	query := "SELECT key, value, version, metadata FROM " + s.metadata.TableName
+	params := make([]any, len(keys))
+	if len(keys) > 0 {
+		// SQLite doesn't support passing an array for an IN clause, so we need to build a custom query
+		inClause := strings.Repeat("?,", len(keys))
+		query += " WHERE key IN (" + inClause[:len(inClause)-1] + ")"
+		for i, k := range keys {
+			params[i] = k
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, s.metadata.Timeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("error in querying configuration store: %w", err)
+	}
+	defer rows.Close()
+
+	res := []sqliteResponse{}
+	for rows.Next() {
+		var (
+			r        sqliteResponse
+			metadata sql.NullString
+		)
+		r.item = &configuration.Item{
+			Metadata: map[string]string{},
+		}
+		err = rows.Scan(&r.key, &r.item.Value, &r.item.Version, &metadata)
+		if err != nil {
+			return nil, fmt.Errorf("error in reading data from configuration store: %w", err)
+		}
+		if metadata.Valid && metadata.String != "" {
+			err = json.Unmarshal([]byte(metadata.String), &r.item.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata for key '%s': %w", r.key, err)
+			}
+			if r.item.Metadata == nil {
+				// The column contains "null"
+				r.item.Metadata = map[string]string{}
+			}
+		}
+		res = append(res, r)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error in reading data from configuration store: %w", err)
+	}
+
+	return getUniqueItemPerKey(res), nil
+}
+
+// Subscribe registers a handler that is invoked when the subscribed items change.
+// If the list of keys is empty, the handler is invoked for changes to any item.
+func (s *ConfigurationStore) Subscribe(ctx context.Context, req *configuration.SubscribeRequest, handler configuration.UpdateHandler) (string, error) {
+	if s.closed.Load() {
+		return "", errors.New("component is closed")
+	}
+
+	afterID, err := s.latestChangeID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	subscribeUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate subscription id - %w", err)
+	}
+	subscribeID := subscribeUID.String()
+
+	s.lock.Lock()
+	s.subscriptions[subscribeID] = &subscription{
+		ctx:     ctx,
+		keys:    req.Keys,
+		handler: handler,
+		afterID: afterID,
+	}
+	s.lock.Unlock()
+
+	return subscribeID, nil
+}
+
+// Unsubscribe removes a subscription.
+func (s *ConfigurationStore) Unsubscribe(_ context.Context, req *configuration.UnsubscribeRequest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.subscriptions[req.ID]; !ok {
+		return fmt.Errorf("subscription with id %s does not exist", req.ID)
+	}
+	delete(s.subscriptions, req.ID)
+	return nil
+}
+
+// Returns the ID of the last change recorded in the changes table.
+func (s *ConfigurationStore) latestChangeID(parentCtx context.Context) (id int64, err error) {
+	ctx, cancel := context.WithTimeout(parentCtx, s.metadata.Timeout)
+	defer cancel()
+	err = s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM "+s.metadata.ChangesTableName()).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the latest change: %w", err)
+	}
+	return id, nil
+}
+
+// In background, periodically polls the changes table and notifies subscribers.
+// Should be invoked in a background goroutine.
+func (s *ConfigurationStore) pollChanges() {
+	defer s.wg.Done()
+
+	t := time.NewTicker(s.metadata.PollInterval)
+	defer t.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		select {
+		case <-s.closeCh:
+			// Component is closing
+			return
+		case <-t.C:
+			err := s.processChanges(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to process changes to the configuration: %v", err)
+			}
+		}
+	}
+}
+
+func (s *ConfigurationStore) processChanges(parentCtx context.Context) error {
+	changes, err := s.readChanges(parentCtx)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	s.lock.Lock()
+	subs := make(map[string]*subscription, len(s.subscriptions))
+	for id, sub := range s.subscriptions {
+		subs[id] = sub
+	}
+	s.lock.Unlock()
+
+	if len(subs) > 0 {
+		keys := make([]string, 0, len(changes))
+		for _, c := range changes {
+			if !slices.Contains(keys, c.key) {
+				keys = append(keys, c.key)
+			}
+		}
+		items, err := s.getItems(parentCtx, keys)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if _, ok := items[k]; !ok {
+				// The item was deleted
+				items[k] = &configuration.Item{}
+			}
+		}
+
+		for id, sub := range subs {
+			s.notifySubscriber(id, sub, changes, items)
+		}
+	}
+
+	s.lastChangeID = changes[len(changes)-1].id
+	return nil
+}
+
+func (s *ConfigurationStore) readChanges(parentCtx context.Context) ([]change, error) {
+	ctx, cancel := context.WithTimeout(parentCtx, s.metadata.Timeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, "SELECT id, key FROM "+s.metadata.ChangesTableName()+" WHERE id > ? ORDER BY id", s.lastChangeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []change{}
+	for rows.Next() {
+		var c change
+		err = rows.Scan(&c.id, &c.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read changes: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (s *ConfigurationStore) notifySubscriber(id string, sub *subscription, changes []change, items map[string]*configuration.Item) {
+	if sub.ctx.Err() != nil {
+		// The subscription's context was canceled
+		s.lock.Lock()
+		delete(s.subscriptions, id)
+		s.lock.Unlock()
+		return
+	}
+
+	e := &configuration.UpdateEvent{
+		ID:    id,
+		Items: make(map[string]*configuration.Item),
+	}
+	for _, c := range changes {
+		if c.id <= sub.afterID {
+			continue
+		}
+		if len(sub.keys) == 0 || slices.Contains(sub.keys, c.key) {
+			e.Items[c.key] = items[c.key]
+		}
+	}
+	if len(e.Items) == 0 {
+		return
+	}
+
+	err := sub.handler(sub.ctx, e)
+	if err != nil {
+		s.logger.Errorf("Failed to call handler to notify event for configuration update subscribe: %v", err)
+	}
+}
+
+// If version is a valid number, return the number
+// If version is not a valid number, return -1
+func getNumericVersion(version string) int {
+	num, err := strconv.Atoi(version)
+	if err != nil {
+		num = -1
+	}
+	return num
+}
+
+// Returns a map of unique items per key, with the highest version of each
+func getUniqueItemPerKey(res []sqliteResponse) map[string]*configuration.Item {
+	items := make(map[string]*configuration.Item)
+	latestNumericVersion := make(map[string]int)
+	for _, r := range res {
+		if items[r.key] == nil {
+			items[r.key] = r.item
+			latestNumericVersion[r.key] = getNumericVersion(r.item.Version)
+		} else {
+			newNumericVersion := getNumericVersion(r.item.Version)
+			if newNumericVersion > latestNumericVersion[r.key] {
+				items[r.key] = r.item
+				latestNumericVersion[r.key] = newNumericVersion
+			}
+		}
+	}
+	return items
+}
+
+// Close implements io.Closer.
+func (s *ConfigurationStore) Close() error {
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.closeCh)
+	}
+	s.wg.Wait()
+
+	errs := make([]error, 0)
+	if s.gc != nil {
+		err := s.gc.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		s.gc = nil
+	}
+	if s.db != nil {
+		err := s.db.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		s.db = nil
+	}
+	return errors.Join(errs...)
+}
+
+// GetComponentMetadata returns the metadata of the component.
+func (s *ConfigurationStore) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := sqliteMetadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.ConfigurationStoreType)
+	return
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	authSqlite "github.com/dapr/components-contrib/internal/authentication/sqlite"
+	"github.com/dapr/kit/metadata"
+)
+
+const (
+	defaultTableName         = "configuration"
+	defaultMetadataTableName = "metadata"
+	defaultPollInterval      = time.Second
+	defaultCleanupInternal   = time.Hour
+)
+
+type sqliteMetadata struct {
+	authSqlite.SqliteAuthMetadata `mapstructure:",squash"`
+
+	TableName         string        `mapstructure:"tableName"`
+	MetadataTableName string        `mapstructure:"metadataTableName"`
+	PollInterval      time.Duration `mapstructure:"pollInterval"`    // Interval for polling the changes table, for subscriptions
+	CleanupInterval   time.Duration `mapstructure:"cleanupInterval"` // Interval for removing old records from the changes table
+}
+
+func (m *sqliteMetadata) InitWithMetadata(meta map[string]string) error {
+	// Reset the object
+	m.reset()
+
+	// Decode the metadata
+	err := metadata.DecodeMetadata(meta, &m)
+	if err != nil {
+		return err
+	}
+
+	// Validate and sanitize input
+	err = m.SqliteAuthMetadata.Validate()
+	if err != nil {
+		return err
+	}
+	if !authSqlite.ValidIdentifier(m.TableName) {
+		return fmt.Errorf("invalid identifier for table name: %s", m.TableName)
+	}
+	if !authSqlite.ValidIdentifier(m.MetadataTableName) {
+		return fmt.Errorf("invalid identifier for metadata table name: %s", m.MetadataTableName)
+	}
+	if m.PollInterval <= 0 {
+		return errors.New("poll interval must be greater than zero")
+	}
+
+	return nil
+}
+
+// ChangesTableName returns the name of the table where the triggers record changes to the configuration table.
+func (m sqliteMetadata) ChangesTableName() string {
+	return m.TableName + "_changes"
+}
+
+// Reset the object
+func (m *sqliteMetadata) reset() {
+	m.SqliteAuthMetadata.Reset()
+
+	m.TableName = defaultTableName
+	m.MetadataTableName = defaultMetadataTableName
+	m.PollInterval = defaultPollInterval
+	m.CleanupInterval = defaultCleanupInternal
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sqlinternal "github.com/dapr/components-contrib/internal/component/sql"
+	sqlitemigrations "github.com/dapr/components-contrib/internal/component/sql/migrations/sqlite"
+	"github.com/dapr/kit/logger"
+)
+
+type migrationOptions struct {
+	ConfigTableName   string
+	ChangesTableName  string
+	MetadataTableName string
+}
+
+// Perform the required migrations
+func performMigrations(ctx context.Context, db *sql.DB, logger logger.Logger, opts migrationOptions) error {
+	m := sqlitemigrations.Migrations{
+		Pool:              db,
+		Logger:            logger,
+		MetadataTableName: opts.MetadataTableName,
+		MetadataKey:       "config-migrations",
+	}
+
+	return m.Perform(ctx, []sqlinternal.MigrationFn{
+		// Migration 0: create the configuration table, and the changes table with the triggers that populate it
+		func(ctx context.Context) error {
+			// The configuration table has the same layout as the one used by the PostgreSQL configuration store, where each key can have multiple versions
+			// We need to add an "IF NOT EXISTS" because the table may have been created by the user already
+			logger.Infof("Creating configuration table '%s'", opts.ConfigTableName)
+			_, err := m.GetConn().ExecContext(
+				ctx,
+				fmt.Sprintf(
+					`CREATE TABLE IF NOT EXISTS %s (
+						key TEXT NOT NULL,
+						value TEXT NOT NULL,
+						version TEXT NOT NULL,
+						metadata TEXT,
+						PRIMARY KEY (key, version)
+					)`,
+					opts.ConfigTableName,
+				),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create configuration table: %w", err)
+			}
+
+			// AUTOINCREMENT guarantees that IDs are never re-used, even after the rows with the largest IDs are removed
+			logger.Infof("Creating changes table '%s'", opts.ChangesTableName)
+			_, err = m.GetConn().ExecContext(
+				ctx,
+				fmt.Sprintf(
+					`CREATE TABLE %[2]s (
+						id INTEGER PRIMARY KEY AUTOINCREMENT,
+						key TEXT NOT NULL,
+						change_time INTEGER NOT NULL DEFAULT (unixepoch(CURRENT_TIMESTAMP))
+					);
+					CREATE INDEX %[2]s_change_time_idx ON %[2]s (change_time);
+					CREATE TRIGGER %[1]s_insert_trigger AFTER INSERT ON %[1]s
+					BEGIN
+						INSERT INTO %[2]s (key) VALUES (NEW.key);
+					END;
+					CREATE TRIGGER %[1]s_update_trigger AFTER UPDATE ON %[1]s
+					BEGIN
+						INSERT INTO %[2]s (key) VALUES (NEW.key);
+						INSERT INTO %[2]s (key) SELECT OLD.key WHERE OLD.key <> NEW.key;
+					END;
+					CREATE TRIGGER %[1]s_delete_trigger AFTER DELETE ON %[1]s
+					BEGIN
+						INSERT INTO %[2]s (key) VALUES (OLD.key);
+					END;`,
+					opts.ConfigTableName, opts.ChangesTableName,
+				),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create changes table: %w", err)
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/configuration"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestSQLiteConfigurationStore(t *testing.T) {
+	s := NewSQLiteConfigurationStore(logger.NewLogger("test")).(*ConfigurationStore)
+	t.Cleanup(func() {
+		s.Close()
+	})
+
+	t.Run("Init", func(t *testing.T) {
+		err := s.Init(context.Background(), configuration.Metadata{Base: contribMetadata.Base{
+			Properties: map[string]string{
+				"connectionString": filepath.Join(t.TempDir(), "config.db"),
+				"pollInterval":     "100ms",
+			},
+		}})
+		require.NoError(t, err)
+	})
+
+	require.False(t, t.Failed(), "Cannot continue if init step failed")
+
+	exec := func(t *testing.T, query string, args ...any) {
+		t.Helper()
+		_, err := s.db.Exec(query, args...)
+		require.NoError(t, err)
+	}
+
+	t.Run("Populate test data", func(t *testing.T) {
+		exec(t, `INSERT INTO configuration (key, value, version, metadata) VALUES
+			('a', 'a1', '1', NULL),
+			('a', 'a2', '2', '{"owner":"team-a"}'),
+			('b', 'b1', '1', '{}'),
+			('c', 'c1', 'v1', NULL)`)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		res, err := s.Get(context.Background(), &configuration.GetRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*configuration.Item{
+			"a": {Value: "a2", Version: "2", Metadata: map[string]string{"owner": "team-a"}},
+			"b": {Value: "b1", Version: "1", Metadata: map[string]string{}},
+			"c": {Value: "c1", Version: "v1", Metadata: map[string]string{}},
+		}, res.Items)
+
+		res, err = s.Get(context.Background(), &configuration.GetRequest{Keys: []string{"b", "notfound"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*configuration.Item{
+			"b": {Value: "b1", Version: "1", Metadata: map[string]string{}},
+		}, res.Items)
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		subscribe := func(keys ...string) (string, chan *configuration.UpdateEvent) {
+			ch := make(chan *configuration.UpdateEvent, 10)
+			id, err := s.Subscribe(context.Background(), &configuration.SubscribeRequest{Keys: keys}, func(ctx context.Context, e *configuration.UpdateEvent) error {
+				ch <- e
+				return nil
+			})
+			require.NoError(t, err)
+			return id, ch
+		}
+		receive := func(ch chan *configuration.UpdateEvent) *configuration.UpdateEvent {
+			select {
+			case e := <-ch:
+				return e
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for update event")
+				return nil
+			}
+		}
+
+		// Changes recorded before subscribing are not sent
+		exec(t, `UPDATE configuration SET value = 'c2' WHERE key = 'c'`)
+		idB, chB := subscribe("b")
+		idAll, chAll := subscribe()
+
+		exec(t, `UPDATE configuration SET value = 'b2' WHERE key = 'b'`)
+		exec(t, `INSERT INTO configuration (key, value, version) VALUES ('a', 'a3', '3')`)
+		exec(t, `DELETE FROM configuration WHERE key = 'c'`)
+
+		e := receive(chB)
+		assert.Equal(t, idB, e.ID)
+		assert.Equal(t, map[string]*configuration.Item{
+			"b": {Value: "b2", Version: "1", Metadata: map[string]string{}},
+		}, e.Items)
+
+		e = receive(chAll)
+		assert.Equal(t, idAll, e.ID)
+		assert.Equal(t, map[string]*configuration.Item{
+			"a": {Value: "a3", Version: "3", Metadata: map[string]string{}},
+			"b": {Value: "b2", Version: "1", Metadata: map[string]string{}},
+			"c": {},
+		}, e.Items)
+
+		// After unsubscribing, no more events are sent
+		require.NoError(t, s.Unsubscribe(context.Background(), &configuration.UnsubscribeRequest{ID: idAll}))
+		exec(t, `UPDATE configuration SET value = 'b3' WHERE key = 'b'`)
+		e = receive(chB)
+		assert.Equal(t, "b3", e.Items["b"].Value)
+		assert.Empty(t, chAll)
+
+		require.Error(t, s.Unsubscribe(context.Background(), &configuration.UnsubscribeRequest{ID: idAll}))
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]string
+		err   string
+	}{
+		{name: "missing connection string", props: map[string]string{}, err: "missing connection string"},
+		{name: "invalid table name", props: map[string]string{"connectionString": ":memory:", "tableName": "config;"}, err: "invalid identifier for table name"},
+		{name: "invalid poll interval", props: map[string]string{"connectionString": ":memory:", "pollInterval": "0"}, err: "poll interval must be greater than zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m sqliteMetadata
+			err := m.InitWithMetadata(tt.props)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	var m sqliteMetadata
+	err := m.InitWithMetadata(map[string]string{"connectionString": ":memory:", "tableName": "config"})
+	require.NoError(t, err)
+	assert.Equal(t, "config_changes", m.ChangesTableName())
+	assert.Equal(t, defaultPollInterval, m.PollInterval)
+}
@@ -15,6 +15,7 @@ package configuration
 
 import (
 	"context"
+	"errors"
 
 	"github.com/dapr/components-contrib/metadata"
 )
@@ -36,5 +37,26 @@ type Store interface {
 	Unsubscribe(ctx context.Context, req *UnsubscribeRequest) error
 }
 
+// StoreWriter is an optional interface for configuration stores that support modifying items.
+// Subscribers of the store are notified of the changes made with the writer.
+type StoreWriter interface {
+	// Set creates or updates configuration items.
+	Set(ctx context.Context, req *SetRequest) error
+
+	// Delete removes configuration items.
+	Delete(ctx context.Context, req *DeleteRequest) error
+}
+
+// ErrVersionMismatch is returned by StoreWriter when the current version of an item does not match the expected version.
+var ErrVersionMismatch = errors.New("configuration item version mismatch")
+
+// StoreConsumerMetadataKey is the metadata property with the name of the configuration store used by a StoreConsumer.
+const StoreConsumerMetadataKey = "configurationStore"
+
+// StoreConsumer is an optional interface for components that load data from a configuration store, provided with SetConfigurationStore.
+type StoreConsumer interface {
+	SetConfigurationStore(store Store)
+}
+
 // UpdateHandler is the handler used to send event to daprd.
 type UpdateHandler func(ctx context.Context, e *UpdateEvent) error
@@ -0,0 +1,446 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// Envelope encryption.
+//
+// Data is encrypted with a random 256-bit data key using AES-GCM, and the data key is wrapped with a key encryption key (KEK) stored in a SubtleCrypto provider.
+//
+// The format of an envelope is:
+//
+//	magic ("dapr.io/envelope/v1\n") | header length (uint32, big endian) | header (JSON) | chunk | chunk | ...
+//
+// The plaintext is split into chunks of the size set in the header (the last chunk may be shorter, or empty); each chunk is encrypted and authenticated individually and is followed by its 16-byte authentication tag.
+// Chunk nonces are made of a random 7-byte prefix, a 32-bit big-endian counter, and a byte that is 1 for the last chunk only, so chunks cannot be reordered, and truncating the envelope is detected.
+// The associated data of each chunk is the SHA-256 hash of the magic and header, followed by the caller's associated data (if any): this binds chunks to the header (including the wrapped data key) and to the caller's context.
+const (
+	envelopeMagic            = "dapr.io/envelope/v1\n"
+	envelopeNoncePrefixSize  = 7
+	envelopeTagSize          = 16
+	envelopeMaxHeaderSize    = 64 << 10
+	envelopeMinChunkSize     = 1 << 10
+	envelopeMaxChunkSize     = 16 << 20
+	envelopeDefaultChunkSize = 64 << 10
+)
+
+var (
+	// ErrInvalidEnvelope is returned when the data is not a valid envelope, or it was truncated.
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+	// ErrEnvelopeDecryption is returned when a chunk of an envelope fails authentication.
+	ErrEnvelopeDecryption = errors.New("failed to decrypt envelope: message authentication failed")
+)
+
+// EnvelopeEncryptOptions contains the options for encrypting data in an envelope.
+type EnvelopeEncryptOptions struct {
+	// Name (or name/version) of the key encryption key.
+	// If the provider implements KeyManager and the name does not include a version, the ID of the latest version is stored in the envelope.
+	KeyName string
+	// Algorithm used to wrap the data key, for example "A256KW" or "RSA-OAEP-256".
+	Algorithm string
+	// Size of each chunk of plaintext, in bytes.
+	// Defaults to 64KiB.
+	ChunkSize int
+	// Optional associated data that is authenticated but not encrypted.
+	// The same value must be passed when decrypting.
+	AssociatedData []byte
+}
+
+// EnvelopeDecryptOptions contains the options for decrypting an envelope.
+// The key encryption key is selected automatically by the name stored in the envelope.
+type EnvelopeDecryptOptions struct {
+	// Associated data that was passed when encrypting, if any.
+	AssociatedData []byte
+}
+
+type envelopeHeader struct {
+	KeyName     string `json:"kid"`
+	Algorithm   string `json:"alg"`
+	WrappedKey  []byte `json:"wk"`
+	WrapNonce   []byte `json:"wn,omitempty"`
+	WrapTag     []byte `json:"wt,omitempty"`
+	ChunkSize   int    `json:"cs"`
+	NoncePrefix []byte `json:"np"`
+}
+
+// NewEnvelopeWriter returns a writer that encrypts data written to it into an envelope written to out.
+// The header is written to out immediately. Callers must call Close to write the last chunk; Close does not close out.
+func NewEnvelopeWriter(ctx context.Context, cp SubtleCrypto, out io.Writer, opts EnvelopeEncryptOptions) (io.WriteCloser, error) {
+	if opts.KeyName == "" {
+		return nil, errors.New("key name is required")
+	}
+	if opts.Algorithm == "" {
+		return nil, errors.New("algorithm is required")
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = envelopeDefaultChunkSize
+	}
+	if opts.ChunkSize < envelopeMinChunkSize || opts.ChunkSize > envelopeMaxChunkSize {
+		return nil, fmt.Errorf("chunk size must be between %d and %d bytes", envelopeMinChunkSize, envelopeMaxChunkSize)
+	}
+
+	// Store the ID of the version of the key, so the same version is used when decrypting after the key has been rotated
+	keyName := opts.KeyName
+	if km, ok := cp.(KeyManager); ok {
+		kv, err := km.GetKeyVersion(ctx, keyName, "")
+		if err == nil {
+			keyName = kv.KeyID
+		}
+	}
+
+	// Generate and wrap the data key
+	rawKey := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	dataKey, err := jwk.FromRaw(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	header := envelopeHeader{
+		KeyName:     keyName,
+		Algorithm:   opts.Algorithm,
+		ChunkSize:   opts.ChunkSize,
+		NoncePrefix: make([]byte, envelopeNoncePrefixSize),
+	}
+	if n := wrapNonceSize(opts.Algorithm); n > 0 {
+		header.WrapNonce = make([]byte, n)
+		_, err = io.ReadFull(rand.Reader, header.WrapNonce)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+	}
+	_, err = io.ReadFull(rand.Reader, header.NoncePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	header.WrappedKey, header.WrapTag, err = cp.WrapKey(ctx, dataKey, opts.Algorithm, keyName, header.WrapNonce, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	// Write the header
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize envelope header: %w", err)
+	}
+	prefix := make([]byte, 0, len(envelopeMagic)+4+len(headerJSON))
+	prefix = append(prefix, envelopeMagic...)
+	prefix = binary.BigEndian.AppendUint32(prefix, uint32(len(headerJSON)))
+	prefix = append(prefix, headerJSON...)
+	_, err = out.Write(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newEnvelopeStream(rawKey, header, prefix, opts.AssociatedData)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeWriter{
+		envelopeStream: s,
+		out:            out,
+		buf:            make([]byte, 0, header.ChunkSize+envelopeTagSize),
+	}, nil
+}
+
+// NewEnvelopeReader returns a reader that decrypts the envelope read from in.
+// The header is read and the data key is unwrapped immediately.
+// Reads return ErrEnvelopeDecryption if a chunk was tampered with, and ErrInvalidEnvelope if the envelope was truncated.
+func NewEnvelopeReader(ctx context.Context, cp SubtleCrypto, in io.Reader, opts EnvelopeDecryptOptions) (io.Reader, error) {
+	src := bufio.NewReader(in)
+
+	// Read the header
+	prefix := make([]byte, len(envelopeMagic)+4)
+	_, err := io.ReadFull(src, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidEnvelope, err)
+	}
+	if string(prefix[:len(envelopeMagic)]) != envelopeMagic {
+		return nil, fmt.Errorf("%w: unsupported format", ErrInvalidEnvelope)
+	}
+	headerLen := binary.BigEndian.Uint32(prefix[len(envelopeMagic):])
+	if headerLen > envelopeMaxHeaderSize {
+		return nil, fmt.Errorf("%w: header is too large", ErrInvalidEnvelope)
+	}
+	prefix = append(prefix, make([]byte, headerLen)...)
+	_, err = io.ReadFull(src, prefix[len(envelopeMagic)+4:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidEnvelope, err)
+	}
+	var header envelopeHeader
+	err = json.Unmarshal(prefix[len(envelopeMagic)+4:], &header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse header: %v", ErrInvalidEnvelope, err)
+	}
+	if header.ChunkSize < envelopeMinChunkSize || header.ChunkSize > envelopeMaxChunkSize || len(header.NoncePrefix) != envelopeNoncePrefixSize || header.KeyName == "" {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidEnvelope)
+	}
+
+	// Unwrap the data key
+	dataKey, err := cp.UnwrapKey(ctx, header.WrappedKey, header.Algorithm, header.KeyName, header.WrapNonce, header.WrapTag, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	var rawKey []byte
+	err = dataKey.Raw(&rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	s, err := newEnvelopeStream(rawKey, header, prefix, opts.AssociatedData)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeReader{
+		envelopeStream: s,
+		src:            src,
+		buf:            make([]byte, header.ChunkSize+envelopeTagSize),
+	}, nil
+}
+
+// SealEnvelope encrypts plaintext into an envelope.
+// It's a convenience wrapper around NewEnvelopeWriter for data that fits in memory.
+func SealEnvelope(ctx context.Context, cp SubtleCrypto, plaintext []byte, opts EnvelopeEncryptOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewEnvelopeWriter(ctx, cp, &buf, opts)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OpenEnvelope decrypts an envelope.
+// It's a convenience wrapper around NewEnvelopeReader for data that fits in memory.
+func OpenEnvelope(ctx context.Context, cp SubtleCrypto, envelope []byte, opts EnvelopeDecryptOptions) ([]byte, error) {
+	r, err := NewEnvelopeReader(ctx, cp, bytes.NewReader(envelope), opts)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// IsEnvelope returns true if data begins with the envelope's magic string.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(envelopeMagic))
+}
+
+// wrapNonceSize returns the size of the nonce required by the algorithm used to wrap the data key, or 0 if the algorithm doesn't use one.
+func wrapNonceSize(algorithm string) int {
+	alg := strings.ToUpper(algorithm)
+	switch {
+	case strings.HasPrefix(alg, "XC20P"):
+		return 24
+	case strings.HasPrefix(alg, "C20P"), strings.HasSuffix(alg, "GCM"), strings.HasSuffix(alg, "GCMKW"):
+		return 12
+	case strings.Contains(alg, "CBC"):
+		return 16
+	default:
+		return 0
+	}
+}
+
+// envelopeStream contains the state shared by the envelope reader and writer.
+type envelopeStream struct {
+	aead        cipher.AEAD
+	noncePrefix []byte
+	aad         []byte
+	chunkSize   int
+	counter     uint64
+}
+
+func newEnvelopeStream(rawKey []byte, header envelopeHeader, prefix []byte, associatedData []byte) (envelopeStream, error) {
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return envelopeStream{}, fmt.Errorf("invalid data key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return envelopeStream{}, err
+	}
+
+	h := sha256.Sum256(prefix)
+	aad := make([]byte, 0, len(h)+len(associatedData))
+	aad = append(aad, h[:]...)
+	aad = append(aad, associatedData...)
+
+	return envelopeStream{
+		aead:        aead,
+		noncePrefix: header.NoncePrefix,
+		aad:         aad,
+		chunkSize:   header.ChunkSize,
+	}, nil
+}
+
+// nextNonce returns the nonce for the next chunk.
+func (s *envelopeStream) nextNonce(last bool) ([]byte, error) {
+	if s.counter > math.MaxUint32 {
+		return nil, errors.New("envelope has too many chunks")
+	}
+	nonce := make([]byte, 0, envelopeNoncePrefixSize+5)
+	nonce = append(nonce, s.noncePrefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, uint32(s.counter))
+	if last {
+		nonce = append(nonce, 1)
+	} else {
+		nonce = append(nonce, 0)
+	}
+	s.counter++
+	return nonce, nil
+}
+
+type envelopeWriter struct {
+	envelopeStream
+
+	out    io.Writer
+	buf    []byte
+	closed bool
+	err    error
+}
+
+func (w *envelopeWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("envelope writer is closed")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is sealed only when there's more data, because the last chunk is sealed differently
+		if len(w.buf) == w.chunkSize {
+			w.err = w.flush(false)
+			if w.err != nil {
+				return written, w.err
+			}
+		}
+		n := copy(w.buf[len(w.buf):w.chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes the last chunk.
+func (w *envelopeWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	return w.flush(true)
+}
+
+func (w *envelopeWriter) flush(last bool) error {
+	nonce, err := w.nextNonce(last)
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(w.aead.Seal(w.buf[:0], nonce, w.buf, w.aad))
+	w.buf = w.buf[:0]
+	return err
+}
+
+type envelopeReader struct {
+	envelopeStream
+
+	src   *bufio.Reader
+	buf   []byte
+	plain []byte
+	done  bool
+	err   error
+}
+
+func (r *envelopeReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.readChunk()
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *envelopeReader) readChunk() error {
+	n, err := io.ReadFull(r.src, r.buf)
+	var last bool
+	switch {
+	case err == nil:
+		// The chunk is the last one if there's no more data after it
+		_, err = r.src.Peek(1)
+		if errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case errors.Is(err, io.EOF):
+		// The last chunk is missing
+		return fmt.Errorf("%w: envelope is truncated", ErrInvalidEnvelope)
+	default:
+		return err
+	}
+	if n < envelopeTagSize {
+		return fmt.Errorf("%w: envelope is truncated", ErrInvalidEnvelope)
+	}
+
+	nonce, err := r.nextNonce(last)
+	if err != nil {
+		return err
+	}
+	r.plain, err = r.aead.Open(r.buf[:0], nonce, r.buf[:n], r.aad)
+	if err != nil {
+		return ErrEnvelopeDecryption
+	}
+	r.done = last
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/crypto/localstorage"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func newLocalStorage(t *testing.T) contribCrypto.SubtleCrypto {
+	t.Helper()
+
+	cp := localstorage.NewLocalStorageCrypto(logger.NewLogger("test"))
+	err := cp.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"path": t.TempDir()},
+	}})
+	require.NoError(t, err)
+
+	km := cp.(contribCrypto.KeyManager)
+	_, err = km.CreateKey(context.Background(), "aeskey", contribCrypto.KeyTypeAES256)
+	require.NoError(t, err)
+	_, err = km.CreateKey(context.Background(), "rsakey", contribCrypto.KeyTypeRSA2048)
+	require.NoError(t, err)
+	return cp
+}
+
+func TestEnvelope(t *testing.T) {
+	ctx := context.Background()
+	cp := newLocalStorage(t)
+
+	randomData := func(n int) []byte {
+		b := make([]byte, n)
+		_, err := io.ReadFull(rand.Reader, b)
+		require.NoError(t, err)
+		return b
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			keyName   string
+			algorithm string
+			size      int
+		}{
+			{name: "empty", keyName: "aeskey", algorithm: "A256KW", size: 0},
+			{name: "smaller than a chunk", keyName: "aeskey", algorithm: "A256KW", size: 100},
+			{name: "exactly one chunk", keyName: "aeskey", algorithm: "A256GCM", size: 1024},
+			{name: "multiple of chunk size", keyName: "aeskey", algorithm: "A256KW", size: 4096},
+			{name: "multiple chunks", keyName: "rsakey", algorithm: "RSA-OAEP-256", size: 5000},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				plaintext := randomData(tt.size)
+				enc, err := contribCrypto.SealEnvelope(ctx, cp, plaintext, contribCrypto.EnvelopeEncryptOptions{
+					KeyName:        tt.keyName,
+					Algorithm:      tt.algorithm,
+					ChunkSize:      1024,
+					AssociatedData: []byte("context"),
+				})
+				require.NoError(t, err)
+				assert.True(t, contribCrypto.IsEnvelope(enc))
+
+				dec, err := contribCrypto.OpenEnvelope(ctx, cp, enc, contribCrypto.EnvelopeDecryptOptions{
+					AssociatedData: []byte("context"),
+				})
+				require.NoError(t, err)
+				assert.Equal(t, plaintext, dec)
+			})
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		plaintext := randomData(300 << 10)
+
+		var buf bytes.Buffer
+		w, err := contribCrypto.NewEnvelopeWriter(ctx, cp, &buf, contribCrypto.EnvelopeEncryptOptions{
+			KeyName:   "aeskey",
+			Algorithm: "A256KW",
+		})
+		require.NoError(t, err)
+		// Write in small, uneven pieces
+		for i := 0; i < len(plaintext); i += 1000 {
+			end := i + 1000
+			if end > len(plaintext) {
+				end = len(plaintext)
+			}
+			_, err = w.Write(plaintext[i:end])
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+
+		r, err := contribCrypto.NewEnvelopeReader(ctx, cp, &buf, contribCrypto.EnvelopeDecryptOptions{})
+		require.NoError(t, err)
+		dec, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, dec)
+	})
+
+	t.Run("decrypts with the key version used to encrypt", func(t *testing.T) {
+		plaintext := []byte("hello world")
+		enc, err := contribCrypto.SealEnvelope(ctx, cp, plaintext, contribCrypto.EnvelopeEncryptOptions{
+			KeyName:   "aeskey",
+			Algorithm: "A256KW",
+		})
+		require.NoError(t, err)
+
+		_, err = cp.(contribCrypto.KeyManager).RotateKey(ctx, "aeskey")
+		require.NoError(t, err)
+
+		dec, err := contribCrypto.OpenEnvelope(ctx, cp, enc, contribCrypto.EnvelopeDecryptOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, dec)
+	})
+
+	t.Run("tampering is detected", func(t *testing.T) {
+		plaintext := randomData(3000)
+		opts := contribCrypto.EnvelopeEncryptOptions{
+			KeyName:        "aeskey",
+			Algorithm:      "A256KW",
+			ChunkSize:      1024,
+			AssociatedData: []byte("context"),
+		}
+		enc, err := contribCrypto.SealEnvelope(ctx, cp, plaintext, opts)
+		require.NoError(t, err)
+		decOpts := contribCrypto.EnvelopeDecryptOptions{AssociatedData: []byte("context")}
+		chunkLen := 1024 + 16
+		headerLen := len(enc) - 2*chunkLen - (3000 - 2*1024 + 16)
+
+		t.Run("wrong associated data", func(t *testing.T) {
+			_, err := contribCrypto.OpenEnvelope(ctx, cp, enc, contribCrypto.EnvelopeDecryptOptions{AssociatedData: []byte("other")})
+			require.ErrorIs(t, err, contribCrypto.ErrEnvelopeDecryption)
+		})
+
+		t.Run("modified chunk", func(t *testing.T) {
+			modified := bytes.Clone(enc)
+			modified[headerLen+chunkLen+10] ^= 1
+			_, err := contribCrypto.OpenEnvelope(ctx, cp, modified, decOpts)
+			require.ErrorIs(t, err, contribCrypto.ErrEnvelopeDecryption)
+		})
+
+		t.Run("reordered chunks", func(t *testing.T) {
+			modified := bytes.Clone(enc)
+			copy(modified[headerLen:], enc[headerLen+chunkLen:headerLen+2*chunkLen])
+			copy(modified[headerLen+chunkLen:], enc[headerLen:headerLen+chunkLen])
+			_, err := contribCrypto.OpenEnvelope(ctx, cp, modified, decOpts)
+			require.ErrorIs(t, err, contribCrypto.ErrEnvelopeDecryption)
+		})
+
+		t.Run("truncated at chunk boundary", func(t *testing.T) {
+			_, err := contribCrypto.OpenEnvelope(ctx, cp, enc[:headerLen+2*chunkLen], decOpts)
+			require.ErrorIs(t, err, contribCrypto.ErrEnvelopeDecryption)
+		})
+
+		t.Run("missing all chunks", func(t *testing.T) {
+			_, err := contribCrypto.OpenEnvelope(ctx, cp, enc[:headerLen], decOpts)
+			require.ErrorIs(t, err, contribCrypto.ErrInvalidEnvelope)
+		})
+
+		t.Run("not an envelope", func(t *testing.T) {
+			_, err := contribCrypto.OpenEnvelope(ctx, cp, []byte("hello world, this is not an envelope"), decOpts)
+			require.ErrorIs(t, err, contribCrypto.ErrInvalidEnvelope)
+		})
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := contribCrypto.SealEnvelope(ctx, cp, nil, contribCrypto.EnvelopeEncryptOptions{Algorithm: "A256KW"})
+		require.Error(t, err)
+		_, err = contribCrypto.SealEnvelope(ctx, cp, nil, contribCrypto.EnvelopeEncryptOptions{KeyName: "aeskey"})
+		require.Error(t, err)
+		_, err = contribCrypto.SealEnvelope(ctx, cp, nil, contribCrypto.EnvelopeEncryptOptions{KeyName: "aeskey", Algorithm: "A256KW", ChunkSize: 10})
+		require.Error(t, err)
+		_, err = contribCrypto.SealEnvelope(ctx, cp, nil, contribCrypto.EnvelopeEncryptOptions{KeyName: "notfound", Algorithm: "A256KW"})
+		require.Error(t, err)
+	})
+}
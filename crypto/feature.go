@@ -20,6 +20,11 @@ import (
 // Feature names a feature that can be implemented by the crypto provider components.
 type Feature string
 
+const (
+	// FeatureKeyManagement advertises that the crypto provider implements the KeyManager interface to create, rotate and retrieve versions of keys.
+	FeatureKeyManagement Feature = "KEY_MANAGEMENT"
+)
+
 // IsPresent checks if a given feature is present in the list.
 func (f Feature) IsPresent(features []Feature) bool {
 	return slices.Contains(features, f)
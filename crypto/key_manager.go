@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// Types of keys that can be generated with GenerateKey.
+const (
+	KeyTypeAES128  = "aes-128"
+	KeyTypeAES192  = "aes-192"
+	KeyTypeAES256  = "aes-256"
+	KeyTypeRSA2048 = "rsa-2048"
+	KeyTypeRSA3072 = "rsa-3072"
+	KeyTypeRSA4096 = "rsa-4096"
+	KeyTypeP256    = "ec-p256"
+	KeyTypeP384    = "ec-p384"
+	KeyTypeP521    = "ec-p521"
+	KeyTypeEd25519 = "ed25519"
+)
+
+// ErrKeyExists is returned when creating a key that already exists.
+var ErrKeyExists = errors.New("key already exists")
+
+// KeyManager is an optional interface implemented by crypto providers that can create and rotate keys.
+// Providers that implement it advertise the FeatureKeyManagement feature.
+//
+// Keys managed by a KeyManager are versioned. A specific version of a key is addressed with a key ID in the format "name/version", which can be used as key name in all methods of SubtleCrypto.
+// When using only the name of the key, the latest version is used.
+// Callers should store the key ID of the version used to encrypt or wrap data, so the same version is selected when decrypting or unwrapping.
+type KeyManager interface {
+	// CreateKey generates a new key of the given type (one of the KeyType* constants).
+	// The key is stored as the first version. Returns ErrKeyExists if a key with the same name exists already.
+	CreateKey(ctx context.Context, keyName string, keyType string) (*KeyVersion, error)
+	// RotateKey generates a new version of an existing key, of the same type, which becomes the latest version.
+	// Previous versions remain available to decrypt or unwrap data.
+	RotateKey(ctx context.Context, keyName string) (*KeyVersion, error)
+	// GetKeyVersion returns information on a version of a key.
+	// If version is empty, the latest version is returned.
+	GetKeyVersion(ctx context.Context, keyName string, version string) (*KeyVersion, error)
+}
+
+// KeyVersion contains information on a version of a key.
+type KeyVersion struct {
+	// Name of the key.
+	Name string
+	// Version of the key.
+	Version string
+	// Key ID in the format "name/version".
+	KeyID string
+	// Public part of the key.
+	// This is nil for symmetric keys.
+	PublicKey jwk.Key
+}
+
+// FormatKeyID returns the key ID for a version of a key.
+func FormatKeyID(name string, version string) string {
+	return name + "/" + version
+}
+
+// ParseKeyID splits a key ID in the format "name/version" into name and version.
+// If keyID does not contain a version, version is empty.
+func ParseKeyID(keyID string) (name string, version string) {
+	idx := strings.LastIndexByte(keyID, '/')
+	if idx < 0 {
+		return keyID, ""
+	}
+	return keyID[:idx], keyID[idx+1:]
+}
+
+// GenerateKey generates a new private or symmetric key of the given type.
+func GenerateKey(keyType string) (jwk.Key, error) {
+	var (
+		raw any
+		err error
+	)
+	switch keyType {
+	case KeyTypeAES128, KeyTypeAES192, KeyTypeAES256:
+		var size int
+		_, err = fmt.Sscanf(keyType, "aes-%d", &size)
+		if err == nil {
+			b := make([]byte, size/8)
+			_, err = rand.Read(b)
+			raw = b
+		}
+	case KeyTypeRSA2048, KeyTypeRSA3072, KeyTypeRSA4096:
+		var size int
+		_, err = fmt.Sscanf(keyType, "rsa-%d", &size)
+		if err == nil {
+			raw, err = rsa.GenerateKey(rand.Reader, size)
+		}
+	case KeyTypeP256:
+		raw, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeP384:
+		raw, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeP521:
+		raw, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case KeyTypeEd25519:
+		_, raw, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", keyType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return jwk.FromRaw(raw)
+}
+
+// KeyTypeOf returns the type of a key, as one of the KeyType* constants.
+func KeyTypeOf(key jwk.Key) (string, error) {
+	switch key.KeyType() {
+	case jwa.OctetSeq:
+		var raw []byte
+		err := key.Raw(&raw)
+		if err != nil {
+			return "", err
+		}
+		switch len(raw) {
+		case 16, 24, 32:
+			return fmt.Sprintf("aes-%d", len(raw)*8), nil
+		}
+	case jwa.RSA:
+		var raw rsa.PrivateKey
+		err := key.Raw(&raw)
+		if err != nil {
+			return "", err
+		}
+		switch raw.N.BitLen() {
+		case 2048, 3072, 4096:
+			return fmt.Sprintf("rsa-%d", raw.N.BitLen()), nil
+		}
+	case jwa.EC:
+		if ecKey, ok := key.(jwk.ECDSAPrivateKey); ok {
+			switch ecKey.Crv() {
+			case jwa.P256:
+				return KeyTypeP256, nil
+			case jwa.P384:
+				return KeyTypeP384, nil
+			case jwa.P521:
+				return KeyTypeP521, nil
+			}
+		}
+	case jwa.OKP:
+		if okpKey, ok := key.(jwk.OKPPrivateKey); ok && okpKey.Crv() == jwa.Ed25519 {
+			return KeyTypeEd25519, nil
+		}
+	}
+	return "", errors.New("unsupported key type")
+}
@@ -61,7 +61,9 @@ func (l *localStorageCrypto) Init(_ context.Context, metadata contribCrypto.Meta
 
 // Features returns the features available in this crypto provider.
 func (l *localStorageCrypto) Features() []contribCrypto.Feature {
-	return []contribCrypto.Feature{} // No Feature supported.
+	return []contribCrypto.Feature{
+		contribCrypto.FeatureKeyManagement,
+	}
 }
 
 // Retrieves a key (public or private or symmetric) from a local file.
@@ -72,8 +74,13 @@ func (l *localStorageCrypto) retrieveKey(parentCtx context.Context, key string)
 		return nil, errors.New("invalid key path: cannot contain '..'")
 	}
 
-	// Load the file
+	// Keys created with CreateKey are stored in directories, one per version
 	path := filepath.Join(l.md.Path, key)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return l.retrieveVersionedKey(key)
+	}
+
+	// Load the file
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load key '%s': %w", key, err)
@@ -0,0 +1,245 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+)
+
+// Versioned keys are stored as "<path>/<name>/<version>/key.json", where version is an increasing integer starting from 1.
+const versionedKeyFile = "key.json"
+
+// Ensure localStorageCrypto implements KeyManager.
+var _ contribCrypto.KeyManager = (*localStorageCrypto)(nil)
+
+// CreateKey generates a new key and stores it as its first version.
+func (l *localStorageCrypto) CreateKey(_ context.Context, keyName string, keyType string) (*contribCrypto.KeyVersion, error) {
+	err := validateKeyName(keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(l.md.Path, keyName)
+	if _, err = os.Stat(dir); err == nil {
+		return nil, contribCrypto.ErrKeyExists
+	}
+
+	key, err := contribCrypto.GenerateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for key '%s': %w", keyName, err)
+	}
+	return l.storeKeyVersion(keyName, 1, key)
+}
+
+// RotateKey generates a new version of an existing key.
+func (l *localStorageCrypto) RotateKey(_ context.Context, keyName string) (*contribCrypto.KeyVersion, error) {
+	err := validateKeyName(keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := l.latestVersion(keyName)
+	if err != nil {
+		return nil, err
+	}
+	current, err := l.loadKeyVersion(keyName, latest)
+	if err != nil {
+		return nil, err
+	}
+	keyType, err := contribCrypto.KeyTypeOf(current)
+	if err != nil {
+		return nil, fmt.Errorf("cannot rotate key '%s': %w", keyName, err)
+	}
+	key, err := contribCrypto.GenerateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	return l.storeKeyVersion(keyName, latest+1, key)
+}
+
+// GetKeyVersion returns information on a version of a key, or the latest version if version is empty.
+func (l *localStorageCrypto) GetKeyVersion(_ context.Context, keyName string, version string) (*contribCrypto.KeyVersion, error) {
+	err := validateKeyName(keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	var v int
+	if version == "" {
+		v, err = l.latestVersion(keyName)
+	} else {
+		v, err = parseVersion(version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := l.loadKeyVersion(keyName, v)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyVersion(keyName, v, key)
+}
+
+// retrieveVersionedKey loads a key created with CreateKey.
+// The key parameter is either the name of the key, to load the latest version, or a key ID in the format "name/version".
+func (l *localStorageCrypto) retrieveVersionedKey(key string) (jwk.Key, error) {
+	var (
+		name    string
+		version int
+		err     error
+	)
+	if _, statErr := os.Stat(filepath.Join(l.md.Path, key, versionedKeyFile)); statErr == nil {
+		// Key is a key ID that includes the version
+		var v string
+		name, v = contribCrypto.ParseKeyID(key)
+		version, err = parseVersion(v)
+	} else {
+		name = key
+		version, err = l.latestVersion(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l.loadKeyVersion(name, version)
+}
+
+// loadKeyVersion loads a version of a key and sets its key ID.
+func (l *localStorageCrypto) loadKeyVersion(name string, version int) (jwk.Key, error) {
+	data, err := os.ReadFile(filepath.Join(l.md.Path, name, strconv.Itoa(version), versionedKeyFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, contribCrypto.ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to load key '%s': %w", name, err)
+	}
+	key, err := jwk.ParseKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key '%s': %w", name, err)
+	}
+	err = key.Set(jwk.KeyIDKey, contribCrypto.FormatKeyID(name, strconv.Itoa(version)))
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// storeKeyVersion writes a new version of a key.
+// The key is written to a temporary directory which is then renamed, so readers never see partially-written versions.
+func (l *localStorageCrypto) storeKeyVersion(name string, version int, key jwk.Key) (*contribCrypto.KeyVersion, error) {
+	dir := filepath.Join(l.md.Path, name)
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize key: %w", err)
+	}
+
+	tmp, err := os.MkdirTemp(dir, ".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory for key '%s': %w", name, err)
+	}
+	defer os.RemoveAll(tmp)
+	err = os.WriteFile(filepath.Join(tmp, versionedKeyFile), data, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write key '%s': %w", name, err)
+	}
+
+	target := filepath.Join(dir, strconv.Itoa(version))
+	if _, err = os.Stat(target); err == nil {
+		return nil, fmt.Errorf("version %d of key '%s' already exists", version, name)
+	}
+	err = os.Rename(tmp, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store key '%s': %w", name, err)
+	}
+
+	return newKeyVersion(name, version, key)
+}
+
+// latestVersion returns the highest version of a key.
+func (l *localStorageCrypto) latestVersion(name string) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(l.md.Path, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, contribCrypto.ErrKeyNotFound
+		}
+		return 0, fmt.Errorf("failed to list versions of key '%s': %w", name, err)
+	}
+
+	latest := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		// Ignores temporary directories and other entries that are not versions
+		v, err := parseVersion(e.Name())
+		if err == nil && v > latest {
+			latest = v
+		}
+	}
+	if latest == 0 {
+		return 0, contribCrypto.ErrKeyNotFound
+	}
+	return latest, nil
+}
+
+func newKeyVersion(name string, version int, key jwk.Key) (*contribCrypto.KeyVersion, error) {
+	res := &contribCrypto.KeyVersion{
+		Name:    name,
+		Version: strconv.Itoa(version),
+		KeyID:   contribCrypto.FormatKeyID(name, strconv.Itoa(version)),
+	}
+	if key.KeyType() != jwa.OctetSeq {
+		pk, err := key.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain public key: %w", err)
+		}
+		res.PublicKey = pk
+	}
+	return res, nil
+}
+
+func parseVersion(version string) (int, error) {
+	v, err := strconv.Atoi(version)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid key version '%s'", version)
+	}
+	return v, nil
+}
+
+func validateKeyName(keyName string) error {
+	if keyName == "" {
+		return errors.New("key name is required")
+	}
+	// Do not allow escaping the root path by including ".." in the key's name
+	if strings.Contains(keyName, "..") {
+		return errors.New("invalid key path: cannot contain '..'")
+	}
+	return nil
+}
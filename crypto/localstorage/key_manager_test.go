@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstorage
+
+import (
+	"context"
+	"crypto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func newTestComponent(t *testing.T) (*localStorageCrypto, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	c := NewLocalStorageCrypto(logger.NewLogger("test")).(*localStorageCrypto)
+	err := c.Init(context.Background(), contribCrypto.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"path": dir},
+	}})
+	require.NoError(t, err)
+	return c, dir
+}
+
+func TestKeyManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("advertises key management", func(t *testing.T) {
+		c, _ := newTestComponent(t)
+		assert.True(t, contribCrypto.FeatureKeyManagement.IsPresent(c.Features()))
+	})
+
+	t.Run("create key", func(t *testing.T) {
+		c, dir := newTestComponent(t)
+
+		kv, err := c.CreateKey(ctx, "mykey", contribCrypto.KeyTypeP256)
+		require.NoError(t, err)
+		assert.Equal(t, "mykey", kv.Name)
+		assert.Equal(t, "1", kv.Version)
+		assert.Equal(t, "mykey/1", kv.KeyID)
+		require.NotNil(t, kv.PublicKey)
+
+		info, err := os.Stat(filepath.Join(dir, "mykey", "1", "key.json"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		_, err = c.CreateKey(ctx, "mykey", contribCrypto.KeyTypeP256)
+		require.ErrorIs(t, err, contribCrypto.ErrKeyExists)
+	})
+
+	t.Run("invalid key names and types", func(t *testing.T) {
+		c, _ := newTestComponent(t)
+
+		_, err := c.CreateKey(ctx, "", contribCrypto.KeyTypeAES256)
+		require.Error(t, err)
+		_, err = c.CreateKey(ctx, "../escape", contribCrypto.KeyTypeAES256)
+		require.Error(t, err)
+		_, err = c.CreateKey(ctx, "mykey", "des")
+		require.Error(t, err)
+	})
+
+	t.Run("rotate key", func(t *testing.T) {
+		c, _ := newTestComponent(t)
+
+		_, err := c.CreateKey(ctx, "mykey", contribCrypto.KeyTypeRSA2048)
+		require.NoError(t, err)
+
+		kv, err := c.RotateKey(ctx, "mykey")
+		require.NoError(t, err)
+		assert.Equal(t, "2", kv.Version)
+		assert.Equal(t, "mykey/2", kv.KeyID)
+
+		latest, err := c.GetKeyVersion(ctx, "mykey", "")
+		require.NoError(t, err)
+		assert.Equal(t, "2", latest.Version)
+
+		first, err := c.GetKeyVersion(ctx, "mykey", "1")
+		require.NoError(t, err)
+		assert.False(t, jwkEqual(t, first.PublicKey, latest.PublicKey))
+
+		_, err = c.RotateKey(ctx, "notfound")
+		require.ErrorIs(t, err, contribCrypto.ErrKeyNotFound)
+		_, err = c.GetKeyVersion(ctx, "mykey", "3")
+		require.ErrorIs(t, err, contribCrypto.ErrKeyNotFound)
+	})
+
+	t.Run("version-addressed GetKey", func(t *testing.T) {
+		c, _ := newTestComponent(t)
+
+		_, err := c.CreateKey(ctx, "mykey", contribCrypto.KeyTypeEd25519)
+		require.NoError(t, err)
+		_, err = c.RotateKey(ctx, "mykey")
+		require.NoError(t, err)
+
+		k, err := c.GetKey(ctx, "mykey")
+		require.NoError(t, err)
+		assert.Equal(t, "mykey/2", k.KeyID())
+
+		k, err = c.GetKey(ctx, "mykey/1")
+		require.NoError(t, err)
+		assert.Equal(t, "mykey/1", k.KeyID())
+	})
+
+	t.Run("decrypt data encrypted with an older version", func(t *testing.T) {
+		c, _ := newTestComponent(t)
+
+		kv, err := c.CreateKey(ctx, "mykey", contribCrypto.KeyTypeAES256)
+		require.NoError(t, err)
+		assert.Nil(t, kv.PublicKey)
+
+		plaintext := []byte("hello world")
+		nonce := make([]byte, 12)
+		ciphertext, tag, err := c.Encrypt(ctx, plaintext, "A256GCM", "mykey", nonce, nil)
+		require.NoError(t, err)
+
+		_, err = c.RotateKey(ctx, "mykey")
+		require.NoError(t, err)
+
+		// Decrypting with the latest version fails
+		_, err = c.Decrypt(ctx, ciphertext, "A256GCM", "mykey", nonce, tag, nil)
+		require.Error(t, err)
+
+		// Decrypting with the key ID of the version used to encrypt succeeds
+		res, err := c.Decrypt(ctx, ciphertext, "A256GCM", kv.KeyID, nonce, tag, nil)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, res)
+	})
+
+	t.Run("unversioned keys are still supported", func(t *testing.T) {
+		c, dir := newTestComponent(t)
+
+		err := os.WriteFile(filepath.Join(dir, "plain.json"), []byte(`{"kty":"oct","k":"AAECAwQFBgcICQoLDA0ODw"}`), 0o600)
+		require.NoError(t, err)
+
+		k, err := c.retrieveKey(ctx, "plain.json")
+		require.NoError(t, err)
+		assert.Empty(t, k.KeyID())
+	})
+}
+
+func jwkEqual(t *testing.T, a, b interface {
+	Thumbprint(crypto.Hash) ([]byte, error)
+}) bool {
+	t.Helper()
+	ta, err := a.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	tb, err := b.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	return string(ta) == string(tb)
+}
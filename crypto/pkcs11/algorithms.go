@@ -0,0 +1,146 @@
+//go:build cgo
+// +build cgo
+
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkcs11
+
+import (
+	"fmt"
+
+	p11 "github.com/miekg/pkcs11"
+
+	internals "github.com/dapr/kit/crypto"
+)
+
+// Size of the authentication tag for AES-GCM, in bytes.
+const gcmTagSize = 16
+
+var (
+	encryptionAlgsList = []string{
+		internals.Algorithm_A128CBC, internals.Algorithm_A192CBC, internals.Algorithm_A256CBC,
+		internals.Algorithm_A128CBC_NOPAD, internals.Algorithm_A192CBC_NOPAD, internals.Algorithm_A256CBC_NOPAD,
+		internals.Algorithm_A128GCM, internals.Algorithm_A192GCM, internals.Algorithm_A256GCM,
+		internals.Algorithm_A128KW, internals.Algorithm_A192KW, internals.Algorithm_A256KW,
+		internals.Algorithm_RSA1_5,
+		internals.Algorithm_RSA_OAEP, internals.Algorithm_RSA_OAEP_256, internals.Algorithm_RSA_OAEP_384, internals.Algorithm_RSA_OAEP_512,
+	}
+	signatureAlgsList = []string{
+		internals.Algorithm_ES256, internals.Algorithm_ES384, internals.Algorithm_ES512,
+		internals.Algorithm_PS256, internals.Algorithm_PS384, internals.Algorithm_PS512,
+		internals.Algorithm_RS256, internals.Algorithm_RS384, internals.Algorithm_RS512,
+	}
+
+	// DER-encoded DigestInfo prefixes for RSASSA-PKCS1-v1_5 signatures, from RFC 8017 section 9.2.
+	digestInfoPrefixes = map[string][]byte{
+		internals.Algorithm_RS256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+		internals.Algorithm_RS384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+		internals.Algorithm_RS512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	}
+)
+
+// encryptionMechanism is a PKCS#11 mechanism used to encrypt data or wrap keys.
+type encryptionMechanism struct {
+	mechanism *p11.Mechanism
+	// If true, the authentication tag is appended to the ciphertext.
+	appendsTag bool
+	// Function that must be invoked when the operation is complete, to release the memory allocated for the mechanism's parameters.
+	free func()
+}
+
+// getEncryptionMechanism returns the PKCS#11 mechanism for the encryption algorithm.
+func getEncryptionMechanism(algorithm string, nonce []byte, associatedData []byte) (*encryptionMechanism, error) {
+	res := &encryptionMechanism{
+		free: func() {},
+	}
+	switch algorithm {
+	case internals.Algorithm_A128CBC, internals.Algorithm_A192CBC, internals.Algorithm_A256CBC:
+		if len(nonce) != 16 {
+			return nil, fmt.Errorf("invalid nonce: must be 16 bytes for algorithm '%s'", algorithm)
+		}
+		res.mechanism = p11.NewMechanism(p11.CKM_AES_CBC_PAD, nonce)
+	case internals.Algorithm_A128CBC_NOPAD, internals.Algorithm_A192CBC_NOPAD, internals.Algorithm_A256CBC_NOPAD:
+		if len(nonce) != 16 {
+			return nil, fmt.Errorf("invalid nonce: must be 16 bytes for algorithm '%s'", algorithm)
+		}
+		res.mechanism = p11.NewMechanism(p11.CKM_AES_CBC, nonce)
+	case internals.Algorithm_A128GCM, internals.Algorithm_A192GCM, internals.Algorithm_A256GCM:
+		if len(nonce) != 12 {
+			return nil, fmt.Errorf("invalid nonce: must be 12 bytes for algorithm '%s'", algorithm)
+		}
+		params := p11.NewGCMParams(nonce, associatedData, gcmTagSize*8)
+		res.mechanism = p11.NewMechanism(p11.CKM_AES_GCM, params)
+		res.appendsTag = true
+		res.free = params.Free
+	case internals.Algorithm_A128KW, internals.Algorithm_A192KW, internals.Algorithm_A256KW:
+		res.mechanism = p11.NewMechanism(p11.CKM_AES_KEY_WRAP, nil)
+	case internals.Algorithm_RSA1_5:
+		res.mechanism = p11.NewMechanism(p11.CKM_RSA_PKCS, nil)
+	case internals.Algorithm_RSA_OAEP:
+		res.mechanism = p11.NewMechanism(p11.CKM_RSA_PKCS_OAEP, p11.NewOAEPParams(p11.CKM_SHA_1, p11.CKG_MGF1_SHA1, p11.CKZ_DATA_SPECIFIED, associatedData))
+	case internals.Algorithm_RSA_OAEP_256:
+		res.mechanism = p11.NewMechanism(p11.CKM_RSA_PKCS_OAEP, p11.NewOAEPParams(p11.CKM_SHA256, p11.CKG_MGF1_SHA256, p11.CKZ_DATA_SPECIFIED, associatedData))
+	case internals.Algorithm_RSA_OAEP_384:
+		res.mechanism = p11.NewMechanism(p11.CKM_RSA_PKCS_OAEP, p11.NewOAEPParams(p11.CKM_SHA384, p11.CKG_MGF1_SHA384, p11.CKZ_DATA_SPECIFIED, associatedData))
+	case internals.Algorithm_RSA_OAEP_512:
+		res.mechanism = p11.NewMechanism(p11.CKM_RSA_PKCS_OAEP, p11.NewOAEPParams(p11.CKM_SHA512, p11.CKG_MGF1_SHA512, p11.CKZ_DATA_SPECIFIED, associatedData))
+	default:
+		return nil, fmt.Errorf("invalid algorithm: %s", algorithm)
+	}
+	return res, nil
+}
+
+// getSignatureMechanism returns the PKCS#11 mechanism for the signature algorithm, and the data to sign or verify.
+func getSignatureMechanism(algorithm string, digest []byte) (*p11.Mechanism, []byte, error) {
+	var (
+		hashSize int
+		mech     *p11.Mechanism
+		data     = digest
+	)
+	switch algorithm {
+	case internals.Algorithm_ES256:
+		hashSize = 32
+		mech = p11.NewMechanism(p11.CKM_ECDSA, nil)
+	case internals.Algorithm_ES384:
+		hashSize = 48
+		mech = p11.NewMechanism(p11.CKM_ECDSA, nil)
+	case internals.Algorithm_ES512:
+		hashSize = 64
+		mech = p11.NewMechanism(p11.CKM_ECDSA, nil)
+	case internals.Algorithm_PS256:
+		hashSize = 32
+		mech = p11.NewMechanism(p11.CKM_RSA_PKCS_PSS, p11.NewPSSParams(p11.CKM_SHA256, p11.CKG_MGF1_SHA256, 32))
+	case internals.Algorithm_PS384:
+		hashSize = 48
+		mech = p11.NewMechanism(p11.CKM_RSA_PKCS_PSS, p11.NewPSSParams(p11.CKM_SHA384, p11.CKG_MGF1_SHA384, 48))
+	case internals.Algorithm_PS512:
+		hashSize = 64
+		mech = p11.NewMechanism(p11.CKM_RSA_PKCS_PSS, p11.NewPSSParams(p11.CKM_SHA512, p11.CKG_MGF1_SHA512, 64))
+	case internals.Algorithm_RS256, internals.Algorithm_RS384, internals.Algorithm_RS512:
+		// CKM_RSA_PKCS signs the DigestInfo structure, which contains the digest prefixed by the identifier of the hash function
+		prefix := digestInfoPrefixes[algorithm]
+		hashSize = int(prefix[len(prefix)-1])
+		mech = p11.NewMechanism(p11.CKM_RSA_PKCS, nil)
+		data = make([]byte, 0, len(prefix)+len(digest))
+		data = append(data, prefix...)
+		data = append(data, digest...)
+	default:
+		return nil, nil, fmt.Errorf("invalid algorithm: %s", algorithm)
+	}
+
+	if len(digest) != hashSize {
+		return nil, nil, fmt.Errorf("invalid digest: must be %d bytes for algorithm '%s'", hashSize, algorithm)
+	}
+	return mech, data, nil
+}
@@ -0,0 +1,593 @@
+//go:build cgo
+// +build cgo
+
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkcs11
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	p11 "github.com/miekg/pkcs11"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// OIDs of the named curves supported in CKA_EC_PARAMS.
+var (
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveP384 = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidNamedCurveP521 = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+)
+
+type pkcs11Crypto struct {
+	md     pkcs11Metadata
+	ctx    *p11.Ctx
+	slot   uint
+	logger logger.Logger
+
+	// Session kept open for the lifetime of the component, so the token remains logged in.
+	loginSession p11.SessionHandle
+}
+
+// NewPKCS11Crypto returns a new crypto provider that performs operations with keys stored in a PKCS#11 token, such as a HSM.
+// Keys are identified by their label (CKA_LABEL), and private keys never leave the token.
+func NewPKCS11Crypto(logger logger.Logger) contribCrypto.SubtleCrypto {
+	return &pkcs11Crypto{
+		logger: logger,
+	}
+}
+
+// Init loads the PKCS#11 module and logs into the token.
+func (k *pkcs11Crypto) Init(_ context.Context, metadata contribCrypto.Metadata) error {
+	// Init the metadata
+	err := k.md.InitWithMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	// Load the module
+	k.ctx = p11.New(k.md.Module)
+	if k.ctx == nil {
+		return fmt.Errorf("failed to load PKCS#11 module '%s'", k.md.Module)
+	}
+	err = k.ctx.Initialize()
+	if err != nil && !errors.Is(err, p11.Error(p11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		k.ctx.Destroy()
+		k.ctx = nil
+		return fmt.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+
+	err = k.openToken()
+	if err != nil {
+		k.ctx.Finalize()
+		k.ctx.Destroy()
+		k.ctx = nil
+		return err
+	}
+
+	return nil
+}
+
+func (k *pkcs11Crypto) openToken() (err error) {
+	k.slot, err = k.findSlot()
+	if err != nil {
+		return err
+	}
+
+	k.loginSession, err = k.ctx.OpenSession(k.slot, p11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return fmt.Errorf("failed to open session: %w", err)
+	}
+	if k.md.PIN != "" {
+		err = k.ctx.Login(k.loginSession, p11.CKU_USER, k.md.PIN)
+		if err != nil && !errors.Is(err, p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN)) {
+			k.ctx.CloseSession(k.loginSession)
+			return fmt.Errorf("failed to log into the token: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// findSlot returns the slot configured in the metadata, either by ID or by the label of the token.
+func (k *pkcs11Crypto) findSlot() (uint, error) {
+	slots, err := k.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		if k.md.SlotID != nil {
+			if slot == *k.md.SlotID {
+				return slot, nil
+			}
+			continue
+		}
+
+		info, err := k.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get information on the token in slot %d: %w", slot, err)
+		}
+		// Labels are padded with spaces
+		if strings.TrimRight(info.Label, " \x00") == k.md.TokenLabel {
+			return slot, nil
+		}
+	}
+
+	if k.md.SlotID != nil {
+		return 0, fmt.Errorf("could not find a token in slot %d", *k.md.SlotID)
+	}
+	return 0, fmt.Errorf("could not find a token with label '%s'", k.md.TokenLabel)
+}
+
+// Close logs out of the token and unloads the module.
+func (k *pkcs11Crypto) Close() error {
+	if k.ctx == nil {
+		return nil
+	}
+
+	if k.md.PIN != "" {
+		_ = k.ctx.Logout(k.loginSession)
+	}
+	_ = k.ctx.CloseSession(k.loginSession)
+	err := k.ctx.Finalize()
+	k.ctx.Destroy()
+	k.ctx = nil
+	return err
+}
+
+// Features returns the features available in this crypto provider.
+func (k *pkcs11Crypto) Features() []contribCrypto.Feature {
+	return []contribCrypto.Feature{} // No Feature supported.
+}
+
+// GetKey returns the public part of a key stored in the token.
+// This method returns an error if the key is symmetric.
+func (k *pkcs11Crypto) GetKey(_ context.Context, key string) (pubKey jwk.Key, err error) {
+	err = k.withSession(func(session p11.SessionHandle) error {
+		obj, err := k.findKey(session, key, p11.CKO_PUBLIC_KEY)
+		if err != nil {
+			return err
+		}
+		pubKey, err = k.publicKey(session, obj)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = pubKey.Set(jwk.KeyIDKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pubKey, nil
+}
+
+func (k *pkcs11Crypto) publicKey(session p11.SessionHandle, obj p11.ObjectHandle) (jwk.Key, error) {
+	attrs, err := k.ctx.GetAttributeValue(session, obj, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key type: %w", err)
+	}
+	keyType, err := bytesToUint(attrs[0].Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyType {
+	case p11.CKK_RSA:
+		attrs, err = k.ctx.GetAttributeValue(session, obj, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_MODULUS, nil),
+			p11.NewAttribute(p11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get RSA public key: %w", err)
+		}
+		return jwk.FromRaw(&rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		})
+	case p11.CKK_EC:
+		attrs, err = k.ctx.GetAttributeValue(session, obj, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_EC_PARAMS, nil),
+			p11.NewAttribute(p11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get EC public key: %w", err)
+		}
+		pk, err := parseECPublicKey(attrs[0].Value, attrs[1].Value)
+		if err != nil {
+			return nil, err
+		}
+		return jwk.FromRaw(pk)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %d", keyType)
+	}
+}
+
+// Encrypt a small message and returns the ciphertext.
+func (k *pkcs11Crypto) Encrypt(_ context.Context, plaintext []byte, algorithm string, key string, nonce []byte, associatedData []byte) (ciphertext []byte, tag []byte, err error) {
+	mech, err := getEncryptionMechanism(algorithm, nonce, associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer mech.free()
+
+	err = k.withSession(func(session p11.SessionHandle) error {
+		obj, err := k.findKey(session, key, p11.CKO_SECRET_KEY, p11.CKO_PUBLIC_KEY)
+		if err != nil {
+			return err
+		}
+		err = k.ctx.EncryptInit(session, []*p11.Mechanism{mech.mechanism}, obj)
+		if err != nil {
+			return err
+		}
+		ciphertext, err = k.ctx.Encrypt(session, plaintext)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+
+	if mech.appendsTag {
+		ciphertext, tag = splitTag(ciphertext)
+	}
+	return ciphertext, tag, nil
+}
+
+// Decrypt a small message and returns the plaintext.
+func (k *pkcs11Crypto) Decrypt(_ context.Context, ciphertext []byte, algorithm string, key string, nonce []byte, tag []byte, associatedData []byte) (plaintext []byte, err error) {
+	mech, err := getEncryptionMechanism(algorithm, nonce, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	defer mech.free()
+
+	if mech.appendsTag {
+		ciphertext = appendTag(ciphertext, tag)
+	}
+
+	err = k.withSession(func(session p11.SessionHandle) error {
+		obj, err := k.findKey(session, key, p11.CKO_SECRET_KEY, p11.CKO_PRIVATE_KEY)
+		if err != nil {
+			return err
+		}
+		err = k.ctx.DecryptInit(session, []*p11.Mechanism{mech.mechanism}, obj)
+		if err != nil {
+			return err
+		}
+		plaintext, err = k.ctx.Decrypt(session, ciphertext)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	return plaintext, nil
+}
+
+// WrapKey wraps a symmetric key.
+// The key to wrap is imported into the token as a temporary session object, which is then wrapped with the key encryption key.
+func (k *pkcs11Crypto) WrapKey(_ context.Context, plaintextKey jwk.Key, algorithm string, key string, nonce []byte, associatedData []byte) (wrappedKey []byte, tag []byte, err error) {
+	if plaintextKey.KeyType() != jwa.OctetSeq {
+		return nil, nil, errors.New("cannot wrap asymmetric keys")
+	}
+	var raw []byte
+	err = plaintextKey.Raw(&raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot serialize key: %w", err)
+	}
+
+	mech, err := getEncryptionMechanism(algorithm, nonce, associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer mech.free()
+
+	err = k.withSession(func(session p11.SessionHandle) error {
+		kek, err := k.findKey(session, key, p11.CKO_SECRET_KEY, p11.CKO_PUBLIC_KEY)
+		if err != nil {
+			return err
+		}
+
+		obj, err := k.ctx.CreateObject(session, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_SECRET_KEY),
+			p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_GENERIC_SECRET),
+			p11.NewAttribute(p11.CKA_TOKEN, false),
+			p11.NewAttribute(p11.CKA_EXTRACTABLE, true),
+			p11.NewAttribute(p11.CKA_VALUE, raw),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import key: %w", err)
+		}
+		defer k.ctx.DestroyObject(session, obj)
+
+		wrappedKey, err = k.ctx.WrapKey(session, []*p11.Mechanism{mech.mechanism}, kek, obj)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap key: %w", err)
+	}
+
+	if mech.appendsTag {
+		wrappedKey, tag = splitTag(wrappedKey)
+	}
+	return wrappedKey, tag, nil
+}
+
+// UnwrapKey unwraps a key.
+// The key is unwrapped into a temporary session object, whose value is then extracted.
+func (k *pkcs11Crypto) UnwrapKey(_ context.Context, wrappedKey []byte, algorithm string, key string, nonce []byte, tag []byte, associatedData []byte) (plaintextKey jwk.Key, err error) {
+	mech, err := getEncryptionMechanism(algorithm, nonce, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	defer mech.free()
+
+	if mech.appendsTag {
+		wrappedKey = appendTag(wrappedKey, tag)
+	}
+
+	var raw []byte
+	err = k.withSession(func(session p11.SessionHandle) error {
+		kek, err := k.findKey(session, key, p11.CKO_SECRET_KEY, p11.CKO_PRIVATE_KEY)
+		if err != nil {
+			return err
+		}
+
+		obj, err := k.ctx.UnwrapKey(session, []*p11.Mechanism{mech.mechanism}, kek, wrappedKey, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_SECRET_KEY),
+			p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_GENERIC_SECRET),
+			p11.NewAttribute(p11.CKA_TOKEN, false),
+			p11.NewAttribute(p11.CKA_SENSITIVE, false),
+			p11.NewAttribute(p11.CKA_EXTRACTABLE, true),
+		})
+		if err != nil {
+			return err
+		}
+		defer k.ctx.DestroyObject(session, obj)
+
+		attrs, err := k.ctx.GetAttributeValue(session, obj, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_VALUE, nil),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read unwrapped key: %w", err)
+		}
+		raw = attrs[0].Value
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+
+	// We allow wrapping/unwrapping only symmetric keys
+	plaintextKey, err = jwk.FromRaw(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWK from raw key: %w", err)
+	}
+	return plaintextKey, nil
+}
+
+// Sign a digest.
+func (k *pkcs11Crypto) Sign(_ context.Context, digest []byte, algorithm string, key string) (signature []byte, err error) {
+	mech, data, err := getSignatureMechanism(algorithm, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = k.withSession(func(session p11.SessionHandle) error {
+		obj, err := k.findKey(session, key, p11.CKO_PRIVATE_KEY)
+		if err != nil {
+			return err
+		}
+		err = k.ctx.SignInit(session, []*p11.Mechanism{mech}, obj)
+		if err != nil {
+			return err
+		}
+		signature, err = k.ctx.Sign(session, data)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign digest: %w", err)
+	}
+	return signature, nil
+}
+
+// Verify a signature.
+func (k *pkcs11Crypto) Verify(_ context.Context, digest []byte, signature []byte, algorithm string, key string) (valid bool, err error) {
+	mech, data, err := getSignatureMechanism(algorithm, digest)
+	if err != nil {
+		return false, err
+	}
+
+	err = k.withSession(func(session p11.SessionHandle) error {
+		obj, err := k.findKey(session, key, p11.CKO_PUBLIC_KEY)
+		if err != nil {
+			return err
+		}
+		err = k.ctx.VerifyInit(session, []*p11.Mechanism{mech}, obj)
+		if err != nil {
+			return err
+		}
+		return k.ctx.Verify(session, data, signature)
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, p11.Error(p11.CKR_SIGNATURE_INVALID)), errors.Is(err, p11.Error(p11.CKR_SIGNATURE_LEN_RANGE)):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to verify signature: %w", err)
+	}
+}
+
+// SupportedEncryptionAlgorithms returns the list of supported encryption algorithms.
+// Whether an algorithm can be used depends on the mechanisms supported by the token.
+func (k *pkcs11Crypto) SupportedEncryptionAlgorithms() []string {
+	return encryptionAlgsList
+}
+
+// SupportedSignatureAlgorithms returns the list of supported signature algorithms.
+// Whether an algorithm can be used depends on the mechanisms supported by the token.
+func (k *pkcs11Crypto) SupportedSignatureAlgorithms() []string {
+	return signatureAlgsList
+}
+
+func (pkcs11Crypto) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := pkcs11Metadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.CryptoType)
+	return
+}
+
+// withSession invokes fn with a new session, which is closed afterwards.
+// PKCS#11 sessions cannot be used concurrently, so each operation uses its own.
+func (k *pkcs11Crypto) withSession(fn func(session p11.SessionHandle) error) error {
+	if k.ctx == nil {
+		return errors.New("component is not initialized")
+	}
+
+	session, err := k.ctx.OpenSession(k.slot, p11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return fmt.Errorf("failed to open session: %w", err)
+	}
+	defer k.ctx.CloseSession(session)
+
+	return fn(session)
+}
+
+// findKey returns the first object with the given label, trying the classes in order.
+func (k *pkcs11Crypto) findKey(session p11.SessionHandle, label string, classes ...uint) (p11.ObjectHandle, error) {
+	for _, class := range classes {
+		objs, err := k.findObjects(session, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_LABEL, label),
+			p11.NewAttribute(p11.CKA_CLASS, class),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to find key: %w", err)
+		}
+		if len(objs) > 0 {
+			return objs[0], nil
+		}
+	}
+	return 0, contribCrypto.ErrKeyNotFound
+}
+
+func (k *pkcs11Crypto) findObjects(session p11.SessionHandle, template []*p11.Attribute) ([]p11.ObjectHandle, error) {
+	err := k.ctx.FindObjectsInit(session, template)
+	if err != nil {
+		return nil, err
+	}
+	objs, _, err := k.ctx.FindObjects(session, 1)
+	finalErr := k.ctx.FindObjectsFinal(session)
+	if err != nil {
+		return nil, err
+	}
+	if finalErr != nil {
+		return nil, finalErr
+	}
+	return objs, nil
+}
+
+// parseECPublicKey parses the values of the CKA_EC_PARAMS and CKA_EC_POINT attributes.
+func parseECPublicKey(params []byte, point []byte) (*ecdsa.PublicKey, error) {
+	var oid asn1.ObjectIdentifier
+	_, err := asn1.Unmarshal(params, &oid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EC parameters: %w", err)
+	}
+	var curve elliptic.Curve
+	switch {
+	case oid.Equal(oidNamedCurveP256):
+		curve = elliptic.P256()
+	case oid.Equal(oidNamedCurveP384):
+		curve = elliptic.P384()
+	case oid.Equal(oidNamedCurveP521):
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", oid)
+	}
+
+	// CKA_EC_POINT should be a DER-encoded octet string, but some modules return the raw point
+	var raw []byte
+	rest, err := asn1.Unmarshal(point, &raw)
+	if err != nil || len(rest) > 0 {
+		raw = point
+	}
+
+	// Only uncompressed points are supported
+	byteLen := (curve.Params().BitSize + 7) / 8
+	if len(raw) != 1+2*byteLen || raw[0] != 4 {
+		return nil, errors.New("invalid EC point")
+	}
+	pk := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(raw[1 : 1+byteLen]),
+		Y:     new(big.Int).SetBytes(raw[1+byteLen:]),
+	}
+	if !curve.IsOnCurve(pk.X, pk.Y) {
+		return nil, errors.New("invalid EC point")
+	}
+	return pk, nil
+}
+
+// nativeEndian is the byte order of the platform, used by PKCS#11 to encode CK_ULONG values.
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// bytesToUint decodes a CK_ULONG attribute value.
+func bytesToUint(b []byte) (uint, error) {
+	switch len(b) {
+	case 4:
+		return uint(nativeEndian.Uint32(b)), nil
+	case 8:
+		return uint(nativeEndian.Uint64(b)), nil
+	default:
+		return 0, errors.New("invalid attribute value")
+	}
+}
+
+func splitTag(data []byte) (ciphertext []byte, tag []byte) {
+	if len(data) < gcmTagSize {
+		return data, nil
+	}
+	return data[:len(data)-gcmTagSize], data[len(data)-gcmTagSize:]
+}
+
+func appendTag(ciphertext []byte, tag []byte) []byte {
+	res := make([]byte, 0, len(ciphertext)+len(tag))
+	res = append(res, ciphertext...)
+	res = append(res, tag...)
+	return res
+}
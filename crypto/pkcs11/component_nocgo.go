@@ -0,0 +1,62 @@
+//go:build !cgo
+// +build !cgo
+
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkcs11
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// Loading PKCS#11 modules requires cgo.
+var errCgoRequired = errors.New("the PKCS#11 crypto provider requires a binary built with cgo enabled")
+
+type pkcs11Crypto struct {
+	contribCrypto.LocalCryptoBaseComponent
+}
+
+// NewPKCS11Crypto returns a new crypto provider that performs operations with keys stored in a PKCS#11 token, such as a HSM.
+// This binary was built without cgo, so the provider cannot be initialized.
+func NewPKCS11Crypto(logger logger.Logger) contribCrypto.SubtleCrypto {
+	k := &pkcs11Crypto{}
+	k.RetrieveKeyFn = func(context.Context, string) (jwk.Key, error) {
+		return nil, errCgoRequired
+	}
+	return k
+}
+
+// Init returns an error because PKCS#11 requires cgo.
+func (k *pkcs11Crypto) Init(context.Context, contribCrypto.Metadata) error {
+	return errCgoRequired
+}
+
+// Features returns the features available in this crypto provider.
+func (k *pkcs11Crypto) Features() []contribCrypto.Feature {
+	return []contribCrypto.Feature{} // No Feature supported.
+}
+
+func (pkcs11Crypto) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := pkcs11Metadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.CryptoType)
+	return
+}
@@ -0,0 +1,234 @@
+//go:build cgo
+// +build cgo
+
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkcs11
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"os"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	p11 "github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/metadata"
+	internals "github.com/dapr/kit/crypto"
+	"github.com/dapr/kit/logger"
+)
+
+func TestMetadata(t *testing.T) {
+	parse := func(props map[string]string) (pkcs11Metadata, error) {
+		var md pkcs11Metadata
+		err := md.InitWithMetadata(contribCrypto.Metadata{Base: metadata.Base{Properties: props}})
+		return md, err
+	}
+
+	t.Run("slot ID", func(t *testing.T) {
+		md, err := parse(map[string]string{"module": "/lib/module.so", "slotID": "0", "pin": "1234"})
+		require.NoError(t, err)
+		require.NotNil(t, md.SlotID)
+		assert.Equal(t, uint(0), *md.SlotID)
+		assert.Equal(t, "1234", md.PIN)
+	})
+
+	t.Run("token label", func(t *testing.T) {
+		md, err := parse(map[string]string{"module": "/lib/module.so", "tokenLabel": "dapr"})
+		require.NoError(t, err)
+		assert.Nil(t, md.SlotID)
+		assert.Equal(t, "dapr", md.TokenLabel)
+	})
+
+	t.Run("missing module", func(t *testing.T) {
+		_, err := parse(map[string]string{"tokenLabel": "dapr"})
+		require.ErrorContains(t, err, "'module'")
+	})
+
+	t.Run("missing slot", func(t *testing.T) {
+		_, err := parse(map[string]string{"module": "/lib/module.so"})
+		require.ErrorContains(t, err, "'slotID'")
+	})
+}
+
+func TestGetMechanisms(t *testing.T) {
+	t.Run("GCM requires a 12-byte nonce", func(t *testing.T) {
+		_, err := getEncryptionMechanism(internals.Algorithm_A256GCM, make([]byte, 16), nil)
+		require.Error(t, err)
+
+		mech, err := getEncryptionMechanism(internals.Algorithm_A256GCM, make([]byte, 12), nil)
+		require.NoError(t, err)
+		assert.True(t, mech.appendsTag)
+		mech.free()
+	})
+
+	t.Run("unsupported encryption algorithm", func(t *testing.T) {
+		_, err := getEncryptionMechanism(internals.Algorithm_C20P, nil, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("RSASSA-PKCS1-v1_5 signs the DigestInfo", func(t *testing.T) {
+		digest := sha256.Sum256([]byte("hello"))
+		mech, data, err := getSignatureMechanism(internals.Algorithm_RS256, digest[:])
+		require.NoError(t, err)
+		assert.Equal(t, uint(p11.CKM_RSA_PKCS), mech.Mechanism)
+		assert.Len(t, data, 19+32)
+		assert.Equal(t, digest[:], data[19:])
+	})
+
+	t.Run("digest size must match the algorithm", func(t *testing.T) {
+		digest := sha256.Sum256([]byte("hello"))
+		_, _, err := getSignatureMechanism(internals.Algorithm_ES384, digest[:])
+		require.Error(t, err)
+		_, _, err = getSignatureMechanism(internals.Algorithm_RS512, digest[:])
+		require.Error(t, err)
+	})
+}
+
+func TestParseECPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	params, err := asn1.Marshal(oidNamedCurveP256)
+	require.NoError(t, err)
+	//nolint:staticcheck
+	rawPoint := elliptic.Marshal(elliptic.P256(), key.X, key.Y)
+	derPoint, err := asn1.Marshal(rawPoint)
+	require.NoError(t, err)
+
+	for name, point := range map[string][]byte{"DER-encoded": derPoint, "raw": rawPoint} {
+		t.Run(name, func(t *testing.T) {
+			pk, err := parseECPublicKey(params, point)
+			require.NoError(t, err)
+			assert.True(t, pk.Equal(&key.PublicKey))
+		})
+	}
+
+	t.Run("unsupported curve", func(t *testing.T) {
+		params, err := asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 10})
+		require.NoError(t, err)
+		_, err = parseECPublicKey(params, derPoint)
+		require.Error(t, err)
+	})
+}
+
+// SETUP TESTS
+// 1. Install SoftHSMv2
+// 2. `softhsm2-util --init-token --free --label dapr --pin 1234 --so-pin 1234`
+// 3. `export PKCS11_TEST_MODULE=/usr/lib/softhsm/libsofthsm2.so PKCS11_TEST_TOKEN_LABEL=dapr PKCS11_TEST_PIN=1234`
+// 4. `go test -v -count=1 ./crypto/pkcs11 -run ^TestPKCS11Integration`
+
+func TestPKCS11Integration(t *testing.T) {
+	module := os.Getenv("PKCS11_TEST_MODULE")
+	if module == "" {
+		t.Skip("Skipping because env var PKCS11_TEST_MODULE is empty")
+	}
+
+	ctx := context.Background()
+	k := NewPKCS11Crypto(logger.NewLogger("test")).(*pkcs11Crypto)
+	err := k.Init(ctx, contribCrypto.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"module":     module,
+		"tokenLabel": os.Getenv("PKCS11_TEST_TOKEN_LABEL"),
+		"pin":        os.Getenv("PKCS11_TEST_PIN"),
+	}}})
+	require.NoError(t, err)
+	defer k.Close()
+
+	// Generate session keys, which are deleted when the component is closed
+	_, err = k.ctx.GenerateKey(k.loginSession, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_KEY_GEN, nil)}, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_LABEL, "aeskey"),
+		p11.NewAttribute(p11.CKA_TOKEN, false),
+		p11.NewAttribute(p11.CKA_VALUE_LEN, 32),
+		p11.NewAttribute(p11.CKA_ENCRYPT, true),
+		p11.NewAttribute(p11.CKA_DECRYPT, true),
+		p11.NewAttribute(p11.CKA_WRAP, true),
+		p11.NewAttribute(p11.CKA_UNWRAP, true),
+	})
+	require.NoError(t, err)
+	ecParams, _ := asn1.Marshal(oidNamedCurveP256)
+	_, _, err = k.ctx.GenerateKeyPair(k.loginSession, []*p11.Mechanism{p11.NewMechanism(p11.CKM_EC_KEY_PAIR_GEN, nil)}, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_LABEL, "eckey"),
+		p11.NewAttribute(p11.CKA_TOKEN, false),
+		p11.NewAttribute(p11.CKA_EC_PARAMS, ecParams),
+		p11.NewAttribute(p11.CKA_VERIFY, true),
+	}, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_LABEL, "eckey"),
+		p11.NewAttribute(p11.CKA_TOKEN, false),
+		p11.NewAttribute(p11.CKA_SIGN, true),
+	})
+	require.NoError(t, err)
+
+	t.Run("encrypt and decrypt", func(t *testing.T) {
+		nonce := make([]byte, 12)
+		ciphertext, tag, err := k.Encrypt(ctx, []byte("hello world"), internals.Algorithm_A256GCM, "aeskey", nonce, []byte("aad"))
+		require.NoError(t, err)
+		assert.Len(t, tag, 16)
+
+		plaintext, err := k.Decrypt(ctx, ciphertext, internals.Algorithm_A256GCM, "aeskey", nonce, tag, []byte("aad"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(plaintext))
+
+		_, err = k.Decrypt(ctx, ciphertext, internals.Algorithm_A256GCM, "aeskey", nonce, tag, []byte("other"))
+		require.Error(t, err)
+	})
+
+	t.Run("wrap and unwrap", func(t *testing.T) {
+		dek, err := jwk.FromRaw([]byte("0123456789abcdef0123456789abcdef"))
+		require.NoError(t, err)
+
+		wrapped, _, err := k.WrapKey(ctx, dek, internals.Algorithm_A256KW, "aeskey", nil, nil)
+		require.NoError(t, err)
+		unwrapped, err := k.UnwrapKey(ctx, wrapped, internals.Algorithm_A256KW, "aeskey", nil, nil, nil)
+		require.NoError(t, err)
+
+		var raw []byte
+		require.NoError(t, unwrapped.Raw(&raw))
+		assert.Equal(t, "0123456789abcdef0123456789abcdef", string(raw))
+	})
+
+	t.Run("sign and verify", func(t *testing.T) {
+		digest := sha256.Sum256([]byte("hello world"))
+		signature, err := k.Sign(ctx, digest[:], internals.Algorithm_ES256, "eckey")
+		require.NoError(t, err)
+
+		valid, err := k.Verify(ctx, digest[:], signature, internals.Algorithm_ES256, "eckey")
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		// Verify with the public key exported from the token
+		pk, err := k.GetKey(ctx, "eckey")
+		require.NoError(t, err)
+		valid, err = internals.VerifyPublicKey(digest[:], signature, internals.Algorithm_ES256, pk)
+		require.NoError(t, err)
+		assert.True(t, valid)
+
+		signature[0] ^= 1
+		valid, err = k.Verify(ctx, digest[:], signature, internals.Algorithm_ES256, "eckey")
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		_, err := k.GetKey(ctx, "notfound")
+		require.ErrorIs(t, err, contribCrypto.ErrKeyNotFound)
+	})
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkcs11
+
+import (
+	"errors"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/kit/metadata"
+)
+
+type pkcs11Metadata struct {
+	// Path to the PKCS#11 module (shared library) to load, for example "/usr/lib/softhsm/libsofthsm2.so" (required).
+	Module string `json:"module" mapstructure:"module"`
+	// ID of the slot that contains the token.
+	// Either slotID or tokenLabel is required.
+	SlotID *uint `json:"slotID" mapstructure:"slotID"`
+	// Label of the token, used to find the slot that contains it.
+	// Either slotID or tokenLabel is required.
+	TokenLabel string `json:"tokenLabel" mapstructure:"tokenLabel"`
+	// PIN of the user, used to log into the token.
+	PIN string `json:"pin" mapstructure:"pin"`
+}
+
+func (m *pkcs11Metadata) InitWithMetadata(meta contribCrypto.Metadata) error {
+	m.reset()
+
+	// Decode the metadata
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return err
+	}
+
+	// Validate
+	if m.Module == "" {
+		return errors.New("metadata property 'module' is required")
+	}
+	if m.SlotID == nil && m.TokenLabel == "" {
+		return errors.New("one of the metadata properties 'slotID' and 'tokenLabel' is required")
+	}
+
+	return nil
+}
+
+// Reset the object
+func (m *pkcs11Metadata) reset() {
+	m.Module = ""
+	m.SlotID = nil
+	m.TokenLabel = ""
+	m.PIN = ""
+}
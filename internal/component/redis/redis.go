@@ -83,6 +83,11 @@ type RedisClient interface {
 	XClaimResult(ctx context.Context, stream string, group string, consumer string, minIdleTime time.Duration, messageIDs []string) ([]RedisXMessage, error)
 	TxPipeline() RedisPipeliner
 	TTLResult(ctx context.Context, key string) (time.Duration, error)
+	HGetAllResult(ctx context.Context, key string) (map[string]string, error)
+	LRangeResult(ctx context.Context, key string, start int64, stop int64) ([]string, error)
+	XRangeResult(ctx context.Context, stream string, start string, end string, count int64) ([]RedisXMessage, error)
+	SMembersResult(ctx context.Context, key string) ([]string, error)
+	EvalResult(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
 }
 
 type ConfigurationSubscribeArgs struct {
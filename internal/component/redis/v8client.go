@@ -316,6 +316,85 @@ func (c v8Client) TTLResult(ctx context.Context, key string) (time.Duration, err
 	return c.client.TTL(writeCtx, key).Result()
 }
 
+func (c v8Client) HGetAllResult(ctx context.Context, key string) (map[string]string, error) {
+	var readCtx context.Context
+	if c.readTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.readTimeout))
+		defer cancel()
+		readCtx = timeoutCtx
+	} else {
+		readCtx = ctx
+	}
+	return c.client.HGetAll(readCtx, key).Result()
+}
+
+func (c v8Client) LRangeResult(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	var readCtx context.Context
+	if c.readTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.readTimeout))
+		defer cancel()
+		readCtx = timeoutCtx
+	} else {
+		readCtx = ctx
+	}
+	return c.client.LRange(readCtx, key, start, stop).Result()
+}
+
+func (c v8Client) XRangeResult(ctx context.Context, stream string, start string, end string, count int64) ([]RedisXMessage, error) {
+	var readCtx context.Context
+	if c.readTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.readTimeout))
+		defer cancel()
+		readCtx = timeoutCtx
+	} else {
+		readCtx = ctx
+	}
+	var (
+		res []v8.XMessage
+		err error
+	)
+	if count > 0 {
+		res, err = c.client.XRangeN(readCtx, stream, start, end, count).Result()
+	} else {
+		res, err = c.client.XRange(readCtx, stream, start, end).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// convert res to []RedisXMessage
+	redisXMessages := make([]RedisXMessage, len(res))
+	for i, xMessage := range res {
+		redisXMessages[i] = RedisXMessage(xMessage)
+	}
+
+	return redisXMessages, nil
+}
+
+func (c v8Client) SMembersResult(ctx context.Context, key string) ([]string, error) {
+	var readCtx context.Context
+	if c.readTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.readTimeout))
+		defer cancel()
+		readCtx = timeoutCtx
+	} else {
+		readCtx = ctx
+	}
+	return c.client.SMembers(readCtx, key).Result()
+}
+
+func (c v8Client) EvalResult(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	var writeCtx context.Context
+	if c.writeTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(c.writeTimeout))
+		defer cancel()
+		writeCtx = timeoutCtx
+	} else {
+		writeCtx = ctx
+	}
+	return c.client.Eval(writeCtx, script, keys, args...).Result()
+}
+
 func newV8FailoverClient(s *Settings) RedisClient {
 	if s == nil {
 		return nil
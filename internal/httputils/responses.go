@@ -1,6 +1,7 @@
 package httputils
 
 import (
+	"bytes"
 	"net/http"
 )
 
@@ -31,3 +32,75 @@ func RespondWithRedirect(w http.ResponseWriter, statusCode int, location string)
 	w.Header().Set("location", location)
 	w.WriteHeader(statusCode)
 }
+
+// ResponseRecorder writes the response to the wrapped http.ResponseWriter, keeping a copy of the status code and of the body.
+// Bodies larger than the maximum size are not kept.
+type ResponseRecorder struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	maxSize     int
+	overflow    bool
+}
+
+// NewResponseRecorder returns a ResponseRecorder that keeps bodies of up to maxSize bytes.
+func NewResponseRecorder(w http.ResponseWriter, maxSize int) *ResponseRecorder {
+	return &ResponseRecorder{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+		maxSize:        maxSize,
+	}
+}
+
+// Status returns the status code of the response.
+func (rr *ResponseRecorder) Status() int {
+	return rr.status
+}
+
+// Body returns the body of the response, or nil if it exceeded the maximum size.
+func (rr *ResponseRecorder) Body() []byte {
+	if rr.overflow {
+		return nil
+	}
+	return rr.body.Bytes()
+}
+
+// Overflow returns true if the body exceeded the maximum size, so it wasn't kept.
+func (rr *ResponseRecorder) Overflow() bool {
+	return rr.overflow
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (rr *ResponseRecorder) WriteHeader(statusCode int) {
+	if rr.wroteHeader {
+		return
+	}
+	rr.wroteHeader = true
+	rr.status = statusCode
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.
+func (rr *ResponseRecorder) Write(b []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	if !rr.overflow {
+		if rr.body.Len()+len(b) > rr.maxSize {
+			rr.overflow = true
+			rr.body = bytes.Buffer{}
+		} else {
+			rr.body.Write(b)
+		}
+	}
+	return rr.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, if the wrapped http.ResponseWriter supports it.
+func (rr *ResponseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
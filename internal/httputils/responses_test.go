@@ -0,0 +1,44 @@
+package httputils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	t.Run("records status and body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := NewResponseRecorder(w, 10)
+		rec.WriteHeader(http.StatusCreated)
+		rec.WriteHeader(http.StatusTeapot)
+		rec.Write([]byte("hello"))
+
+		assert.Equal(t, http.StatusCreated, rec.Status())
+		assert.Equal(t, "hello", string(rec.Body()))
+		assert.False(t, rec.Overflow())
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("defaults to 200", func(t *testing.T) {
+		rec := NewResponseRecorder(httptest.NewRecorder(), 10)
+		rec.Write([]byte("a"))
+		assert.Equal(t, http.StatusOK, rec.Status())
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rec := NewResponseRecorder(w, 10)
+		rec.Write([]byte("hello "))
+		rec.Write([]byte("world"))
+
+		assert.True(t, rec.Overflow())
+		assert.Nil(t, rec.Body())
+		assert.Equal(t, "hello world", w.Body.String())
+		rec.Flush()
+		assert.True(t, w.Flushed)
+	})
+}
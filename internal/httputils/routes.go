@@ -0,0 +1,117 @@
+package httputils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// PathPattern is a pattern for request paths, made of segments separated by "/".
+// Each segment is matched with path.Match, so it can contain wildcards such as "*", which matches any one segment;
+// segments in the format "{name}" match any one segment too, and capture its value.
+// Patterns ending with "/**" match all paths with that prefix.
+type PathPattern struct {
+	segments []string
+	prefix   bool
+}
+
+// ParsePathPattern parses and validates a path pattern, which must begin with "/".
+func ParsePathPattern(val string) (PathPattern, error) {
+	if !strings.HasPrefix(val, "/") {
+		return PathPattern{}, fmt.Errorf("path '%s' must begin with '/'", val)
+	}
+	p := PathPattern{}
+	val, p.prefix = strings.CutSuffix(val, "/**")
+	if val != "" && val != "/" {
+		p.segments = strings.Split(val[1:], "/")
+	}
+	for _, s := range p.segments {
+		if isCaptureSegment(s) {
+			continue
+		}
+		if strings.ContainsAny(s, "{}") {
+			return PathPattern{}, fmt.Errorf("invalid segment '%s' in path", s)
+		}
+		_, err := path.Match(s, "")
+		if err != nil {
+			return PathPattern{}, fmt.Errorf("invalid segment '%s' in path: %w", s, err)
+		}
+	}
+	return p, nil
+}
+
+// Match matches the path against the pattern, returning the values of the "{name}" segments.
+// The path is cleaned first, so requests for paths such as "//admin" or "/public/../admin" can't bypass the pattern "/admin/**".
+func (p PathPattern) Match(reqPath string) (captured []string, ok bool) {
+	reqPath = path.Clean("/" + reqPath)
+	var parts []string
+	if reqPath != "/" {
+		parts = strings.Split(strings.TrimPrefix(reqPath, "/"), "/")
+	}
+	if len(parts) < len(p.segments) || (!p.prefix && len(parts) != len(p.segments)) {
+		return nil, false
+	}
+
+	for i, s := range p.segments {
+		if isCaptureSegment(s) {
+			captured = append(captured, parts[i])
+			continue
+		}
+		match, _ := path.Match(s, parts[i])
+		if !match {
+			return nil, false
+		}
+	}
+	return captured, true
+}
+
+func isCaptureSegment(s string) bool {
+	return len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}'
+}
+
+// Route matches requests by path and method.
+// It is embedded in the rules of middlewares that are configured with JSON, which have the "path" and "methods" properties.
+type Route struct {
+	// Path pattern; see PathPattern.
+	Path string `json:"path"`
+	// HTTP methods; if empty, all methods match.
+	Methods []string `json:"methods"`
+
+	pattern PathPattern
+}
+
+// Parse validates the path pattern and normalizes the methods.
+// It must be invoked before Matches.
+func (r *Route) Parse() (err error) {
+	if r.Path == "" {
+		return errors.New("missing the path")
+	}
+	r.pattern, err = ParsePathPattern(r.Path)
+	if err != nil {
+		return fmt.Errorf("invalid path pattern: %w", err)
+	}
+	for i := range r.Methods {
+		r.Methods[i] = strings.ToUpper(r.Methods[i])
+	}
+	return nil
+}
+
+// Matches returns true if the request matches the path pattern and one of the methods.
+func (r *Route) Matches(req *http.Request) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if m == req.Method {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	_, ok := r.pattern.Match(req.URL.Path)
+	return ok
+}
@@ -0,0 +1,88 @@
+package httputils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		match    bool
+		captured []string
+	}{
+		{pattern: "/", path: "/", match: true},
+		{pattern: "/", path: "/a", match: false},
+		{pattern: "/a/b", path: "/a/b", match: true},
+		{pattern: "/a/b", path: "/a/b/c", match: false},
+		{pattern: "/a/*", path: "/a/b", match: true},
+		{pattern: "/a/*", path: "/a/b/c", match: false},
+		{pattern: "/a/b*", path: "/a/bc", match: true},
+		{pattern: "/a/**", path: "/a", match: true},
+		{pattern: "/a/**", path: "/a/b/c", match: true},
+		{pattern: "/a/**", path: "/ab", match: false},
+		{pattern: "/**", path: "/a/b", match: true},
+		{pattern: "/users/{id}/orders/{order}", path: "/users/1/orders/2", match: true, captured: []string{"1", "2"}},
+		{pattern: "/users/{id}", path: "/users/1/orders", match: false},
+		{pattern: "/users/{id}/**", path: "/users/1/orders", match: true, captured: []string{"1"}},
+		{pattern: "/users/{id}/**", path: "/users", match: false},
+		// Paths are cleaned before matching
+		{pattern: "/a/**", path: "//a/b", match: true},
+		{pattern: "/a/**", path: "/a/./b", match: true},
+		{pattern: "/a/**", path: "/c/../a/b", match: true},
+		{pattern: "/a/b", path: "/a/b/", match: true},
+		{pattern: "/a/{x}", path: "/a//b", match: true, captured: []string{"b"}},
+		{pattern: "/c/**", path: "/c/../a/b", match: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p, err := ParsePathPattern(tt.pattern)
+			require.NoError(t, err)
+			captured, ok := p.Match(tt.path)
+			assert.Equal(t, tt.match, ok)
+			assert.Equal(t, tt.captured, captured)
+		})
+	}
+
+	t.Run("invalid patterns", func(t *testing.T) {
+		for _, pattern := range []string{"", "a/b", "/a/[", "/a/{id", "/a/{}"} {
+			_, err := ParsePathPattern(pattern)
+			assert.Error(t, err, pattern)
+		}
+	})
+}
+
+func TestRoute(t *testing.T) {
+	t.Run("matches path and methods", func(t *testing.T) {
+		r := Route{Path: "/admin/**", Methods: []string{"post", "Put"}}
+		require.NoError(t, r.Parse())
+		assert.Equal(t, []string{"POST", "PUT"}, r.Methods)
+
+		assert.True(t, r.Matches(httptest.NewRequest("POST", "/admin/users", nil)))
+		assert.True(t, r.Matches(httptest.NewRequest("PUT", "/admin", nil)))
+		assert.False(t, r.Matches(httptest.NewRequest("GET", "/admin/users", nil)))
+		assert.False(t, r.Matches(httptest.NewRequest("POST", "/users", nil)))
+	})
+
+	t.Run("all methods", func(t *testing.T) {
+		r := Route{Path: "/users/*"}
+		require.NoError(t, r.Parse())
+
+		assert.True(t, r.Matches(httptest.NewRequest("DELETE", "/users/1", nil)))
+		assert.False(t, r.Matches(httptest.NewRequest("DELETE", "/users/1/orders", nil)))
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		r := Route{}
+		require.ErrorContains(t, r.Parse(), "missing the path")
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		r := Route{Path: "/a/["}
+		require.ErrorContains(t, r.Parse(), "invalid path pattern")
+	})
+}
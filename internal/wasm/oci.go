@@ -0,0 +1,651 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	ociMediaTypeImageIndex      = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeImageManifest   = "application/vnd.oci.image.manifest.v1+json"
+	dockerMediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	dockerMediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// Maximum size of manifests, image configs and signature payloads.
+	ociMaxManifestSize = 4 << 20
+	// Maximum size of layers and modules.
+	ociMaxBlobSize = 256 << 20
+
+	// Registry used for references that don't include one.
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Media types of the layers that contain a wasm module, used by the Wasm OCI artifact format and by tools such as wasm-to-oci.
+var ociWasmLayerMediaTypes = map[string]struct{}{
+	"application/wasm":                                  {},
+	"application/vnd.wasm.content.layer.v1+wasm":        {},
+	"application/vnd.module.wasm.content.layer.v1+wasm": {},
+}
+
+var (
+	ociRepositoryRegex = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	ociTagRegex        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestRegex        = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// ociReference is a reference to an image in an OCI registry, in the format oci://registry/repository[:tag][@digest].
+type ociReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+// parseOCIReference parses a reference from an oci:// URL.
+// References without a registry are resolved against Docker Hub.
+func parseOCIReference(u string) (*ociReference, error) {
+	name, ok := strings.CutPrefix(u, "oci://")
+	if !ok {
+		return nil, fmt.Errorf("invalid OCI reference: %s", u)
+	}
+
+	ref := &ociReference{}
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		ref.digest = name[i+1:]
+		name = name[:i]
+		if err := validateDigest(ref.digest); err != nil {
+			return nil, fmt.Errorf("invalid OCI reference %s: %w", u, err)
+		}
+	}
+	if i := strings.LastIndexByte(name, ':'); i > strings.LastIndexByte(name, '/') {
+		ref.tag = name[i+1:]
+		name = name[:i]
+		if !ociTagRegex.MatchString(ref.tag) {
+			return nil, fmt.Errorf("invalid OCI reference %s: invalid tag", u)
+		}
+	}
+
+	registry, repository, ok := strings.Cut(name, "/")
+	if !ok || !(strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		registry = "docker.io"
+		repository = name
+	}
+	if registry == "docker.io" || registry == "index.docker.io" {
+		registry = dockerHubRegistry
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	if !ociRepositoryRegex.MatchString(repository) {
+		return nil, fmt.Errorf("invalid OCI reference %s: invalid repository name", u)
+	}
+	ref.registry = registry
+	ref.repository = repository
+
+	if ref.tag == "" && ref.digest == "" {
+		ref.tag = "latest"
+	}
+	return ref, nil
+}
+
+// reference returns the digest if the reference is pinned, or the tag otherwise.
+func (r *ociReference) reference() string {
+	if r.digest != "" {
+		return r.digest
+	}
+	return r.tag
+}
+
+// ociDescriptor describes content in a registry.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+}
+
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// ociManifest is an image manifest or, if Manifests is set, an image index.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+func (m *ociManifest) isIndex() bool {
+	switch m.MediaType {
+	case ociMediaTypeImageIndex, dockerMediaTypeManifestList:
+		return true
+	case ociMediaTypeImageManifest, dockerMediaTypeManifest:
+		return false
+	default:
+		return len(m.Manifests) > 0 && len(m.Layers) == 0
+	}
+}
+
+// selectManifest returns the manifest in the index for the wasm platform.
+func (m *ociManifest) selectManifest() (ociDescriptor, error) {
+	if len(m.Manifests) == 1 {
+		return m.Manifests[0], nil
+	}
+	for _, d := range m.Manifests {
+		if d.Platform != nil && (d.Platform.Architecture == "wasm" || d.Platform.OS == "wasip1" || d.Platform.OS == "wasi") {
+			return d, nil
+		}
+	}
+	return ociDescriptor{}, errors.New("image index does not contain a manifest for the wasm platform")
+}
+
+// ociClient pulls wasm modules from OCI registries.
+type ociClient struct {
+	c         http.Client
+	scheme    string
+	username  string
+	password  string
+	publicKey crypto.PublicKey
+	cache     ociCache
+
+	// Value of the authorization header, set after the registry challenges the client
+	authorization string
+}
+
+// newOCIClient returns a new ociClient configured from the metadata.
+func newOCIClient(transport http.RoundTripper, m *InitMetadata) (*ociClient, error) {
+	c := &ociClient{
+		c:        http.Client{Transport: transport},
+		scheme:   "https",
+		username: m.OCIUsername,
+		password: m.OCIPassword,
+		cache:    ociCache{dir: m.OCICacheDir},
+	}
+	if m.OCIPlainHTTP {
+		c.scheme = "http"
+	}
+	if m.OCIPublicKey != "" {
+		var err error
+		c.publicKey, err = parsePublicKey(m.OCIPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ociPublicKey: %w", err)
+		}
+	}
+	if c.cache.dir == "" {
+		// The cache is best-effort, so it's disabled if there's no cache directory
+		dir, err := os.UserCacheDir()
+		if err == nil {
+			c.cache.dir = filepath.Join(dir, "dapr", "wasm")
+		}
+	}
+	return c, nil
+}
+
+// pull returns the wasm module referenced by ref.
+//
+// The module is either a layer of a Wasm OCI artifact, or the file named by the entrypoint of a container image.
+// If a public key is configured, the manifest must have a valid cosign signature.
+func (c *ociClient) pull(ctx context.Context, ref *ociReference) ([]byte, error) {
+	manifest, digest, err := c.fetchManifest(ctx, ref, ref.reference())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.publicKey != nil {
+		err = c.verifySignature(ctx, ref, digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.isIndex() {
+		desc, err := manifest.selectManifest()
+		if err != nil {
+			return nil, err
+		}
+		manifest, _, err = c.fetchManifest(ctx, ref, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, layer := range manifest.Layers {
+		if _, ok := ociWasmLayerMediaTypes[layer.MediaType]; ok {
+			return c.fetchBlob(ctx, ref, layer, ociMaxBlobSize)
+		}
+	}
+	return c.extractEntrypoint(ctx, ref, manifest)
+}
+
+// extractEntrypoint returns the file named by the entrypoint of a container image, searching its layers from the top.
+func (c *ociClient) extractEntrypoint(ctx context.Context, ref *ociReference, manifest *ociManifest) ([]byte, error) {
+	if len(manifest.Layers) == 0 || manifest.Config.Digest == "" {
+		return nil, errors.New("image does not contain a wasm module")
+	}
+
+	configData, err := c.fetchBlob(ctx, ref, manifest.Config, ociMaxManifestSize)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Config struct {
+			Entrypoint []string `json:"Entrypoint"`
+			Cmd        []string `json:"Cmd"`
+		} `json:"config"`
+	}
+	err = json.Unmarshal(configData, &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image config: %w", err)
+	}
+	var entrypoint string
+	if len(config.Config.Entrypoint) > 0 {
+		entrypoint = config.Config.Entrypoint[0]
+	} else if len(config.Config.Cmd) > 0 {
+		entrypoint = config.Config.Cmd[0]
+	}
+	if entrypoint == "" {
+		return nil, errors.New("image does not contain a wasm layer or an entrypoint")
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+entrypoint), "/")
+	for i := len(manifest.Layers) - 1; i >= 0; i-- {
+		layer, err := c.fetchBlob(ctx, ref, manifest.Layers[i], ociMaxBlobSize)
+		if err != nil {
+			return nil, err
+		}
+		data, found, err := findFileInLayer(layer, name)
+		if errors.Is(err, errWhiteout) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", manifest.Layers[i].Digest, err)
+		}
+		if found {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("entrypoint %s was not found in the image", entrypoint)
+}
+
+// Error returned when a file was removed from the image by a layer.
+var errWhiteout = errors.New("file was removed from the image")
+
+// findFileInLayer returns the contents of a regular file in a layer, which is a tar archive, optionally compressed with gzip.
+func findFileInLayer(layer []byte, name string) ([]byte, bool, error) {
+	var r io.Reader = bytes.NewReader(layer)
+	if len(layer) >= 2 && layer[0] == 0x1f && layer[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, false, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	whiteout := path.Join(path.Dir(name), ".wh."+path.Base(name))
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+
+		switch strings.TrimPrefix(path.Clean("/"+h.Name), "/") {
+		case whiteout:
+			return nil, false, errWhiteout
+		case name:
+			if h.Typeflag != tar.TypeReg {
+				return nil, false, fmt.Errorf("%s is not a regular file", name)
+			}
+			data, err := readLimited(tr, ociMaxBlobSize)
+			if err != nil {
+				return nil, false, err
+			}
+			return data, true, nil
+		}
+	}
+}
+
+// fetchManifest returns a manifest and its digest.
+// Manifests referenced by digest are read from the cache if present.
+func (c *ociClient) fetchManifest(ctx context.Context, ref *ociReference, reference string) (*ociManifest, string, error) {
+	pinned := digestRegex.MatchString(reference)
+
+	var data []byte
+	if pinned {
+		data, _ = c.cache.get(reference)
+	}
+	if data == nil {
+		res, err := c.get(ctx, ref, "manifests/"+reference,
+			ociMediaTypeImageManifest, ociMediaTypeImageIndex, dockerMediaTypeManifest, dockerMediaTypeManifestList)
+		if err != nil {
+			return nil, "", err
+		}
+		data, err = readLimited(res.Body, ociMaxManifestSize)
+		res.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	digest := digestOf(data)
+	if pinned && digest != reference {
+		return nil, "", fmt.Errorf("content digest %s does not match the expected digest %s", digest, reference)
+	}
+	c.cache.put(digest, data)
+
+	manifest := &ociManifest{}
+	err := json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, digest, nil
+}
+
+// fetchBlob returns the content of a blob, after verifying its digest.
+func (c *ociClient) fetchBlob(ctx context.Context, ref *ociReference, desc ociDescriptor, maxSize int64) ([]byte, error) {
+	err := validateDigest(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if desc.Size > maxSize {
+		return nil, fmt.Errorf("blob %s exceeds the maximum size of %d bytes", desc.Digest, maxSize)
+	}
+
+	if data, ok := c.cache.get(desc.Digest); ok {
+		return data, nil
+	}
+
+	res, err := c.get(ctx, ref, "blobs/"+desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readLimited(res.Body, maxSize)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = verifyDigest(data, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if desc.Size > 0 && int64(len(data)) != desc.Size {
+		return nil, fmt.Errorf("blob %s has size %d, expected %d", desc.Digest, len(data), desc.Size)
+	}
+	c.cache.put(desc.Digest, data)
+	return data, nil
+}
+
+// get sends a GET request to the registry API of the repository, authenticating if the registry requests it.
+func (c *ociClient) get(ctx context.Context, ref *ociReference, p string, accept ...string) (*http.Response, error) {
+	u := c.scheme + "://" + ref.registry + "/v2/" + ref.repository + "/" + p
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		res, err := c.c.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := res.Header.Get("WWW-Authenticate")
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			err = c.authenticate(ctx, ref, challenge)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if res.StatusCode != http.StatusOK {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			return nil, fmt.Errorf("received %v status code from %q", res.StatusCode, u)
+		}
+		return res, nil
+	}
+}
+
+// authenticate responds to the authentication challenge of the registry.
+// Registries can request basic authentication or, more commonly, a bearer token obtained from an authorization service.
+func (c *ociClient) authenticate(ctx context.Context, ref *ociReference, challenge string) error {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return errors.New("registry requires authentication, but no credentials were provided")
+		}
+		c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+	case "bearer":
+		token, err := c.fetchToken(ctx, ref, params)
+		if err != nil {
+			return err
+		}
+		c.authorization = "Bearer " + token
+	default:
+		return fmt.Errorf("unsupported authentication challenge from registry: %q", challenge)
+	}
+	return nil
+}
+
+// fetchToken obtains a bearer token from the authorization service, using the credentials if present.
+func (c *ociClient) fetchToken(ctx context.Context, ref *ociReference, params map[string]string) (string, error) {
+	if params["realm"] == "" {
+		return "", errors.New("authentication challenge from registry is missing the realm")
+	}
+	u, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid realm in authentication challenge: %w", err)
+	}
+	q := u.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.repository + ":pull"
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	res, err := c.c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, res.Body)
+		return "", fmt.Errorf("failed to obtain a token from the registry: received %v status code from %q", res.StatusCode, u.Scheme+"://"+u.Host+u.Path)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(io.LimitReader(res.Body, ociMaxManifestSize)).Decode(&body)
+	if err != nil {
+		return "", fmt.Errorf("invalid token response from the registry: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("token response from the registry does not contain a token")
+}
+
+// parseAuthChallenge parses the value of a WWW-Authenticate header, such as `Bearer realm="https://auth.example.com/token",service="example.com"`.
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var val string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.IndexByte(after[1:], '"')
+			if end < 0 {
+				val, rest = after[1:], ""
+			} else {
+				val, rest = after[1:end+1], after[end+2:]
+			}
+		} else {
+			val, rest, _ = strings.Cut(after, ",")
+			val = strings.TrimSpace(val)
+		}
+		params[key] = val
+	}
+	return scheme, params
+}
+
+// ociCache is a local content-addressed cache of manifests and blobs, with the same layout as the blobs directory of an OCI image layout.
+// The cache is best-effort: errors writing to it are ignored.
+type ociCache struct {
+	dir string
+}
+
+func (c ociCache) path(digest string) string {
+	algo, hash, _ := strings.Cut(digest, ":")
+	return filepath.Join(c.dir, "blobs", algo, hash)
+}
+
+// get returns the cached content with the given digest.
+// Content that is corrupted is removed.
+func (c ociCache) get(digest string) ([]byte, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	p := c.path(digest)
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	if digestOf(data) != digest {
+		_ = os.Remove(p)
+		return nil, false
+	}
+	return data, true
+}
+
+// put adds content to the cache.
+// Content is written to a temporary file first, so concurrent readers never see partial files.
+func (c ociCache) put(digest string, data []byte) {
+	if c.dir == "" {
+		return
+	}
+	p := c.path(digest)
+	if _, err := os.Stat(p); err == nil {
+		return
+	}
+	err := os.MkdirAll(filepath.Dir(p), 0o700)
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+// digestOf returns the sha256 digest of the data, in the format "sha256:<hex>".
+func digestOf(data []byte) string {
+	h := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// validateDigest returns an error if the digest is not a sha256 digest in the format "sha256:<hex>".
+func validateDigest(digest string) error {
+	if !digestRegex.MatchString(digest) {
+		return fmt.Errorf("invalid digest %q: only sha256 digests in the format sha256:<hex> are supported", digest)
+	}
+	return nil
+}
+
+// verifyDigest returns an error if the digest of the data doesn't match the expected one.
+func verifyDigest(data []byte, expected string) error {
+	err := validateDigest(expected)
+	if err != nil {
+		return err
+	}
+	if digest := digestOf(data); digest != expected {
+		return fmt.Errorf("content digest %s does not match the expected digest %s", digest, expected)
+	}
+	return nil
+}
+
+// readLimited reads the entire reader, returning an error if it contains more than maxSize bytes.
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("content exceeds the maximum size of %d bytes", maxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Annotation of the layers of cosign signatures, containing the base64-encoded signature of the layer.
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// parsePublicKey parses a PEM-encoded ECDSA, RSA or Ed25519 public key.
+func parsePublicKey(val string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(val))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// verifySignature verifies that the manifest with the given digest has a cosign signature made with the public key.
+//
+// Cosign stores signatures in the same repository, with the tag "sha256-<hex>.sig".
+// Each layer of the signature manifest is a payload that references the digest of the signed manifest, and the signature of the payload is in an annotation.
+func (c *ociClient) verifySignature(ctx context.Context, ref *ociReference, digest string) error {
+	manifest, _, err := c.fetchManifest(ctx, ref, strings.Replace(digest, ":", "-", 1)+".sig")
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the signatures of %s: %w", digest, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		sig, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := c.fetchBlob(ctx, ref, layer, ociMaxManifestSize)
+		if err != nil {
+			return err
+		}
+		if verifyCosignSignature(c.publicKey, payload, sig, digest) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no valid signature found for %s", digest)
+}
+
+// verifyCosignSignature verifies the signature of a cosign payload, and that the payload references the digest.
+func verifyCosignSignature(key crypto.PublicKey, payload []byte, sig string, digest string) error {
+	sigBytes, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	h := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, h[:], sigBytes) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sigBytes)
+		if err != nil {
+			return err
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, sigBytes) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	var p struct {
+		Critical struct {
+			Image struct {
+				Digest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	err = json.Unmarshal(payload, &p)
+	if err != nil {
+		return fmt.Errorf("invalid signature payload: %w", err)
+	}
+	if p.Critical.Image.Digest != digest {
+		return fmt.Errorf("signature is for %s, not %s", p.Critical.Image.Digest, digest)
+	}
+	return nil
+}
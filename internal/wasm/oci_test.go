@@ -0,0 +1,455 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+// testRegistry is a minimal implementation of the pull side of the OCI distribution API.
+type testRegistry struct {
+	server *httptest.Server
+
+	// If set, requests must be authenticated with a token, obtained with these credentials
+	username string
+	password string
+
+	lock      sync.Mutex
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	requests  int
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+
+	reg := &testRegistry{
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+	}
+	reg.server = httptest.NewServer(http.HandlerFunc(reg.handle))
+	t.Cleanup(reg.server.Close)
+	return reg
+}
+
+func (reg *testRegistry) handle(w http.ResponseWriter, r *http.Request) {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	reg.requests++
+
+	if r.URL.Path == "/token" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != reg.username || pass != reg.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "token-" + r.URL.Query().Get("scope")})
+		return
+	}
+
+	p, ok := strings.CutPrefix(r.URL.Path, "/v2/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var (
+		store map[string][]byte
+		repo  string
+		ref   string
+	)
+	if i := strings.LastIndex(p, "/manifests/"); i >= 0 {
+		store, repo, ref = reg.manifests, p[:i], p[i+len("/manifests/"):]
+	} else if i := strings.LastIndex(p, "/blobs/"); i >= 0 {
+		store, repo, ref = reg.blobs, p[:i], p[i+len("/blobs/"):]
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if reg.username != "" && r.Header.Get("Authorization") != "Bearer token-repository:"+repo+":pull" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+reg.server.URL+`/token",service="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, ok := store[repo+"/"+ref]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+func (reg *testRegistry) host() string {
+	return strings.TrimPrefix(reg.server.URL, "http://")
+}
+
+func (reg *testRegistry) requestCount() int {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	return reg.requests
+}
+
+func (reg *testRegistry) pushBlob(repo string, mediaType string, data []byte) ociDescriptor {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+
+	digest := digestOf(data)
+	reg.blobs[repo+"/"+digest] = data
+	return ociDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(data))}
+}
+
+// pushManifest stores the manifest with the given tag, and returns its digest.
+func (reg *testRegistry) pushManifest(t *testing.T, repo string, tag string, manifest any) string {
+	t.Helper()
+
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	digest := digestOf(data)
+	reg.manifests[repo+"/"+digest] = data
+	if tag != "" {
+		reg.manifests[repo+"/"+tag] = data
+	}
+	return digest
+}
+
+// pushArtifact pushes a Wasm OCI artifact, and returns the digest of its manifest.
+func (reg *testRegistry) pushArtifact(t *testing.T, repo string, tag string, wasm []byte) string {
+	t.Helper()
+
+	return reg.pushManifest(t, repo, tag, ociManifest{
+		MediaType: ociMediaTypeImageManifest,
+		Config:    reg.pushBlob(repo, "application/vnd.wasm.config.v0+json", []byte("{}")),
+		Layers:    []ociDescriptor{reg.pushBlob(repo, "application/wasm", wasm)},
+	})
+}
+
+// sign pushes a cosign signature of the manifest with the given digest.
+func (reg *testRegistry) sign(t *testing.T, repo string, digest string, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	payload, err := json.Marshal(map[string]any{
+		"critical": map[string]any{
+			"identity": map[string]string{"docker-reference": reg.host() + "/" + repo},
+			"image":    map[string]string{"docker-manifest-digest": digest},
+			"type":     "cosign container image signature",
+		},
+	})
+	require.NoError(t, err)
+	h := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	require.NoError(t, err)
+
+	layer := reg.pushBlob(repo, "application/vnd.dev.cosign.simplesigning.v1+json", payload)
+	layer.Annotations = map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+	reg.pushManifest(t, repo, strings.Replace(digest, ":", "-", 1)+".sig", ociManifest{
+		MediaType: ociMediaTypeImageManifest,
+		Config:    reg.pushBlob(repo, "application/vnd.oci.image.config.v1+json", []byte("{}")),
+		Layers:    []ociDescriptor{layer},
+	})
+}
+
+func newTestKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestGetInitMetadataOCI(t *testing.T) {
+	reg := newTestRegistry(t)
+	digest := reg.pushArtifact(t, "wasm/args", "v1", binArgs)
+
+	getInitMetadata := func(t *testing.T, url string, props map[string]string) (*InitMetadata, error) {
+		t.Helper()
+		md := metadata.Base{Properties: map[string]string{
+			"url":          url,
+			"ociPlainHTTP": "true",
+			"ociCacheDir":  t.TempDir(),
+		}}
+		for k, v := range props {
+			md.Properties[k] = v
+		}
+		return GetInitMetadata(context.Background(), md)
+	}
+
+	t.Run("artifact by tag", func(t *testing.T) {
+		md, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", nil)
+		require.NoError(t, err)
+		assert.Equal(t, binArgs, md.Guest)
+		assert.Equal(t, "args", md.GuestName)
+	})
+
+	t.Run("pinned digest", func(t *testing.T) {
+		md, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args@"+digest, nil)
+		require.NoError(t, err)
+		assert.Equal(t, binArgs, md.Guest)
+
+		// The manifest with the digest of another manifest is rejected
+		other := reg.pushArtifact(t, "wasm/args", "v2", binStrict)
+		reg.lock.Lock()
+		reg.manifests["wasm/args/"+digest], reg.manifests["wasm/args/"+other] = reg.manifests["wasm/args/"+other], reg.manifests["wasm/args/"+digest]
+		reg.lock.Unlock()
+		defer func() {
+			reg.lock.Lock()
+			reg.manifests["wasm/args/"+digest], reg.manifests["wasm/args/"+other] = reg.manifests["wasm/args/"+other], reg.manifests["wasm/args/"+digest]
+			reg.lock.Unlock()
+		}()
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/args@"+digest, nil)
+		require.ErrorContains(t, err, "does not match the expected digest "+digest)
+	})
+
+	t.Run("module digest", func(t *testing.T) {
+		_, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", map[string]string{
+			"digest": digestOf(binArgs),
+		})
+		require.NoError(t, err)
+
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", map[string]string{
+			"digest": digestOf(binStrict),
+		})
+		require.ErrorContains(t, err, "does not match the expected digest")
+
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", map[string]string{
+			"digest": "md5:foo",
+		})
+		require.ErrorContains(t, err, "invalid digest")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v3", nil)
+		require.ErrorContains(t, err, "received 404 status code")
+	})
+
+	t.Run("container image", func(t *testing.T) {
+		config, _ := json.Marshal(map[string]any{
+			"config": map[string]any{"Entrypoint": []string{"/bin/main.wasm"}},
+		})
+		base := reg.pushBlob("wasm/image", "application/vnd.oci.image.layer.v1.tar+gzip", tarGz(t, map[string][]byte{
+			"bin/main.wasm": binStrict,
+			"etc/config":    []byte("foo"),
+		}))
+		top := reg.pushBlob("wasm/image", "application/vnd.oci.image.layer.v1.tar+gzip", tarGz(t, map[string][]byte{
+			"./bin/main.wasm": binArgs,
+		}))
+		image := reg.pushManifest(t, "wasm/image", "", ociManifest{
+			MediaType: ociMediaTypeImageManifest,
+			Config:    reg.pushBlob("wasm/image", "application/vnd.oci.image.config.v1+json", config),
+			Layers:    []ociDescriptor{base, top},
+		})
+		reg.pushManifest(t, "wasm/image", "latest", ociManifest{
+			MediaType: ociMediaTypeImageIndex,
+			Manifests: []ociDescriptor{
+				{MediaType: ociMediaTypeImageManifest, Digest: digest, Platform: &ociPlatform{OS: "linux", Architecture: "amd64"}},
+				{MediaType: ociMediaTypeImageManifest, Digest: image, Platform: &ociPlatform{OS: "wasip1", Architecture: "wasm"}},
+			},
+		})
+
+		// The file in the top layer is used
+		md, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/image", nil)
+		require.NoError(t, err)
+		assert.Equal(t, binArgs, md.Guest)
+		assert.Equal(t, "image", md.GuestName)
+	})
+
+	t.Run("authentication", func(t *testing.T) {
+		reg.lock.Lock()
+		reg.username, reg.password = "user", "pass"
+		reg.lock.Unlock()
+		defer func() {
+			reg.lock.Lock()
+			reg.username, reg.password = "", ""
+			reg.lock.Unlock()
+		}()
+
+		md, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", map[string]string{
+			"ociUsername": "user",
+			"ociPassword": "pass",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, binArgs, md.Guest)
+
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", map[string]string{
+			"ociUsername": "user",
+			"ociPassword": "wrong",
+		})
+		require.ErrorContains(t, err, "failed to obtain a token from the registry: received 401 status code")
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		props := map[string]string{"ociCacheDir": cacheDir}
+
+		_, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args@"+digest, props)
+		require.NoError(t, err)
+
+		// Images pinned by digest are loaded from the cache
+		n := reg.requestCount()
+		md, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/args@"+digest, props)
+		require.NoError(t, err)
+		assert.Equal(t, binArgs, md.Guest)
+		assert.Equal(t, n, reg.requestCount())
+
+		// Tags are resolved by the registry, but blobs are read from the cache
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", props)
+		require.NoError(t, err)
+		assert.Equal(t, n+1, reg.requestCount())
+	})
+
+	t.Run("signatures", func(t *testing.T) {
+		key, pub := newTestKey(t)
+		otherKey, otherPub := newTestKey(t)
+		signed := reg.pushArtifact(t, "wasm/signed", "v1", binStrict)
+		reg.sign(t, "wasm/signed", signed, key)
+
+		md, err := getInitMetadata(t, "oci://"+reg.host()+"/wasm/signed:v1", map[string]string{
+			"ociPublicKey": pub,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, binStrict, md.Guest)
+
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/signed:v1", map[string]string{
+			"ociPublicKey": otherPub,
+		})
+		require.ErrorContains(t, err, "no valid signature found for "+signed)
+
+		// Signature for another image
+		reg.sign(t, "wasm/signed", digest, otherKey)
+		reg.lock.Lock()
+		reg.manifests["wasm/signed/"+strings.Replace(signed, ":", "-", 1)+".sig"] = reg.manifests["wasm/signed/"+strings.Replace(digest, ":", "-", 1)+".sig"]
+		reg.lock.Unlock()
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/signed:v1", map[string]string{
+			"ociPublicKey": otherPub,
+		})
+		require.ErrorContains(t, err, "no valid signature found for "+signed)
+
+		// Unsigned image
+		_, err = getInitMetadata(t, "oci://"+reg.host()+"/wasm/args:v1", map[string]string{
+			"ociPublicKey": pub,
+		})
+		require.ErrorContains(t, err, "failed to retrieve the signatures")
+	})
+}
+
+func TestParseOCIReference(t *testing.T) {
+	digest := digestOf([]byte("foo"))
+	tests := []struct {
+		url         string
+		expected    *ociReference
+		expectedErr string
+	}{
+		{
+			url:      "oci://ghcr.io/vmware-labs/python-wasm:3.11.3",
+			expected: &ociReference{registry: "ghcr.io", repository: "vmware-labs/python-wasm", tag: "3.11.3"},
+		},
+		{
+			url:      "oci://localhost:5000/app@" + digest,
+			expected: &ociReference{registry: "localhost:5000", repository: "app", digest: digest},
+		},
+		{
+			url:      "oci://localhost/app:v1@" + digest,
+			expected: &ociReference{registry: "localhost", repository: "app", tag: "v1", digest: digest},
+		},
+		{
+			url:      "oci://python-wasm",
+			expected: &ociReference{registry: dockerHubRegistry, repository: "library/python-wasm", tag: "latest"},
+		},
+		{
+			url:      "oci://docker.io/wasmedge/app:v2",
+			expected: &ociReference{registry: dockerHubRegistry, repository: "wasmedge/app", tag: "v2"},
+		},
+		{
+			url:         "oci://ghcr.io/Upper/app",
+			expectedErr: "invalid repository name",
+		},
+		{
+			url:         "oci://ghcr.io/app:bad/tag",
+			expectedErr: "invalid repository name",
+		},
+		{
+			url:         "oci://ghcr.io/app@sha256:abc",
+			expectedErr: "invalid digest",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			ref, err := parseOCIReference(tc.url)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:app:pull,push"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:app:pull,push",
+	}, params)
+
+	scheme, params = parseAuthChallenge(`Basic realm=registry`)
+	assert.Equal(t, "Basic", scheme)
+	assert.Equal(t, map[string]string{"realm": "registry"}, params)
+}
@@ -39,12 +39,13 @@ type InitMetadata struct {
 	// URL is how to load a `%.wasm` file that implements a command, usually
 	// compiled to target WASI.
 	//
-	// If no scheme is present, this defaults to oci://, referencing an OCI
-	// image. The wasm is identified be ENTRYPOINT and any other files in the
-	// corresponding layers will be mounted read-only as the root file system.
+	// Valid schemes are oci:// for a module in an OCI registry, file:// for a
+	// local file or http[s]:// for one retrieved via HTTP.
 	//
-	// Other valid schemes are file:// for a local file or http[s]:// for one
-	// retrieved via HTTP. In these cases, no filesystem will be mounted.
+	// OCI references are in the format oci://registry/repository[:tag][@digest].
+	// The wasm is either a layer of a Wasm OCI artifact, or the file identified
+	// by the ENTRYPOINT of a container image. Other files in the image are not
+	// mounted.
 	URL string `mapstructure:"url"`
 
 	// StrictSandbox when true uses fake sources to avoid vulnerabilities such
@@ -60,6 +61,31 @@ type InitMetadata struct {
 	//   - Random number generators are seeded with a deterministic source.
 	StrictSandbox bool `mapstructure:"strictSandbox"`
 
+	// Digest is the expected sha256 digest of the wasm, in the format
+	// "sha256:<hex>". When set, loading fails if the wasm doesn't match,
+	// regardless of the URL scheme.
+	Digest string `mapstructure:"digest"`
+
+	// OCIUsername and OCIPassword are the credentials for the OCI registry.
+	// They are used for basic authentication or to obtain a token, depending
+	// on what the registry requests.
+	OCIUsername string `mapstructure:"ociUsername"`
+	OCIPassword string `mapstructure:"ociPassword"`
+
+	// OCIPublicKey is a PEM-encoded public key. When set, the image must have
+	// a cosign signature made with the corresponding private key.
+	OCIPublicKey string `mapstructure:"ociPublicKey"`
+
+	// OCICacheDir is the directory of the local content-addressed cache of
+	// manifests and blobs pulled from OCI registries. Images pinned by digest
+	// are loaded from the cache without contacting the registry. Defaults to
+	// "dapr/wasm" in the user's cache directory.
+	OCICacheDir string `mapstructure:"ociCacheDir"`
+
+	// OCIPlainHTTP when true connects to the OCI registry over HTTP instead of
+	// HTTPS. This should only be used with local registries.
+	OCIPlainHTTP bool `mapstructure:"ociPlainHTTP"`
+
 	// Guest is WebAssembly binary implementing the guest, loaded from URL.
 	Guest []byte `mapstructure:"-"`
 
@@ -69,8 +95,6 @@ type InitMetadata struct {
 
 // GetInitMetadata returns InitMetadata from the input metadata.
 func GetInitMetadata(ctx context.Context, md metadata.Base) (*InitMetadata, error) {
-	// Note: the ctx will be used for other schemes such as HTTP and OCI.
-
 	var m InitMetadata
 	// Decode the metadata
 	if err := kitmd.DecodeMetadata(md.Properties, &m); err != nil {
@@ -80,6 +104,11 @@ func GetInitMetadata(ctx context.Context, md metadata.Base) (*InitMetadata, erro
 	if m.URL == "" {
 		return nil, errors.New("missing url")
 	}
+	if m.Digest != "" {
+		if err := validateDigest(m.Digest); err != nil {
+			return nil, err
+		}
+	}
 
 	firstColon := strings.IndexByte(m.URL, ':')
 	if firstColon == -1 {
@@ -89,7 +118,20 @@ func GetInitMetadata(ctx context.Context, md metadata.Base) (*InitMetadata, erro
 	scheme := m.URL[:firstColon]
 	switch m.URL[:firstColon] {
 	case "oci":
-		return nil, fmt.Errorf("TODO %s", scheme)
+		ref, err := parseOCIReference(m.URL)
+		if err != nil {
+			return nil, err
+		}
+		c, err := newOCIClient(http.DefaultTransport, &m)
+		if err != nil {
+			return nil, err
+		}
+		m.Guest, err = c.pull(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		// Use the name of the repository as the module name.
+		m.GuestName = path.Base(ref.repository)
 	case "http", "https":
 		u, err := url.Parse(m.URL)
 		if err != nil {
@@ -114,6 +156,12 @@ func GetInitMetadata(ctx context.Context, md metadata.Base) (*InitMetadata, erro
 		return nil, fmt.Errorf("unsupported URL scheme: %s", scheme)
 	}
 
+	if m.Digest != "" {
+		if err := verifyDigest(m.Guest, m.Digest); err != nil {
+			return nil, err
+		}
+	}
+
 	return &m, nil
 }
 
@@ -29,10 +29,7 @@ import (
 	"github.com/dapr/components-contrib/metadata"
 )
 
-const (
-	urlArgsFile  = "file://testdata/args/main.wasm"
-	urlPythonOCI = "oci://ghcr.io/vmware-labs/python-wasm:3.11.3"
-)
+const urlArgsFile = "file://testdata/args/main.wasm"
 
 //go:embed testdata/args/main.wasm
 var binArgs []byte
@@ -93,11 +90,27 @@ func TestGetInitMetadata(t *testing.T) {
 			expectedErr: "parse \"https:// \": invalid character \" \" in host name",
 		},
 		{
-			name: "TODO oci",
+			name: "oci invalid",
+			metadata: metadata.Base{Properties: map[string]string{
+				"url": "oci://ghcr.io/Vmware-labs/python-wasm:3.11.3",
+			}},
+			expectedErr: "invalid OCI reference oci://ghcr.io/Vmware-labs/python-wasm:3.11.3: invalid repository name",
+		},
+		{
+			name: "digest invalid",
+			metadata: metadata.Base{Properties: map[string]string{
+				"url":    urlArgsFile,
+				"digest": "sha256:foo",
+			}},
+			expectedErr: "invalid digest",
+		},
+		{
+			name: "digest mismatch",
 			metadata: metadata.Base{Properties: map[string]string{
-				"url": urlPythonOCI,
+				"url":    urlArgsFile,
+				"digest": "sha256:0000000000000000000000000000000000000000000000000000000000000000",
 			}},
-			expectedErr: "TODO oci",
+			expectedErr: "does not match the expected digest",
 		},
 		{
 			name: "TODO http",
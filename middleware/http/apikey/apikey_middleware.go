@@ -0,0 +1,242 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+// Metadata is the API key middleware config.
+type apiKeyMiddlewareMetadata struct {
+	// Name of the secret store the keys are loaded from.
+	SecretStore string `json:"secretStore" mapstructure:"secretStore"`
+	// Name of the secret containing the keys; each item in the secret maps a client ID to its key, or to "sha256:" followed by the hex-encoded SHA-256 hash of the key.
+	SecretName string `json:"secretName" mapstructure:"secretName"`
+	// Name of the header containing the API key.
+	HeaderName string `json:"headerName" mapstructure:"headerName"`
+	// Name of the header set to the ID of the client on requests forwarded to the app.
+	IdentityHeaderName string `json:"identityHeaderName" mapstructure:"identityHeaderName"`
+	// Interval for reloading the keys from the secret store; 0 disables reloading.
+	RefreshInterval time.Duration `json:"refreshInterval" mapstructure:"refreshInterval"`
+	// JSON object mapping client IDs (or "*" for all other clients) to the list of paths and methods they are allowed to access.
+	Scopes string `json:"scopes" mapstructure:"scopes"`
+}
+
+const (
+	defaultHeaderName         = "X-API-Key"
+	defaultIdentityHeaderName = "X-Client-Id"
+	defaultRefreshInterval    = 5 * time.Minute
+)
+
+// NewAPIKeyMiddleware returns a new API key middleware.
+func NewAPIKeyMiddleware(log logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger:  log,
+		clock:   clock.RealClock{},
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Middleware is a middleware that authenticates requests with API keys stored in a secret store.
+type Middleware struct {
+	logger      logger.Logger
+	clock       clock.WithTicker
+	lock        sync.Mutex
+	secretStore secretstores.SecretStore
+	closed      atomic.Bool
+	closeCh     chan struct{}
+	wg          sync.WaitGroup
+}
+
+// SetSecretStore sets the secret store named in the secretStore metadata property, which the keys are loaded from.
+// It implements secretstores.StoreConsumer.
+func (m *Middleware) SetSecretStore(store secretstores.SecretStore) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.secretStore = store
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(ctx context.Context, metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := m.getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var scopes map[string][]scope
+	if meta.Scopes != "" {
+		scopes, err = parseScopes(meta.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata property 'scopes': %w", err)
+		}
+	}
+
+	m.lock.Lock()
+	store := m.secretStore
+	m.lock.Unlock()
+	if store == nil {
+		return nil, fmt.Errorf("secret store '%s' was not set", meta.SecretStore)
+	}
+
+	h := &handler{
+		logger: m.logger,
+		meta:   meta,
+		scopes: scopes,
+		store:  store,
+	}
+	err = h.loadKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.RefreshInterval > 0 {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.refreshKeys(h, meta.RefreshInterval)
+		}()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r, next)
+		})
+	}, nil
+}
+
+// Close stops reloading the keys.
+func (m *Middleware) Close() error {
+	if m.closed.CompareAndSwap(false, true) {
+		close(m.closeCh)
+	}
+	m.wg.Wait()
+	return nil
+}
+
+// Reloads the keys periodically, until the middleware is closed.
+func (m *Middleware) refreshKeys(h *handler, interval time.Duration) {
+	ticker := m.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.closeCh:
+			return
+		case <-ticker.C():
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err := h.loadKeys(ctx)
+			cancel()
+			if err != nil {
+				// Keep using the keys loaded previously
+				m.logger.Errorf("Failed to reload API keys: %v", err)
+			}
+		}
+	}
+}
+
+type handler struct {
+	logger logger.Logger
+	meta   *apiKeyMiddlewareMetadata
+	scopes map[string][]scope
+	store  secretstores.SecretStore
+	keys   atomic.Pointer[[]clientKey]
+}
+
+// Loads the keys from the secret store.
+func (h *handler) loadKeys(ctx context.Context) error {
+	res, err := h.store.GetSecret(ctx, secretstores.GetSecretRequest{
+		Name: h.meta.SecretName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve secret '%s': %w", h.meta.SecretName, err)
+	}
+	keys, err := parseKeys(res.Data)
+	if err != nil {
+		return fmt.Errorf("invalid keys in secret '%s': %w", h.meta.SecretName, err)
+	}
+	h.keys.Store(&keys)
+	return nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	key := r.Header.Get(h.meta.HeaderName)
+	// Do not forward the key, nor identities set by the client, to the app
+	r.Header.Del(h.meta.HeaderName)
+	r.Header.Del(h.meta.IdentityHeaderName)
+
+	if key == "" {
+		httputils.RespondWithError(w, http.StatusUnauthorized)
+		return
+	}
+	clientID := findClient(*h.keys.Load(), key)
+	if clientID == "" {
+		httputils.RespondWithError(w, http.StatusUnauthorized)
+		return
+	}
+	if !isAllowed(h.scopes, clientID, r) {
+		httputils.RespondWithError(w, http.StatusForbidden)
+		return
+	}
+
+	r.Header.Set(h.meta.IdentityHeaderName, clientID)
+	next.ServeHTTP(w, r)
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*apiKeyMiddlewareMetadata, error) {
+	middlewareMetadata := apiKeyMiddlewareMetadata{
+		HeaderName:         defaultHeaderName,
+		IdentityHeaderName: defaultIdentityHeaderName,
+		RefreshInterval:    defaultRefreshInterval,
+	}
+	err := kitmd.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.SecretStore == "" || middlewareMetadata.SecretName == "" {
+		return nil, errors.New("metadata properties secretStore and secretName are required")
+	}
+	if middlewareMetadata.HeaderName == "" || middlewareMetadata.IdentityHeaderName == "" {
+		return nil, errors.New("metadata properties headerName and identityHeaderName must not be empty")
+	}
+	if middlewareMetadata.RefreshInterval < 0 {
+		return nil, errors.New("metadata property refreshInterval must not be negative")
+	}
+
+	return &middlewareMetadata, nil
+}
+
+func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := apiKeyMiddlewareMetadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.MiddlewareType)
+	return
+}
+
+var _ secretstores.StoreConsumer = (*Middleware)(nil)
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+// Secret store that returns the keys from a map.
+type fakeSecretStore struct {
+	lock sync.Mutex
+	data map[string]string
+	err  error
+}
+
+func (s *fakeSecretStore) Init(ctx context.Context, metadata secretstores.Metadata) error {
+	return nil
+}
+
+func (s *fakeSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return secretstores.GetSecretResponse{}, s.err
+	}
+	if req.Name != "api-keys" {
+		return secretstores.GetSecretResponse{}, errors.New("secret not found")
+	}
+	data := make(map[string]string, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return secretstores.GetSecretResponse{Data: data}, nil
+}
+
+func (s *fakeSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	return secretstores.BulkGetSecretResponse{}, errors.New("not implemented")
+}
+
+func (s *fakeSecretStore) Features() []secretstores.Feature {
+	return nil
+}
+
+func (s *fakeSecretStore) GetComponentMetadata() metadata.MetadataMap {
+	return nil
+}
+
+func (s *fakeSecretStore) set(data map[string]string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data = data
+	s.err = err
+}
+
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return sha256Prefix + hex.EncodeToString(h[:])
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	log := logger.NewLogger("apikey.test")
+	store := &fakeSecretStore{data: map[string]string{
+		"client1": "key1",
+		"client2": hashKey("key2"),
+		"admin":   "admin-key",
+	}}
+	clk := clocktesting.NewFakeClock(time.Now())
+	m := &Middleware{logger: log, clock: clk, closeCh: make(chan struct{})}
+	m.SetSecretStore(store)
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"secretStore":     "secretstore",
+			"secretName":      "api-keys",
+			"refreshInterval": "1m",
+			"scopes":          `{"admin": [{"path": "/**"}], "*": [{"path": "/orders/**", "methods": ["GET"]}, {"path": "/public/*"}]}`,
+		},
+	}})
+	require.NoError(t, err)
+	defer m.Close()
+
+	var lastRequest *http.Request
+	h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	do := func(method string, path string, key string) int {
+		lastRequest = nil
+		r := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		r.Header.Set("X-Client-Id", "spoofed")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("valid keys", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/orders/1", "key1"))
+		require.NotNil(t, lastRequest)
+		assert.Equal(t, []string{"client1"}, lastRequest.Header.Values("X-Client-Id"))
+		assert.Empty(t, lastRequest.Header.Get("X-API-Key"))
+
+		// Hashed key
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/orders/1", "key2"))
+		require.NotNil(t, lastRequest)
+		assert.Equal(t, "client2", lastRequest.Header.Get("X-Client-Id"))
+
+		// The hash itself is not a valid key
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/orders/1", hashKey("key2")))
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/orders/1", ""))
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/orders/1", "key3"))
+		assert.Nil(t, lastRequest)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/orders/1", "key1"))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin", "key1"))
+		assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/public/file", "key1"))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/public/dir/file", "key1"))
+		// Paths are cleaned before matching the scopes
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/orders/../admin", "key1"))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/public/./dir/file", "key1"))
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/admin", "admin-key"))
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		store.set(map[string]string{"client3": "key3"}, nil)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/orders/1", "key3"))
+
+		// Wait for the refresh goroutine to create its ticker, so the tick isn't lost
+		assert.Eventually(t, clk.HasWaiters, 5*time.Second, 10*time.Millisecond)
+		clk.Step(time.Minute)
+		assert.Eventually(t, func() bool {
+			return do(http.MethodGet, "/orders/1", "key3") == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/orders/1", "key1"))
+
+		// Keys loaded previously are kept if the secret store fails
+		store.set(nil, errors.New("simulated"))
+		clk.Step(time.Minute)
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/orders/1", "key3"))
+	})
+}
+
+func TestAPIKeyMiddlewareInit(t *testing.T) {
+	log := logger.NewLogger("apikey.test")
+	props := map[string]string{"secretStore": "secretstore", "secretName": "api-keys", "refreshInterval": "0"}
+
+	t.Run("requires a secret store", func(t *testing.T) {
+		_, err := NewAPIKeyMiddleware(log).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{Properties: props}})
+		require.ErrorContains(t, err, "secret store 'secretstore' was not set")
+	})
+
+	t.Run("secret store error", func(t *testing.T) {
+		m := NewAPIKeyMiddleware(log).(*Middleware)
+		m.SetSecretStore(&fakeSecretStore{err: errors.New("simulated")})
+		_, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{Properties: props}})
+		require.ErrorContains(t, err, "simulated")
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		m := NewAPIKeyMiddleware(log).(*Middleware)
+		m.SetSecretStore(&fakeSecretStore{data: map[string]string{"client1": "sha256:abc"}})
+		_, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{Properties: props}})
+		require.ErrorContains(t, err, "not a valid SHA-256 hash")
+	})
+
+	t.Run("missing secret store or name", func(t *testing.T) {
+		_, err := NewAPIKeyMiddleware(log).GetHandler(context.Background(), middleware.Metadata{})
+		require.ErrorContains(t, err, "secretStore and secretName are required")
+	})
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+)
+
+// Prefix for values in the secret that contain the hash of the key rather than the key itself
+const sha256Prefix = "sha256:"
+
+// API key of a client.
+type clientKey struct {
+	clientID string
+	// SHA-256 hash of the key
+	hash [sha256.Size]byte
+}
+
+// Parses the keys from the data of the secret, where each item maps a client ID to its key.
+// Values with the "sha256:" prefix contain the hex-encoded SHA-256 hash of the key.
+func parseKeys(data map[string]string) ([]clientKey, error) {
+	keys := make([]clientKey, 0, len(data))
+	for clientID, val := range data {
+		k := clientKey{clientID: clientID}
+		if encoded, ok := strings.CutPrefix(val, sha256Prefix); ok {
+			hash, err := hex.DecodeString(encoded)
+			if err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("key for client '%s' is not a valid SHA-256 hash", clientID)
+			}
+			copy(k.hash[:], hash)
+		} else {
+			if val == "" {
+				return nil, fmt.Errorf("key for client '%s' is empty", clientID)
+			}
+			k.hash = sha256.Sum256([]byte(val))
+		}
+		keys = append(keys, k)
+	}
+
+	// Sort for consistent results when the same key is assigned to multiple clients
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].clientID < keys[j].clientID
+	})
+	return keys, nil
+}
+
+// Returns the ID of the client that owns the key, or an empty string.
+// All keys are compared in constant time, so the time taken doesn't reveal which keys match.
+func findClient(keys []clientKey, key string) string {
+	hash := sha256.Sum256([]byte(key))
+	found := -1
+	for i := range keys {
+		if subtle.ConstantTimeCompare(hash[:], keys[i].hash[:]) == 1 && found < 0 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return ""
+	}
+	return keys[found].clientID
+}
+
+// Wildcard used in the scopes for all clients that don't have specific scopes
+const anyClient = "*"
+
+// Path and methods a client is allowed to access.
+// If the methods are empty, all methods are allowed.
+type scope struct {
+	httputils.Route
+}
+
+// Parses the scopes, which are a JSON object mapping client IDs to the list of their scopes.
+func parseScopes(val string) (map[string][]scope, error) {
+	var scopes map[string][]scope
+	err := json.Unmarshal([]byte(val), &scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	for clientID, list := range scopes {
+		for i := range list {
+			err = list[i].Parse()
+			if err != nil {
+				return nil, fmt.Errorf("scope %d of client '%s': %w", i, clientID, err)
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// Returns true if the client can access the request's path with the method.
+// Clients without scopes use the ones for "*"; if there are none, they are allowed everything.
+func isAllowed(scopes map[string][]scope, clientID string, r *http.Request) bool {
+	list, ok := scopes[clientID]
+	if !ok {
+		list, ok = scopes[anyClient]
+		if !ok {
+			return true
+		}
+	}
+	for _, s := range list {
+		if s.Matches(r) {
+			return true
+		}
+	}
+	return false
+}
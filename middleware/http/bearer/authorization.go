@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bearer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+)
+
+// Default name of the claim containing the roles
+const defaultRolesClaim = "roles"
+
+// Authorization rule, applied to requests matching the path and methods.
+type authorizationRule struct {
+	// Path and methods the rule applies to.
+	httputils.Route
+	// Scopes that must all be granted, from the "scope" or "scp" claims.
+	Scopes []string `json:"scopes"`
+	// Roles of which at least one must be present in the roles claim.
+	Roles []string `json:"roles"`
+	// Claims that must have the given value (or contain it, for claims that are arrays).
+	Claims map[string]string `json:"claims"`
+}
+
+// Parses and validates the authorization rules.
+func parseAuthorizationRules(val string) ([]*authorizationRule, error) {
+	var rules []*authorizationRule
+	err := json.Unmarshal([]byte(val), &rules)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range rules {
+		if r == nil {
+			return nil, fmt.Errorf("rule %d is empty", i)
+		}
+		err = r.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// Returns the first rule that matches the request, or nil.
+func findAuthorizationRule(rules []*authorizationRule, r *http.Request) *authorizationRule {
+	for _, rule := range rules {
+		if rule.Matches(r) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Returns an error if the token doesn't satisfy the rule.
+func (rule *authorizationRule) authorize(token jwt.Token, rolesClaim string) error {
+	if len(rule.Scopes) > 0 {
+		granted := tokenScopes(token)
+		for _, s := range rule.Scopes {
+			if _, ok := granted[s]; !ok {
+				return fmt.Errorf("missing required scope '%s'", s)
+			}
+		}
+	}
+
+	if len(rule.Roles) > 0 {
+		val, _ := token.Get(rolesClaim)
+		roles := claimValues(val)
+		found := false
+		for _, r := range rule.Roles {
+			if containsString(roles, r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("missing required role")
+		}
+	}
+
+	for name, expect := range rule.Claims {
+		val, ok := token.Get(name)
+		if !ok || !containsString(claimValues(val), expect) {
+			return fmt.Errorf("claim '%s' does not have the required value", name)
+		}
+	}
+
+	return nil
+}
+
+// Returns the scopes granted to the token, from the "scope" claim (a space-separated string) and the "scp" claim (a string or an array).
+func tokenScopes(token jwt.Token) map[string]struct{} {
+	res := map[string]struct{}{}
+	for _, name := range []string{"scope", "scp"} {
+		val, ok := token.Get(name)
+		if !ok {
+			continue
+		}
+		for _, v := range claimValues(val) {
+			for _, s := range strings.Fields(v) {
+				res[s] = struct{}{}
+			}
+		}
+	}
+	return res
+}
+
+// Returns the values of a claim as a slice of strings.
+func claimValues(val any) []string {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, e := range v {
+			res = append(res, claimToString(e))
+		}
+		return res
+	default:
+		return []string{claimToString(v)}
+	}
+}
+
+// Returns the value of a claim as string.
+// Strings are returned as-is, times as UNIX timestamps, and everything else is encoded as JSON.
+func claimToString(val any) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case time.Time:
+		return strconv.FormatInt(v.Unix(), 10)
+	default:
+		enc, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(enc)
+	}
+}
+
+func containsString(list []string, val string) bool {
+	for _, e := range list {
+		if e == val {
+			return true
+		}
+	}
+	return false
+}
@@ -63,35 +63,50 @@ func (m *Middleware) GetHandler(ctx context.Context, metadata middleware.Metadat
 		return nil, err
 	}
 
-	// Retrieve the OpenID Configuration document if needed
+	// Retrieve the OpenID Configuration documents if needed
 	getCtx, getCancel := context.WithTimeout(ctx, 30*time.Second)
 	defer getCancel()
-	err = meta.retrieveOpenIDConfigurationDocument(getCtx)
+	err = meta.retrieveOpenIDConfigurationDocuments(getCtx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve OpenID Configuration document: %w", err)
 	}
 
 	// Create a JWKS cache that is refreshed automatically
+	// Each issuer's JWKS is cached and refreshed separately
 	cache := jwk.NewCache(ctx,
 		jwk.WithErrSink(httprc.ErrSinkFunc(func(err error) {
 			m.logger.Warnf("Error while refreshing JWKS cache: %v", err)
 		})),
 	)
-	err = cache.Register(meta.JWKSURL,
-		jwk.WithMinRefreshInterval(minRefreshInterval),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to register JWKS cache: %w", err)
-	}
-
-	// Fetch the JWKS right away to start, so we can check it's valid and populate the cache
-	_, err = cache.Refresh(ctx, meta.JWKSURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	issuers := make(map[string]*issuerMetadata, len(meta.issuers))
+	for _, iss := range meta.issuers {
+		issuers[iss.Issuer] = iss
+		if cache.IsRegistered(iss.JWKSURL) {
+			// Multiple issuers can share the same JWKS
+			continue
+		}
+
+		err = cache.Register(iss.JWKSURL,
+			jwk.WithMinRefreshInterval(minRefreshInterval),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register JWKS cache: %w", err)
+		}
+
+		// Fetch the JWKS right away to start, so we can check it's valid and populate the cache
+		_, err = cache.Refresh(ctx, iss.JWKSURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS for issuer '%s': %w", iss.Issuer, err)
+		}
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Remove headers used to forward claims, so they can't be set by the client
+			for _, header := range meta.forwardClaims {
+				r.Header.Del(header)
+			}
+
 			authHeader := r.Header.Get("authorization")
 			if (len(authHeader) < len(bearerPrefix)+1) || strings.ToLower(authHeader[0:len(bearerPrefix)]) != bearerPrefix {
 				httputils.RespondWithError(w, http.StatusUnauthorized)
@@ -103,25 +118,60 @@ func (m *Middleware) GetHandler(ctx context.Context, metadata middleware.Metadat
 				return
 			}
 
-			keyset, err := cache.Get(r.Context(), meta.JWKSURL)
+			// Find the issuer of the token before validating it, so we know which JWKS to use
+			// The token is fully validated below, including the issuer
+			unverified, err := jwt.ParseInsecure([]byte(rawToken))
+			if err != nil {
+				httputils.RespondWithError(w, http.StatusUnauthorized)
+				return
+			}
+			iss, ok := issuers[unverified.Issuer()]
+			if !ok {
+				httputils.RespondWithError(w, http.StatusUnauthorized)
+				return
+			}
+
+			keyset, err := cache.Get(r.Context(), iss.JWKSURL)
 			if err != nil {
 				m.logger.Errorf("Failed to retrieve JWKS cache: %v", err)
 				httputils.RespondWithError(w, http.StatusInternalServerError)
 				return
 			}
 
-			_, err = jwt.Parse([]byte(rawToken),
+			token, err := jwt.Parse([]byte(rawToken),
 				jwt.WithContext(r.Context()),
 				jwt.WithAcceptableSkew(allowedClockSkew),
 				jwt.WithKeySet(keyset, jws.WithInferAlgorithmFromKey(true)),
-				jwt.WithAudience(meta.Audience),
-				jwt.WithIssuer(meta.Issuer),
+				jwt.WithAudience(iss.Audience),
+				jwt.WithIssuer(iss.Issuer),
 			)
 			if err != nil {
 				httputils.RespondWithError(w, http.StatusUnauthorized)
 				return
 			}
 
+			// Check the authorization rules
+			rule := findAuthorizationRule(meta.rules, r)
+			if rule != nil {
+				err = rule.authorize(token, meta.RolesClaim)
+				if err != nil {
+					m.logger.Debugf("Request to '%s %s' is not authorized: %v", r.Method, r.URL.Path, err)
+					httputils.RespondWithError(w, http.StatusForbidden)
+					return
+				}
+			}
+
+			// Forward the claims
+			for claim, header := range meta.forwardClaims {
+				val, ok := token.Get(claim)
+				if !ok {
+					continue
+				}
+				for _, v := range claimValues(val) {
+					r.Header.Add(header, v)
+				}
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}, nil
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bearer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+type testIssuer struct {
+	name   string
+	key    jwk.Key
+	server *httptest.Server
+}
+
+func newTestIssuer(t *testing.T, name string) *testIssuer {
+	t.Helper()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(ecKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, name))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256))
+
+	pub, err := key.PublicKey()
+	require.NoError(t, err)
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(pub))
+	setJSON, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write(setJSON)
+	}))
+	t.Cleanup(server.Close)
+
+	return &testIssuer{
+		name:   name,
+		key:    key,
+		server: server,
+	}
+}
+
+func (i *testIssuer) token(t *testing.T, audience string, claims map[string]any) string {
+	t.Helper()
+
+	builder := jwt.NewBuilder().
+		Issuer(i.name).
+		Audience([]string{audience}).
+		Subject("user1").
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(time.Hour))
+	for k, v := range claims {
+		builder = builder.Claim(k, v)
+	}
+	tok, err := builder.Build()
+	require.NoError(t, err)
+
+	signed, err := jwt.Sign(tok, jwt.WithKey(jwa.ES256, i.key))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestBearerMiddleware(t *testing.T) {
+	iss1 := newTestIssuer(t, "https://issuer1")
+	iss2 := newTestIssuer(t, "https://issuer2")
+	issuers, _ := json.Marshal([]map[string]string{
+		{"issuer": iss2.name, "audience": "aud2", "jwksURL": iss2.server.URL},
+	})
+
+	m := NewBearerMiddleware(logger.NewLogger("test"))
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Name: "test",
+		Properties: map[string]string{
+			"issuer":             iss1.name,
+			"audience":           "aud1",
+			"jwksURL":            iss1.server.URL,
+			"issuers":            string(issuers),
+			"authorizationRules": `[{"path": "/admin/**", "methods": ["POST"], "scopes": ["admin"], "roles": ["ops", "owner"]}, {"path": "/tenants/*", "claims": {"tenant": "acme"}}]`,
+			"forwardClaims":      "sub=X-User-Id,roles=X-User-Roles",
+		},
+	}})
+	require.NoError(t, err)
+
+	var lastRequest *http.Request
+	h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(method string, path string, token string, headers map[string]string) int {
+		lastRequest = nil
+		r := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("missing token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/", "", nil))
+	})
+
+	t.Run("tokens from both issuers", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/", iss1.token(t, "aud1", nil), nil))
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/", iss2.token(t, "aud2", nil), nil))
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		// Wrong audience for the issuer
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/", iss1.token(t, "aud2", nil), nil))
+
+		// Signed by the key of another issuer
+		forged := &testIssuer{name: iss2.name, key: iss1.key}
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/", forged.token(t, "aud2", nil), nil))
+
+		// Untrusted issuer
+		untrusted := newTestIssuer(t, "https://issuer3")
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/", untrusted.token(t, "aud1", nil), nil))
+	})
+
+	t.Run("authorization rules", func(t *testing.T) {
+		admin := iss1.token(t, "aud1", map[string]any{"scope": "read admin", "roles": []string{"owner"}})
+		notAdmin := iss1.token(t, "aud1", map[string]any{"scope": "read", "roles": []string{"owner"}})
+		noRole := iss1.token(t, "aud1", map[string]any{"scp": []string{"admin"}, "roles": "viewer"})
+
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/admin/users", admin, nil))
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/admin", admin, nil))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/admin/users/1", notAdmin, nil))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/admin/users", noRole, nil))
+
+		// Non-canonical paths can't be used to bypass rules
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "//admin/users", notAdmin, nil))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/admin/./users", notAdmin, nil))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/public/../admin/users", notAdmin, nil))
+
+		// Rule doesn't apply to other methods or paths
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/users", notAdmin, nil))
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/administrator", notAdmin, nil))
+
+		// Claim values
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/tenants/1", iss2.token(t, "aud2", map[string]any{"tenant": "acme"}), nil))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/tenants/1", iss2.token(t, "aud2", map[string]any{"tenant": "other"}), nil))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/tenants/1", iss2.token(t, "aud2", nil), nil))
+	})
+
+	t.Run("forward claims", func(t *testing.T) {
+		token := iss1.token(t, "aud1", map[string]any{"roles": []string{"owner", "ops"}})
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/", token, map[string]string{
+			"X-User-Id": "spoofed",
+		}))
+		require.NotNil(t, lastRequest)
+		assert.Equal(t, []string{"user1"}, lastRequest.Header.Values("X-User-Id"))
+		assert.Equal(t, []string{"owner", "ops"}, lastRequest.Header.Values("X-User-Roles"))
+
+		// Client-supplied headers are removed even if the claim is missing
+		token = iss1.token(t, "aud1", nil)
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/", token, map[string]string{
+			"X-User-Roles": "admin",
+		}))
+		require.NotNil(t, lastRequest)
+		assert.Empty(t, lastRequest.Header.Values("X-User-Roles"))
+	})
+}
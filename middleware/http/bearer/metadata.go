@@ -35,9 +35,34 @@ type bearerMiddlewareMetadata struct {
 	// Optional address of the JKWS file.
 	// If missing, will try to fetch the URL set in the OpenID Configuration document `<issuer>/.well-known/openid-configuration`.
 	JWKSURL string `json:"jwksURL" mapstructure:"jwksURL"`
+	// Optional list of additional trusted issuers, as a JSON array of objects with the "issuer", "audience" and "jwksURL" properties.
+	// If "audience" is empty, the value of the "audience" metadata property is used.
+	Issuers string `json:"issuers" mapstructure:"issuers"`
+	// Optional authorization rules, as a JSON array.
+	// The first rule matching the path and method of a request is applied; requests that don't match any rule only need a valid token.
+	AuthorizationRules string `json:"authorizationRules" mapstructure:"authorizationRules"`
+	// Optional claims to forward to the app as request headers, as a comma-separated list of "claim=header" pairs.
+	// Those headers are always removed from the incoming request.
+	ForwardClaims string `json:"forwardClaims" mapstructure:"forwardClaims"`
+	// Name of the claim containing the roles of the user.
+	// Default: "roles"
+	RolesClaim string `json:"rolesClaim" mapstructure:"rolesClaim"`
 
 	// Internal properties
-	logger logger.Logger `json:"-" mapstructure:"-"`
+	logger        logger.Logger        `json:"-" mapstructure:"-"`
+	issuers       []*issuerMetadata    `json:"-" mapstructure:"-"`
+	rules         []*authorizationRule `json:"-" mapstructure:"-"`
+	forwardClaims map[string]string    `json:"-" mapstructure:"-"`
+}
+
+// Trusted issuer.
+type issuerMetadata struct {
+	// Issuer authority.
+	Issuer string `json:"issuer"`
+	// Audience to expect in the token.
+	Audience string `json:"audience"`
+	// Optional address of the JKWS file.
+	JWKSURL string `json:"jwksURL"`
 }
 
 // Parse the component's metadata into the object.
@@ -49,16 +74,92 @@ func (md *bearerMiddlewareMetadata) fromMetadata(metadata middleware.Metadata) e
 	}
 
 	// Validate properties
-	if md.Issuer == "" {
+	if md.Issuer == "" && md.Issuers == "" {
 		return errors.New("metadata property 'issuer' is required")
 	}
-	if md.Audience == "" {
+	if md.Issuer != "" && md.Audience == "" {
 		return errors.New("metadata property 'audience' is required")
 	}
+	if md.RolesClaim == "" {
+		md.RolesClaim = defaultRolesClaim
+	}
+
+	// Collect the trusted issuers
+	md.issuers = make([]*issuerMetadata, 0, 1)
+	if md.Issuer != "" {
+		md.issuers = append(md.issuers, &issuerMetadata{
+			Issuer:   md.Issuer,
+			Audience: md.Audience,
+			JWKSURL:  md.JWKSURL,
+		})
+	}
+	if md.Issuers != "" {
+		var issuers []*issuerMetadata
+		err = json.Unmarshal([]byte(md.Issuers), &issuers)
+		if err != nil {
+			return fmt.Errorf("metadata property 'issuers' is invalid: %w", err)
+		}
+		for i, iss := range issuers {
+			if iss == nil || iss.Issuer == "" {
+				return fmt.Errorf("metadata property 'issuers' is invalid: item %d is missing the issuer", i)
+			}
+			if iss.Audience == "" {
+				iss.Audience = md.Audience
+			}
+			if iss.Audience == "" {
+				return fmt.Errorf("metadata property 'issuers' is invalid: item %d is missing the audience", i)
+			}
+			md.issuers = append(md.issuers, iss)
+		}
+	}
+	seen := make(map[string]struct{}, len(md.issuers))
+	for _, iss := range md.issuers {
+		if _, ok := seen[iss.Issuer]; ok {
+			return fmt.Errorf("issuer '%s' is configured more than once", iss.Issuer)
+		}
+		seen[iss.Issuer] = struct{}{}
+	}
+
+	// Parse the authorization rules
+	md.rules = nil
+	if md.AuthorizationRules != "" {
+		md.rules, err = parseAuthorizationRules(md.AuthorizationRules)
+		if err != nil {
+			return fmt.Errorf("metadata property 'authorizationRules' is invalid: %w", err)
+		}
+	}
+
+	// Parse the claims to forward
+	md.forwardClaims = nil
+	if md.ForwardClaims != "" {
+		md.forwardClaims, err = parseForwardClaims(md.ForwardClaims)
+		if err != nil {
+			return fmt.Errorf("metadata property 'forwardClaims' is invalid: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// Parses the list of "claim=header" pairs.
+func parseForwardClaims(val string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, pair := range strings.Split(val, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		claim, header, ok := strings.Cut(pair, "=")
+		claim = strings.TrimSpace(claim)
+		header = strings.TrimSpace(header)
+		if !ok || claim == "" || header == "" {
+			return nil, fmt.Errorf("invalid pair '%s': must be in the format 'claim=header'", pair)
+		}
+		res[claim] = http.CanonicalHeaderKey(header)
+	}
+	return res, nil
+}
+
 // Contains a subset of the properties defined in the openid-configuration document.
 // See: https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfig .
 type openIDConfigurationJSON struct {
@@ -66,17 +167,28 @@ type openIDConfigurationJSON struct {
 	JWKSURL string `json:"jwks_uri"`
 }
 
+// Retrieves the OpenID Configuration documents of all issuers
+func (md *bearerMiddlewareMetadata) retrieveOpenIDConfigurationDocuments(ctx context.Context) error {
+	for _, iss := range md.issuers {
+		err := iss.retrieveOpenIDConfigurationDocument(ctx, md.logger)
+		if err != nil {
+			return fmt.Errorf("issuer '%s': %w", iss.Issuer, err)
+		}
+	}
+	return nil
+}
+
 // Retrieves the OpenID Configuration document
-func (md *bearerMiddlewareMetadata) retrieveOpenIDConfigurationDocument(ctx context.Context) error {
+func (md *issuerMetadata) retrieveOpenIDConfigurationDocument(ctx context.Context, logger logger.Logger) error {
 	// If we already have a fixed JWKS URL, use that
 	if md.JWKSURL != "" {
-		md.logger.Debug("Using JWKS URL from metadata: " + md.JWKSURL)
+		logger.Debug("Using JWKS URL from metadata: " + md.JWKSURL)
 		return nil
 	}
 
 	// Retrieve the openid-configuration document
 	oidcConfigURL := strings.TrimSuffix(md.Issuer, "/") + "/.well-known/openid-configuration"
-	md.logger.Debug("Fetching OpenID Configuration: " + oidcConfigURL)
+	logger.Debug("Fetching OpenID Configuration: " + oidcConfigURL)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, oidcConfigURL, nil)
 	if err != nil {
@@ -121,7 +233,7 @@ func (md *bearerMiddlewareMetadata) retrieveOpenIDConfigurationDocument(ctx cont
 
 	// Update the object and return
 	md.JWKSURL = oidcConfig.JWKSURL
-	md.logger.Debug("Found JWKS URL: " + md.JWKSURL)
+	logger.Debug("Found JWKS URL: " + md.JWKSURL)
 
 	return nil
 }
@@ -107,4 +107,93 @@ func TestParseMetadata(t *testing.T) {
 		require.Error(t, err)
 		assert.ErrorContains(t, err, "metadata property 'audience' is required")
 	})
+
+	t.Run("multiple issuers", func(t *testing.T) {
+		md, err := newMetadata(map[string]string{
+			"issuer":   "http://localhost",
+			"audience": "foo",
+			"issuers":  `[{"issuer": "http://localhost2", "jwksURL": "http://localhost2/jwks.json"}, {"issuer": "http://localhost3", "audience": "bar"}]`,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*issuerMetadata{
+			{Issuer: "http://localhost", Audience: "foo"},
+			{Issuer: "http://localhost2", Audience: "foo", JWKSURL: "http://localhost2/jwks.json"},
+			{Issuer: "http://localhost3", Audience: "bar"},
+		}, md.issuers)
+	})
+
+	t.Run("issuers without issuer", func(t *testing.T) {
+		md, err := newMetadata(map[string]string{
+			"issuers": `[{"issuer": "http://localhost2", "audience": "bar"}]`,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*issuerMetadata{
+			{Issuer: "http://localhost2", Audience: "bar"},
+		}, md.issuers)
+	})
+
+	t.Run("invalid issuers", func(t *testing.T) {
+		_, err := newMetadata(map[string]string{
+			"issuers": `[{"issuer": "http://localhost2"}]`,
+		})
+		assert.ErrorContains(t, err, "item 0 is missing the audience")
+
+		_, err = newMetadata(map[string]string{
+			"issuer":   "http://localhost",
+			"audience": "foo",
+			"issuers":  `[{"issuer": "http://localhost"}]`,
+		})
+		assert.ErrorContains(t, err, "configured more than once")
+
+		_, err = newMetadata(map[string]string{
+			"issuers": `{}`,
+		})
+		assert.ErrorContains(t, err, "metadata property 'issuers' is invalid")
+	})
+
+	t.Run("authorization rules", func(t *testing.T) {
+		md, err := newMetadata(map[string]string{
+			"issuer":             "http://localhost",
+			"audience":           "foo",
+			"authorizationRules": `[{"path": "/admin/**", "methods": ["post"], "scopes": ["admin"]}]`,
+		})
+		require.NoError(t, err)
+		require.Len(t, md.rules, 1)
+		assert.Equal(t, []string{"POST"}, md.rules[0].Methods)
+		assert.Equal(t, defaultRolesClaim, md.RolesClaim)
+
+		_, err = newMetadata(map[string]string{
+			"issuer":             "http://localhost",
+			"audience":           "foo",
+			"authorizationRules": `[{"methods": ["post"]}]`,
+		})
+		assert.ErrorContains(t, err, "rule 0: missing the path")
+
+		_, err = newMetadata(map[string]string{
+			"issuer":             "http://localhost",
+			"audience":           "foo",
+			"authorizationRules": `[{"path": "/admin/["}]`,
+		})
+		assert.ErrorContains(t, err, "rule 0: invalid path pattern")
+	})
+
+	t.Run("forward claims", func(t *testing.T) {
+		md, err := newMetadata(map[string]string{
+			"issuer":        "http://localhost",
+			"audience":      "foo",
+			"forwardClaims": "sub=x-user-id, email = X-User-Email",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"sub":   "X-User-Id",
+			"email": "X-User-Email",
+		}, md.forwardClaims)
+
+		_, err = newMetadata(map[string]string{
+			"issuer":        "http://localhost",
+			"audience":      "foo",
+			"forwardClaims": "sub",
+		})
+		assert.ErrorContains(t, err, "must be in the format 'claim=header'")
+	})
 }
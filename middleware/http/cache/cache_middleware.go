@@ -0,0 +1,297 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+// Metadata is the cache middleware config.
+type cacheMiddlewareMetadata struct {
+	// Name of the state store where responses are cached.
+	StateStore string `json:"stateStore" mapstructure:"stateStore"`
+	// TTL for successful responses that don't have a Cache-Control max-age or Expires header.
+	// If zero, those responses are not cached.
+	DefaultTTL time.Duration `json:"defaultTTL" mapstructure:"defaultTTL"`
+	// Maximum TTL for cached responses.
+	MaxTTL time.Duration `json:"maxTTL" mapstructure:"maxTTL"`
+	// Maximum size of the body of cached responses, in bytes.
+	MaxEntrySize int `json:"maxEntrySize" mapstructure:"maxEntrySize"`
+	// If set, POST or DELETE requests to this path invalidate all responses whose path begins with the value of the "prefix" query string parameter.
+	// Requests must include the invalidationToken as bearer token in the Authorization header.
+	InvalidationPath string `json:"invalidationPath" mapstructure:"invalidationPath"`
+	// Token required to invalidate responses; it is required when invalidationPath is set.
+	InvalidationToken string `json:"invalidationToken" mapstructure:"invalidationToken"`
+}
+
+const (
+	defaultMaxTTL       = 24 * time.Hour
+	defaultMaxEntrySize = 1 << 20
+)
+
+// NewCacheMiddleware returns a new cache middleware.
+func NewCacheMiddleware(log logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: log,
+		clock:  clock.RealClock{},
+	}
+}
+
+// Middleware is a middleware that caches responses in a state store.
+type Middleware struct {
+	logger     logger.Logger
+	clock      clock.Clock
+	lock       sync.Mutex
+	stateStore state.Store
+}
+
+// SetStateStore sets the state store named in the stateStore metadata property, where responses are cached.
+// It implements state.StoreConsumer.
+func (m *Middleware) SetStateStore(store state.Store) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.stateStore = store
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(_ context.Context, metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := m.getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	store := m.stateStore
+	m.lock.Unlock()
+	if store == nil {
+		return nil, fmt.Errorf("state store '%s' was not set", meta.StateStore)
+	}
+
+	h := &handler{
+		logger:  m.logger,
+		clock:   m.clock,
+		meta:    meta,
+		entries: newEntryStore(store, metadata.Name),
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r, next)
+		})
+	}, nil
+}
+
+type handler struct {
+	logger  logger.Logger
+	clock   clock.Clock
+	meta    *cacheMiddlewareMetadata
+	entries *entryStore
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if h.meta.InvalidationPath != "" && r.URL.Path == h.meta.InvalidationPath {
+		h.invalidate(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		next.ServeHTTP(w, r)
+		return
+	}
+	reqCC := parseCacheControl(r.Header)
+	if reqCC.has("no-store") {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	now := h.clock.Now()
+	if !requestBypassesCache(reqCC) {
+		entry, err := h.lookup(r.Context(), r, now)
+		if err != nil {
+			h.logger.Warnf("Failed to retrieve response from cache: %v", err)
+		}
+		if entry != nil {
+			h.serveEntry(w, r, entry, now)
+			return
+		}
+	}
+
+	// Responses to HEAD requests don't have a body, so they can't be used for GET requests
+	if r.Method == http.MethodHead {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	rec := httputils.NewResponseRecorder(w, h.meta.MaxEntrySize)
+	next.ServeHTTP(rec, r)
+	if rec.Overflow() {
+		return
+	}
+
+	ttl := responseTTL(r, rec.Status(), w.Header(), now, h.meta.DefaultTTL)
+	if ttl > h.meta.MaxTTL {
+		ttl = h.meta.MaxTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	header := w.Header().Clone()
+	header.Del("Age")
+	entry := &cacheEntry{
+		Path:    r.URL.Path,
+		Status:  rec.Status(),
+		Header:  header,
+		Body:    rec.Body(),
+		Stored:  now.UnixMicro(),
+		Expires: now.Add(ttl).UnixMicro(),
+	}
+	err := h.entries.Set(r.Context(), r, entry, ttl)
+	if err != nil {
+		h.logger.Warnf("Failed to save response in cache: %v", err)
+	}
+}
+
+// Returns the cached response for the request, if it's still fresh.
+func (h *handler) lookup(ctx context.Context, r *http.Request, now time.Time) (*cacheEntry, error) {
+	entry, err := h.entries.Get(ctx, r)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if now.UnixMicro() >= entry.Expires {
+		return nil, nil
+	}
+
+	invalidations, err := h.entries.Invalidations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if isInvalidated(entry, invalidations) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Sends the cached response, or 304 Not Modified if the request is conditional and the response hasn't changed.
+func (h *handler) serveEntry(w http.ResponseWriter, r *http.Request, entry *cacheEntry, now time.Time) {
+	header := w.Header()
+	for k, v := range entry.Header {
+		header[k] = v
+	}
+	age := (now.UnixMicro() - entry.Stored) / int64(time.Second/time.Microsecond)
+	header.Set("Age", strconv.FormatInt(age, 10))
+
+	if notModified(r, entry.Header) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(entry.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(entry.Body)
+	}
+}
+
+// Handles requests to the invalidation endpoint.
+func (h *handler) invalidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		httputils.RespondWithError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.validInvalidationToken(r) {
+		httputils.RespondWithError(w, http.StatusUnauthorized)
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	if !strings.HasPrefix(prefix, "/") {
+		httputils.RespondWithErrorAndMessage(w, http.StatusBadRequest, "query string parameter 'prefix' must begin with '/'")
+		return
+	}
+
+	err := h.entries.Invalidate(r.Context(), prefix, h.clock.Now(), h.meta.MaxTTL)
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Failed to invalidate cached responses: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns true if the request has the invalidation token in the Authorization header.
+// Hashes are compared in constant time, so the time taken doesn't reveal the token or its length.
+func (h *handler) validInvalidationToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	got := sha256.Sum256([]byte(token))
+	expect := sha256.Sum256([]byte(h.meta.InvalidationToken))
+	return subtle.ConstantTimeCompare(got[:], expect[:]) == 1
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*cacheMiddlewareMetadata, error) {
+	middlewareMetadata := cacheMiddlewareMetadata{
+		MaxTTL:       defaultMaxTTL,
+		MaxEntrySize: defaultMaxEntrySize,
+	}
+	err := kitmd.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.StateStore == "" {
+		return nil, errors.New("metadata property stateStore is required")
+	}
+	if middlewareMetadata.DefaultTTL < 0 {
+		return nil, errors.New("metadata property defaultTTL must not be negative")
+	}
+	if middlewareMetadata.MaxTTL <= 0 {
+		return nil, errors.New("metadata property maxTTL must be a positive value")
+	}
+	if middlewareMetadata.MaxEntrySize <= 0 {
+		return nil, errors.New("metadata property maxEntrySize must be a positive value")
+	}
+	if middlewareMetadata.InvalidationPath != "" && middlewareMetadata.InvalidationToken == "" {
+		return nil, errors.New("metadata property invalidationToken is required when invalidationPath is set")
+	}
+
+	return &middlewareMetadata, nil
+}
+
+func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := cacheMiddlewareMetadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.MiddlewareType)
+	return
+}
+
+var _ state.StoreConsumer = (*Middleware)(nil)
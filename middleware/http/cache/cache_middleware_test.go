@@ -0,0 +1,289 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+type testBackend struct {
+	calls   int
+	headers http.Header
+	status  int
+}
+
+func (b *testBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.calls++
+	for k, v := range b.headers {
+		w.Header()[k] = v
+	}
+	status := b.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("response " + strconv.Itoa(b.calls) + " for " + r.URL.Path + " " + r.Header.Get("Accept-Language")))
+}
+
+func newTestHandler(t *testing.T, properties map[string]string) (*clocktesting.FakeClock, *testBackend, func(method string, target string, headers map[string]string) *http.Response) {
+	t.Helper()
+
+	log := logger.NewLogger("cache.test")
+	store := inmemory.NewInMemoryStateStore(log)
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+
+	props := map[string]string{"stateStore": "statestore"}
+	for k, v := range properties {
+		props[k] = v
+	}
+	clk := clocktesting.NewFakeClock(time.Now())
+	m := &Middleware{logger: log, clock: clk}
+	m.SetStateStore(store)
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Name:       "test",
+		Properties: props,
+	}})
+	require.NoError(t, err)
+
+	backend := &testBackend{headers: http.Header{}}
+	h := handler(backend)
+	return clk, backend, func(method string, target string, headers map[string]string) *http.Response {
+		r := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+}
+
+func readBody(t *testing.T, res *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	t.Run("requires a state store", func(t *testing.T) {
+		_, err := NewCacheMiddleware(logger.NewLogger("test")).GetHandler(context.Background(), middleware.Metadata{})
+		require.ErrorContains(t, err, "stateStore is required")
+
+		_, err = NewCacheMiddleware(logger.NewLogger("test")).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"stateStore": "statestore"},
+		}})
+		require.ErrorContains(t, err, "state store 'statestore' was not set")
+	})
+
+	t.Run("caches responses with max-age", func(t *testing.T) {
+		clk, backend, do := newTestHandler(t, nil)
+		backend.headers.Set("Cache-Control", "max-age=60")
+
+		assert.Equal(t, "response 1 for /foo ", readBody(t, do(http.MethodGet, "/foo", nil)))
+		clk.Step(10 * time.Second)
+		res := do(http.MethodGet, "/foo", nil)
+		assert.Equal(t, "response 1 for /foo ", readBody(t, res))
+		assert.Equal(t, "10", res.Header.Get("Age"))
+		assert.Equal(t, 1, backend.calls)
+
+		// Query strings are part of the key
+		assert.Equal(t, "response 2 for /foo ", readBody(t, do(http.MethodGet, "/foo?a=1", nil)))
+
+		// HEAD requests are served from the cache
+		res = do(http.MethodHead, "/foo", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, readBody(t, res))
+		assert.Equal(t, 2, backend.calls)
+
+		// Other methods are not cached
+		do(http.MethodPost, "/foo", nil)
+		assert.Equal(t, 3, backend.calls)
+
+		// Responses expire
+		clk.Step(time.Minute)
+		assert.Equal(t, "response 4 for /foo ", readBody(t, do(http.MethodGet, "/foo", nil)))
+	})
+
+	t.Run("does not cache uncacheable responses", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			headers http.Header
+			status  int
+			req     map[string]string
+		}{
+			{name: "no freshness"},
+			{name: "no-store", headers: http.Header{"Cache-Control": {"no-store, max-age=60"}}},
+			{name: "private", headers: http.Header{"Cache-Control": {"private, max-age=60"}}},
+			{name: "set-cookie", headers: http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"a=b"}}},
+			{name: "vary all", headers: http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}}},
+			{name: "error status", headers: http.Header{"Cache-Control": {"max-age=60"}}, status: http.StatusInternalServerError},
+			{name: "authorized request", headers: http.Header{"Cache-Control": {"max-age=60"}}, req: map[string]string{"Authorization": "Bearer x"}},
+			{name: "request no-store", headers: http.Header{"Cache-Control": {"max-age=60"}}, req: map[string]string{"Cache-Control": "no-store"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, backend, do := newTestHandler(t, nil)
+				backend.headers = tt.headers
+				backend.status = tt.status
+				do(http.MethodGet, "/foo", tt.req)
+				do(http.MethodGet, "/foo", tt.req)
+				assert.Equal(t, 2, backend.calls)
+			})
+		}
+	})
+
+	t.Run("default and maximum TTL", func(t *testing.T) {
+		clk, backend, do := newTestHandler(t, map[string]string{
+			"defaultTTL": "10s",
+			"maxTTL":     "30s",
+		})
+
+		do(http.MethodGet, "/a", nil)
+		do(http.MethodGet, "/a", nil)
+		assert.Equal(t, 1, backend.calls)
+		clk.Step(10 * time.Second)
+		do(http.MethodGet, "/a", nil)
+		assert.Equal(t, 2, backend.calls)
+
+		backend.headers.Set("Cache-Control", "public, s-maxage=3600")
+		do(http.MethodGet, "/b", map[string]string{"Authorization": "Bearer x"})
+		clk.Step(29 * time.Second)
+		do(http.MethodGet, "/b", nil)
+		assert.Equal(t, 3, backend.calls)
+		clk.Step(time.Second)
+		do(http.MethodGet, "/b", nil)
+		assert.Equal(t, 4, backend.calls)
+	})
+
+	t.Run("request cache-control", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, nil)
+		backend.headers.Set("Cache-Control", "max-age=60")
+
+		do(http.MethodGet, "/foo", nil)
+		res := do(http.MethodGet, "/foo", map[string]string{"Cache-Control": "no-cache"})
+		assert.Equal(t, "response 2 for /foo ", readBody(t, res))
+
+		// The new response replaced the cached one
+		assert.Equal(t, "response 2 for /foo ", readBody(t, do(http.MethodGet, "/foo", nil)))
+		assert.Equal(t, 2, backend.calls)
+	})
+
+	t.Run("vary", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, nil)
+		backend.headers.Set("Cache-Control", "max-age=60")
+		backend.headers.Set("Vary", "Accept-Language")
+
+		assert.Equal(t, "response 1 for /foo en", readBody(t, do(http.MethodGet, "/foo", map[string]string{"Accept-Language": "en"})))
+		assert.Equal(t, "response 2 for /foo it", readBody(t, do(http.MethodGet, "/foo", map[string]string{"Accept-Language": "it"})))
+		assert.Equal(t, "response 2 for /foo it", readBody(t, do(http.MethodGet, "/foo", map[string]string{"Accept-Language": "it"})))
+		assert.Equal(t, 2, backend.calls)
+	})
+
+	t.Run("conditional requests", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, nil)
+		backend.headers.Set("Cache-Control", "max-age=60")
+		backend.headers.Set("ETag", `"v1"`)
+		backend.headers.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+		do(http.MethodGet, "/foo", nil)
+
+		res := do(http.MethodGet, "/foo", map[string]string{"If-None-Match": `"v0", W/"v1"`})
+		assert.Equal(t, http.StatusNotModified, res.StatusCode)
+		assert.Equal(t, `"v1"`, res.Header.Get("ETag"))
+		assert.Empty(t, readBody(t, res))
+
+		res = do(http.MethodGet, "/foo", map[string]string{"If-None-Match": `"v2"`})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res = do(http.MethodGet, "/foo", map[string]string{"If-Modified-Since": "Tue, 03 Jan 2006 15:04:05 GMT"})
+		assert.Equal(t, http.StatusNotModified, res.StatusCode)
+		res = do(http.MethodGet, "/foo", map[string]string{"If-Modified-Since": "Sun, 01 Jan 2006 15:04:05 GMT"})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		assert.Equal(t, 1, backend.calls)
+	})
+
+	t.Run("maximum entry size", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, map[string]string{
+			"maxEntrySize": "10",
+		})
+		backend.headers.Set("Cache-Control", "max-age=60")
+
+		do(http.MethodGet, "/foo", nil)
+		assert.Equal(t, "response 2 for /foo ", readBody(t, do(http.MethodGet, "/foo", nil)))
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		clk, backend, do := newTestHandler(t, map[string]string{
+			"invalidationPath":  "/_cache/invalidate",
+			"invalidationToken": "s3cret",
+		})
+		auth := map[string]string{"Authorization": "Bearer s3cret"}
+		backend.headers.Set("Cache-Control", "max-age=60")
+
+		do(http.MethodGet, "/items/1", nil)
+		do(http.MethodGet, "/items/2", nil)
+		do(http.MethodGet, "/other", nil)
+		assert.Equal(t, 3, backend.calls)
+
+		clk.Step(time.Second)
+		assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "/_cache/invalidate?prefix=/items/", auth).StatusCode)
+		clk.Step(time.Second)
+
+		do(http.MethodGet, "/items/1", nil)
+		do(http.MethodGet, "/items/2", nil)
+		do(http.MethodGet, "/other", nil)
+		assert.Equal(t, 5, backend.calls)
+
+		// Responses stored after the invalidation are cached
+		do(http.MethodGet, "/items/1", nil)
+		assert.Equal(t, 5, backend.calls)
+
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/_cache/invalidate", auth).StatusCode)
+		assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/_cache/invalidate?prefix=/", auth).StatusCode)
+
+		// Requests without the token are rejected
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/_cache/invalidate?prefix=/", nil).StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodDelete, "/_cache/invalidate?prefix=/", map[string]string{"Authorization": "Bearer wrong"}).StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/_cache/invalidate?prefix=/", map[string]string{"Authorization": "s3cret"}).StatusCode)
+		do(http.MethodGet, "/items/1", nil)
+		assert.Equal(t, 5, backend.calls)
+	})
+
+	t.Run("invalidation requires a token", func(t *testing.T) {
+		m := &Middleware{logger: logger.NewLogger("test")}
+		_, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"stateStore": "statestore", "invalidationPath": "/_cache/invalidate"},
+		}})
+		require.ErrorContains(t, err, "invalidationToken is required")
+	})
+}
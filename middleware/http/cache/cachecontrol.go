@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Directives of a Cache-Control header.
+type cacheControl map[string]string
+
+// Parses the Cache-Control headers.
+// Directive names are lowercased, and values are unquoted.
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, line := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, val, _ := strings.Cut(part, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(val), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// Returns the value of a directive in seconds, such as max-age.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	val, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || n < 0 {
+		// Invalid values are treated as stale
+		return 0, true
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// Status codes of responses that can be cached.
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Returns how long the response can be stored for, or 0 if the response can't be cached.
+// defaultTTL is used for successful responses that don't specify a freshness lifetime.
+func responseTTL(req *http.Request, status int, h http.Header, now time.Time, defaultTTL time.Duration) time.Duration {
+	if !cacheableStatusCodes[status] {
+		return 0
+	}
+
+	cc := parseCacheControl(h)
+	if cc.has("no-store") || cc.has("no-cache") || cc.has("private") {
+		return 0
+	}
+	// Responses that set cookies or vary on all headers are never shared
+	if h.Get("Set-Cookie") != "" || h.Get("Vary") == "*" {
+		return 0
+	}
+	// Responses to authenticated requests can only be shared if explicitly allowed
+	if req.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return 0
+	}
+
+	if ttl, ok := cc.seconds("s-maxage"); ok {
+		return ttl
+	}
+	if ttl, ok := cc.seconds("max-age"); ok {
+		return ttl
+	}
+	if expires := h.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		base := now
+		if date, err := http.ParseTime(h.Get("Date")); err == nil {
+			base = date
+		}
+		return t.Sub(base)
+	}
+	if status == http.StatusOK {
+		return defaultTTL
+	}
+	return 0
+}
+
+// Returns true if the request can't be answered with a cached response.
+func requestBypassesCache(cc cacheControl) bool {
+	if cc.has("no-cache") {
+		return true
+	}
+	maxAge, ok := cc.seconds("max-age")
+	return ok && maxAge == 0
+}
+
+// Returns true if the ETag matches one of the values in the If-None-Match header, using the weak comparison.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the cached response can be answered with 304 Not Modified.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, h.Get("ETag"))
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		lastModified, err := http.ParseTime(h.Get("Last-Modified"))
+		return err == nil && !lastModified.After(since)
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseTTL(t *testing.T) {
+	now := time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		expect time.Duration
+	}{
+		{"no headers", http.Header{}, 5 * time.Second},
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=60"}}, time.Minute},
+		{"s-maxage takes precedence", http.Header{"Cache-Control": {`max-age=60, s-maxage="120"`}}, 2 * time.Minute},
+		{"invalid max-age", http.Header{"Cache-Control": {"max-age=foo"}}, 0},
+		{"expires", http.Header{"Expires": {"Mon, 02 Jan 2023 15:10:00 GMT"}}, 10 * time.Minute},
+		{"expires with date", http.Header{"Expires": {"Mon, 02 Jan 2023 15:10:00 GMT"}, "Date": {"Mon, 02 Jan 2023 15:09:00 GMT"}}, time.Minute},
+		{"invalid expires", http.Header{"Expires": {"0"}}, 0},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, responseTTL(req, http.StatusOK, tt.header, now, 5*time.Second))
+		})
+	}
+
+	assert.Equal(t, time.Duration(0), responseTTL(req, http.StatusNotFound, http.Header{}, now, 5*time.Second))
+	assert.Equal(t, time.Minute, responseTTL(req, http.StatusNotFound, http.Header{"Cache-Control": {"max-age=60"}}, now, 5*time.Second))
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b", W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`"a"`, `W/"a"`))
+	assert.True(t, etagMatches(`*`, `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+	assert.False(t, etagMatches(`*`, ""))
+}
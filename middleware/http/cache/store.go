@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// Maximum number of attempts when there's a conflict updating the list of invalidations
+const invalidationMaxAttempts = 5
+
+// Cached response.
+type cacheEntry struct {
+	// Path of the request, used for invalidation
+	Path   string      `json:"path"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Time the response was stored and when it expires, as UNIX timestamps in microseconds
+	Stored  int64 `json:"stored"`
+	Expires int64 `json:"expires"`
+}
+
+// Index record saved for each URL, containing the names of the headers the responses vary on.
+type cacheIndex struct {
+	Vary []string `json:"vary,omitempty"`
+}
+
+// Invalidation of all entries whose path has the prefix, stored before the time (in microseconds).
+type invalidation struct {
+	Prefix string `json:"p"`
+	Time   int64  `json:"t"`
+}
+
+// Stores cache entries in a state store.
+type entryStore struct {
+	store     state.Store
+	keyPrefix string
+	hasETags  bool
+}
+
+func newEntryStore(store state.Store, name string) *entryStore {
+	return &entryStore{
+		store:     store,
+		keyPrefix: "dapr-cache||" + name + "||",
+		hasETags:  state.FeatureETag.IsPresent(store.Features()),
+	}
+}
+
+// Returns the key of the index record for the request.
+func (s *entryStore) indexKey(r *http.Request) string {
+	h := sha256.Sum256([]byte(r.URL.RequestURI()))
+	return s.keyPrefix + "i||" + hex.EncodeToString(h[:])
+}
+
+// Returns the key of the entry for the request, using the values of the headers the response varies on.
+func (s *entryStore) entryKey(r *http.Request, vary []string) string {
+	h := sha256.New()
+	h.Write([]byte(r.URL.RequestURI()))
+	for _, name := range vary {
+		v := strings.Join(r.Header.Values(name), ",")
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.Itoa(len(v))))
+		h.Write([]byte{':'})
+		h.Write([]byte(v))
+	}
+	return s.keyPrefix + "e||" + hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *entryStore) invalidationsKey() string {
+	return s.keyPrefix + "invalidations"
+}
+
+// Returns the entry for the request, or nil if there's none.
+func (s *entryStore) Get(ctx context.Context, r *http.Request) (*cacheEntry, error) {
+	var idx cacheIndex
+	ok, _, err := s.getJSON(ctx, s.indexKey(r), &idx)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var entry cacheEntry
+	ok, _, err = s.getJSON(ctx, s.entryKey(r, idx.Vary), &entry)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Saves the entry for the request.
+func (s *entryStore) Set(ctx context.Context, r *http.Request, entry *cacheEntry, ttl time.Duration) error {
+	idx := cacheIndex{
+		Vary: varyHeaders(entry.Header),
+	}
+	ttlMetadata := map[string]string{
+		"ttlInSeconds": strconv.Itoa(int(math.Ceil(ttl.Seconds()))),
+	}
+
+	err := s.setJSON(ctx, s.entryKey(r, idx.Vary), entry, ttlMetadata, false, nil)
+	if err != nil {
+		return err
+	}
+	return s.setJSON(ctx, s.indexKey(r), idx, ttlMetadata, false, nil)
+}
+
+// Returns the list of invalidations.
+func (s *entryStore) Invalidations(ctx context.Context) ([]invalidation, error) {
+	var list []invalidation
+	_, _, err := s.getJSON(ctx, s.invalidationsKey(), &list)
+	return list, err
+}
+
+// Invalidates all entries with the path prefix stored before now.
+// Invalidations older than maxAge are removed, since all entries stored before then have expired.
+func (s *entryStore) Invalidate(ctx context.Context, prefix string, now time.Time, maxAge time.Duration) error {
+	for i := 0; i < invalidationMaxAttempts; i++ {
+		var list []invalidation
+		ok, etag, err := s.getJSON(ctx, s.invalidationsKey(), &list)
+		if err != nil {
+			return err
+		}
+
+		minTime := now.Add(-maxAge).UnixMicro()
+		updated := make([]invalidation, 0, len(list)+1)
+		for _, inv := range list {
+			if inv.Time >= minTime && inv.Prefix != prefix {
+				updated = append(updated, inv)
+			}
+		}
+		updated = append(updated, invalidation{Prefix: prefix, Time: now.UnixMicro()})
+
+		if !ok {
+			etag = nil
+		}
+		err = s.setJSON(ctx, s.invalidationsKey(), updated, nil, s.hasETags, etag)
+		var etagErr *state.ETagError
+		if errors.As(err, &etagErr) && etagErr.Kind() == state.ETagMismatch {
+			// Updated concurrently; try again
+			continue
+		}
+		return err
+	}
+	return errors.New("failed to save invalidation: too many concurrent updates")
+}
+
+// Returns true if the entry was invalidated.
+func isInvalidated(entry *cacheEntry, list []invalidation) bool {
+	for _, inv := range list {
+		if entry.Stored <= inv.Time && strings.HasPrefix(entry.Path, inv.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *entryStore) getJSON(ctx context.Context, key string, val any) (bool, *string, error) {
+	res, err := s.store.Get(ctx, &state.GetRequest{Key: key})
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to retrieve key from state store: %w", err)
+	}
+	if res == nil || len(res.Data) == 0 {
+		return false, nil, nil
+	}
+	err = json.Unmarshal(res.Data, val)
+	if err != nil {
+		return false, nil, fmt.Errorf("invalid data in state store: %w", err)
+	}
+	return true, res.ETag, nil
+}
+
+// If firstWrite is true, the value is saved only if it wasn't modified since it was read with the etag, or, if etag is nil, only if it doesn't exist.
+func (s *entryStore) setJSON(ctx context.Context, key string, val any, metadata map[string]string, firstWrite bool, etag *string) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	req := &state.SetRequest{
+		Key:      key,
+		Value:    data,
+		Metadata: metadata,
+	}
+	if firstWrite {
+		req.Options.Concurrency = state.FirstWrite
+		if etag != nil && *etag != "" {
+			req.ETag = etag
+		}
+	}
+	err = s.store.Set(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to save key in state store: %w", err)
+	}
+	return nil
+}
+
+// Returns the canonical names of the headers listed in the Vary header, sorted.
+func varyHeaders(h http.Header) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, line := range h.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
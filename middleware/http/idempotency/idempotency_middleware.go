@@ -0,0 +1,300 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+// Metadata is the idempotency middleware config.
+type idempotencyMiddlewareMetadata struct {
+	// Name of the state store where requests are recorded; it must support ETags.
+	StateStore string `json:"stateStore" mapstructure:"stateStore"`
+	// Name of the header containing the idempotency key.
+	HeaderName string `json:"headerName" mapstructure:"headerName"`
+	// Comma-separated list of HTTP methods the middleware applies to.
+	Methods string `json:"methods" mapstructure:"methods"`
+	// How long responses are stored for.
+	TTL time.Duration `json:"ttl" mapstructure:"ttl"`
+	// Maximum time a request can be processing; after that, the key can be used again.
+	LockTimeout time.Duration `json:"lockTimeout" mapstructure:"lockTimeout"`
+	// Maximum size of the body of requests and responses, in bytes.
+	MaxBodySize int `json:"maxBodySize" mapstructure:"maxBodySize"`
+}
+
+const (
+	defaultHeaderName  = "Idempotency-Key"
+	defaultMethods     = "POST,PATCH"
+	defaultTTL         = 24 * time.Hour
+	defaultLockTimeout = time.Minute
+	defaultMaxBodySize = 1 << 20
+
+	// Header added to responses that are replayed
+	replayedHeader = "Idempotent-Replayed"
+)
+
+// NewIdempotencyMiddleware returns a new idempotency middleware.
+func NewIdempotencyMiddleware(log logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: log,
+		clock:  clock.RealClock{},
+	}
+}
+
+// Middleware is a middleware that ensures requests with the same idempotency key are processed only once.
+type Middleware struct {
+	logger     logger.Logger
+	clock      clock.Clock
+	lock       sync.Mutex
+	stateStore state.Store
+}
+
+// SetStateStore sets the state store named in the stateStore metadata property, where requests are recorded.
+// It implements state.StoreConsumer.
+func (m *Middleware) SetStateStore(store state.Store) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.stateStore = store
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(_ context.Context, metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := m.getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	store := m.stateStore
+	m.lock.Unlock()
+	if store == nil {
+		return nil, fmt.Errorf("state store '%s' was not set", meta.StateStore)
+	}
+	if !state.FeatureETag.IsPresent(store.Features()) {
+		return nil, errors.New("the state store does not support ETags, which are required by the idempotency middleware")
+	}
+
+	methods := map[string]bool{}
+	for _, method := range strings.Split(meta.Methods, ",") {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method != "" {
+			methods[method] = true
+		}
+	}
+
+	h := &handler{
+		logger:    m.logger,
+		clock:     m.clock,
+		meta:      meta,
+		methods:   methods,
+		records:   &recordStore{store: store},
+		keyPrefix: "dapr-idempotency||" + metadata.Name + "||",
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r, next)
+		})
+	}, nil
+}
+
+type handler struct {
+	logger    logger.Logger
+	clock     clock.Clock
+	meta      *idempotencyMiddlewareMetadata
+	methods   map[string]bool
+	records   *recordStore
+	keyPrefix string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	idempotencyKey := r.Header.Get(h.meta.HeaderName)
+	if idempotencyKey == "" || !h.methods[r.Method] {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// Read the body to compute the fingerprint, then restore it for the next handler
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(h.meta.MaxBodySize)+1))
+	if err != nil {
+		httputils.RespondWithErrorAndMessage(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	if len(body) > h.meta.MaxBodySize {
+		httputils.RespondWithError(w, http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	fingerprint := requestFingerprint(r, body)
+
+	keyHash := sha256.Sum256([]byte(idempotencyKey))
+	key := h.keyPrefix + hex.EncodeToString(keyHash[:])
+
+	locked, existing, err := h.acquire(r.Context(), key, fingerprint)
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Failed to process idempotency key: %v", err)
+		return
+	}
+	if !locked {
+		h.respondExisting(w, existing, fingerprint)
+		return
+	}
+
+	rec := httputils.NewResponseRecorder(w, h.meta.MaxBodySize)
+	next.ServeHTTP(rec, r)
+
+	// Server errors and responses that are too large are not saved, so the request can be retried
+	if rec.Overflow() || rec.Status() >= http.StatusInternalServerError {
+		err = h.records.Delete(r.Context(), key)
+		if err != nil {
+			h.logger.Warnf("Failed to release idempotency key: %v", err)
+		}
+		return
+	}
+
+	err = h.records.Complete(r.Context(), key, &record{
+		Status:         recordStatusCompleted,
+		Fingerprint:    fingerprint,
+		ResponseStatus: rec.Status(),
+		Header:         w.Header().Clone(),
+		Body:           rec.Body(),
+	}, h.meta.TTL)
+	if err != nil {
+		h.logger.Errorf("Failed to save response for idempotency key: %v", err)
+	}
+}
+
+// Tries to lock the key for processing the request.
+// If the key can't be locked, returns the existing record.
+func (h *handler) acquire(ctx context.Context, key string, fingerprint string) (bool, *record, error) {
+	// Try a second time if the record is modified concurrently
+	for i := 0; i < 2; i++ {
+		existing, etag, err := h.records.Get(ctx, key)
+		if err != nil {
+			return false, nil, err
+		}
+
+		now := h.clock.Now()
+		if existing != nil && (existing.Status == recordStatusCompleted || now.UnixMicro() < existing.LockExpires) {
+			return false, existing, nil
+		}
+		if existing == nil {
+			// Lock if there's no record
+			etag = nil
+		}
+
+		ok, err := h.records.Lock(ctx, key, &record{
+			Status:      recordStatusProcessing,
+			Fingerprint: fingerprint,
+			LockExpires: now.Add(h.meta.LockTimeout).UnixMicro(),
+		}, etag, h.meta.LockTimeout)
+		if err != nil || ok {
+			return ok, nil, err
+		}
+	}
+	return false, nil, errors.New("record was modified concurrently")
+}
+
+// Responds to a request whose key has an existing record.
+func (h *handler) respondExisting(w http.ResponseWriter, existing *record, fingerprint string) {
+	if existing.Fingerprint != fingerprint {
+		httputils.RespondWithErrorAndMessage(w, http.StatusUnprocessableEntity, "idempotency key was already used for a different request")
+		return
+	}
+	if existing.Status != recordStatusCompleted {
+		httputils.RespondWithErrorAndMessage(w, http.StatusConflict, "a request with the same idempotency key is being processed")
+		return
+	}
+
+	header := w.Header()
+	for k, v := range existing.Header {
+		header[k] = v
+	}
+	header.Set(replayedHeader, "true")
+	w.WriteHeader(existing.ResponseStatus)
+	_, _ = w.Write(existing.Body)
+}
+
+// Returns the fingerprint of the request, computed from the method, URL, content type, and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, v := range []string{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type")} {
+		h.Write([]byte(strconv.Itoa(len(v))))
+		h.Write([]byte{':'})
+		h.Write([]byte(v))
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*idempotencyMiddlewareMetadata, error) {
+	middlewareMetadata := idempotencyMiddlewareMetadata{
+		HeaderName:  defaultHeaderName,
+		Methods:     defaultMethods,
+		TTL:         defaultTTL,
+		LockTimeout: defaultLockTimeout,
+		MaxBodySize: defaultMaxBodySize,
+	}
+	err := kitmd.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.StateStore == "" {
+		return nil, errors.New("metadata property stateStore is required")
+	}
+	if middlewareMetadata.HeaderName == "" {
+		return nil, errors.New("metadata property headerName must not be empty")
+	}
+	if middlewareMetadata.TTL <= 0 {
+		return nil, errors.New("metadata property ttl must be a positive value")
+	}
+	if middlewareMetadata.LockTimeout <= 0 {
+		return nil, errors.New("metadata property lockTimeout must be a positive value")
+	}
+	if middlewareMetadata.MaxBodySize <= 0 {
+		return nil, errors.New("metadata property maxBodySize must be a positive value")
+	}
+
+	return &middlewareMetadata, nil
+}
+
+func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := idempotencyMiddlewareMetadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.MiddlewareType)
+	return
+}
+
+var _ state.StoreConsumer = (*Middleware)(nil)
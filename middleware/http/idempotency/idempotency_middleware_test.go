@@ -0,0 +1,244 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idempotency
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/components-contrib/state/redis"
+	"github.com/dapr/kit/logger"
+)
+
+type testBackend struct {
+	calls  atomic.Int32
+	status int
+	block  chan struct{}
+}
+
+func (b *testBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := b.calls.Add(1)
+	if b.block != nil {
+		<-b.block
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("X-Order", strconv.Itoa(int(n)))
+	status := b.status
+	if status == 0 {
+		status = http.StatusCreated
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("order " + strconv.Itoa(int(n)) + ": " + string(body)))
+}
+
+type testResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func newTestHandler(t *testing.T, properties map[string]string) (*clocktesting.FakeClock, *testBackend, func(method string, key string, body string) testResponse) {
+	t.Helper()
+
+	log := logger.NewLogger("idempotency.test")
+	store := inmemory.NewInMemoryStateStore(log)
+	require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+
+	props := map[string]string{"stateStore": "statestore"}
+	for k, v := range properties {
+		props[k] = v
+	}
+	clk := clocktesting.NewFakeClock(time.Now())
+	m := &Middleware{logger: log, clock: clk}
+	m.SetStateStore(store)
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Name:       "test",
+		Properties: props,
+	}})
+	require.NoError(t, err)
+
+	backend := &testBackend{}
+	h := handler(backend)
+	return clk, backend, func(method string, key string, body string) testResponse {
+		r := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return testResponse{
+			status: w.Code,
+			header: w.Header(),
+			body:   w.Body.String(),
+		}
+	}
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	t.Run("requires a state store", func(t *testing.T) {
+		_, err := NewIdempotencyMiddleware(logger.NewLogger("test")).GetHandler(context.Background(), middleware.Metadata{})
+		require.ErrorContains(t, err, "stateStore is required")
+
+		_, err = NewIdempotencyMiddleware(logger.NewLogger("test")).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"stateStore": "statestore"},
+		}})
+		require.ErrorContains(t, err, "state store 'statestore' was not set")
+	})
+
+	t.Run("replays responses", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, nil)
+
+		res := do(http.MethodPost, "k1", "pizza")
+		assert.Equal(t, http.StatusCreated, res.status)
+		assert.Equal(t, "order 1: pizza", res.body)
+		assert.Empty(t, res.header.Get(replayedHeader))
+
+		res = do(http.MethodPost, "k1", "pizza")
+		assert.Equal(t, http.StatusCreated, res.status)
+		assert.Equal(t, "order 1: pizza", res.body)
+		assert.Equal(t, "1", res.header.Get("X-Order"))
+		assert.Equal(t, "true", res.header.Get(replayedHeader))
+		assert.Equal(t, int32(1), backend.calls.Load())
+
+		// Other keys, requests without a key, and other methods are not affected
+		assert.Equal(t, "order 2: pizza", do(http.MethodPost, "k2", "pizza").body)
+		assert.Equal(t, "order 3: pizza", do(http.MethodPost, "", "pizza").body)
+		assert.Equal(t, "order 4: pizza", do(http.MethodPost, "", "pizza").body)
+		assert.Equal(t, "order 5: ", do(http.MethodGet, "k1", "").body)
+	})
+
+	t.Run("rejects different requests with the same key", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, nil)
+
+		do(http.MethodPost, "k1", "pizza")
+		res := do(http.MethodPost, "k1", "pasta")
+		assert.Equal(t, http.StatusUnprocessableEntity, res.status)
+		res = do(http.MethodPatch, "k1", "pizza")
+		assert.Equal(t, http.StatusUnprocessableEntity, res.status)
+		assert.Equal(t, int32(1), backend.calls.Load())
+	})
+
+	t.Run("concurrent duplicates", func(t *testing.T) {
+		clk, backend, do := newTestHandler(t, map[string]string{
+			"lockTimeout": "30s",
+		})
+		backend.block = make(chan struct{})
+
+		done := make(chan testResponse)
+		go func() {
+			done <- do(http.MethodPost, "k1", "pizza")
+		}()
+		require.Eventually(t, func() bool {
+			return backend.calls.Load() == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		res := do(http.MethodPost, "k1", "pizza")
+		assert.Equal(t, http.StatusConflict, res.status)
+		res = do(http.MethodPost, "k1", "pasta")
+		assert.Equal(t, http.StatusUnprocessableEntity, res.status)
+
+		// The lock expires if the request takes too long
+		clk.Step(30 * time.Second)
+		close(backend.block)
+		res = do(http.MethodPost, "k1", "pizza")
+		assert.Equal(t, http.StatusCreated, res.status)
+		assert.Equal(t, int32(2), backend.calls.Load())
+
+		assert.Equal(t, "order 1: pizza", (<-done).body)
+	})
+
+	t.Run("server errors are not saved", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, nil)
+		backend.status = http.StatusServiceUnavailable
+
+		assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodPost, "k1", "pizza").status)
+
+		backend.status = 0
+		res := do(http.MethodPost, "k1", "pizza")
+		assert.Equal(t, http.StatusCreated, res.status)
+		assert.Equal(t, "order 2: pizza", res.body)
+	})
+
+	t.Run("maximum body size", func(t *testing.T) {
+		_, backend, do := newTestHandler(t, map[string]string{
+			"maxBodySize": "10",
+		})
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, do(http.MethodPost, "k1", "a very large pizza").status)
+		assert.Equal(t, int32(0), backend.calls.Load())
+
+		// The response is too large to be saved
+		do(http.MethodPost, "k1", "pizza")
+		do(http.MethodPost, "k1", "pizza")
+		assert.Equal(t, int32(2), backend.calls.Load())
+	})
+}
+
+func TestRecordStoreRedis(t *testing.T) {
+	s := miniredis.RunT(t)
+	// miniredis doesn't support the replication section of INFO, which the state store reads
+	s.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if strings.EqualFold(cmd, "INFO") && len(args) == 1 && strings.EqualFold(args[0], "replication") {
+			c.WriteBulk("# Replication\r\nrole:master\r\nconnected_slaves:0\r\n")
+			return true
+		}
+		return false
+	})
+	store := redis.NewRedisStateStore(logger.NewLogger("idempotency.test"))
+	require.NoError(t, store.Init(context.Background(), state.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"redisHost": s.Addr()},
+	}}))
+	t.Cleanup(func() { store.(io.Closer).Close() })
+	records := &recordStore{store: store}
+	ctx := context.Background()
+
+	ok, err := records.Lock(ctx, "key1", &record{Status: recordStatusProcessing, Fingerprint: "f1"}, nil, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Redis returns a generic error when the key exists, which must be treated as a lost race
+	ok, err = records.Lock(ctx, "key1", &record{Status: recordStatusProcessing, Fingerprint: "f2"}, nil, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	existing, etag, err := records.Get(ctx, "key1")
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, "f1", existing.Fingerprint)
+
+	// Locks expired records only if they weren't modified
+	ok, err = records.Lock(ctx, "key1", &record{Status: recordStatusProcessing, Fingerprint: "f3"}, etag, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = records.Lock(ctx, "key1", &record{Status: recordStatusProcessing, Fingerprint: "f4"}, etag, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
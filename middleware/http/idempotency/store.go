@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// Status of a record.
+type recordStatus string
+
+const (
+	// The request is being processed, and the record acts as a lock
+	recordStatusProcessing recordStatus = "processing"
+	// The request was processed, and the record contains the response
+	recordStatusCompleted recordStatus = "completed"
+)
+
+// Record saved for each idempotency key.
+type record struct {
+	Status recordStatus `json:"s"`
+	// Fingerprint of the request
+	Fingerprint string `json:"f"`
+	// For records that are processing, when the lock expires, as UNIX timestamp in microseconds
+	LockExpires int64 `json:"l,omitempty"`
+
+	// Response, for records that are completed
+	ResponseStatus int         `json:"status,omitempty"`
+	Header         http.Header `json:"header,omitempty"`
+	Body           []byte      `json:"body,omitempty"`
+}
+
+// Stores records in a state store.
+type recordStore struct {
+	store state.Store
+}
+
+// Returns the record with the key and its ETag, or nil if there's none.
+func (s *recordStore) Get(ctx context.Context, key string) (*record, *string, error) {
+	res, err := s.store.Get(ctx, &state.GetRequest{
+		Key: key,
+		Options: state.GetStateOption{
+			Consistency: state.Strong,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve record from state store: %w", err)
+	}
+	if res == nil || len(res.Data) == 0 {
+		return nil, nil, nil
+	}
+
+	rec := &record{}
+	err = json.Unmarshal(res.Data, rec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid record in state store: %w", err)
+	}
+	return rec, res.ETag, nil
+}
+
+// Saves a record that is processing, only if the existing record wasn't modified since it was read with the etag, or, if etag is nil, only if there's no record.
+// Returns false if the record was modified concurrently.
+func (s *recordStore) Lock(ctx context.Context, key string, rec *record, etag *string, ttl time.Duration) (bool, error) {
+	err := s.set(ctx, key, rec, ttl, true, etag)
+	if err == nil {
+		return true, nil
+	}
+	var etagErr *state.ETagError
+	if errors.As(err, &etagErr) && etagErr.Kind() == state.ETagMismatch {
+		return false, nil
+	}
+
+	// Some state stores, such as Redis, don't return an ETag error when a first write without an ETag fails because the key exists
+	if etag == nil || *etag == "" {
+		existing, _, getErr := s.Get(ctx, key)
+		if getErr == nil && existing != nil {
+			return false, nil
+		}
+	}
+	return false, err
+}
+
+// Saves a record that is completed.
+func (s *recordStore) Complete(ctx context.Context, key string, rec *record, ttl time.Duration) error {
+	return s.set(ctx, key, rec, ttl, false, nil)
+}
+
+// Deletes the record, so the request can be retried.
+func (s *recordStore) Delete(ctx context.Context, key string) error {
+	err := s.store.Delete(ctx, &state.DeleteRequest{Key: key})
+	if err != nil {
+		return fmt.Errorf("failed to delete record from state store: %w", err)
+	}
+	return nil
+}
+
+func (s *recordStore) set(ctx context.Context, key string, rec *record, ttl time.Duration, firstWrite bool, etag *string) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	req := &state.SetRequest{
+		Key:   key,
+		Value: data,
+		Metadata: map[string]string{
+			"ttlInSeconds": strconv.Itoa(int(math.Ceil(ttl.Seconds()))),
+		},
+		Options: state.SetStateOption{
+			Consistency: state.Strong,
+		},
+	}
+	if firstWrite {
+		req.Options.Concurrency = state.FirstWrite
+		if etag != nil && *etag != "" {
+			req.ETag = etag
+		}
+	}
+	err = s.store.Set(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to save record in state store: %w", err)
+	}
+	return nil
+}
@@ -15,18 +15,24 @@ package oauth2
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/fasthttp-contrib/sessions"
 	"github.com/google/uuid"
 	"golang.org/x/oauth2"
 
 	"github.com/dapr/components-contrib/internal/httputils"
 	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 	kitmd "github.com/dapr/kit/metadata"
 	"github.com/dapr/kit/utils"
@@ -42,6 +48,21 @@ type oAuth2MiddlewareMetadata struct {
 	AuthHeaderName string `json:"authHeaderName" mapstructure:"authHeaderName"`
 	RedirectURL    string `json:"redirectURL" mapstructure:"redirectURL"`
 	ForceHTTPS     string `json:"forceHTTPS" mapstructure:"forceHTTPS"`
+	// Secret used to encrypt and sign the cookies. All replicas must use the same value.
+	// If empty, a random one is generated, and sessions are only valid on this instance.
+	CookieSecret string `json:"cookieSecret" mapstructure:"cookieSecret"`
+	// Name of the session cookie.
+	CookieName string `json:"cookieName" mapstructure:"cookieName"`
+	// Maximum lifetime of a session; it is not extended when the access token is refreshed.
+	SessionMaxAge time.Duration `json:"sessionMaxAge" mapstructure:"sessionMaxAge"`
+	// If set, requests to this path end the session of the user.
+	LogoutPath string `json:"logoutPath" mapstructure:"logoutPath"`
+	// URL to redirect users to after they log out.
+	LogoutRedirectURL string `json:"logoutRedirectURL" mapstructure:"logoutRedirectURL"`
+	// Optional URL of the token revocation endpoint (RFC 7009), used to revoke tokens when users log out.
+	RevocationURL string `json:"revocationURL" mapstructure:"revocationURL"`
+	// Name of the state store where sessions are saved; if empty, sessions are saved in cookies, which are limited to 4KB.
+	StateStore string `json:"stateStore" mapstructure:"stateStore"`
 }
 
 // NewOAuth2Middleware returns a new oAuth2 middleware.
@@ -53,16 +74,35 @@ func NewOAuth2Middleware(log logger.Logger) middleware.Middleware {
 
 // Middleware is an oAuth2 authentication middleware.
 type Middleware struct {
-	logger logger.Logger
+	logger     logger.Logger
+	stateStore state.Store
+	lock       sync.Mutex
 }
 
 const (
-	stateParam   = "state"
-	savedState   = "auth-state"
-	redirectPath = "redirect-url"
-	codeParam    = "code"
+	stateParam = "state"
+	codeParam  = "code"
+
+	defaultCookieName        = "_dapr_oauth2"
+	defaultSessionMaxAge     = 24 * time.Hour
+	defaultLogoutRedirectURL = "/"
+
+	// Suffix for the name of the cookie storing the state of authorization requests
+	stateCookieSuffix = "_state"
+	// Maximum time the user has to complete the authorization
+	stateCookieMaxAge = 10 * time.Minute
+	// Access tokens are refreshed when they expire within this interval
+	tokenRefreshMargin = time.Minute
 )
 
+// SetStateStore sets the state store named in the stateStore metadata property, where sessions are saved.
+// It implements state.StoreConsumer.
+func (m *Middleware) SetStateStore(store state.Store) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.stateStore = store
+}
+
 // GetHandler retruns the HTTP handler provided by the middleware.
 func (m *Middleware) GetHandler(ctx context.Context, metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
 	meta, err := m.getNativeMetadata(metadata)
@@ -82,77 +122,295 @@ func (m *Middleware) GetHandler(ctx context.Context, metadata middleware.Metadat
 		},
 	}
 
+	secret := []byte(meta.CookieSecret)
+	if len(secret) == 0 {
+		m.logger.Warn("Metadata property 'cookieSecret' is empty: sessions will not be valid on other instances and will be lost when restarting")
+		secret = make([]byte, 32)
+		_, err = io.ReadFull(rand.Reader, secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate cookie secret: %w", err)
+		}
+	}
+	codec, err := newCookieCodec(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cookie encryption: %w", err)
+	}
+
+	h := &handler{
+		logger:     m.logger,
+		meta:       meta,
+		conf:       conf,
+		codec:      codec,
+		forceHTTPS: forceHTTPS,
+		stateCookie: cookieOptions{
+			name:   meta.CookieName + stateCookieSuffix,
+			secure: forceHTTPS,
+		},
+	}
+	sessionCookie := cookieOptions{
+		name:   meta.CookieName,
+		secure: forceHTTPS,
+	}
+
+	if meta.StateStore != "" {
+		m.lock.Lock()
+		store := m.stateStore
+		m.lock.Unlock()
+		if store == nil {
+			return nil, fmt.Errorf("state store '%s' was not set", meta.StateStore)
+		}
+		h.sessions = &stateSessionStore{store: store, codec: codec, opts: sessionCookie}
+	} else {
+		h.sessions = &cookieSessionStore{codec: codec, opts: sessionCookie}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			session := sessions.Start(w, r)
-
-			if session.GetString(meta.AuthHeaderName) != "" {
-				r.Header.Add(meta.AuthHeaderName, session.GetString(meta.AuthHeaderName))
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			// Redirect to the auth server
-			state := r.URL.Query().Get(stateParam)
-			if state == "" {
-				id, err := uuid.NewRandom()
-				if err != nil {
-					httputils.RespondWithError(w, http.StatusInternalServerError)
-					m.logger.Errorf("Failed to generate UUID: %v", err)
-					return
-				}
-				idStr := id.String()
-
-				session.Set(savedState, idStr)
-				session.Set(redirectPath, r.URL)
-
-				url := conf.AuthCodeURL(idStr, oauth2.AccessTypeOffline)
-				httputils.RespondWithRedirect(w, http.StatusFound, url)
-			} else {
-				authState := session.GetString(savedState)
-				redirectURL, ok := session.Get(redirectPath).(*url.URL)
-				if !ok {
-					httputils.RespondWithError(w, http.StatusInternalServerError)
-					m.logger.Errorf("Value saved in state key '%s' is not a *url.URL", redirectPath)
-					return
-				}
-
-				if forceHTTPS {
-					redirectURL.Scheme = "https"
-				}
-
-				if state != authState {
-					httputils.RespondWithErrorAndMessage(w, http.StatusBadRequest, "invalid state")
-					return
-				}
-
-				code := r.URL.Query().Get(codeParam)
-				if code == "" {
-					httputils.RespondWithErrorAndMessage(w, http.StatusBadRequest, "code not found")
-					return
-				}
-
-				token, err := conf.Exchange(r.Context(), code)
-				if err != nil {
-					httputils.RespondWithError(w, http.StatusInternalServerError)
-					m.logger.Error("Failed to exchange token")
-					return
-				}
-
-				authHeader := token.Type() + " " + token.AccessToken
-				session.Set(meta.AuthHeaderName, authHeader)
-				httputils.RespondWithRedirect(w, http.StatusFound, redirectURL.String())
-			}
+			h.ServeHTTP(w, r, next)
 		})
 	}, nil
 }
 
+type handler struct {
+	logger      logger.Logger
+	meta        *oAuth2MiddlewareMetadata
+	conf        *oauth2.Config
+	codec       *cookieCodec
+	sessions    sessionStore
+	stateCookie cookieOptions
+	forceHTTPS  bool
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if h.meta.LogoutPath != "" && r.URL.Path == h.meta.LogoutPath {
+		h.logout(w, r)
+		return
+	}
+
+	sess, err := h.sessions.Get(r)
+	if err != nil {
+		h.logger.Debugf("Ignoring invalid session: %v", err)
+	}
+	if sess != nil {
+		sess, err = h.refreshSession(w, r, sess)
+		if err != nil {
+			h.logger.Debugf("Failed to refresh session: %v", err)
+		}
+	}
+
+	if sess != nil {
+		r.Header.Set(h.meta.AuthHeaderName, sess.authHeader())
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	state := r.URL.Query().Get(stateParam)
+	if state == "" {
+		h.startAuthorization(w, r)
+	} else {
+		h.completeAuthorization(w, r, state)
+	}
+}
+
+// Redirects the user to the auth server.
+func (h *handler) startAuthorization(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Failed to generate UUID: %v", err)
+		return
+	}
+
+	as := authState{
+		State:        id.String(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		RedirectURL:  r.URL.String(),
+	}
+	err = h.codec.setCookie(w, r, h.stateCookie, as, time.Now().Add(stateCookieMaxAge))
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Failed to save the authorization state: %v", err)
+		return
+	}
+
+	url := h.conf.AuthCodeURL(as.State, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(as.CodeVerifier))
+	httputils.RespondWithRedirect(w, http.StatusFound, url)
+}
+
+// Handles the redirect from the auth server.
+func (h *handler) completeAuthorization(w http.ResponseWriter, r *http.Request, state string) {
+	var as authState
+	ok, err := h.codec.getCookie(r, h.stateCookie, &as)
+	if err != nil {
+		h.logger.Debugf("Invalid authorization state cookie: %v", err)
+	}
+	if !ok || state != as.State {
+		httputils.RespondWithErrorAndMessage(w, http.StatusBadRequest, "invalid state")
+		return
+	}
+
+	redirectURL, err := url.Parse(as.RedirectURL)
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Value saved in the authorization state is not a valid URL: %v", err)
+		return
+	}
+	if h.forceHTTPS {
+		redirectURL.Scheme = "https"
+	}
+
+	code := r.URL.Query().Get(codeParam)
+	if code == "" {
+		httputils.RespondWithErrorAndMessage(w, http.StatusBadRequest, "code not found")
+		return
+	}
+
+	token, err := h.conf.Exchange(r.Context(), code, oauth2.VerifierOption(as.CodeVerifier))
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Failed to exchange token: %v", err)
+		return
+	}
+
+	// The state can't be used again
+	deleteCookie(w, r, h.stateCookie)
+
+	err = h.sessions.Save(w, r, newSession(token, "", time.Now().Add(h.meta.SessionMaxAge)))
+	if err != nil {
+		httputils.RespondWithError(w, http.StatusInternalServerError)
+		h.logger.Errorf("Failed to save session: %v", err)
+		return
+	}
+
+	httputils.RespondWithRedirect(w, http.StatusFound, redirectURL.String())
+}
+
+// Refreshes the access token if it is about to expire.
+// Returns nil if the session can't be used anymore.
+func (h *handler) refreshSession(w http.ResponseWriter, r *http.Request, sess *session) (*session, error) {
+	if !sess.SessionExpiry.IsZero() && !time.Now().Before(sess.SessionExpiry) {
+		_ = h.sessions.Delete(w, r)
+		return nil, errors.New("session has expired")
+	}
+
+	if sess.Expiry.IsZero() || time.Until(sess.Expiry) > tokenRefreshMargin {
+		return sess, nil
+	}
+
+	if sess.RefreshToken == "" {
+		if time.Now().Before(sess.Expiry) {
+			// Can't refresh, but the token is still valid
+			return sess, nil
+		}
+		_ = h.sessions.Delete(w, r)
+		return nil, errors.New("access token has expired and there's no refresh token")
+	}
+
+	// Setting an expiry in the past forces the token source to refresh the token
+	token, err := h.conf.TokenSource(r.Context(), &oauth2.Token{
+		RefreshToken: sess.RefreshToken,
+		Expiry:       time.Unix(1, 0),
+	}).Token()
+	if err != nil {
+		_ = h.sessions.Delete(w, r)
+		return nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	// The refreshed session ends when the original one does
+	sessionExpiry := sess.SessionExpiry
+	if sessionExpiry.IsZero() {
+		sessionExpiry = time.Now().Add(h.meta.SessionMaxAge)
+	}
+	sess = newSession(token, sess.ID, sessionExpiry)
+	err = h.sessions.Save(w, r, sess)
+	if err != nil {
+		// The refreshed token can still be used for this request
+		h.logger.Warnf("Failed to save refreshed session: %v", err)
+	}
+	return sess, nil
+}
+
+// Ends the session of the user.
+func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
+	sess, err := h.sessions.Get(r)
+	if err != nil {
+		h.logger.Debugf("Ignoring invalid session: %v", err)
+	}
+
+	if sess != nil && h.meta.RevocationURL != "" {
+		err = h.revokeToken(r.Context(), sess)
+		if err != nil {
+			// Continue with the logout anyways
+			h.logger.Warnf("Failed to revoke token: %v", err)
+		}
+	}
+
+	err = h.sessions.Delete(w, r)
+	if err != nil {
+		h.logger.Warnf("Failed to delete session: %v", err)
+	}
+
+	httputils.RespondWithRedirect(w, http.StatusFound, h.meta.LogoutRedirectURL)
+}
+
+// Revokes the refresh token, or the access token if there's no refresh token, as per RFC 7009.
+func (h *handler) revokeToken(ctx context.Context, sess *session) error {
+	form := url.Values{}
+	if sess.RefreshToken != "" {
+		form.Set("token", sess.RefreshToken)
+		form.Set("token_type_hint", "refresh_token")
+	} else {
+		form.Set("token", sess.AccessToken)
+		form.Set("token_type_hint", "access_token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.meta.RevocationURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(h.conf.ClientID), url.QueryEscape(h.conf.ClientSecret))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Drain before closing
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid response status code: %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Returns a session for the token, which ends at sessionExpiry.
+func newSession(token *oauth2.Token, id string, sessionExpiry time.Time) *session {
+	return &session{
+		ID:            id,
+		AccessToken:   token.AccessToken,
+		TokenType:     token.Type(),
+		RefreshToken:  token.RefreshToken,
+		Expiry:        token.Expiry,
+		SessionExpiry: sessionExpiry,
+	}
+}
+
 func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*oAuth2MiddlewareMetadata, error) {
-	var middlewareMetadata oAuth2MiddlewareMetadata
+	middlewareMetadata := oAuth2MiddlewareMetadata{
+		CookieName:        defaultCookieName,
+		SessionMaxAge:     defaultSessionMaxAge,
+		LogoutRedirectURL: defaultLogoutRedirectURL,
+	}
 	err := kitmd.DecodeMetadata(metadata.Properties, &middlewareMetadata)
 	if err != nil {
 		return nil, err
 	}
+	if middlewareMetadata.SessionMaxAge <= 0 {
+		return nil, errors.New("metadata property 'sessionMaxAge' must be greater than zero")
+	}
 	return &middlewareMetadata, nil
 }
 
@@ -161,3 +419,5 @@ func (m *Middleware) GetComponentMetadata() (metadataInfo mdutils.MetadataMap) {
 	mdutils.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, mdutils.MiddlewareType)
 	return
 }
+
+var _ state.StoreConsumer = (*Middleware)(nil)
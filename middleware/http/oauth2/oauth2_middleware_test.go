@@ -15,50 +15,407 @@ package oauth2
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/fasthttp-contrib/sessions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 
 	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
 	"github.com/dapr/kit/logger"
 )
 
-func TestOAuth2CreatesAuthorizationHeaderWhenInSessionState(t *testing.T) {
+// Fake authorization server.
+type testIdP struct {
+	server *httptest.Server
+
+	lock          sync.Mutex
+	codeChallenge string
+	expiresIn     int
+	tokenPadding  int
+	refreshed     int
+	revoked       []string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	idp := &testIdP{expiresIn: 3600}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idp.lock.Lock()
+		defer idp.lock.Unlock()
+
+		_ = r.ParseForm()
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			// Verify the PKCE code verifier
+			h := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if r.PostForm.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(h[:]) != idp.codeChallenge {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "refresh1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			idp.refreshed++
+		}
+
+		w.Header().Set("content-type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access" + strconv.Itoa(idp.refreshed+1) + strings.Repeat("x", idp.tokenPadding),
+			"token_type":    "Bearer",
+			"refresh_token": "refresh1",
+			"expires_in":    idp.expiresIn,
+		})
+	})
+	mux.HandleFunc("/revoke", func(w http.ResponseWriter, r *http.Request) {
+		idp.lock.Lock()
+		defer idp.lock.Unlock()
+
+		_ = r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+		if user != "testId" || pass != "testSecret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		idp.revoked = append(idp.revoked, r.PostForm.Get("token"))
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *testIdP) metadata() middleware.Metadata {
 	var metadata middleware.Metadata
 	metadata.Properties = map[string]string{
 		"clientID":       "testId",
 		"clientSecret":   "testSecret",
 		"scopes":         "ascope",
-		"authURL":        "https://idp:9999",
-		"tokenURL":       "https://idp:9999",
-		"redirectUrl":    "https://localhost:9999",
+		"authURL":        idp.server.URL + "/authorize",
+		"tokenURL":       idp.server.URL + "/token",
+		"revocationURL":  idp.server.URL + "/revoke",
+		"redirectURL":    "http://localhost:9999/callback",
 		"authHeaderName": "someHeader",
+		"cookieSecret":   "my-secret",
+		"logoutPath":     "/logout",
 	}
+	return metadata
+}
 
-	log := logger.NewLogger("oauth2.test")
-	handler, err := NewOAuth2Middleware(log).GetHandler(context.Background(), metadata)
+type testClient struct {
+	handler    http.Handler
+	cookies    map[string]*http.Cookie
+	lastHeader string
+}
+
+func newTestClient(t *testing.T, m middleware.Middleware, metadata middleware.Metadata) *testClient {
+	t.Helper()
+
+	handler, err := m.GetHandler(context.Background(), metadata)
 	require.NoError(t, err)
 
-	// Create request and recorder
-	r := httptest.NewRequest(http.MethodGet, "http://dapr.io", nil)
+	c := &testClient{
+		cookies: map[string]*http.Cookie{},
+	}
+	c.handler = handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.lastHeader = r.Header.Get("someHeader")
+		w.WriteHeader(http.StatusOK)
+	}))
+	return c
+}
+
+func (c *testClient) do(target string) *http.Response {
+	c.lastHeader = ""
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range c.cookies {
+		r.AddCookie(cookie)
+	}
 	w := httptest.NewRecorder()
-	session := sessions.Start(w, r)
-	session.Set("someHeader", "Bearer abcd")
+	c.handler.ServeHTTP(w, r)
+
+	res := w.Result()
+	for _, cookie := range res.Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie
+		}
+	}
+	return res
+}
+
+// Performs the authorization code flow, checking that the user is redirected to the target at the end.
+func (c *testClient) login(t *testing.T, idp *testIdP, target string) {
+	t.Helper()
+
+	res := c.authorize(t, idp, target)
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	assert.Equal(t, target, res.Header.Get("location"))
+}
+
+// Performs the authorization code flow and returns the response to the callback.
+func (c *testClient) authorize(t *testing.T, idp *testIdP, target string) *http.Response {
+	t.Helper()
+
+	res := c.do(target)
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	authURL, err := url.Parse(res.Header.Get("location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", authURL.Path)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	require.NotEmpty(t, authURL.Query().Get("code_challenge"))
+	idp.lock.Lock()
+	idp.codeChallenge = authURL.Query().Get("code_challenge")
+	idp.lock.Unlock()
+
+	return c.do("http://localhost:9999/callback?code=good-code&state=" + url.QueryEscape(authURL.Query().Get("state")))
+}
+
+// Decodes the session from the cookie.
+func (c *testClient) session(t *testing.T) *session {
+	t.Helper()
+
+	codec, err := newCookieCodec([]byte("my-secret"))
+	require.NoError(t, err)
+	sess := &session{}
+	require.Contains(t, c.cookies, defaultCookieName)
+	require.NoError(t, codec.decode(defaultCookieName, c.cookies[defaultCookieName].Value, sess))
+	return sess
+}
+
+func TestOAuth2Middleware(t *testing.T) {
+	log := logger.NewLogger("oauth2.test")
+
+	t.Run("authorization code flow with PKCE", func(t *testing.T) {
+		idp := newTestIdP(t)
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+
+		c.login(t, idp, "http://localhost:9999/foo?bar=1")
+		require.Contains(t, c.cookies, defaultCookieName)
+		assert.NotContains(t, c.cookies, defaultCookieName+stateCookieSuffix)
 
-	// Copy the session cookie to the request
-	cookie := w.Header().Get("Set-Cookie")
-	r.Header.Add("Cookie", cookie)
+		res := c.do("http://localhost:9999/foo")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "Bearer access1", c.lastHeader)
+	})
 
-	handler(
-		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("from mock"))
-		}),
-	).ServeHTTP(w, r)
+	t.Run("sessions are shared by instances with the same secret", func(t *testing.T) {
+		idp := newTestIdP(t)
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+		c.login(t, idp, "http://localhost:9999/")
+
+		other := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+		other.cookies = c.cookies
+		res := other.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "Bearer access1", other.lastHeader)
+
+		// Cookies signed with another secret are rejected
+		metadata := idp.metadata()
+		metadata.Properties["cookieSecret"] = "other-secret"
+		other = newTestClient(t, NewOAuth2Middleware(log), metadata)
+		other.cookies = c.cookies
+		res = other.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Empty(t, other.lastHeader)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		idp := newTestIdP(t)
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+
+		res := c.do("http://localhost:9999/")
+		require.Equal(t, http.StatusFound, res.StatusCode)
+
+		res = c.do("http://localhost:9999/callback?code=good-code&state=wrong")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.NotContains(t, c.cookies, defaultCookieName)
+	})
+
+	t.Run("invalid code verifier", func(t *testing.T) {
+		idp := newTestIdP(t)
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+
+		res := c.do("http://localhost:9999/")
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		authURL, err := url.Parse(res.Header.Get("location"))
+		require.NoError(t, err)
+		idp.codeChallenge = "not-the-challenge"
+
+		res = c.do("http://localhost:9999/callback?code=good-code&state=" + url.QueryEscape(authURL.Query().Get("state")))
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.NotContains(t, c.cookies, defaultCookieName)
+	})
+
+	t.Run("refresh expiring tokens", func(t *testing.T) {
+		idp := newTestIdP(t)
+		// Tokens expire within the refresh margin
+		idp.expiresIn = 30
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+		c.login(t, idp, "http://localhost:9999/")
+		sessionExpiry := c.session(t).SessionExpiry
+		assert.WithinDuration(t, time.Now().Add(defaultSessionMaxAge), sessionExpiry, time.Minute)
+
+		res := c.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "Bearer access2", c.lastHeader)
+		assert.Equal(t, 1, idp.refreshed)
+
+		// Refreshing the token doesn't extend the session
+		sess := c.session(t)
+		assert.Equal(t, "access2", sess.AccessToken)
+		assert.True(t, sessionExpiry.Equal(sess.SessionExpiry))
+		assert.True(t, c.cookies[defaultCookieName].Expires.Before(sessionExpiry.Add(time.Second)))
+	})
+
+	t.Run("sessions end at their expiry", func(t *testing.T) {
+		idp := newTestIdP(t)
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+
+		// The cookie is still valid, but the session has ended
+		codec, err := newCookieCodec([]byte("my-secret"))
+		require.NoError(t, err)
+		enc, err := codec.encode(defaultCookieName, &session{
+			AccessToken:   "access1",
+			RefreshToken:  "refresh1",
+			Expiry:        time.Now().Add(time.Hour),
+			SessionExpiry: time.Now().Add(-time.Second),
+		}, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		c.cookies[defaultCookieName] = &http.Cookie{Name: defaultCookieName, Value: enc}
+
+		res := c.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Empty(t, c.lastHeader)
+		assert.Equal(t, 0, idp.refreshed)
+	})
+
+	t.Run("sessions too large for a cookie", func(t *testing.T) {
+		idp := newTestIdP(t)
+		idp.tokenPadding = maxCookieSize
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+
+		res := c.authorize(t, idp, "http://localhost:9999/")
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.NotContains(t, c.cookies, defaultCookieName)
+
+		// Large sessions can be saved in a state store
+		store := inmemory.NewInMemoryStateStore(log)
+		require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+		md := idp.metadata()
+		md.Properties["stateStore"] = "statestore"
+		m := NewOAuth2Middleware(log)
+		m.(*Middleware).SetStateStore(store)
+		c = newTestClient(t, m, md)
+		c.login(t, idp, "http://localhost:9999/")
+		res = c.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Len(t, c.lastHeader, len("Bearer access1")+maxCookieSize)
+	})
+
+	t.Run("logout revokes tokens", func(t *testing.T) {
+		idp := newTestIdP(t)
+		c := newTestClient(t, NewOAuth2Middleware(log), idp.metadata())
+		c.login(t, idp, "http://localhost:9999/")
+
+		res := c.do("http://localhost:9999/logout")
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Equal(t, "/", res.Header.Get("location"))
+		assert.Equal(t, []string{"refresh1"}, idp.revoked)
+		assert.NotContains(t, c.cookies, defaultCookieName)
+
+		res = c.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+	})
+
+	t.Run("sessions in state store", func(t *testing.T) {
+		store := inmemory.NewInMemoryStateStore(log)
+		require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+
+		idp := newTestIdP(t)
+		md := idp.metadata()
+		md.Properties["stateStore"] = "statestore"
+
+		// The state store must be set
+		_, err := NewOAuth2Middleware(log).GetHandler(context.Background(), md)
+		require.ErrorContains(t, err, "state store 'statestore' was not set")
+
+		m := NewOAuth2Middleware(log)
+		m.(*Middleware).SetStateStore(store)
+		c := newTestClient(t, m, md)
+		c.login(t, idp, "http://localhost:9999/")
+
+		res := c.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "Bearer access1", c.lastHeader)
+
+		// The cookie only contains the session ID
+		var id string
+		codec, err := newCookieCodec([]byte("my-secret"))
+		require.NoError(t, err)
+		require.NoError(t, codec.decode(defaultCookieName, c.cookies[defaultCookieName].Value, &id))
+		stored, err := store.Get(context.Background(), &state.GetRequest{Key: stateStoreKeyPrefix + id})
+		require.NoError(t, err)
+		assert.Contains(t, string(stored.Data), "access1")
+
+		// Deleting the session from the store revokes it
+		saved := c.cookies[defaultCookieName]
+		res = c.do("http://localhost:9999/logout")
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		c.cookies[defaultCookieName] = saved
+		res = c.do("http://localhost:9999/")
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Empty(t, c.lastHeader)
+	})
+}
+
+func TestCookieCodec(t *testing.T) {
+	codec, err := newCookieCodec([]byte("secret"))
+	require.NoError(t, err)
+
+	enc, err := codec.encode("c1", &session{AccessToken: "abc"}, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	var sess session
+	require.NoError(t, codec.decode("c1", enc, &sess))
+	assert.Equal(t, "abc", sess.AccessToken)
+
+	// Values can't be moved to another cookie
+	require.Error(t, codec.decode("c2", enc, &sess))
+
+	// Tampered values
+	raw, _ := base64.RawURLEncoding.DecodeString(enc)
+	raw[len(raw)-1] ^= 1
+	require.Error(t, codec.decode("c1", base64.RawURLEncoding.EncodeToString(raw), &sess))
+
+	// Expired values
+	enc, err = codec.encode("c1", &session{AccessToken: "abc"}, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	require.ErrorContains(t, codec.decode("c1", enc, &sess), "expired")
+}
 
-	assert.Equal(t, "Bearer abcd", r.Header.Get("someHeader"))
+func TestNewSession(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+	sess := newSession(&oauth2.Token{AccessToken: "abc", TokenType: "bearer"}, "id1", expiry)
+	assert.Equal(t, "Bearer abc", sess.authHeader())
+	assert.Equal(t, "id1", sess.ID)
+	assert.Equal(t, expiry, sess.SessionExpiry)
 }
@@ -0,0 +1,326 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oauth2
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// Prefix for the keys of the sessions saved in a state store
+const stateStoreKeyPrefix = "oauth2-session||"
+
+// Maximum size of a cookie, including its name, that all browsers accept
+const maxCookieSize = 4096
+
+// Error returned when a value doesn't fit in a cookie
+var errCookieTooLarge = errors.New("cookie exceeds the maximum size")
+
+// Session of an authenticated user.
+type session struct {
+	// ID of the session; only used when sessions are saved in a state store
+	ID           string    `json:"id,omitempty"`
+	AccessToken  string    `json:"at"`
+	TokenType    string    `json:"tt,omitempty"`
+	RefreshToken string    `json:"rt,omitempty"`
+	Expiry       time.Time `json:"exp"`
+	// End of the session, after which the user must authenticate again; it's not extended when the token is refreshed
+	SessionExpiry time.Time `json:"sexp"`
+}
+
+// Returns the value for the authorization header.
+func (s *session) authHeader() string {
+	tokenType := s.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + s.AccessToken
+}
+
+// State of an authorization request, saved while the user is redirected to the authorization server.
+type authState struct {
+	State        string `json:"s"`
+	CodeVerifier string `json:"cv"`
+	RedirectURL  string `json:"r"`
+}
+
+// Cookie values, encrypted and authenticated with AES-GCM, so they can't be read or modified by clients.
+type cookieCodec struct {
+	aead cipher.AEAD
+}
+
+// Payload of the cookies, which includes the expiration time.
+type cookiePayload struct {
+	Expiry int64           `json:"exp"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Returns a new cookieCodec with a key derived from the secret.
+func newCookieCodec(secret []byte) (*cookieCodec, error) {
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &cookieCodec{aead: aead}, nil
+}
+
+// Encodes the value for the cookie with the given name.
+// The cookie's name is used as additional data, so values can't be moved between cookies.
+func (c *cookieCodec) encode(name string, val any, expiry time.Time) (string, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(cookiePayload{
+		Expiry: expiry.Unix(),
+		Data:   data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(payload)+c.aead.Overhead())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+	out := c.aead.Seal(nonce, nonce, payload, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Decodes the value of the cookie with the given name.
+func (c *cookieCodec) decode(name string, encoded string, val any) error {
+	enc, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid cookie encoding: %w", err)
+	}
+	if len(enc) < c.aead.NonceSize() {
+		return errors.New("cookie value is too short")
+	}
+	payload, err := c.aead.Open(nil, enc[:c.aead.NonceSize()], enc[c.aead.NonceSize():], []byte(name))
+	if err != nil {
+		return errors.New("cookie could not be decrypted")
+	}
+
+	var p cookiePayload
+	err = json.Unmarshal(payload, &p)
+	if err != nil {
+		return fmt.Errorf("invalid cookie payload: %w", err)
+	}
+	if time.Now().Unix() >= p.Expiry {
+		return errors.New("cookie has expired")
+	}
+	return json.Unmarshal(p.Data, val)
+}
+
+// Options for the cookies.
+type cookieOptions struct {
+	name   string
+	secure bool
+}
+
+// Sets the cookie with the encoded value on the response, expiring at the given time.
+// Returns errCookieTooLarge if the cookie would be too large for browsers to store it.
+func (c *cookieCodec) setCookie(w http.ResponseWriter, r *http.Request, opts cookieOptions, val any, expiry time.Time) error {
+	maxAge := int(time.Until(expiry).Seconds())
+	if maxAge <= 0 {
+		return errors.New("cookie has expired")
+	}
+	encoded, err := c.encode(opts.name, val, expiry)
+	if err != nil {
+		return err
+	}
+	if len(opts.name)+len(encoded) > maxCookieSize {
+		return fmt.Errorf("%w: cookie '%s' would be %d bytes", errCookieTooLarge, opts.name, len(opts.name)+len(encoded))
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     opts.name,
+		Value:    encoded,
+		Path:     "/",
+		Expires:  expiry,
+		MaxAge:   maxAge,
+		Secure:   opts.secure || r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// Reads and decodes the cookie from the request.
+// Returns false if the cookie is missing or is not valid.
+func (c *cookieCodec) getCookie(r *http.Request, opts cookieOptions, val any) (bool, error) {
+	cookie, err := r.Cookie(opts.name)
+	if errors.Is(err, http.ErrNoCookie) || (err == nil && cookie.Value == "") {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	err = c.decode(opts.name, cookie.Value, val)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Removes the cookie.
+func deleteCookie(w http.ResponseWriter, r *http.Request, opts cookieOptions) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     opts.name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   opts.secure || r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sessionStore persists the sessions of users.
+type sessionStore interface {
+	// Get returns the session for the request, or nil if there's none.
+	Get(r *http.Request) (*session, error)
+	// Save the session and set the cookie on the response.
+	Save(w http.ResponseWriter, r *http.Request, s *session) error
+	// Delete the session and remove the cookie.
+	Delete(w http.ResponseWriter, r *http.Request) error
+}
+
+// Stores the entire session in a cookie.
+type cookieSessionStore struct {
+	codec *cookieCodec
+	opts  cookieOptions
+}
+
+func (s *cookieSessionStore) Get(r *http.Request) (*session, error) {
+	sess := &session{}
+	ok, err := s.codec.getCookie(r, s.opts, sess)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *cookieSessionStore) Save(w http.ResponseWriter, r *http.Request, sess *session) error {
+	err := s.codec.setCookie(w, r, s.opts, sess, sess.SessionExpiry)
+	if errors.Is(err, errCookieTooLarge) {
+		return fmt.Errorf("%w; sessions with large tokens must be saved in a state store, using the stateStore metadata property", err)
+	}
+	return err
+}
+
+func (s *cookieSessionStore) Delete(w http.ResponseWriter, r *http.Request) error {
+	deleteCookie(w, r, s.opts)
+	return nil
+}
+
+// Stores sessions in a state store, with the session ID in a cookie.
+type stateSessionStore struct {
+	store state.Store
+	codec *cookieCodec
+	opts  cookieOptions
+}
+
+func (s *stateSessionStore) Get(r *http.Request) (*session, error) {
+	var id string
+	ok, err := s.codec.getCookie(r, s.opts, &id)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	res, err := s.store.Get(r.Context(), &state.GetRequest{
+		Key: stateStoreKeyPrefix + id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve session from state store: %w", err)
+	}
+	if res == nil || len(res.Data) == 0 {
+		// Session has expired or was revoked
+		return nil, nil
+	}
+
+	sess := &session{}
+	err = json.Unmarshal(res.Data, sess)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session data: %w", err)
+	}
+	sess.ID = id
+	return sess, nil
+}
+
+func (s *stateSessionStore) Save(w http.ResponseWriter, r *http.Request, sess *session) error {
+	if sess.ID == "" {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return fmt.Errorf("failed to generate session ID: %w", err)
+		}
+		sess.ID = id.String()
+	}
+
+	ttl := int(time.Until(sess.SessionExpiry).Seconds())
+	if ttl <= 0 {
+		return errors.New("session has expired")
+	}
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	err = s.store.Set(r.Context(), &state.SetRequest{
+		Key:   stateStoreKeyPrefix + sess.ID,
+		Value: data,
+		Metadata: map[string]string{
+			"ttlInSeconds": strconv.Itoa(ttl),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save session in state store: %w", err)
+	}
+
+	return s.codec.setCookie(w, r, s.opts, sess.ID, sess.SessionExpiry)
+}
+
+func (s *stateSessionStore) Delete(w http.ResponseWriter, r *http.Request) error {
+	deleteCookie(w, r, s.opts)
+
+	var id string
+	ok, err := s.codec.getCookie(r, s.opts, &id)
+	if !ok || err != nil {
+		return err
+	}
+	err = s.store.Delete(r.Context(), &state.DeleteRequest{
+		Key: stateStoreKeyPrefix + id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session from state store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/opa/bundle"
+)
+
+// Maximum size of bundles downloaded via HTTP.
+const maxBundleSize = 64 << 20
+
+// bundleLoader loads OPA bundles from a tarball or directory on disk, or from a tarball served via HTTP(S).
+// It keeps track of the content that was loaded last, so unchanged bundles are not parsed again.
+type bundleLoader struct {
+	url    string
+	client *http.Client
+
+	// ETag returned by the server, sent in the If-None-Match header of the next request
+	etag string
+	// Digest of the content that was loaded last
+	digest string
+}
+
+func newBundleLoader(u string) (*bundleLoader, error) {
+	switch {
+	case strings.HasPrefix(u, "file://"), strings.HasPrefix(u, "http://"), strings.HasPrefix(u, "https://"):
+		return &bundleLoader{
+			url:    u,
+			client: &http.Client{},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported bundle URL, must start with file://, http:// or https://: %s", u)
+	}
+}
+
+// load returns the bundle and the digest of its content, or nil if the bundle hasn't changed since it was loaded last.
+func (l *bundleLoader) load(ctx context.Context) (*bundle.Bundle, string, error) {
+	if p, ok := strings.CutPrefix(l.url, "file://"); ok {
+		return l.loadFile(p)
+	}
+	return l.loadHTTP(ctx)
+}
+
+func (l *bundleLoader) loadFile(p string) (*bundle.Bundle, string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if info.IsDir() {
+		digest, err := digestDir(p)
+		if err != nil {
+			return nil, "", err
+		}
+		if digest == l.digest {
+			return nil, "", nil
+		}
+		b, err := bundle.NewCustomReader(bundle.NewDirectoryLoader(p)).Read()
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid bundle: %w", err)
+		}
+		l.digest = digest
+		return &b, digest, nil
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, "", err
+	}
+	return l.readTarball(data)
+}
+
+func (l *bundleLoader) loadHTTP(ctx context.Context) (*bundle.Bundle, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if l.etag != "" {
+		req.Header.Set("If-None-Match", l.etag)
+	}
+	res, err := l.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return nil, "", nil
+	case http.StatusOK:
+		// Continue
+	default:
+		io.Copy(io.Discard, res.Body)
+		return nil, "", fmt.Errorf("received %v status code from %q", res.StatusCode, l.url)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxBundleSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxBundleSize {
+		return nil, "", fmt.Errorf("bundle exceeds the maximum size of %d bytes", maxBundleSize)
+	}
+
+	b, digest, err := l.readTarball(data)
+	if err != nil {
+		return nil, "", err
+	}
+	l.etag = res.Header.Get("ETag")
+	return b, digest, nil
+}
+
+// Parses a bundle tarball, unless it's the same as the one loaded last.
+func (l *bundleLoader) readTarball(data []byte) (*bundle.Bundle, string, error) {
+	h := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(h[:])
+	if digest == l.digest {
+		return nil, "", nil
+	}
+
+	b, err := bundle.NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid bundle: %w", err)
+	}
+	l.digest = digest
+	return &b, digest, nil
+}
+
+// Returns a digest of the names and contents of all files in the directory.
+func digestDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return digestFile(h, filepath.ToSlash(rel), p)
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func digestFile(h hash.Hash, name string, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	_, err = io.Copy(h, f)
+	h.Write([]byte{0})
+	return err
+}
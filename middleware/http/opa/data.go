@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dapr/components-contrib/configuration"
+	"github.com/dapr/components-contrib/state"
+)
+
+// dataDocument is a document loaded from a store, which is available to policies at a path under "data".
+type dataDocument struct {
+	// Path of the document, for example ["acl", "roles"] for data.acl.roles
+	path []string
+	// Key of the document in the store
+	key string
+}
+
+// Parses a comma-separated list of documents, each in the format "path=key" or "key".
+// Paths are separated by dots; when the path is omitted, the key is used as path.
+func parseDataDocuments(val string) ([]dataDocument, error) {
+	var docs []dataDocument
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p, key, ok := strings.Cut(item, "=")
+		if !ok {
+			key = p
+		}
+		p = strings.TrimSpace(p)
+		key = strings.TrimSpace(key)
+		if p == "" || key == "" {
+			return nil, fmt.Errorf("invalid data document '%s'", item)
+		}
+
+		doc := dataDocument{
+			path: strings.Split(p, "."),
+			key:  key,
+		}
+		for _, segment := range doc.path {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid path for data document '%s'", item)
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// dataLoader loads data documents from a state store and a configuration store.
+type dataLoader struct {
+	stateStore  state.Store
+	stateDocs   []dataDocument
+	configStore configuration.Store
+	configDocs  []dataDocument
+}
+
+// load returns the data documents, keyed by their path joined with dots.
+// Documents whose key doesn't exist are omitted.
+func (l *dataLoader) load(ctx context.Context) (map[string]any, error) {
+	res := make(map[string]any, len(l.stateDocs)+len(l.configDocs))
+
+	for _, doc := range l.stateDocs {
+		item, err := l.stateStore.Get(ctx, &state.GetRequest{Key: doc.key})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve key '%s' from the state store: %w", doc.key, err)
+		}
+		if item == nil || item.Data == nil {
+			continue
+		}
+		res[strings.Join(doc.path, ".")] = parseDataValue(item.Data)
+	}
+
+	if len(l.configDocs) > 0 {
+		keys := make([]string, len(l.configDocs))
+		for i, doc := range l.configDocs {
+			keys[i] = doc.key
+		}
+		items, err := l.configStore.Get(ctx, &configuration.GetRequest{Keys: keys})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve keys from the configuration store: %w", err)
+		}
+		for _, doc := range l.configDocs {
+			item := items.Items[doc.key]
+			if item == nil {
+				continue
+			}
+			res[strings.Join(doc.path, ".")] = parseDataValue([]byte(item.Value))
+		}
+	}
+
+	return res, nil
+}
+
+// Returns the value decoded from JSON, or as string if it's not valid JSON.
+func parseDataValue(val []byte) any {
+	var res any
+	err := json.Unmarshal(val, &res)
+	if err != nil {
+		return string(val)
+	}
+	return res
+}
+
+// Returns a copy of data with the value set at the path.
+// Objects along the path are copied, so the original data is never modified.
+func withValue(data map[string]any, path []string, val any) (map[string]any, error) {
+	res := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		res[k] = v
+	}
+	if len(path) == 1 {
+		res[path[0]] = val
+		return res, nil
+	}
+
+	var child map[string]any
+	if existing, ok := data[path[0]]; ok && existing != nil {
+		child, ok = existing.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("data at '%s' is not an object", path[0])
+		}
+	}
+	child, err := withValue(child, path[1:], val)
+	if err != nil {
+		return nil, err
+	}
+	res[path[0]] = child
+	return res, nil
+}
@@ -26,14 +26,20 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/open-policy-agent/opa/rego"
 	"golang.org/x/exp/slices"
+	"k8s.io/utils/clock"
 
+	"github.com/dapr/components-contrib/configuration"
 	"github.com/dapr/components-contrib/internal/httputils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 	kitmd "github.com/dapr/kit/metadata"
 	"github.com/dapr/kit/utils"
@@ -42,21 +48,81 @@ import (
 type Status int
 
 type middlewareMetadata struct {
-	Rego                          string   `json:"rego" mapstructure:"rego"`
-	DefaultStatus                 Status   `json:"defaultStatus,omitempty" mapstructure:"defaultStatus"`
-	IncludedHeaders               string   `json:"includedHeaders,omitempty" mapstructure:"includedHeaders"`
-	ReadBody                      string   `json:"readBody,omitempty" mapstructure:"readBody"`
+	Rego            string `json:"rego" mapstructure:"rego"`
+	DefaultStatus   Status `json:"defaultStatus,omitempty" mapstructure:"defaultStatus"`
+	IncludedHeaders string `json:"includedHeaders,omitempty" mapstructure:"includedHeaders"`
+	ReadBody        string `json:"readBody,omitempty" mapstructure:"readBody"`
+	// URL of an OPA bundle, which can be a file:// path to a tarball or a directory, or a http(s):// URL of a tarball.
+	BundleURL string `json:"bundleURL,omitempty" mapstructure:"bundleURL"`
+	// Interval for checking if the bundle has changed; 0 disables reloading.
+	BundleRefreshInterval time.Duration `json:"bundleRefreshInterval,omitempty" mapstructure:"bundleRefreshInterval"`
+	// Name of the state store the data documents in stateDataKeys are loaded from.
+	StateStore string `json:"stateStore,omitempty" mapstructure:"stateStore"`
+	// Comma-separated list of data documents loaded from the state store, each in the format "path=key" or "key"; for example, "acl.roles=roles" makes the value of the key "roles" available as data.acl.roles.
+	StateDataKeys string `json:"stateDataKeys,omitempty" mapstructure:"stateDataKeys"`
+	// Name of the configuration store the data documents in configurationDataKeys are loaded from.
+	ConfigurationStore string `json:"configurationStore,omitempty" mapstructure:"configurationStore"`
+	// Comma-separated list of data documents loaded from the configuration store, in the same format as stateDataKeys.
+	ConfigurationDataKeys string `json:"configurationDataKeys,omitempty" mapstructure:"configurationDataKeys"`
+	// Interval for reloading the data documents; 0 disables reloading.
+	DataRefreshInterval time.Duration `json:"dataRefreshInterval,omitempty" mapstructure:"dataRefreshInterval"`
+	// If true, each decision is logged with its input, result and the revision of the policy.
+	// The values of the Authorization, Proxy-Authorization and Cookie headers are masked, and the body is omitted.
+	DecisionLogs bool `json:"decisionLogs,omitempty" mapstructure:"decisionLogs"`
+	// Comma-separated list of additional headers whose values are masked in the decision logs.
+	DecisionLogsMaskedHeaders string `json:"decisionLogsMaskedHeaders,omitempty" mapstructure:"decisionLogsMaskedHeaders"`
+	// If true, the decision logs include the request body.
+	DecisionLogsIncludeBody       bool     `json:"decisionLogsIncludeBody,omitempty" mapstructure:"decisionLogsIncludeBody"`
 	internalIncludedHeadersParsed []string `json:"-" mapstructure:"-"`
+	internalMaskedHeadersParsed   []string `json:"-" mapstructure:"-"`
 }
 
+const (
+	defaultBundleRefreshInterval = time.Minute
+	defaultDataRefreshInterval   = time.Minute
+
+	// Value that replaces the masked headers in the decision logs
+	maskedHeaderValue = "<masked>"
+)
+
+// Headers that are always masked in the decision logs, as they contain credentials
+var defaultMaskedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
 // NewMiddleware returns a new Open Policy Agent middleware.
 func NewMiddleware(logger logger.Logger) middleware.Middleware {
-	return &Middleware{logger: logger}
+	return &Middleware{
+		logger:  logger,
+		clock:   clock.RealClock{},
+		closeCh: make(chan struct{}),
+	}
 }
 
 // Middleware is an OPA  middleware.
 type Middleware struct {
-	logger logger.Logger
+	logger      logger.Logger
+	clock       clock.WithTicker
+	lock        sync.Mutex
+	stateStore  state.Store
+	configStore configuration.Store
+	closed      atomic.Bool
+	closeCh     chan struct{}
+	wg          sync.WaitGroup
+}
+
+// SetStateStore sets the state store named in the stateStore metadata property, which the data documents in stateDataKeys are loaded from.
+// It implements state.StoreConsumer.
+func (m *Middleware) SetStateStore(store state.Store) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.stateStore = store
+}
+
+// SetConfigurationStore sets the configuration store named in the configurationStore metadata property, which the data documents in configurationDataKeys are loaded from.
+// It implements configuration.StoreConsumer.
+func (m *Middleware) SetConfigurationStore(store configuration.Store) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.configStore = store
 }
 
 // RegoResult is the expected result from rego policy.
@@ -115,19 +181,39 @@ func (m *Middleware) GetHandler(parentCtx context.Context, metadata middleware.M
 		return nil, err
 	}
 
+	p := &policyLoader{
+		rego: meta.Rego,
+	}
+	if meta.BundleURL != "" {
+		p.bundles, err = newBundleLoader(meta.BundleURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if meta.StateDataKeys != "" || meta.ConfigurationDataKeys != "" {
+		p.data, err = m.newDataLoader(meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(parentCtx, time.Minute)
-	query, err := rego.New(
-		rego.Query("result = data.http.allow"),
-		rego.Module("inline.rego", meta.Rego),
-	).PrepareForEval(ctx)
+	err = p.refresh(ctx, true, true)
 	cancel()
 	if err != nil {
 		return nil, err
 	}
 
+	if p.bundles != nil && meta.BundleRefreshInterval > 0 {
+		m.startRefresh(p, meta.BundleRefreshInterval, true, false)
+	}
+	if p.data != nil && meta.DataRefreshInterval > 0 {
+		m.startRefresh(p, meta.DataRefreshInterval, false, true)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if allow := m.evalRequest(w, r, meta, &query); !allow {
+			if allow := m.evalRequest(w, r, meta, p.policy.Load()); !allow {
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -135,7 +221,77 @@ func (m *Middleware) GetHandler(parentCtx context.Context, metadata middleware.M
 	}, nil
 }
 
-func (m *Middleware) evalRequest(w http.ResponseWriter, r *http.Request, meta *middlewareMetadata, query *rego.PreparedEvalQuery) bool {
+// Returns the loader for the data documents, after validating that the stores are set.
+func (m *Middleware) newDataLoader(meta *middlewareMetadata) (l *dataLoader, err error) {
+	m.lock.Lock()
+	l = &dataLoader{
+		stateStore:  m.stateStore,
+		configStore: m.configStore,
+	}
+	m.lock.Unlock()
+
+	if meta.StateDataKeys != "" {
+		if meta.StateStore == "" {
+			return nil, errors.New("metadata property stateDataKeys requires the stateStore property")
+		}
+		if l.stateStore == nil {
+			return nil, fmt.Errorf("state store '%s' was not set", meta.StateStore)
+		}
+		l.stateDocs, err = parseDataDocuments(meta.StateDataKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata property 'stateDataKeys': %w", err)
+		}
+	}
+	if meta.ConfigurationDataKeys != "" {
+		if meta.ConfigurationStore == "" {
+			return nil, errors.New("metadata property configurationDataKeys requires the configurationStore property")
+		}
+		if l.configStore == nil {
+			return nil, fmt.Errorf("configuration store '%s' was not set", meta.ConfigurationStore)
+		}
+		l.configDocs, err = parseDataDocuments(meta.ConfigurationDataKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata property 'configurationDataKeys': %w", err)
+		}
+	}
+	return l, nil
+}
+
+// Starts a background goroutine that reloads the bundle or the data documents periodically, until the middleware is closed.
+func (m *Middleware) startRefresh(p *policyLoader, interval time.Duration, bundles bool, data bool) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := m.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.closeCh:
+				return
+			case <-ticker.C():
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				err := p.refresh(ctx, bundles, data)
+				cancel()
+				if err != nil {
+					// Keep using the policy compiled previously
+					m.logger.Errorf("Failed to reload OPA policy: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops reloading the bundles and data documents.
+func (m *Middleware) Close() error {
+	if m.closed.CompareAndSwap(false, true) {
+		close(m.closeCh)
+	}
+	m.wg.Wait()
+	return nil
+}
+
+func (m *Middleware) evalRequest(w http.ResponseWriter, r *http.Request, meta *middlewareMetadata, p *policy) bool {
 	headers := map[string]string{}
 
 	for key, value := range r.Header {
@@ -167,18 +323,65 @@ func (m *Middleware) evalRequest(w http.ResponseWriter, r *http.Request, meta *m
 		},
 	}
 
-	results, err := query.Eval(r.Context(), rego.EvalInput(input))
-	if err != nil {
+	var (
+		result  any
+		allowed bool
+	)
+	results, err := p.query.Eval(r.Context(), rego.EvalInput(input))
+	switch {
+	case err != nil:
 		m.opaError(w, meta, err)
-		return false
+	case len(results) == 0:
+		err = errOpaNoResult
+		m.opaError(w, meta, err)
+	default:
+		result = results[0].Bindings["result"]
+		allowed = m.handleRegoResult(w, r, meta, result)
 	}
 
-	if len(results) == 0 {
-		m.opaError(w, meta, errOpaNoResult)
-		return false
+	if meta.DecisionLogs {
+		m.logDecision(p.revision, decisionLogInput(meta, input), result, allowed, err)
+	}
+	return allowed
+}
+
+// Logs the decision made for a request.
+func (m *Middleware) logDecision(revision string, input any, result any, allowed bool, err error) {
+	fields := map[string]any{
+		"decision_id": uuid.NewString(),
+		"revision":    revision,
+		"input":       input,
+		"result":      result,
+		"allowed":     allowed,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	m.logger.WithFields(fields).Info("OPA decision")
+}
+
+// Returns a copy of the input for the decision logs, with the values of sensitive headers masked and, unless configured otherwise, without the body.
+func decisionLogInput(meta *middlewareMetadata, input map[string]any) map[string]any {
+	req, _ := input["request"].(map[string]any)
+	logged := make(map[string]any, len(req))
+	for k, v := range req {
+		logged[k] = v
+	}
+
+	headers, _ := req["headers"].(map[string]string)
+	masked := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if slices.Contains(meta.internalMaskedHeadersParsed, k) {
+			v = maskedHeaderValue
+		}
+		masked[k] = v
 	}
+	logged["headers"] = masked
 
-	return m.handleRegoResult(w, r, meta, results[0].Bindings["result"])
+	if !meta.DecisionLogsIncludeBody {
+		delete(logged, "body")
+	}
+	return map[string]any{"request": logged}
 }
 
 // handleRegoResult takes the in process request and open policy agent evaluation result
@@ -240,25 +443,40 @@ func (m *Middleware) opaError(w http.ResponseWriter, meta *middlewareMetadata, e
 
 func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*middlewareMetadata, error) {
 	meta := middlewareMetadata{
-		DefaultStatus: 403,
+		DefaultStatus:         403,
+		BundleRefreshInterval: defaultBundleRefreshInterval,
+		DataRefreshInterval:   defaultDataRefreshInterval,
 	}
 	err := kitmd.DecodeMetadata(metadata.Properties, &meta)
 	if err != nil {
 		return nil, err
 	}
 
-	meta.internalIncludedHeadersParsed = strings.Split(meta.IncludedHeaders, ",")
+	if meta.Rego == "" && meta.BundleURL == "" {
+		return nil, errors.New("metadata property rego or bundleURL is required")
+	}
+	if meta.BundleRefreshInterval < 0 || meta.DataRefreshInterval < 0 {
+		return nil, errors.New("metadata properties bundleRefreshInterval and dataRefreshInterval must not be negative")
+	}
+
+	meta.internalIncludedHeadersParsed = parseHeaderList(meta.IncludedHeaders)
+	meta.internalMaskedHeadersParsed = append(parseHeaderList(meta.DecisionLogsMaskedHeaders), defaultMaskedHeaders...)
+
+	return &meta, nil
+}
+
+// Parses a comma-separated list of header names, returning them in canonical format.
+func parseHeaderList(val string) []string {
+	res := strings.Split(val, ",")
 	n := 0
-	for i := range meta.internalIncludedHeadersParsed {
-		scrubbed := strings.ReplaceAll(meta.internalIncludedHeadersParsed[i], " ", "")
+	for i := range res {
+		scrubbed := strings.ReplaceAll(res[i], " ", "")
 		if scrubbed != "" {
-			meta.internalIncludedHeadersParsed[n] = textproto.CanonicalMIMEHeaderKey(scrubbed)
+			res[n] = textproto.CanonicalMIMEHeaderKey(scrubbed)
 			n++
 		}
 	}
-	meta.internalIncludedHeadersParsed = meta.internalIncludedHeadersParsed[:n]
-
-	return &meta, nil
+	return res[:n]
 }
 
 func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
@@ -266,3 +484,8 @@ func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.Metada
 	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.MiddlewareType)
 	return
 }
+
+var (
+	_ state.StoreConsumer         = (*Middleware)(nil)
+	_ configuration.StoreConsumer = (*Middleware)(nil)
+)
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+// policy is a compiled policy, ready for evaluation.
+type policy struct {
+	query rego.PreparedEvalQuery
+	// Revision of the bundle, or digest of the inline policy
+	revision string
+}
+
+// policyLoader compiles the policy from the inline rego, the bundle and the data documents, and compiles it again when the bundle or the data documents change.
+type policyLoader struct {
+	rego    string
+	bundles *bundleLoader
+	data    *dataLoader
+
+	lock         sync.Mutex
+	bundle       *bundle.Bundle
+	bundleDigest string
+	docs         map[string]any
+	policy       atomic.Pointer[policy]
+}
+
+// refresh reloads the bundle and/or the data documents, and compiles the policy again if anything changed.
+// If the bundle or the data documents can't be loaded, the policy is compiled with the ones loaded previously, and an error is returned.
+func (p *policyLoader) refresh(ctx context.Context, bundles bool, data bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var errs []error
+	changed := p.policy.Load() == nil
+	if bundles && p.bundles != nil {
+		b, digest, err := p.bundles.load(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load bundle: %w", err))
+		} else if b != nil {
+			p.bundle = b
+			p.bundleDigest = digest
+			changed = true
+		}
+	}
+	if data && p.data != nil {
+		docs, err := p.data.load(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load data documents: %w", err))
+		} else if !reflect.DeepEqual(docs, p.docs) {
+			p.docs = docs
+			changed = true
+		}
+	}
+
+	// The policy can't be compiled without its bundle
+	if changed && (p.bundles == nil || p.bundle != nil) {
+		pol, err := p.compile(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			p.policy.Store(pol)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *policyLoader) compile(ctx context.Context) (*policy, error) {
+	opts := []func(*rego.Rego){
+		rego.Query("result = data.http.allow"),
+	}
+	pol := &policy{}
+
+	if p.rego != "" {
+		opts = append(opts, rego.Module("inline.rego", p.rego))
+		h := sha256.Sum256([]byte(p.rego))
+		pol.revision = "sha256:" + hex.EncodeToString(h[:])
+	}
+
+	data := map[string]any{}
+	if p.bundle != nil {
+		for _, m := range p.bundle.Modules {
+			opts = append(opts, rego.Module(m.Path, string(m.Raw)))
+		}
+		if p.bundle.Data != nil {
+			data = p.bundle.Data
+		}
+		pol.revision = p.bundle.Manifest.Revision
+		if pol.revision == "" {
+			pol.revision = p.bundleDigest
+		}
+	}
+	for path, val := range p.docs {
+		var err error
+		data, err = withValue(data, strings.Split(path, "."), val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add data document '%s': %w", path, err)
+		}
+	}
+	opts = append(opts, rego.Store(inmem.NewFromObject(data)))
+
+	var err error
+	pol.query, err = rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pol, nil
+}
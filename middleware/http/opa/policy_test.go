@@ -0,0 +1,454 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/components-contrib/configuration"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+const rolesPolicy = `
+package http
+
+default allow = false
+
+allow {
+	input.request.headers["X-Role"] == data.roles[_]
+}`
+
+// Files of a bundle that allows the roles in the data.
+func bundleFiles(revision string, roles ...string) map[string]string {
+	data, _ := json.Marshal(map[string]any{"roles": roles})
+	return map[string]string{
+		".manifest":       `{"revision": "` + revision + `"}`,
+		"http/allow.rego": rolesPolicy,
+		"data.json":       string(data),
+	}
+}
+
+func bundleTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "/" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// Returns a function that sends a request with the role header, and returns the status code.
+func roleRequester(handler func(next http.Handler) http.Handler) func(role string) int {
+	h := handler(http.HandlerFunc(mockedRequestHandler))
+	return func(role string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Role", role)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+}
+
+// bundleServer serves a bundle tarball, with its revision as ETag.
+type bundleServer struct {
+	lock        sync.Mutex
+	revision    string
+	tarball     []byte
+	notModified int
+}
+
+func (s *bundleServer) set(revision string, tarball []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.revision = revision
+	s.tarball = tarball
+}
+
+func (s *bundleServer) notModifiedCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.notModified
+}
+
+func (s *bundleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	etag := `"` + s.revision + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Write(s.tarball)
+}
+
+func TestBundles(t *testing.T) {
+	log := logger.NewLogger("opa.test")
+
+	t.Run("http", func(t *testing.T) {
+		srv := &bundleServer{}
+		srv.set("v1", bundleTarball(t, bundleFiles("v1", "admin")))
+		server := httptest.NewServer(srv)
+		defer server.Close()
+
+		clk := clocktesting.NewFakeClock(time.Now())
+		m := &Middleware{logger: log, clock: clk, closeCh: make(chan struct{})}
+		handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{
+				"bundleURL":             server.URL + "/bundle.tar.gz",
+				"bundleRefreshInterval": "1m",
+				"includedHeaders":       "x-role",
+			},
+		}})
+		require.NoError(t, err)
+		defer m.Close()
+		do := roleRequester(handler)
+
+		assert.Equal(t, http.StatusOK, do("admin"))
+		assert.Equal(t, http.StatusForbidden, do("user"))
+
+		// Unchanged bundles are not downloaded again
+		assert.Eventually(t, clk.HasWaiters, 5*time.Second, 10*time.Millisecond)
+		clk.Step(time.Minute)
+		assert.Eventually(t, func() bool {
+			return srv.notModifiedCount() == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// Updated bundle
+		srv.set("v2", bundleTarball(t, bundleFiles("v2", "user")))
+		clk.Step(time.Minute)
+		assert.Eventually(t, func() bool {
+			return do("user") == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, http.StatusForbidden, do("admin"))
+
+		// The bundle loaded previously is kept if the new one is invalid
+		srv.set("v3", []byte("not a bundle"))
+		clk.Step(time.Minute)
+		assert.Equal(t, http.StatusOK, do("user"))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		p := filepath.Join(t.TempDir(), "bundle.tar.gz")
+		require.NoError(t, os.WriteFile(p, bundleTarball(t, bundleFiles("v1", "admin")), 0o600))
+
+		m := NewMiddleware(log)
+		handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{
+				"bundleURL":             "file://" + p,
+				"bundleRefreshInterval": "0",
+				"includedHeaders":       "x-role",
+			},
+		}})
+		require.NoError(t, err)
+		do := roleRequester(handler)
+		assert.Equal(t, http.StatusOK, do("admin"))
+		assert.Equal(t, http.StatusForbidden, do("user"))
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range bundleFiles("v1", "admin") {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o700))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+		}
+
+		clk := clocktesting.NewFakeClock(time.Now())
+		m := &Middleware{logger: log, clock: clk, closeCh: make(chan struct{})}
+		handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{
+				"bundleURL":       "file://" + dir,
+				"includedHeaders": "x-role",
+				// Inline policies are combined with the bundle
+				"rego": `
+					package http
+					allow { input.request.headers["X-Role"] == "root" }`,
+			},
+		}})
+		require.NoError(t, err)
+		defer m.Close()
+		do := roleRequester(handler)
+		assert.Equal(t, http.StatusOK, do("admin"))
+		assert.Equal(t, http.StatusOK, do("root"))
+		assert.Equal(t, http.StatusForbidden, do("user"))
+
+		data, _ := json.Marshal(map[string]any{"roles": []string{"user"}})
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "data.json"), data, 0o600))
+		assert.Eventually(t, clk.HasWaiters, 5*time.Second, 10*time.Millisecond)
+		clk.Step(time.Minute)
+		assert.Eventually(t, func() bool {
+			return do("user") == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		m := NewMiddleware(log)
+		_, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"bundleURL": "ftp://example.com/bundle.tar.gz"},
+		}})
+		require.ErrorContains(t, err, "unsupported bundle URL")
+
+		_, err = m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"bundleURL": "file://" + filepath.Join(t.TempDir(), "missing.tar.gz")},
+		}})
+		require.ErrorContains(t, err, "failed to load bundle")
+	})
+}
+
+type fakeConfigurationStore struct {
+	lock  sync.Mutex
+	items map[string]*configuration.Item
+}
+
+func (s *fakeConfigurationStore) Init(ctx context.Context, md configuration.Metadata) error {
+	return nil
+}
+
+func (s *fakeConfigurationStore) Get(ctx context.Context, req *configuration.GetRequest) (*configuration.GetResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := &configuration.GetResponse{Items: map[string]*configuration.Item{}}
+	for _, key := range req.Keys {
+		if item, ok := s.items[key]; ok {
+			res.Items[key] = item
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeConfigurationStore) Subscribe(ctx context.Context, req *configuration.SubscribeRequest, handler configuration.UpdateHandler) (string, error) {
+	return "", nil
+}
+
+func (s *fakeConfigurationStore) Unsubscribe(ctx context.Context, req *configuration.UnsubscribeRequest) error {
+	return nil
+}
+
+func (s *fakeConfigurationStore) GetComponentMetadata() metadata.MetadataMap {
+	return metadata.MetadataMap{}
+}
+
+func TestDataDocuments(t *testing.T) {
+	log := logger.NewLogger("opa.test")
+
+	stateStore := inmemory.NewInMemoryStateStore(log)
+	require.NoError(t, stateStore.Init(context.Background(), state.Metadata{}))
+	defer stateStore.(interface{ Close() error }).Close()
+	require.NoError(t, stateStore.Set(context.Background(), &state.SetRequest{Key: "roles", Value: []string{"admin"}}))
+	configStore := &fakeConfigurationStore{items: map[string]*configuration.Item{
+		"tenants": {Value: `{"acme": {"enabled": true}}`},
+		"tier":    {Value: "gold"},
+	}}
+
+	props := map[string]string{
+		"rego": `
+			package http
+
+			default allow = false
+
+			allow {
+				input.request.headers["X-Role"] == data.acl.roles[_]
+				data.tenants.acme.enabled
+				data.tier == "gold"
+			}`,
+		"stateStore":            "statestore",
+		"stateDataKeys":         "acl.roles=roles",
+		"configurationStore":    "configstore",
+		"configurationDataKeys": "tenants, tier",
+		"dataRefreshInterval":   "1m",
+		"includedHeaders":       "x-role",
+	}
+
+	t.Run("requires the stores", func(t *testing.T) {
+		m := NewMiddleware(log).(*Middleware)
+		m.SetStateStore(stateStore)
+		_, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{Properties: props}})
+		require.ErrorContains(t, err, "configuration store 'configstore' was not set")
+
+		m = NewMiddleware(log).(*Middleware)
+		_, err = m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"rego":          rolesPolicy,
+			"stateDataKeys": "roles",
+		}}})
+		require.ErrorContains(t, err, "stateDataKeys requires the stateStore property")
+	})
+
+	clk := clocktesting.NewFakeClock(time.Now())
+	m := &Middleware{logger: log, clock: clk, closeCh: make(chan struct{})}
+	m.SetStateStore(stateStore)
+	m.SetConfigurationStore(configStore)
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{Properties: props}})
+	require.NoError(t, err)
+	defer m.Close()
+	do := roleRequester(handler)
+
+	assert.Equal(t, http.StatusOK, do("admin"))
+	assert.Equal(t, http.StatusForbidden, do("user"))
+
+	require.NoError(t, stateStore.Set(context.Background(), &state.SetRequest{Key: "roles", Value: []string{"admin", "user"}}))
+	assert.Eventually(t, clk.HasWaiters, 5*time.Second, 10*time.Millisecond)
+	clk.Step(time.Minute)
+	assert.Eventually(t, func() bool {
+		return do("user") == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	configStore.lock.Lock()
+	configStore.items["tier"] = &configuration.Item{Value: "silver"}
+	configStore.lock.Unlock()
+	clk.Step(time.Minute)
+	assert.Eventually(t, func() bool {
+		return do("admin") == http.StatusForbidden
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDecisionLogs(t *testing.T) {
+	log := logger.NewLogger("opa.decisions.test")
+	log.EnableJSONOutput(true)
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stdout)
+
+	m := NewMiddleware(log)
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Properties: map[string]string{
+			"rego":         rolesPolicy,
+			"decisionLogs": "true",
+		},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, roleRequester(handler)("admin"))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "OPA decision", entry["msg"])
+	assert.NotEmpty(t, entry["decision_id"])
+	assert.Equal(t, "sha256:", entry["revision"].(string)[:7])
+	assert.Equal(t, false, entry["allowed"])
+	assert.Equal(t, false, entry["result"])
+	assert.Equal(t, "GET", entry["input"].(map[string]any)["request"].(map[string]any)["method"])
+}
+
+func TestDecisionLogsMasking(t *testing.T) {
+	// Returns the request in the input logged for a request with credentials
+	logRequest := func(t *testing.T, props map[string]string) map[string]any {
+		log := logger.NewLogger("opa.decisions.test")
+		log.EnableJSONOutput(true)
+		var out bytes.Buffer
+		log.SetOutput(&out)
+
+		props["rego"] = rolesPolicy
+		props["decisionLogs"] = "true"
+		props["includedHeaders"] = "X-Role, Authorization, Cookie, X-Api-Key"
+		props["readBody"] = "true"
+		handler, err := NewMiddleware(log).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: props,
+		}})
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"password":"secret"}`))
+		r.Header.Set("X-Role", "admin")
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("Cookie", "session=secret")
+		r.Header.Set("X-Api-Key", "secret")
+		w := httptest.NewRecorder()
+		handler(http.HandlerFunc(mockedRequestHandler)).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		return entry["input"].(map[string]any)["request"].(map[string]any)
+	}
+
+	t.Run("default", func(t *testing.T) {
+		req := logRequest(t, map[string]string{})
+		assert.Equal(t, map[string]any{
+			"X-Role":        "admin",
+			"Authorization": maskedHeaderValue,
+			"Cookie":        maskedHeaderValue,
+			"X-Api-Key":     "secret",
+		}, req["headers"])
+		assert.NotContains(t, req, "body")
+	})
+
+	t.Run("additional masked headers and body", func(t *testing.T) {
+		req := logRequest(t, map[string]string{
+			"decisionLogsMaskedHeaders": "x-api-key",
+			"decisionLogsIncludeBody":   "true",
+		})
+		assert.Equal(t, map[string]any{
+			"X-Role":        "admin",
+			"Authorization": maskedHeaderValue,
+			"Cookie":        maskedHeaderValue,
+			"X-Api-Key":     maskedHeaderValue,
+		}, req["headers"])
+		assert.Equal(t, `{"password":"secret"}`, req["body"])
+	})
+}
+
+func TestParseDataDocuments(t *testing.T) {
+	docs, err := parseDataDocuments("acl.roles=roles, tenants ,")
+	require.NoError(t, err)
+	assert.Equal(t, []dataDocument{
+		{path: []string{"acl", "roles"}, key: "roles"},
+		{path: []string{"tenants"}, key: "tenants"},
+	}, docs)
+
+	_, err = parseDataDocuments("=roles")
+	require.ErrorContains(t, err, "invalid data document")
+	_, err = parseDataDocuments("acl..roles=roles")
+	require.ErrorContains(t, err, "invalid path")
+}
+
+func TestWithValue(t *testing.T) {
+	data := map[string]any{"acl": map[string]any{"users": []any{"a"}}, "tier": "gold"}
+
+	res, err := withValue(data, []string{"acl", "roles"}, []any{"admin"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"acl": map[string]any{"users": []any{"a"}, "roles": []any{"admin"}}, "tier": "gold"}, res)
+	// The original data is not modified
+	assert.Equal(t, map[string]any{"acl": map[string]any{"users": []any{"a"}}, "tier": "gold"}, data)
+
+	_, err = withValue(data, []string{"tier", "name"}, "x")
+	require.ErrorContains(t, err, "data at 'tier' is not an object")
+}
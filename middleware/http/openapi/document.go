@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Maximum size of the OpenAPI document, in bytes
+const maxDocumentSize = 10 << 20
+
+// Loads the OpenAPI document from a URL with scheme file://, http://, or https://.
+func loadDocument(ctx context.Context, specURL string) (*openapi3.T, error) {
+	if specURL == "" {
+		return nil, errors.New("missing specURL")
+	}
+
+	firstColon := strings.IndexByte(specURL, ':')
+	if firstColon == -1 {
+		return nil, fmt.Errorf("invalid URL: %s", specURL)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	scheme := specURL[:firstColon]
+	switch scheme {
+	case "http", "https":
+		var u *url.URL
+		u, err = url.Parse(specURL)
+		if err != nil {
+			return nil, err
+		}
+		data, err = fetchDocument(ctx, u)
+	case "file":
+		data, err = os.ReadFile(specURL[7:])
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme: %s", scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	doc, err := loader.LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	err = doc.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	relativeServers(doc.Servers)
+	for _, item := range doc.Paths {
+		relativeServers(item.Servers)
+	}
+	return doc, nil
+}
+
+// Returns the document found at the URL.
+func fetchDocument(ctx context.Context, u *url.URL) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("received %v status code from %q", resp.StatusCode, u)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("document at %q is larger than %d bytes", u, maxDocumentSize)
+	}
+	return data, nil
+}
+
+// Removes the scheme and host from the URLs of the servers, so routes are matched on the path only.
+// Requests reach the middleware with the address of the sidecar, not the one in the document.
+func relativeServers(servers openapi3.Servers) {
+	for _, s := range servers {
+		if s == nil {
+			continue
+		}
+		_, rest, ok := strings.Cut(s.URL, "://")
+		if !ok {
+			continue
+		}
+		slash := strings.IndexByte(rest, '/')
+		if slash == -1 {
+			s.URL = "/"
+		} else {
+			s.URL = rest[slash:]
+		}
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+// Metadata is the OpenAPI middleware config.
+type openAPIMiddlewareMetadata struct {
+	// URL of the OpenAPI 3 document, with scheme file://, http://, or https://.
+	SpecURL string `json:"specURL" mapstructure:"specURL"`
+	// If true, requests for routes that are not in the document are allowed; otherwise, they are rejected.
+	AllowUnknownRoutes bool `json:"allowUnknownRoutes" mapstructure:"allowUnknownRoutes"`
+	// If true, responses are validated too. Violations are logged, and responses are sent unchanged.
+	ValidateResponses bool `json:"validateResponses" mapstructure:"validateResponses"`
+	// Maximum size of the response bodies that are validated, in bytes.
+	MaxResponseBodySize int `json:"maxResponseBodySize" mapstructure:"maxResponseBodySize"`
+	// Maximum size of the request bodies that are validated, in bytes; larger requests are rejected.
+	MaxRequestBodySize int `json:"maxRequestBodySize" mapstructure:"maxRequestBodySize"`
+}
+
+const (
+	defaultMaxResponseBodySize = 1 << 20
+	defaultMaxRequestBodySize  = 4 << 20
+)
+
+// NewOpenAPIMiddleware returns a new OpenAPI validation middleware.
+func NewOpenAPIMiddleware(log logger.Logger) middleware.Middleware {
+	return &Middleware{logger: log}
+}
+
+// Middleware is a middleware that validates requests and responses against an OpenAPI document.
+type Middleware struct {
+	logger logger.Logger
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(ctx context.Context, metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := m.getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := loadDocument(ctx, meta.SpecURL)
+	if err != nil {
+		return nil, err
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &handler{
+		logger: m.logger,
+		meta:   meta,
+		router: router,
+		options: &openapi3filter.Options{
+			MultiError: true,
+			// Authentication is the responsibility of other middlewares
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			// Do not modify requests
+			SkipSettingDefaults: true,
+		},
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r, next)
+		})
+	}, nil
+}
+
+type handler struct {
+	logger  logger.Logger
+	meta    *openAPIMiddlewareMetadata
+	router  routers.Router
+	options *openapi3filter.Options
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	route, pathParams, err := h.router.FindRoute(r)
+	if err != nil {
+		switch {
+		case h.meta.AllowUnknownRoutes:
+			next.ServeHTTP(w, r)
+		case errors.Is(err, routers.ErrMethodNotAllowed):
+			respondWithProblem(w, r, http.StatusMethodNotAllowed, "The method is not allowed for the route", nil)
+		default:
+			respondWithProblem(w, r, http.StatusNotFound, "The route is not defined in the OpenAPI document", nil)
+		}
+		return
+	}
+
+	// The body is read in memory to be validated, so its size is limited
+	if route.Operation != nil && route.Operation.RequestBody != nil && r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(h.meta.MaxRequestBodySize)))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondWithProblem(w, r, http.StatusRequestEntityTooLarge, "The request body is too large", nil)
+			} else {
+				respondWithProblem(w, r, http.StatusBadRequest, "The request body could not be read", nil)
+			}
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    h.options,
+	}
+	err = openapi3filter.ValidateRequest(r.Context(), input)
+	if err != nil {
+		respondWithProblem(w, r, http.StatusBadRequest, "The request is not valid", problemErrors(err))
+		return
+	}
+
+	if !h.meta.ValidateResponses {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	rec := httputils.NewResponseRecorder(w, h.meta.MaxResponseBodySize)
+	next.ServeHTTP(rec, r)
+	if rec.Overflow() {
+		h.logger.Debugf("Response for %s %s is too large to be validated", r.Method, r.URL.Path)
+		return
+	}
+
+	err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 rec.Status(),
+		Header:                 w.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rec.Body())),
+		Options:                h.options,
+	})
+	if err != nil {
+		h.logger.Warnf("Response for %s %s does not match the OpenAPI document: %s", r.Method, r.URL.Path, describeErrors(err))
+	}
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*openAPIMiddlewareMetadata, error) {
+	middlewareMetadata := openAPIMiddlewareMetadata{
+		MaxResponseBodySize: defaultMaxResponseBodySize,
+		MaxRequestBodySize:  defaultMaxRequestBodySize,
+	}
+	err := kitmd.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if middlewareMetadata.SpecURL == "" {
+		return nil, errors.New("metadata property specURL is required")
+	}
+	if middlewareMetadata.MaxResponseBodySize <= 0 {
+		return nil, errors.New("metadata property maxResponseBodySize must be a positive value")
+	}
+	if middlewareMetadata.MaxRequestBodySize <= 0 {
+		return nil, errors.New("metadata property maxRequestBodySize must be a positive value")
+	}
+
+	return &middlewareMetadata, nil
+}
+
+func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.MetadataMap) {
+	metadataStruct := openAPIMiddlewareMetadata{}
+	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.MiddlewareType)
+	return
+}
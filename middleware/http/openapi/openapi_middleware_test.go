@@ -0,0 +1,243 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info:
+  title: Orders
+  version: 1.0.0
+servers:
+  - url: https://orders.example.com/api
+paths:
+  /orders/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+        - name: fields
+          in: query
+          schema:
+            type: string
+            enum: [all, summary]
+      responses:
+        "200":
+          description: The order
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Order"
+  /orders:
+    post:
+      parameters:
+        - name: X-Tenant
+          in: header
+          required: true
+          schema:
+            type: string
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Order"
+      responses:
+        "201":
+          description: Created
+components:
+  schemas:
+    Order:
+      type: object
+      required: [item, quantity]
+      properties:
+        item:
+          type: string
+        quantity:
+          type: integer
+          minimum: 1
+`
+
+func newTestHandler(t *testing.T, properties map[string]string, response string) http.Handler {
+	t.Helper()
+
+	handler, err := NewOpenAPIMiddleware(logger.NewLogger("openapi.test")).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Properties: properties,
+	}})
+	require.NoError(t, err)
+
+	return handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(response))
+	}))
+}
+
+func do(h http.Handler, method string, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("content-type", "application/json")
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func readProblem(t *testing.T, w *httptest.ResponseRecorder) problem {
+	t.Helper()
+	assert.Equal(t, "application/problem+json", w.Header().Get("content-type"))
+	var p problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return p
+}
+
+func TestOpenAPIMiddleware(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "spec.yaml")
+	require.NoError(t, os.WriteFile(specFile, []byte(testSpec), 0o600))
+
+	t.Run("valid requests", func(t *testing.T) {
+		h := newTestHandler(t, map[string]string{"specURL": "file://" + specFile}, `{}`)
+
+		assert.Equal(t, http.StatusOK, do(h, http.MethodGet, "/api/orders/1?fields=all", "", nil).Code)
+		assert.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/api/orders", `{"item": "pizza", "quantity": 2}`, map[string]string{"X-Tenant": "acme"}).Code)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		h := newTestHandler(t, map[string]string{"specURL": "file://" + specFile}, `{}`)
+
+		w := do(h, http.MethodGet, "/api/orders/abc?fields=none", "", nil)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		p := readProblem(t, w)
+		assert.Equal(t, http.StatusBadRequest, p.Status)
+		assert.Equal(t, "Bad Request", p.Title)
+		assert.Equal(t, "/api/orders/abc", p.Instance)
+		require.Len(t, p.Errors, 2)
+		assert.Equal(t, "path", p.Errors[0].In)
+		assert.Equal(t, "id", p.Errors[0].Name)
+		assert.Equal(t, "query", p.Errors[1].In)
+		assert.Equal(t, "fields", p.Errors[1].Name)
+	})
+
+	t.Run("invalid header and body", func(t *testing.T) {
+		h := newTestHandler(t, map[string]string{"specURL": "file://" + specFile}, `{}`)
+
+		w := do(h, http.MethodPost, "/api/orders", `{"quantity": 0}`, nil)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		p := readProblem(t, w)
+		require.Len(t, p.Errors, 3)
+		assert.Equal(t, problemError{In: "header", Name: "X-Tenant", Reason: "value is required but missing"}, p.Errors[0])
+		assert.Equal(t, "body", p.Errors[1].In)
+		assert.Equal(t, "body", p.Errors[2].In)
+		pointers := []string{p.Errors[1].Pointer, p.Errors[2].Pointer}
+		assert.ElementsMatch(t, []string{"/item", "/quantity"}, pointers)
+	})
+
+	t.Run("request body too large", func(t *testing.T) {
+		h := newTestHandler(t, map[string]string{
+			"specURL":            "file://" + specFile,
+			"maxRequestBodySize": "40",
+		}, `{}`)
+		headers := map[string]string{"X-Tenant": "acme"}
+
+		assert.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/api/orders", `{"item": "pizza", "quantity": 2}`, headers).Code)
+		w := do(h, http.MethodPost, "/api/orders", `{"item": "`+strings.Repeat("a", 40)+`", "quantity": 2}`, headers)
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, readProblem(t, w).Status)
+	})
+
+	t.Run("unknown routes", func(t *testing.T) {
+		h := newTestHandler(t, map[string]string{"specURL": "file://" + specFile}, `{}`)
+		w := do(h, http.MethodGet, "/api/items", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, http.StatusNotFound, readProblem(t, w).Status)
+		assert.Equal(t, http.StatusMethodNotAllowed, do(h, http.MethodDelete, "/api/orders/1", "", nil).Code)
+
+		h = newTestHandler(t, map[string]string{
+			"specURL":            "file://" + specFile,
+			"allowUnknownRoutes": "true",
+		}, `{}`)
+		assert.Equal(t, http.StatusOK, do(h, http.MethodGet, "/api/items", "", nil).Code)
+	})
+
+	t.Run("report-only response validation", func(t *testing.T) {
+		// The response is not valid, but it's sent unchanged
+		h := newTestHandler(t, map[string]string{
+			"specURL":           "file://" + specFile,
+			"validateResponses": "true",
+		}, `{"item": 42}`)
+		w := do(h, http.MethodGet, "/api/orders/1", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"item": 42}`, w.Body.String())
+	})
+
+	t.Run("load from HTTP", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/spec.yaml" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(testSpec))
+		}))
+		defer server.Close()
+
+		h := newTestHandler(t, map[string]string{"specURL": server.URL + "/spec.yaml"}, `{}`)
+		assert.Equal(t, http.StatusOK, do(h, http.MethodGet, "/api/orders/1", "", nil).Code)
+
+		_, err := NewOpenAPIMiddleware(logger.NewLogger("test")).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"specURL": server.URL + "/missing.yaml"},
+		}})
+		require.ErrorContains(t, err, "received 404 status code")
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		m := NewOpenAPIMiddleware(logger.NewLogger("test"))
+		_, err := m.GetHandler(context.Background(), middleware.Metadata{})
+		require.ErrorContains(t, err, "specURL is required")
+
+		_, err = m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"specURL": "ftp://example.com/spec.yaml"},
+		}})
+		require.ErrorContains(t, err, "unsupported URL scheme")
+
+		_, err = m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"specURL": "file://" + specFile, "maxRequestBodySize": "0"},
+		}})
+		require.ErrorContains(t, err, "maxRequestBodySize must be a positive value")
+	})
+}
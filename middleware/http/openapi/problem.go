@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// Problem details, as per RFC 7807.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extension member with the list of validation errors
+	Errors []problemError `json:"errors,omitempty"`
+}
+
+// Validation error included in problem details.
+type problemError struct {
+	// Location of the invalid value: "path", "query", "header", "cookie", or "body"
+	In string `json:"in"`
+	// Name of the parameter
+	Name string `json:"name,omitempty"`
+	// JSON pointer to the invalid value in the body
+	Pointer string `json:"pointer,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Sends the problem details as response.
+func respondWithProblem(w http.ResponseWriter, r *http.Request, status int, detail string, errs []problemError) {
+	p := problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   errs,
+	}
+	data, _ := json.Marshal(p)
+
+	w.Header().Set("content-type", "application/problem+json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Converts the errors returned by the validation of a request to the list of errors included in problem details.
+func problemErrors(err error) []problemError {
+	var res []problemError
+
+	// Check for RequestError first, since it can wrap a MultiError
+	reqErr, ok := err.(*openapi3filter.RequestError)
+	if !ok {
+		var multi openapi3.MultiError
+		if errors.As(err, &multi) {
+			for _, e := range multi {
+				res = append(res, problemErrors(e)...)
+			}
+			return res
+		}
+		if !errors.As(err, &reqErr) {
+			return []problemError{withReason(problemError{}, "", err)}
+		}
+	}
+
+	pe := problemError{}
+	switch {
+	case reqErr.Parameter != nil:
+		pe.In = reqErr.Parameter.In
+		pe.Name = reqErr.Parameter.Name
+	case reqErr.RequestBody != nil:
+		pe.In = "body"
+	}
+
+	// Errors in the body can include multiple schema errors
+	var inner openapi3.MultiError
+	if errors.As(reqErr.Err, &inner) {
+		for _, e := range inner {
+			res = append(res, withReason(pe, reqErr.Reason, e))
+		}
+		return res
+	}
+	return []problemError{withReason(pe, reqErr.Reason, reqErr.Err)}
+}
+
+// Escapes tokens in JSON pointers, as per RFC 6901.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Sets the reason and pointer from the error.
+func withReason(pe problemError, reason string, err error) problemError {
+	var schemaErr *openapi3.SchemaError
+	switch {
+	case errors.As(err, &schemaErr):
+		pe.Reason = schemaErr.Reason
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			for i := range pointer {
+				pointer[i] = pointerEscaper.Replace(pointer[i])
+			}
+			pe.Pointer = "/" + strings.Join(pointer, "/")
+		}
+	case err != nil && reason != "" && reason != err.Error():
+		pe.Reason = reason + ": " + err.Error()
+	case err != nil:
+		pe.Reason = err.Error()
+	default:
+		pe.Reason = reason
+	}
+	return pe
+}
+
+// Returns a short description of the validation errors, for logging.
+func describeErrors(err error) string {
+	errs := problemErrors(err)
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Reason
+		if e.Pointer != "" {
+			msgs[i] = e.Pointer + ": " + msgs[i]
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
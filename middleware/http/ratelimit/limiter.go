@@ -0,0 +1,249 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/components-contrib/state"
+)
+
+// Limit of a token bucket.
+type limit struct {
+	// Tokens added to the bucket every second
+	rate float64
+	// Capacity of the bucket
+	burst int
+}
+
+// Result of taking a token from a bucket.
+type takeResult struct {
+	allowed bool
+	// Limit of the bucket
+	limit int
+	// Tokens left in the bucket
+	remaining int
+	// Time until the bucket is full again
+	reset time.Duration
+	// If the request is not allowed, time until a token is available
+	retryAfter time.Duration
+}
+
+// State of a token bucket.
+type bucketState struct {
+	Tokens float64 `json:"t"`
+	// Time of the last update, as UNIX timestamp in microseconds
+	Updated int64 `json:"u"`
+}
+
+// Takes a token from the bucket, updating its state.
+// A nil or empty state is a full bucket.
+func (l limit) take(s *bucketState, now time.Time) takeResult {
+	nowMicro := now.UnixMicro()
+	tokens := float64(l.burst)
+	if s.Updated > 0 {
+		elapsed := float64(nowMicro-s.Updated) / float64(time.Second/time.Microsecond)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(float64(l.burst), s.Tokens+elapsed*l.rate)
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	s.Tokens = tokens
+	s.Updated = nowMicro
+
+	return l.result(allowed, tokens)
+}
+
+// Returns the result for the number of tokens left in the bucket after a request.
+func (l limit) result(allowed bool, tokens float64) takeResult {
+	res := takeResult{
+		allowed:   allowed,
+		limit:     l.burst,
+		remaining: int(math.Floor(tokens)),
+		reset:     l.duration(float64(l.burst) - tokens),
+	}
+	if !allowed {
+		res.retryAfter = l.duration(1 - tokens)
+	}
+	return res
+}
+
+// Returns the time needed to add n tokens to the bucket.
+func (l limit) duration(n float64) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n / l.rate * float64(time.Second))
+}
+
+// Returns the time after which a bucket that is not used is full, and can be discarded.
+func (l limit) ttl() time.Duration {
+	return l.duration(float64(l.burst)) + time.Second
+}
+
+// limiter is the backend that stores the token buckets.
+type limiter interface {
+	// Take a token from the bucket with the given key.
+	Take(ctx context.Context, key string, l limit) (takeResult, error)
+	Close() error
+}
+
+// Stores buckets in memory; limits are enforced per-instance.
+type memoryLimiter struct {
+	clock       clock.Clock
+	lock        sync.Mutex
+	buckets     map[string]*memoryBucket
+	lastCleanup time.Time
+}
+
+type memoryBucket struct {
+	bucketState
+	expires time.Time
+}
+
+// Interval for removing expired buckets
+const memoryCleanupInterval = time.Minute
+
+func newMemoryLimiter(clk clock.Clock) *memoryLimiter {
+	return &memoryLimiter{
+		clock:       clk,
+		buckets:     make(map[string]*memoryBucket),
+		lastCleanup: clk.Now(),
+	}
+}
+
+func (m *memoryLimiter) Take(_ context.Context, key string, l limit) (takeResult, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.clock.Now()
+	if now.Sub(m.lastCleanup) >= memoryCleanupInterval {
+		for k, b := range m.buckets {
+			if !now.Before(b.expires) {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastCleanup = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok || !now.Before(b.expires) {
+		b = &memoryBucket{}
+		m.buckets[key] = b
+	}
+	res := l.take(&b.bucketState, now)
+	b.expires = now.Add(l.ttl())
+	return res, nil
+}
+
+func (m *memoryLimiter) Close() error {
+	return nil
+}
+
+// Maximum number of attempts when there's a conflict updating a bucket in the state store
+const stateMaxAttempts = 5
+
+// Stores buckets in a state store, using ETags for optimistic concurrency control.
+type stateLimiter struct {
+	store state.Store
+	clock clock.Clock
+}
+
+func newStateLimiter(store state.Store, clk clock.Clock) (*stateLimiter, error) {
+	if !state.FeatureETag.IsPresent(store.Features()) {
+		return nil, errors.New("the state store does not support ETags, which are required for rate limiting")
+	}
+	return &stateLimiter{
+		store: store,
+		clock: clk,
+	}, nil
+}
+
+func (s *stateLimiter) Take(ctx context.Context, key string, l limit) (takeResult, error) {
+	for i := 0; i < stateMaxAttempts; i++ {
+		res, err := s.tryTake(ctx, key, l)
+		var etagErr *state.ETagError
+		if errors.As(err, &etagErr) && etagErr.Kind() == state.ETagMismatch {
+			// Another request updated the bucket; try again
+			continue
+		}
+		return res, err
+	}
+	return takeResult{}, errors.New("failed to update the bucket: too many concurrent requests")
+}
+
+func (s *stateLimiter) tryTake(ctx context.Context, key string, l limit) (takeResult, error) {
+	getRes, err := s.store.Get(ctx, &state.GetRequest{
+		Key: key,
+		Options: state.GetStateOption{
+			Consistency: state.Strong,
+		},
+	})
+	if err != nil {
+		return takeResult{}, fmt.Errorf("failed to retrieve bucket: %w", err)
+	}
+
+	var b bucketState
+	if getRes != nil && len(getRes.Data) > 0 {
+		err = json.Unmarshal(getRes.Data, &b)
+		if err != nil {
+			return takeResult{}, fmt.Errorf("invalid bucket data: %w", err)
+		}
+	}
+
+	res := l.take(&b, s.clock.Now())
+	data, err := json.Marshal(b)
+	if err != nil {
+		return takeResult{}, err
+	}
+
+	setReq := &state.SetRequest{
+		Key:   key,
+		Value: data,
+		Metadata: map[string]string{
+			"ttlInSeconds": strconv.Itoa(int(math.Ceil(l.ttl().Seconds()))),
+		},
+		Options: state.SetStateOption{
+			Concurrency: state.FirstWrite,
+			Consistency: state.Strong,
+		},
+	}
+	if getRes != nil && getRes.ETag != nil && *getRes.ETag != "" {
+		setReq.ETag = getRes.ETag
+	}
+	err = s.store.Set(ctx, setReq)
+	if err != nil {
+		return takeResult{}, err
+	}
+	return res, nil
+}
+
+func (s *stateLimiter) Close() error {
+	// The state store is owned by the runtime
+	return nil
+}
@@ -15,15 +15,24 @@ package ratelimit
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	tollbooth "github.com/didip/tollbooth/v7"
-	libstring "github.com/didip/tollbooth/v7/libstring"
+	"k8s.io/utils/clock"
 
+	"github.com/dapr/components-contrib/internal/httputils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 	kitmd "github.com/dapr/kit/metadata"
 )
@@ -31,22 +40,58 @@ import (
 // Metadata is the ratelimit middleware config.
 type rateLimitMiddlewareMetadata struct {
 	MaxRequestsPerSecond float64 `json:"maxRequestsPerSecond"`
+	// Maximum number of requests allowed in a burst; defaults to maxRequestsPerSecond, rounded up.
+	Burst int `json:"burst"`
+	// Where the buckets are stored: "memory" (the default), "redis", or "state".
+	// With "redis", the connection is configured with the same metadata properties as the Redis state store.
+	// With "state", the state store named in stateStore is used, and it must support ETags.
+	Backend string `json:"backend"`
+	// Name of the state store used by the "state" backend.
+	StateStore string `json:"stateStore"`
+	// Comma-separated list of the values used to group requests: "ip" (the default), "header:<name>", "claim:<name>", and "path:<pattern>".
+	Keys string `json:"keys"`
+	// JSON array of limit tiers for specific routes, with properties "path", "methods", "maxRequestsPerSecond", and "burst".
+	Routes string `json:"routes"`
 }
 
 const (
 	maxRequestsPerSecondKey = "maxRequestsPerSecond"
 
+	backendMemory = "memory"
+	backendRedis  = "redis"
+	backendState  = "state"
+
+	// Prefix for the keys of the buckets
+	bucketKeyPrefix = "dapr-ratelimit||"
+
 	// Defaults.
 	defaultMaxRequestsPerSecond = 100
 )
 
 // NewRateLimitMiddleware returns a new ratelimit middleware.
-func NewRateLimitMiddleware(_ logger.Logger) middleware.Middleware {
-	return &Middleware{}
+func NewRateLimitMiddleware(log logger.Logger) middleware.Middleware {
+	return &Middleware{
+		logger: log,
+		clock:  clock.RealClock{},
+	}
 }
 
 // Middleware is an ratelimit middleware.
-type Middleware struct{}
+type Middleware struct {
+	logger     logger.Logger
+	clock      clock.Clock
+	lock       sync.Mutex
+	stateStore state.Store
+	limiters   []limiter
+}
+
+// SetStateStore sets the state store named in the stateStore metadata property, used by the "state" backend.
+// It implements state.StoreConsumer.
+func (m *Middleware) SetStateStore(store state.Store) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.stateStore = store
+}
 
 // GetHandler returns the HTTP handler provided by the middleware.
 func (m *Middleware) GetHandler(_ context.Context, metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
@@ -55,28 +100,56 @@ func (m *Middleware) GetHandler(_ context.Context, metadata middleware.Metadata)
 		return nil, err
 	}
 
-	limiter := tollbooth.NewLimiter(meta.MaxRequestsPerSecond, nil)
+	defaultLimit, err := newLimit(meta.MaxRequestsPerSecond, meta.Burst)
+	if err != nil {
+		return nil, err
+	}
+	var routes []*routeLimit
+	if meta.Routes != "" {
+		routes, err = parseRouteLimits(meta.Routes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata property 'routes': %w", err)
+		}
+	}
+	keys, err := parseKeySources(meta.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata property 'keys': %w", err)
+	}
+
+	l, err := m.newLimiter(meta, metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	m.limiters = append(m.limiters, l)
+	m.lock.Unlock()
+
+	keyPrefix := bucketKeyPrefix + metadata.Name + "||"
 
 	return func(next http.Handler) http.Handler {
-		// Adapted from toolbooth.LimitHandler
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// The tollbooth library requires a remote IP. If this isn't present in the request's headers, then we need to set a value for X-Forwarded-For or the rate limiter won't work
-			remoteIP := libstring.RemoteIP(limiter.GetIPLookups(), limiter.GetForwardedForIndexFromBehind(), r)
-			remoteIP = libstring.CanonicalizeIP(remoteIP)
-			if remoteIP == "" {
-				// Forcefully set a remote IP
-				r.Header.Set("X-Forwarded-For", "0.0.0.0")
+			lim := defaultLimit
+			tier := "default"
+			if i := findRouteLimit(routes, r); i >= 0 {
+				lim = routes[i].limit
+				tier = strconv.Itoa(i)
+			}
+
+			res, err := l.Take(r.Context(), keyPrefix+tier+"||"+requestKey(keys, r), lim)
+			if err != nil {
+				// Allow the request if the backend is not available, rather than failing all requests
+				m.logger.Errorf("Failed to apply the rate limit: %v", err)
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			httpError := tollbooth.LimitByRequest(limiter, w, r)
-			if httpError != nil {
-				limiter.ExecOnLimitReached(w, r)
-				if limiter.GetOverrideDefaultResponseWriter() {
-					return
-				}
-				w.Header().Add("Content-Type", limiter.GetMessageContentType())
-				w.WriteHeader(httpError.StatusCode)
-				w.Write([]byte(httpError.Message))
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.reset)))
+			if !res.allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.retryAfter)))
+				httputils.RespondWithErrorAndMessage(w, http.StatusTooManyRequests, "You have reached maximum request limit.")
 				return
 			}
 
@@ -86,6 +159,77 @@ func (m *Middleware) GetHandler(_ context.Context, metadata middleware.Metadata)
 	}, nil
 }
 
+// Close releases the connections to the backends.
+func (m *Middleware) Close() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	errs := make([]error, 0, len(m.limiters))
+	for _, l := range m.limiters {
+		errs = append(errs, l.Close())
+	}
+	m.limiters = nil
+	return errors.Join(errs...)
+}
+
+func (m *Middleware) newLimiter(meta *rateLimitMiddlewareMetadata, properties map[string]string) (limiter, error) {
+	switch strings.ToLower(meta.Backend) {
+	case "", backendMemory:
+		return newMemoryLimiter(m.clock), nil
+	case backendRedis:
+		l, err := newRedisLimiter(properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis backend: %w", err)
+		}
+		return l, nil
+	case backendState:
+		if meta.StateStore == "" {
+			return nil, errors.New("backend 'state' requires the metadata property 'stateStore'")
+		}
+		m.lock.Lock()
+		store := m.stateStore
+		m.lock.Unlock()
+		if store == nil {
+			return nil, fmt.Errorf("state store '%s' was not set", meta.StateStore)
+		}
+		return newStateLimiter(store, m.clock)
+	default:
+		return nil, fmt.Errorf("invalid metadata property 'backend': %s", meta.Backend)
+	}
+}
+
+// Returns the key of the bucket for the request.
+// Values are hashed so sensitive values, such as API keys, are not stored in the backend.
+func requestKey(keys []keySource, r *http.Request) string {
+	h := sha256.New()
+	for _, k := range keys {
+		v := k(r)
+		h.Write([]byte(strconv.Itoa(len(v))))
+		h.Write([]byte{':'})
+		h.Write([]byte(v))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Returns a limit, validating the values and applying the default burst.
+func newLimit(maxRequestsPerSecond float64, burst int) (limit, error) {
+	if maxRequestsPerSecond <= 0 {
+		return limit{}, fmt.Errorf("metadata property %s must be a positive value", maxRequestsPerSecondKey)
+	}
+	if burst < 0 {
+		return limit{}, errors.New("metadata property burst must not be negative")
+	}
+	if burst == 0 {
+		burst = int(math.Ceil(maxRequestsPerSecond))
+	}
+	return limit{rate: maxRequestsPerSecond, burst: burst}, nil
+}
+
+// Returns the duration in seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
 func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*rateLimitMiddlewareMetadata, error) {
 	middlewareMetadata := rateLimitMiddlewareMetadata{
 		MaxRequestsPerSecond: defaultMaxRequestsPerSecond,
@@ -107,3 +251,5 @@ func (m *Middleware) GetComponentMetadata() (metadataInfo contribMetadata.Metada
 	contribMetadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, contribMetadata.MiddlewareType)
 	return
 }
+
+var _ state.StoreConsumer = (*Middleware)(nil)
@@ -14,13 +14,22 @@ limitations under the License.
 package ratelimit
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
 )
 
 func TestMiddlewareGetNativeMetadata(t *testing.T) {
@@ -71,3 +80,177 @@ func TestMiddlewareGetNativeMetadata(t *testing.T) {
 		assert.Equal(t, float64(42.42), res.MaxRequestsPerSecond)
 	})
 }
+
+func newTestHandler(t *testing.T, m *Middleware, properties map[string]string) func(path string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	handler, err := m.GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+		Name:       "test",
+		Properties: properties,
+	}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, m.Close())
+	})
+
+	h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	return func(path string, headers map[string]string) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	log := logger.NewLogger("ratelimit.test")
+
+	t.Run("token bucket and headers", func(t *testing.T) {
+		clk := clocktesting.NewFakeClock(time.Now())
+		m := &Middleware{logger: log, clock: clk}
+		do := newTestHandler(t, m, map[string]string{
+			maxRequestsPerSecondKey: "2",
+			"burst":                 "3",
+		})
+
+		for i := 0; i < 3; i++ {
+			res := do("/", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "3", res.Header.Get("RateLimit-Limit"))
+			assert.Equal(t, []string{"2", "1", "0"}[i], res.Header.Get("RateLimit-Remaining"))
+		}
+
+		res := do("/", nil)
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, "1", res.Header.Get("Retry-After"))
+		assert.Equal(t, "2", res.Header.Get("RateLimit-Reset"))
+
+		// Tokens are added at the configured rate
+		clk.Step(500 * time.Millisecond)
+		assert.Equal(t, http.StatusOK, do("/", nil).StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, do("/", nil).StatusCode)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		m := &Middleware{logger: log, clock: clocktesting.NewFakeClock(time.Now())}
+		do := newTestHandler(t, m, map[string]string{
+			maxRequestsPerSecondKey: "1",
+			"keys":                  "header:X-API-Key, path:/tenants/{tenant}/**",
+		})
+
+		assert.Equal(t, http.StatusOK, do("/tenants/a/foo", map[string]string{"X-API-Key": "k1"}).StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, do("/tenants/a/bar", map[string]string{"X-API-Key": "k1"}).StatusCode)
+		// Non-canonical paths are in the same bucket
+		assert.Equal(t, http.StatusTooManyRequests, do("/tenants//a/bar", map[string]string{"X-API-Key": "k1"}).StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, do("/tenants/b/../a/bar", map[string]string{"X-API-Key": "k1"}).StatusCode)
+		assert.Equal(t, http.StatusOK, do("/tenants/b/foo", map[string]string{"X-API-Key": "k1"}).StatusCode)
+		assert.Equal(t, http.StatusOK, do("/tenants/a/foo", map[string]string{"X-API-Key": "k2"}).StatusCode)
+	})
+
+	t.Run("route tiers", func(t *testing.T) {
+		m := &Middleware{logger: log, clock: clocktesting.NewFakeClock(time.Now())}
+		do := newTestHandler(t, m, map[string]string{
+			maxRequestsPerSecondKey: "1",
+			"routes":                `[{"path": "/api/*/orders", "maxRequestsPerSecond": 10, "burst": 5}]`,
+		})
+
+		for i := 0; i < 5; i++ {
+			res := do("/api/v1/orders", nil)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "5", res.Header.Get("RateLimit-Limit"))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, do("/api/v1/orders", nil).StatusCode)
+		// Non-canonical paths can't bypass the route's limit
+		assert.Equal(t, http.StatusTooManyRequests, do("/api/v1//orders", nil).StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, do("/api/v1/./orders/", nil).StatusCode)
+
+		// Other routes use the default limit and separate buckets
+		assert.Equal(t, http.StatusOK, do("/api/v1/items", nil).StatusCode)
+		res := do("/api/v1/items", nil)
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, "1", res.Header.Get("RateLimit-Limit"))
+	})
+
+	t.Run("state store backend", func(t *testing.T) {
+		store := inmemory.NewInMemoryStateStore(log)
+		require.NoError(t, store.Init(context.Background(), state.Metadata{}))
+
+		props := map[string]string{
+			maxRequestsPerSecondKey: "1",
+			"burst":                 "2",
+			"backend":               "state",
+			"stateStore":            "statestore",
+		}
+		clk := clocktesting.NewFakeClock(time.Now())
+		m1 := &Middleware{logger: log, clock: clk}
+		m1.SetStateStore(store)
+		do1 := newTestHandler(t, m1, props)
+		m2 := &Middleware{logger: log, clock: clk}
+		m2.SetStateStore(store)
+		do2 := newTestHandler(t, m2, props)
+
+		// Instances share the buckets
+		assert.Equal(t, http.StatusOK, do1("/", nil).StatusCode)
+		assert.Equal(t, http.StatusOK, do2("/", nil).StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, do1("/", nil).StatusCode)
+		assert.Equal(t, http.StatusTooManyRequests, do2("/", nil).StatusCode)
+	})
+
+	t.Run("state store backend requires a state store", func(t *testing.T) {
+		_, err := NewRateLimitMiddleware(log).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"backend": "state"},
+		}})
+		require.ErrorContains(t, err, "requires the metadata property 'stateStore'")
+
+		_, err = NewRateLimitMiddleware(log).GetHandler(context.Background(), middleware.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"backend": "state", "stateStore": "statestore"},
+		}})
+		require.ErrorContains(t, err, "state store 'statestore' was not set")
+	})
+
+	t.Run("redis backend", func(t *testing.T) {
+		s := miniredis.RunT(t)
+		s.SetTime(time.Now())
+
+		props := map[string]string{
+			maxRequestsPerSecondKey: "1",
+			"burst":                 "2",
+			"backend":               "redis",
+			"redisHost":             s.Addr(),
+		}
+		do1 := newTestHandler(t, NewRateLimitMiddleware(log).(*Middleware), props)
+		do2 := newTestHandler(t, NewRateLimitMiddleware(log).(*Middleware), props)
+
+		assert.Equal(t, http.StatusOK, do1("/", nil).StatusCode)
+		res := do2("/", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+		res = do1("/", nil)
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, "1", res.Header.Get("Retry-After"))
+
+		// Uses the clock of the Redis server
+		s.SetTime(time.Now().Add(time.Second))
+		assert.Equal(t, http.StatusOK, do2("/", nil).StatusCode)
+	})
+}
+
+func TestParseKeySources(t *testing.T) {
+	keys, err := parseKeySources("")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	keys, err = parseKeySources("ip,header:X-Tenant,claim:sub,path:/t/{id}")
+	require.NoError(t, err)
+	assert.Len(t, keys, 4)
+
+	_, err = parseKeySources("header")
+	require.Error(t, err)
+	_, err = parseKeySources("cookie:foo")
+	require.Error(t, err)
+}
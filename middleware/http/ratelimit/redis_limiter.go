@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
+)
+
+// Script that takes a token from the bucket atomically.
+// It uses the time of the Redis server so all instances share the same clock.
+// KEYS[1]: key of the bucket
+// ARGV[1]: rate (tokens per second)
+// ARGV[2]: burst
+// ARGV[3]: TTL of the bucket, in seconds
+// Returns: allowed (0 or 1) and the tokens left as string.
+const redisTakeScript = `
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tokens = burst
+local data = redis.call('HMGET', KEYS[1], 't', 'u')
+if data[1] and data[2] then
+	local elapsed = math.max(0, now - tonumber(data[2])) / 1000000
+	tokens = math.min(burst, tonumber(data[1]) + elapsed * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 't', tostring(tokens), 'u', tostring(now))
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return {allowed, tostring(tokens)}
+`
+
+// Stores buckets in Redis.
+type redisLimiter struct {
+	client rediscomponent.RedisClient
+}
+
+func newRedisLimiter(properties map[string]string) (*redisLimiter, error) {
+	client, settings, err := rediscomponent.ParseClientFromProperties(properties, contribMetadata.MiddlewareType)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Host == "" {
+		client.Close()
+		return nil, errors.New("metadata property redisHost is empty")
+	}
+	return &redisLimiter{client: client}, nil
+}
+
+func (r *redisLimiter) Take(ctx context.Context, key string, l limit) (takeResult, error) {
+	ttl := int(math.Ceil(l.ttl().Seconds()))
+	res, err := r.client.EvalResult(ctx, redisTakeScript, []string{key},
+		strconv.FormatFloat(l.rate, 'f', -1, 64), l.burst, ttl,
+	)
+	if err != nil {
+		return takeResult{}, fmt.Errorf("failed to execute script: %w", err)
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return takeResult{}, fmt.Errorf("unexpected response from script: %v", res)
+	}
+	allowed, _ := vals[0].(int64)
+	tokensStr, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return takeResult{}, fmt.Errorf("unexpected response from script: %w", err)
+	}
+	return l.result(allowed == 1, tokens), nil
+}
+
+func (r *redisLimiter) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	libstring "github.com/didip/tollbooth/v7/libstring"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/dapr/components-contrib/internal/httputils"
+)
+
+// Limit tier for the requests matching the path and methods.
+type routeLimit struct {
+	// Path and methods the tier applies to.
+	httputils.Route
+	// Maximum number of requests per second.
+	MaxRequestsPerSecond float64 `json:"maxRequestsPerSecond"`
+	// Maximum number of requests allowed in a burst; defaults to maxRequestsPerSecond, rounded up.
+	Burst int `json:"burst"`
+
+	limit limit
+}
+
+// Parses and validates the route limits.
+func parseRouteLimits(val string) ([]*routeLimit, error) {
+	var routes []*routeLimit
+	err := json.Unmarshal([]byte(val), &routes)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range routes {
+		if r == nil {
+			return nil, fmt.Errorf("route %d is empty", i)
+		}
+		err = r.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		r.limit, err = newLimit(r.MaxRequestsPerSecond, r.Burst)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+	}
+
+	return routes, nil
+}
+
+// Returns the index of the first route that matches the request, or -1.
+func findRouteLimit(routes []*routeLimit, r *http.Request) int {
+	for i, route := range routes {
+		if route.Matches(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Source of (part of) the key used to group requests in buckets.
+type keySource func(r *http.Request) string
+
+// Parses the list of key sources.
+// Supported values are "ip", "header:<name>", "claim:<name>", and "path:<pattern>".
+func parseKeySources(val string) ([]keySource, error) {
+	var res []keySource
+	for _, k := range strings.Split(val, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+
+		kind, arg, _ := strings.Cut(k, ":")
+		switch strings.ToLower(kind) {
+		case "ip":
+			res = append(res, remoteIPKey)
+		case "header":
+			if arg == "" {
+				return nil, fmt.Errorf("key '%s' is missing the header name", k)
+			}
+			res = append(res, headerKey(arg))
+		case "claim":
+			if arg == "" {
+				return nil, fmt.Errorf("key '%s' is missing the claim name", k)
+			}
+			res = append(res, claimKey(arg))
+		case "path":
+			p, err := httputils.ParsePathPattern(arg)
+			if err != nil {
+				return nil, fmt.Errorf("key '%s' has an invalid path: %w", k, err)
+			}
+			res = append(res, pathKey(p))
+		default:
+			return nil, fmt.Errorf("invalid key '%s'", k)
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, remoteIPKey)
+	}
+	return res, nil
+}
+
+var ipLookups = []string{"RemoteAddr", "X-Forwarded-For", "X-Real-IP"}
+
+func remoteIPKey(r *http.Request) string {
+	return libstring.CanonicalizeIP(libstring.RemoteIP(ipLookups, 0, r))
+}
+
+func headerKey(name string) keySource {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Returns the value of a claim from the bearer token.
+// The token is not validated, so this middleware should be placed after one that does, such as the bearer middleware.
+func claimKey(name string) keySource {
+	return func(r *http.Request) string {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return ""
+		}
+		parsed, err := jwt.ParseString(token, jwt.WithVerify(false), jwt.WithValidate(false))
+		if err != nil {
+			return ""
+		}
+		val, ok := parsed.Get(name)
+		if !ok {
+			return ""
+		}
+		if s, ok := val.(string); ok {
+			return s
+		}
+		enc, _ := json.Marshal(val)
+		return string(enc)
+	}
+}
+
+func pathKey(p httputils.PathPattern) keySource {
+	return func(r *http.Request) string {
+		captured, ok := p.Match(r.URL.Path)
+		if !ok {
+			return ""
+		}
+		return strings.Join(captured, "/")
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nameresolution
+
+// HealthStatus is the health of a candidate instance.
+type HealthStatus string
+
+const (
+	// HealthStatusUnknown is used when the resolver doesn't know the health of the instance.
+	HealthStatusUnknown HealthStatus = ""
+	// HealthStatusHealthy is used when the instance is known to be healthy.
+	HealthStatusHealthy HealthStatus = "healthy"
+	// HealthStatusUnhealthy is used when the instance is known to be unhealthy.
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// Candidate is an instance of an app that was returned by the name resolver.
+type Candidate struct {
+	// Address of the instance, including the port.
+	Address string
+	// Zone the instance is running in.
+	Zone string
+	// Relative weight of the instance. Values less than 1 are treated as 1.
+	Weight int
+	// Health of the instance.
+	Health HealthStatus
+	// Additional metadata for the instance.
+	Metadata map[string]string
+}
+
+// GetWeight returns the weight of the candidate, with a minimum of 1.
+func (c Candidate) GetWeight() int {
+	if c.Weight < 1 {
+		return 1
+	}
+	return c.Weight
+}
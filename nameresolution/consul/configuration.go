@@ -20,10 +20,14 @@ import (
 
 	consul "github.com/hashicorp/consul/api"
 
+	"github.com/dapr/components-contrib/nameresolution/selector"
 	"github.com/dapr/kit/config"
 )
 
-const defaultDaprPortMetaKey string = "DAPR_PORT" // default key for DaprPort in meta
+const (
+	defaultDaprPortMetaKey string = "DAPR_PORT" // default key for DaprPort in meta
+	defaultZoneMetaKey     string = "DAPR_ZONE" // default key for the zone in meta
+)
 
 // The intermediateConfig is based off of the consul api types. User configurations are
 // deserialized into this type before being converted to the equivalent consul types
@@ -39,6 +43,10 @@ type intermediateConfig struct {
 	SelfRegister         bool
 	SelfDeregister       bool
 	UseCache             bool
+	SelectionPolicy      string
+	Zone                 string
+	ZoneMetaKey          string
+	Weight               int
 }
 
 type configSpec struct {
@@ -52,11 +60,16 @@ type configSpec struct {
 	SelfRegister         bool
 	SelfDeregister       bool
 	UseCache             bool
+	SelectionPolicy      selector.Policy
+	Zone                 string
+	ZoneMetaKey          string
+	Weight               int
 }
 
 func newIntermediateConfig() intermediateConfig {
 	return intermediateConfig{
 		DaprPortMetaKey: defaultDaprPortMetaKey,
+		ZoneMetaKey:     defaultZoneMetaKey,
 	}
 }
 
@@ -95,6 +108,10 @@ func mapConfig(config intermediateConfig) configSpec {
 		SelfDeregister:       config.SelfDeregister,
 		DaprPortMetaKey:      config.DaprPortMetaKey,
 		UseCache:             config.UseCache,
+		SelectionPolicy:      selector.Policy(config.SelectionPolicy),
+		Zone:                 config.Zone,
+		ZoneMetaKey:          config.ZoneMetaKey,
+		Weight:               config.Weight,
 	}
 }
 
@@ -16,7 +16,6 @@ package consul
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"net"
 	"strconv"
 	"sync"
@@ -25,6 +24,7 @@ import (
 	consul "github.com/hashicorp/consul/api"
 
 	nr "github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/components-contrib/nameresolution/selector"
 	"github.com/dapr/kit/logger"
 )
 
@@ -73,6 +73,7 @@ type resolver struct {
 	logger             logger.Logger
 	client             clientInterface
 	registry           registryInterface
+	selector           *selector.Selector
 	watcherStarted     atomic.Bool
 	watcherStopChannel chan struct{}
 }
@@ -116,30 +117,28 @@ func (r *registry) get(service string) *registryEntry {
 	return nil
 }
 
-func (e *registryEntry) next() *consul.ServiceEntry {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+func (e *registryEntry) all() []*consul.ServiceEntry {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 
 	if len(e.services) == 0 {
 		return nil
 	}
 
-	// gosec is complaining that we are using a non-crypto-safe PRNG. This is fine in this scenario since we are using it only for selecting a random address for load-balancing.
-	//nolint:gosec
-	return e.services[rand.Int()%len(e.services)]
+	result := make([]*consul.ServiceEntry, len(e.services))
+	copy(result, e.services)
+	return result
 }
 
-func (r *resolver) getService(service string) (*consul.ServiceEntry, error) {
-	var services []*consul.ServiceEntry
-
+func (r *resolver) getServices(service string) ([]*consul.ServiceEntry, error) {
 	if r.config.UseCache {
 		r.startWatcher()
 
 		entry := r.registry.get(service)
 		if entry != nil {
-			result := entry.next()
+			result := entry.all()
 
-			if result != nil {
+			if len(result) > 0 {
 				return result, nil
 			}
 		} else {
@@ -158,8 +157,7 @@ func (r *resolver) getService(service string) (*consul.ServiceEntry, error) {
 		return nil, fmt.Errorf("no healthy services found with AppID '%s'", service)
 	}
 
-	//nolint:gosec
-	return services[rand.Int()%len(services)], nil
+	return services, nil
 }
 
 func (r *registry) addOrUpdate(service string, services []*consul.ServiceEntry) {
@@ -220,7 +218,9 @@ type resolverConfig struct {
 	Registration      *consul.AgentServiceRegistration
 	DeregisterOnClose bool
 	DaprPortMetaKey   string
+	ZoneMetaKey       string
 	UseCache          bool
+	Selector          selector.Options
 }
 
 // NewResolver creates Consul name resolver.
@@ -234,10 +234,16 @@ func newResolver(logger logger.Logger, resolverConfig resolverConfig, client cli
 		config:             resolverConfig,
 		client:             client,
 		registry:           registry,
+		selector:           selector.New(resolverConfig.Selector),
 		watcherStopChannel: watcherStopChannel,
 	}
 }
 
+var (
+	_ nr.CandidateResolver = (*resolver)(nil)
+	_ nr.FailureReporter   = (*resolver)(nil)
+)
+
 // Init will configure component. It will also register service or validate client connection based on config.
 func (r *resolver) Init(ctx context.Context, metadata nr.Metadata) (err error) {
 	r.config, err = getConfig(metadata)
@@ -245,6 +251,8 @@ func (r *resolver) Init(ctx context.Context, metadata nr.Metadata) (err error) {
 		return err
 	}
 
+	r.selector = selector.New(r.config.Selector)
+
 	if r.config.Client.TLSConfig.InsecureSkipVerify {
 		r.logger.Infof("hashicorp consul: you are using 'insecureSkipVerify' to skip server config verify which is unsafe!")
 	}
@@ -276,26 +284,89 @@ func (r *resolver) Init(ctx context.Context, metadata nr.Metadata) (err error) {
 
 // ResolveID resolves name to address via consul.
 func (r *resolver) ResolveID(ctx context.Context, req nr.ResolveRequest) (addr string, err error) {
-	cfg := r.config
-	svc, err := r.getService(req.ID)
+	candidates, err := r.ResolveCandidates(ctx, req)
 	if err != nil {
 		return "", err
 	}
 
+	c, err := r.selector.Select(req.ID, candidates)
+	if err != nil {
+		return "", fmt.Errorf("no healthy services found with AppID '%s'", req.ID)
+	}
+
+	return c.Address, nil
+}
+
+// ResolveCandidates returns all the healthy instances of the service via consul.
+func (r *resolver) ResolveCandidates(ctx context.Context, req nr.ResolveRequest) ([]nr.Candidate, error) {
+	services, err := r.getServices(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]nr.Candidate, 0, len(services))
+	for _, svc := range services {
+		var c nr.Candidate
+		c, err = r.getCandidate(req.ID, svc)
+		if err != nil {
+			r.logger.Debugf("Skipping instance of service AppID '%s': %v", req.ID, err)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+
+	// If no instance is valid, return the last error
+	if len(candidates) == 0 {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// ReportFailure records that a call to the instance at the given address failed.
+func (r *resolver) ReportFailure(address string) {
+	r.selector.ReportFailure(address)
+}
+
+func (r *resolver) getCandidate(appID string, svc *consul.ServiceEntry) (nr.Candidate, error) {
+	cfg := r.config
+
 	port := svc.Service.Meta[cfg.DaprPortMetaKey]
 	if port == "" {
-		return "", fmt.Errorf("target service AppID '%s' found but %s missing from meta", req.ID, cfg.DaprPortMetaKey)
+		return nr.Candidate{}, fmt.Errorf("target service AppID '%s' found but %s missing from meta", appID, cfg.DaprPortMetaKey)
 	}
 
+	var addr string
 	if svc.Service.Address != "" {
 		addr = svc.Service.Address
-	} else if svc.Node.Address != "" {
+	} else if svc.Node != nil && svc.Node.Address != "" {
 		addr = svc.Node.Address
 	} else {
-		return "", fmt.Errorf("no healthy services found with AppID '%s'", req.ID)
+		return nr.Candidate{}, fmt.Errorf("no healthy services found with AppID '%s'", appID)
+	}
+
+	addr, err := formatAddress(addr, port)
+	if err != nil {
+		return nr.Candidate{}, err
+	}
+
+	c := nr.Candidate{
+		Address:  addr,
+		Weight:   svc.Service.Weights.Passing,
+		Metadata: svc.Service.Meta,
+	}
+	if cfg.ZoneMetaKey != "" {
+		c.Zone = svc.Service.Meta[cfg.ZoneMetaKey]
 	}
 
-	return formatAddress(addr, port)
+	switch svc.Checks.AggregatedStatus() {
+	case consul.HealthPassing:
+		c.Health = nr.HealthStatusHealthy
+	case consul.HealthCritical, consul.HealthMaint:
+		c.Health = nr.HealthStatusUnhealthy
+	}
+
+	return c, nil
 }
 
 // Close will stop the watcher and deregister app from consul
@@ -341,6 +412,18 @@ func getConfig(metadata nr.Metadata) (resolverCfg resolverConfig, err error) {
 	resolverCfg.DaprPortMetaKey = cfg.DaprPortMetaKey
 	resolverCfg.DeregisterOnClose = cfg.SelfDeregister
 	resolverCfg.UseCache = cfg.UseCache
+	resolverCfg.ZoneMetaKey = cfg.ZoneMetaKey
+	resolverCfg.Selector = selector.Options{
+		Policy: cfg.SelectionPolicy,
+		Zone:   cfg.Zone,
+	}
+	err = resolverCfg.Selector.Validate()
+	if err != nil {
+		return resolverCfg, err
+	}
+	if cfg.Weight < 0 {
+		return resolverCfg, fmt.Errorf("weight must not be negative")
+	}
 
 	resolverCfg.Client = getClientConfig(cfg)
 	resolverCfg.Registration, err = getRegistrationConfig(cfg, props)
@@ -356,6 +439,17 @@ func getConfig(metadata nr.Metadata) (resolverCfg resolverConfig, err error) {
 		}
 
 		resolverCfg.Registration.Meta[resolverCfg.DaprPortMetaKey] = props[nr.DaprPort]
+
+		// Publish the zone and weight so other instances can use them when selecting an instance
+		if cfg.Zone != "" && resolverCfg.ZoneMetaKey != "" {
+			resolverCfg.Registration.Meta[resolverCfg.ZoneMetaKey] = cfg.Zone
+		}
+		if cfg.Weight > 0 && resolverCfg.Registration.Weights == nil {
+			resolverCfg.Registration.Weights = &consul.AgentWeights{
+				Passing: cfg.Weight,
+				Warning: 1,
+			}
+		}
 	}
 
 	return resolverCfg, nil
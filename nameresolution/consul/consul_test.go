@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	nr "github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/components-contrib/nameresolution/selector"
 	"github.com/dapr/kit/logger"
 )
 
@@ -968,6 +969,78 @@ func TestResolveID(t *testing.T) {
 				assert.Error(t, err)
 			},
 		},
+		{
+			"should skip unhealthy services and prefer same zone",
+			nr.ResolveRequest{
+				ID: "test-app",
+			},
+			func(t *testing.T, req nr.ResolveRequest) {
+				mock := mockClient{
+					mockHealth: mockHealth{
+						serviceResult: []*consul.ServiceEntry{
+							{
+								Service: &consul.AgentService{
+									Address: "10.3.245.137",
+									Meta: map[string]string{
+										"DAPR_PORT": "50005",
+										"DAPR_ZONE": "zone-a",
+									},
+									Weights: consul.AgentWeights{Passing: 3},
+								},
+								Checks: consul.HealthChecks{{Status: consul.HealthPassing}},
+							},
+							{
+								Service: &consul.AgentService{
+									Address: "10.3.245.138",
+									Meta: map[string]string{
+										"DAPR_PORT": "50005",
+										"DAPR_ZONE": "zone-b",
+									},
+								},
+								Checks: consul.HealthChecks{{Status: consul.HealthCritical}},
+							},
+							{
+								Service: &consul.AgentService{
+									Address: "10.3.245.139",
+									Meta: map[string]string{
+										"DAPR_PORT": "50005",
+										"DAPR_ZONE": "zone-b",
+									},
+								},
+							},
+							{
+								// Skipped because DAPR_PORT is missing
+								Service: &consul.AgentService{
+									Address: "10.3.245.140",
+								},
+							},
+						},
+					},
+				}
+				cfg := testConfig
+				cfg.ZoneMetaKey = defaultZoneMetaKey
+				cfg.Selector = selector.Options{Policy: selector.PolicySameZoneFirst, Zone: "zone-b"}
+				resolver := newResolver(logger.NewLogger("test"), cfg, &mock, &registry{}, make(chan struct{})).(*resolver)
+
+				candidates, err := resolver.ResolveCandidates(context.Background(), req)
+				assert.NoError(t, err)
+				assert.Len(t, candidates, 3)
+				assert.Equal(t, nr.Candidate{
+					Address:  "10.3.245.137:50005",
+					Zone:     "zone-a",
+					Weight:   3,
+					Health:   nr.HealthStatusHealthy,
+					Metadata: map[string]string{"DAPR_PORT": "50005", "DAPR_ZONE": "zone-a"},
+				}, candidates[0])
+				assert.Equal(t, nr.HealthStatusUnhealthy, candidates[1].Health)
+
+				for i := 0; i < 10; i++ {
+					addr, err := resolver.ResolveID(context.Background(), req)
+					assert.NoError(t, err)
+					assert.Equal(t, "10.3.245.139:50005", addr)
+				}
+			},
+		},
 		{
 			"error if consul service missing DaprPortMetaKey",
 			nr.ResolveRequest{
@@ -1301,6 +1374,7 @@ func TestParseConfig(t *testing.T) {
 					Filter:   "Checks.ServiceTags contains dapr",
 				},
 				DaprPortMetaKey: "DAPR_PORT",
+				ZoneMetaKey:     defaultZoneMetaKey,
 				UseCache:        false,
 			},
 		},
@@ -1310,6 +1384,7 @@ func TestParseConfig(t *testing.T) {
 			nil,
 			configSpec{
 				DaprPortMetaKey: defaultDaprPortMetaKey,
+				ZoneMetaKey:     defaultZoneMetaKey,
 			},
 		},
 		{
@@ -1425,6 +1500,39 @@ func TestGetConfig(t *testing.T) {
 				assert.Equal(t, daprPort, actual.Registration.Meta["random_key"])
 			},
 		},
+		{
+			"Zone and Weight should set registration meta and weights",
+			nr.Metadata{
+				Instance: getInstanceInfoWithoutKey(""),
+				Configuration: map[any]any{
+					"SelfRegister":    true,
+					"SelectionPolicy": "sameZoneFirst",
+					"Zone":            "zone-a",
+					"Weight":          5,
+				},
+			},
+			func(t *testing.T, metadata nr.Metadata) {
+				actual, err := getConfig(metadata)
+				assert.NoError(t, err)
+
+				assert.Equal(t, "zone-a", actual.Registration.Meta[defaultZoneMetaKey])
+				assert.Equal(t, &consul.AgentWeights{Passing: 5, Warning: 1}, actual.Registration.Weights)
+				assert.Equal(t, selector.Options{Policy: selector.PolicySameZoneFirst, Zone: "zone-a"}, actual.Selector)
+			},
+		},
+		{
+			"invalid SelectionPolicy should return an error",
+			nr.Metadata{
+				Instance: getInstanceInfoWithoutKey(""),
+				Configuration: map[any]any{
+					"SelectionPolicy": "foo",
+				},
+			},
+			func(t *testing.T, metadata nr.Metadata) {
+				_, err := getConfig(metadata)
+				assert.ErrorContains(t, err, "invalid selection policy")
+			},
+		},
 		{
 			"SelfDeregister should set DeregisterOnClose",
 			nr.Metadata{
@@ -17,9 +17,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -28,7 +28,9 @@ import (
 	"github.com/grandcat/zeroconf"
 
 	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/components-contrib/nameresolution/selector"
 	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
 )
 
 const (
@@ -51,13 +53,28 @@ const (
 	// addressTTL is the duration an address has before
 	// becoming stale and being evicted.
 	addressTTL = time.Second * 60
+	// txtZonePrefix and txtWeightPrefix are the prefixes
+	// of the TXT records containing the zone and weight
+	// of an instance.
+	txtZonePrefix   = "zone="
+	txtWeightPrefix = "weight="
 )
 
+// mdnsMetadata contains the configuration of the resolver.
+type mdnsMetadata struct {
+	selector.Options `mapstructure:",squash"`
+
+	// Weight of this instance, announced to other instances.
+	Weight int `mapstructure:"weight"`
+}
+
 // address is used to store an ip address along with
 // an expiry time at which point the address is considered
 // too stale to trust.
 type address struct {
 	ip        string
+	zone      string
+	weight    int
 	expiresAt time.Time
 }
 
@@ -65,7 +82,6 @@ type address struct {
 // data used to control and access said addresses.
 type addressList struct {
 	addresses []address
-	counter   atomic.Uint32
 	mu        sync.RWMutex
 }
 
@@ -87,45 +103,49 @@ func (a *addressList) expire() {
 
 // add adds a new address to the address list with a
 // maximum expiry time. For existing addresses, the
-// expiry time is updated to the maximum.
+// expiry time is updated to the maximum, as well as
+// the zone and weight.
 // TODO: Consider enforcing a maximum address list size.
-func (a *addressList) add(ip string) {
+func (a *addressList) add(ip string, zone string, weight int) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	for i := range a.addresses {
 		if a.addresses[i].ip == ip {
+			a.addresses[i].zone = zone
+			a.addresses[i].weight = weight
 			a.addresses[i].expiresAt = time.Now().Add(addressTTL)
 			return
 		}
 	}
 	a.addresses = append(a.addresses, address{
 		ip:        ip,
+		zone:      zone,
+		weight:    weight,
 		expiresAt: time.Now().Add(addressTTL),
 	})
 }
 
-// next gets the next address from the list given
-// the current round robin implementation.
-// There are no guarantees on the selection
-// beyond best effort linear iteration.
-func (a *addressList) next() *string {
+// candidates returns the addresses in the list
+// as candidates for the selector.
+func (a *addressList) candidates() []nameresolution.Candidate {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	l := uint32(len(a.addresses))
-	if l == 0 {
+	if len(a.addresses) == 0 {
 		return nil
 	}
 
-	if a.counter.Load() == math.MaxUint32 {
-		// This will only reset unless another goroutine has done that already
-		a.counter.CompareAndSwap(math.MaxUint32, 0)
+	res := make([]nameresolution.Candidate, len(a.addresses))
+	for i, addr := range a.addresses {
+		res[i] = nameresolution.Candidate{
+			Address: addr.ip,
+			Zone:    addr.zone,
+			Weight:  addr.weight,
+		}
 	}
-	counter := a.counter.Add(1) - 1
-	addr := a.addresses[counter%l]
 
-	return &addr.ip
+	return res
 }
 
 // SubscriberPool is used to manage
@@ -169,6 +189,11 @@ func NewSubscriber() Subscriber {
 	}
 }
 
+var (
+	_ nameresolution.CandidateResolver = (*Resolver)(nil)
+	_ nameresolution.FailureReporter   = (*Resolver)(nil)
+)
+
 // NewResolver creates the instance of mDNS name resolver.
 func NewResolver(logger logger.Logger) nameresolution.Resolver {
 	runCtx, runCancel := context.WithCancel(context.Background())
@@ -190,6 +215,9 @@ func NewResolver(logger logger.Logger) nameresolution.Resolver {
 		// registrations channel to signal the resolver to
 		// stop serving queries for registered app ids.
 		registrations: make(map[string]chan struct{}),
+		// round robin is used unless another policy
+		// is configured in Init.
+		selector: selector.New(selector.Options{Policy: selector.PolicyRoundRobin}),
 		// shutdown refreshers
 		runCtx:    runCtx,
 		runCancel: runCancel,
@@ -222,6 +250,13 @@ type Resolver struct {
 	// expected to be 1 when initialized by the dapr runtime.
 	registrationMu sync.RWMutex
 	registrations  map[string]chan struct{}
+	// selector is used to select one of the cached
+	// addresses for an app id. The zone and weight
+	// are announced in the TXT records of the
+	// registered app ids.
+	selector *selector.Selector
+	zone     string
+	weight   int
 	// shutdown refreshes.
 	runCtx         context.Context
 	runCancel      context.CancelFunc
@@ -280,7 +315,27 @@ func (m *Resolver) Init(ctx context.Context, metadata nameresolution.Metadata) e
 		return errors.New("port is missing or invalid")
 	}
 
-	err := m.registerMDNS("", metadata.Instance.AppID, []string{metadata.Instance.Address}, metadata.Instance.DaprInternalPort)
+	md := mdnsMetadata{
+		Options: selector.Options{Policy: selector.PolicyRoundRobin},
+	}
+	if metadata.Configuration != nil {
+		err := kitmd.DecodeMetadata(metadata.Configuration, &md)
+		if err != nil {
+			return fmt.Errorf("failed to decode configuration: %w", err)
+		}
+	}
+	err := md.Options.Validate()
+	if err != nil {
+		return err
+	}
+	if md.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	m.selector = selector.New(md.Options)
+	m.zone = md.Zone
+	m.weight = md.Weight
+
+	err = m.registerMDNS("", metadata.Instance.AppID, []string{metadata.Instance.Address}, metadata.Instance.DaprInternalPort)
 	if err != nil {
 		return err
 	}
@@ -363,7 +418,7 @@ func (m *Resolver) registerMDNS(instanceID string, appID string, ips []string, p
 		defer m.serversRunning.Done()
 
 		host, _ := os.Hostname()
-		info := []string{appID}
+		info := m.txtRecords(appID)
 
 		// default instance id is unique to the process.
 		if instanceID == "" {
@@ -400,15 +455,39 @@ func (m *Resolver) registerMDNS(instanceID string, appID string, ips []string, p
 	return <-started
 }
 
-// ResolveID resolves name to address via mDNS.
-func (m *Resolver) ResolveID(parentCtx context.Context, req nameresolution.ResolveRequest) (string, error) {
-	// check for cached IPv4 addresses for this app id first.
-	if addr := m.nextIPv4Address(req.ID); addr != nil {
-		return *addr, nil
+// txtRecords returns the TXT records announced for the app id.
+// The first record is always the app id, followed by the zone
+// and weight of the instance if they are set.
+func (m *Resolver) txtRecords(appID string) []string {
+	info := []string{appID}
+	if m.zone != "" {
+		info = append(info, txtZonePrefix+m.zone)
 	}
+	if m.weight > 0 {
+		info = append(info, txtWeightPrefix+strconv.Itoa(m.weight))
+	}
+	return info
+}
 
-	// check for cached IPv6 addresses for this app id second.
-	if addr := m.nextIPv6Address(req.ID); addr != nil {
+// parseTXTRecords returns the zone and weight
+// from the TXT records that follow the app id.
+func parseTXTRecords(text []string) (zone string, weight int) {
+	for _, t := range text {
+		switch {
+		case strings.HasPrefix(t, txtZonePrefix):
+			zone = t[len(txtZonePrefix):]
+		case strings.HasPrefix(t, txtWeightPrefix):
+			// invalid weights are ignored.
+			weight, _ = strconv.Atoi(t[len(txtWeightPrefix):])
+		}
+	}
+	return zone, weight
+}
+
+// ResolveID resolves name to address via mDNS.
+func (m *Resolver) ResolveID(parentCtx context.Context, req nameresolution.ResolveRequest) (string, error) {
+	// check for cached addresses for this app id first.
+	if addr := m.selectAddress(req.ID); addr != nil {
 		return *addr, nil
 	}
 
@@ -453,10 +532,7 @@ func (m *Resolver) ResolveID(parentCtx context.Context, req nameresolution.Resol
 	// browser as they must wait on the published channel and perform
 	// the cleanup before returning.
 	if once == nil {
-		if addr := m.nextIPv4Address(req.ID); addr != nil {
-			return *addr, nil
-		}
-		if addr := m.nextIPv6Address(req.ID); addr != nil {
+		if addr := m.selectAddress(req.ID); addr != nil {
 			return *addr, nil
 		}
 	}
@@ -514,16 +590,37 @@ func (m *Resolver) ResolveID(parentCtx context.Context, req nameresolution.Resol
 		// If no address or error has been received
 		// within the timeout, we will check the cache again and
 		// if no address is present we will return an error.
-		if addr := m.nextIPv4Address(req.ID); addr != nil {
-			return *addr, nil
-		}
-		if addr := m.nextIPv6Address(req.ID); addr != nil {
+		if addr := m.selectAddress(req.ID); addr != nil {
 			return *addr, nil
 		}
 		return "", fmt.Errorf("timeout waiting for address for app id %s", req.ID)
 	}
 }
 
+// ResolveCandidates returns the cached addresses for the app id.
+// If there are none, the network is browsed as in ResolveID.
+func (m *Resolver) ResolveCandidates(ctx context.Context, req nameresolution.ResolveRequest) ([]nameresolution.Candidate, error) {
+	if candidates := m.cachedCandidates(req.ID); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	// ResolveID populates the cache when browsing the network.
+	addr, err := m.ResolveID(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if candidates := m.cachedCandidates(req.ID); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	return []nameresolution.Candidate{{Address: addr}}, nil
+}
+
+// ReportFailure records that a call to the instance at the given address failed.
+func (m *Resolver) ReportFailure(address string) {
+	m.selector.ReportFailure(address)
+}
+
 // browseOne will perform a mDNS network browse for an address
 // matching the provided app id. It will return the first address it
 // receives and stop browsing for any more.
@@ -690,40 +787,40 @@ func (m *Resolver) browse(ctx context.Context, appID string, onEach func(ip stri
 	entries := make(chan *zeroconf.ServiceEntry)
 
 	handleEntry := func(entry *zeroconf.ServiceEntry) {
-		for _, text := range entry.Text {
-			if text != appID {
-				m.logger.Debugf("mDNS response doesn't match app id %s, skipping.", appID)
-				break
-			}
+		// the first TXT record is the app id.
+		if len(entry.Text) == 0 || entry.Text[0] != appID {
+			m.logger.Debugf("mDNS response doesn't match app id %s, skipping.", appID)
+			return
+		}
 
-			m.logger.Debugf("mDNS response for app id %s received.", appID)
+		m.logger.Debugf("mDNS response for app id %s received.", appID)
 
-			hasIPv4Address := len(entry.AddrIPv4) > 0
-			hasIPv6Address := len(entry.AddrIPv6) > 0
+		hasIPv4Address := len(entry.AddrIPv4) > 0
+		hasIPv6Address := len(entry.AddrIPv6) > 0
 
-			if !hasIPv4Address && !hasIPv6Address {
-				m.logger.Debugf("mDNS response for app id %s doesn't contain any IPv4 or IPv6 addresses, skipping.", appID)
-				break
-			}
-
-			var addr string
-			port := entry.Port
+		if !hasIPv4Address && !hasIPv6Address {
+			m.logger.Debugf("mDNS response for app id %s doesn't contain any IPv4 or IPv6 addresses, skipping.", appID)
+			return
+		}
 
-			// TODO: we currently only use the first IPv4 and IPv6 address.
-			// We should understand the cases in which additional addresses
-			// are returned and whether we need to support them.
-			if hasIPv4Address {
-				addr = entry.AddrIPv4[0].String() + ":" + strconv.Itoa(port)
-				m.addAppAddressIPv4(appID, addr)
-			}
-			if hasIPv6Address {
-				addr = entry.AddrIPv6[0].String() + ":" + strconv.Itoa(port)
-				m.addAppAddressIPv6(appID, addr)
-			}
+		var addr string
+		port := entry.Port
+		zone, weight := parseTXTRecords(entry.Text[1:])
+
+		// TODO: we currently only use the first IPv4 and IPv6 address.
+		// We should understand the cases in which additional addresses
+		// are returned and whether we need to support them.
+		if hasIPv4Address {
+			addr = entry.AddrIPv4[0].String() + ":" + strconv.Itoa(port)
+			m.addAppAddressIPv4(appID, addr, zone, weight)
+		}
+		if hasIPv6Address {
+			addr = entry.AddrIPv6[0].String() + ":" + strconv.Itoa(port)
+			m.addAppAddressIPv6(appID, addr, zone, weight)
+		}
 
-			if onEach != nil {
-				onEach(addr) // invoke callback.
-			}
+		if onEach != nil {
+			onEach(addr) // invoke callback.
 		}
 	}
 
@@ -762,7 +859,7 @@ func (m *Resolver) browse(ctx context.Context, appID string, onEach func(ip stri
 
 // addAppAddressIPv4 adds an IPv4 address to the
 // cache for the provided app id.
-func (m *Resolver) addAppAddressIPv4(appID string, addr string) {
+func (m *Resolver) addAppAddressIPv4(appID string, addr string, zone string, weight int) {
 	m.ipv4Mu.Lock()
 	defer m.ipv4Mu.Unlock()
 
@@ -771,12 +868,12 @@ func (m *Resolver) addAppAddressIPv4(appID string, addr string) {
 		var addrList addressList
 		m.appAddressesIPv4[appID] = &addrList
 	}
-	m.appAddressesIPv4[appID].add(addr)
+	m.appAddressesIPv4[appID].add(addr, zone, weight)
 }
 
 // addAppIPv4Address adds an IPv6 address to the
 // cache for the provided app id.
-func (m *Resolver) addAppAddressIPv6(appID string, addr string) {
+func (m *Resolver) addAppAddressIPv6(appID string, addr string, zone string, weight int) {
 	m.ipv6Mu.Lock()
 	defer m.ipv6Mu.Unlock()
 
@@ -785,7 +882,7 @@ func (m *Resolver) addAppAddressIPv6(appID string, addr string) {
 		var addrList addressList
 		m.appAddressesIPv6[appID] = &addrList
 	}
-	m.appAddressesIPv6[appID].add(addr)
+	m.appAddressesIPv6[appID].add(addr, zone, weight)
 }
 
 // getAppIDsIPv4 returns a list of the current IPv4 app IDs.
@@ -828,40 +925,47 @@ func (m *Resolver) getAppIDs() []string {
 	return union(m.getAppIDsIPv4(), m.getAppIDsIPv6())
 }
 
-// nextIPv4Address returns the next IPv4 address for
-// the provided app id from the cache.
-func (m *Resolver) nextIPv4Address(appID string) *string {
+// cachedCandidates returns the cached addresses for the
+// provided app id, preferring IPv4 addresses over IPv6.
+func (m *Resolver) cachedCandidates(appID string) []nameresolution.Candidate {
 	m.ipv4Mu.RLock()
-	defer m.ipv4Mu.RUnlock()
 	addrList, exists := m.appAddressesIPv4[appID]
+	m.ipv4Mu.RUnlock()
 	if exists {
-		addr := addrList.next()
-		if addr != nil {
-			m.logger.Debugf("found mDNS IPv4 address in cache: %s", *addr)
-			return addr
+		if candidates := addrList.candidates(); len(candidates) > 0 {
+			return candidates
 		}
 	}
 
-	return nil
-}
-
-// nextIPv6Address returns the next IPv6 address for
-// the provided app id from the cache.
-func (m *Resolver) nextIPv6Address(appID string) *string {
 	m.ipv6Mu.RLock()
-	defer m.ipv6Mu.RUnlock()
-	addrList, exists := m.appAddressesIPv6[appID]
+	addrList, exists = m.appAddressesIPv6[appID]
+	m.ipv6Mu.RUnlock()
 	if exists {
-		addr := addrList.next()
-		if addr != nil {
-			m.logger.Debugf("found mDNS IPv6 address in cache: %s", *addr)
-			return addr
+		if candidates := addrList.candidates(); len(candidates) > 0 {
+			return candidates
 		}
 	}
 
 	return nil
 }
 
+// selectAddress returns one of the cached addresses
+// for the provided app id, chosen by the selector.
+func (m *Resolver) selectAddress(appID string) *string {
+	candidates := m.cachedCandidates(appID)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	c, err := m.selector.Select(appID, candidates)
+	if err != nil {
+		return nil
+	}
+
+	m.logger.Debugf("found mDNS address in cache: %s", c.Address)
+	return &c.Address
+}
+
 // union merges the elements from two lists into a set.
 func union(first []string, second []string) []string {
 	keys := make(map[string]struct{}, len(first)+len(second))
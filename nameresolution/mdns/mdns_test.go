@@ -16,7 +16,6 @@ package mdns
 import (
 	"context"
 	"fmt"
-	"math"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -480,11 +479,13 @@ func TestAddressListAddNewAddress(t *testing.T) {
 	}
 
 	// act
-	addressList.add("addr2")
+	addressList.add("addr2", "zone-a", 2)
 
 	// assert
 	require.Len(t, addressList.addresses, 3)
 	require.Equal(t, "addr2", addressList.addresses[2].ip)
+	require.Equal(t, "zone-a", addressList.addresses[2].zone)
+	require.Equal(t, 2, addressList.addresses[2].weight)
 }
 
 func TestAddressListAddExisitingAddress(t *testing.T) {
@@ -504,15 +505,16 @@ func TestAddressListAddExisitingAddress(t *testing.T) {
 	}
 
 	// act
-	addressList.add("addr1")
+	addressList.add("addr1", "zone-b", 0)
 	deltaSec := int(addressList.addresses[1].expiresAt.Sub(expiry).Seconds())
 
 	// assert
 	require.Len(t, addressList.addresses, 2)
 	require.Greater(t, deltaSec, 0) // Ensures expiry has been extended for existing address.
+	require.Equal(t, "zone-b", addressList.addresses[1].zone)
 }
 
-func TestAddressListNext(t *testing.T) {
+func TestAddressListCandidates(t *testing.T) {
 	// arrange
 	expiry := time.Now().Add(10 * time.Second)
 	addressList := &addressList{
@@ -523,46 +525,29 @@ func TestAddressListNext(t *testing.T) {
 			},
 			{
 				ip:        "addr1",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr2",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr3",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr4",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr5",
+				zone:      "zone-a",
+				weight:    3,
 				expiresAt: expiry,
 			},
 		},
 	}
 
 	// act & assert
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
-	require.Equal(t, "addr2", *addressList.next())
-	require.Equal(t, "addr3", *addressList.next())
-	require.Equal(t, "addr4", *addressList.next())
-	require.Equal(t, "addr5", *addressList.next())
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
+	require.Equal(t, []nr.Candidate{
+		{Address: "addr0"},
+		{Address: "addr1", Zone: "zone-a", Weight: 3},
+	}, addressList.candidates())
 }
 
-func TestAddressListNextMaxCounter(t *testing.T) {
+func TestAddressListCandidatesWithExpiration(t *testing.T) {
 	// arrange
 	expiry := time.Now().Add(10 * time.Second)
+	expired := time.Now().Add(-60 * time.Second)
 	addressList := &addressList{
 		addresses: []address{
 			{
 				ip:        "addr0",
-				expiresAt: expiry,
+				expiresAt: expired,
 			},
 			{
 				ip:        "addr1",
@@ -570,132 +555,89 @@ func TestAddressListNextMaxCounter(t *testing.T) {
 			},
 			{
 				ip:        "addr2",
-				expiresAt: expiry,
+				weight:    2,
+				expiresAt: expired,
 			},
 			{
 				ip:        "addr3",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr4",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr5",
+				zone:      "zone-a",
 				expiresAt: expiry,
 			},
 		},
 	}
 
 	// act & assert
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
-	require.Equal(t, "addr2", *addressList.next())
-	require.Equal(t, "addr3", *addressList.next())
-	addressList.counter.Store(math.MaxUint32)
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
-	require.Equal(t, "addr2", *addressList.next())
+	require.Equal(t, []nr.Candidate{
+		{Address: "addr0"},
+		{Address: "addr1"},
+		{Address: "addr2", Weight: 2},
+		{Address: "addr3", Zone: "zone-a"},
+	}, addressList.candidates())
+	addressList.expire()
+	require.Equal(t, []nr.Candidate{
+		{Address: "addr1"},
+		{Address: "addr3", Zone: "zone-a"},
+	}, addressList.candidates())
+
+	// all addresses expired
+	addressList.addresses[0].expiresAt = expired
+	addressList.addresses[1].expiresAt = expired
+	addressList.expire()
+	require.Nil(t, addressList.candidates())
 }
 
-func TestAddressListNextNoAddress(t *testing.T) {
+func TestAddressListCandidatesNoAddress(t *testing.T) {
 	// arrange
 	addressList := &addressList{
 		addresses: []address{},
 	}
 
 	// act & assert
-	require.Nil(t, addressList.next())
+	require.Nil(t, addressList.candidates())
 }
 
-func TestAddressListNextWithAdd(t *testing.T) {
+func TestTXTRecords(t *testing.T) {
 	// arrange
-	expiry := time.Now().Add(10 * time.Second)
-	addressList := &addressList{
-		addresses: []address{
-			{
-				ip:        "addr0",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr1",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr2",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr3",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr4",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr5",
-				expiresAt: expiry,
-			},
-		},
-	}
+	resolver := NewResolver(logger.NewLogger("test")).(*Resolver)
+	defer resolver.Close()
 
 	// act & assert
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
-	require.Equal(t, "addr2", *addressList.next())
-	require.Equal(t, "addr3", *addressList.next())
-	addressList.add("addr6")
-	require.Equal(t, "addr4", *addressList.next())
-	require.Equal(t, "addr5", *addressList.next())
-	require.Equal(t, "addr6", *addressList.next())
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
+	require.Equal(t, []string{"testAppID"}, resolver.txtRecords("testAppID"))
+
+	resolver.zone = "zone-a"
+	resolver.weight = 5
+	info := resolver.txtRecords("testAppID")
+	require.Equal(t, []string{"testAppID", "zone=zone-a", "weight=5"}, info)
+
+	zone, weight := parseTXTRecords(info[1:])
+	require.Equal(t, "zone-a", zone)
+	require.Equal(t, 5, weight)
+
+	zone, weight = parseTXTRecords([]string{"weight=foo", "other"})
+	require.Equal(t, "", zone)
+	require.Equal(t, 0, weight)
 }
 
-func TestAddressListNextWithExpiration(t *testing.T) {
+func TestInitInvalidConfiguration(t *testing.T) {
 	// arrange
-	expiry := time.Now().Add(10 * time.Second)
-	expired := time.Now().Add(-60 * time.Second)
-	addressList := &addressList{
-		addresses: []address{
-			{
-				ip:        "addr0",
-				expiresAt: expired,
-			},
-			{
-				ip:        "addr1",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr2",
-				expiresAt: expired,
-			},
-			{
-				ip:        "addr3",
-				expiresAt: expiry,
-			},
-			{
-				ip:        "addr4",
-				expiresAt: expired,
-			},
-			{
-				ip:        "addr5",
-				expiresAt: expiry,
-			},
+	resolver := NewResolver(logger.NewLogger("test")).(*Resolver)
+	defer resolver.Close()
+	md := nr.Metadata{
+		Instance: nr.Instance{
+			AppID:            "testAppID",
+			Address:          localhost,
+			DaprInternalPort: 1234,
+		},
+		Configuration: map[string]string{
+			"selectionPolicy": "foo",
 		},
 	}
 
-	// act & assert
-	require.Equal(t, "addr0", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
-	require.Equal(t, "addr2", *addressList.next())
-	require.Equal(t, "addr3", *addressList.next())
-	addressList.expire()
-	require.Equal(t, "addr3", *addressList.next())
-	require.Equal(t, "addr5", *addressList.next())
-	require.Equal(t, "addr1", *addressList.next())
-	require.Equal(t, "addr3", *addressList.next())
+	// act
+	err := resolver.Init(context.Background(), md)
+
+	// assert
+	require.ErrorContains(t, err, "invalid selection policy")
 }
 
 func TestUnion(t *testing.T) {
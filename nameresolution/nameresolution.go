@@ -24,3 +24,15 @@ type Resolver interface {
 	// ResolveID resolves name to address.
 	ResolveID(ctx context.Context, req ResolveRequest) (string, error)
 }
+
+// CandidateResolver is an optional interface implemented by name resolvers that can return all the instances of an app.
+type CandidateResolver interface {
+	// ResolveCandidates returns the instances that can serve requests for the app.
+	ResolveCandidates(ctx context.Context, req ResolveRequest) ([]Candidate, error)
+}
+
+// FailureReporter is an optional interface implemented by name resolvers that take failed calls into account when selecting an instance.
+type FailureReporter interface {
+	// ReportFailure records that a call to the instance at the given address failed.
+	ReportFailure(address string)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selector contains the policies used by name resolvers to pick one instance among the candidates for an app.
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/dapr/components-contrib/nameresolution"
+)
+
+// Policy is the policy used to select an instance among the candidates.
+type Policy string
+
+const (
+	// PolicyRoundRobin cycles through the candidates in order.
+	PolicyRoundRobin Policy = "roundRobin"
+	// PolicyWeightedRandom picks a random candidate, with a probability proportional to its weight.
+	PolicyWeightedRandom Policy = "weightedRandom"
+	// PolicyLeastRecentlyFailed cycles through the candidates that haven't failed recently; if all have, it picks the one whose last failure is the oldest.
+	PolicyLeastRecentlyFailed Policy = "leastRecentlyFailed"
+	// PolicySameZoneFirst cycles through the candidates in the same zone as the caller, falling back to candidates in other zones if there's none.
+	PolicySameZoneFirst Policy = "sameZoneFirst"
+)
+
+// Failures older than this are forgotten.
+const failureExpiry = 5 * time.Minute
+
+// ErrNoCandidates is returned by Select when there's no healthy candidate.
+var ErrNoCandidates = errors.New("no healthy instances found")
+
+// Options for the selector.
+type Options struct {
+	// Selection policy. Defaults to weighted random if empty.
+	Policy Policy `mapstructure:"selectionPolicy" json:"selectionPolicy"`
+	// Zone of the caller, used by the same-zone-first policy.
+	Zone string `mapstructure:"zone" json:"zone"`
+}
+
+// Validate the options.
+func (o Options) Validate() error {
+	switch o.Policy {
+	case "", PolicyRoundRobin, PolicyWeightedRandom, PolicyLeastRecentlyFailed, PolicySameZoneFirst:
+		return nil
+	default:
+		return fmt.Errorf("invalid selection policy: %s", o.Policy)
+	}
+}
+
+// Selector picks one instance among the candidates for an app.
+// It is safe for concurrent use.
+type Selector struct {
+	policy Policy
+	zone   string
+	clock  clock.Clock
+
+	lock     sync.Mutex
+	counters map[string]uint32
+	failures map[string]time.Time
+}
+
+// New returns a new Selector.
+// Options are expected to have been validated; unknown policies are treated as weighted random.
+func New(opts Options) *Selector {
+	return newSelector(opts, clock.RealClock{})
+}
+
+func newSelector(opts Options, clk clock.Clock) *Selector {
+	policy := opts.Policy
+	if policy == "" {
+		policy = PolicyWeightedRandom
+	}
+	return &Selector{
+		policy:   policy,
+		zone:     opts.Zone,
+		clock:    clk,
+		counters: make(map[string]uint32),
+		failures: make(map[string]time.Time),
+	}
+}
+
+// Select returns one of the healthy candidates.
+// The key is normally the app ID, and it's used to keep track of the state of the round-robin policies.
+func (s *Selector) Select(key string, candidates []nameresolution.Candidate) (nameresolution.Candidate, error) {
+	candidates = healthyCandidates(candidates)
+	if len(candidates) == 0 {
+		return nameresolution.Candidate{}, ErrNoCandidates
+	}
+
+	switch s.policy {
+	case PolicyRoundRobin:
+		return s.roundRobin(key, candidates), nil
+	case PolicyLeastRecentlyFailed:
+		return s.leastRecentlyFailed(key, candidates), nil
+	case PolicySameZoneFirst:
+		return s.roundRobin(key, s.sameZone(candidates)), nil
+	default:
+		return weightedRandom(candidates), nil
+	}
+}
+
+// ReportFailure records that a call to the instance at the given address failed.
+func (s *Selector) ReportFailure(address string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	for addr, t := range s.failures {
+		if now.Sub(t) >= failureExpiry {
+			delete(s.failures, addr)
+		}
+	}
+	s.failures[address] = now
+}
+
+func (s *Selector) roundRobin(key string, candidates []nameresolution.Candidate) nameresolution.Candidate {
+	s.lock.Lock()
+	n := s.counters[key]
+	s.counters[key] = n + 1
+	s.lock.Unlock()
+
+	return candidates[n%uint32(len(candidates))]
+}
+
+func (s *Selector) leastRecentlyFailed(key string, candidates []nameresolution.Candidate) nameresolution.Candidate {
+	s.lock.Lock()
+	now := s.clock.Now()
+	notFailed := make([]nameresolution.Candidate, 0, len(candidates))
+	oldest := -1
+	var oldestFailure time.Time
+	for i, c := range candidates {
+		t, ok := s.failures[c.Address]
+		if !ok || now.Sub(t) >= failureExpiry {
+			notFailed = append(notFailed, c)
+			continue
+		}
+		if oldest < 0 || t.Before(oldestFailure) {
+			oldest = i
+			oldestFailure = t
+		}
+	}
+	s.lock.Unlock()
+
+	if len(notFailed) == 0 {
+		return candidates[oldest]
+	}
+	return s.roundRobin(key, notFailed)
+}
+
+func (s *Selector) sameZone(candidates []nameresolution.Candidate) []nameresolution.Candidate {
+	if s.zone == "" {
+		return candidates
+	}
+
+	res := make([]nameresolution.Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Zone == s.zone {
+			res = append(res, c)
+		}
+	}
+	if len(res) == 0 {
+		return candidates
+	}
+	return res
+}
+
+func weightedRandom(candidates []nameresolution.Candidate) nameresolution.Candidate {
+	total := 0
+	for _, c := range candidates {
+		total += c.GetWeight()
+	}
+
+	// gosec is complaining that we are using a non-crypto-safe PRNG. This is fine in this scenario since we are using it only for selecting a random address for load-balancing.
+	//nolint:gosec
+	n := rand.Intn(total)
+	for _, c := range candidates {
+		n -= c.GetWeight()
+		if n < 0 {
+			return c
+		}
+	}
+
+	// Should never get here
+	return candidates[len(candidates)-1]
+}
+
+// Returns the candidates that are not known to be unhealthy, sorted by address so the round-robin policies iterate in a consistent order.
+func healthyCandidates(candidates []nameresolution.Candidate) []nameresolution.Candidate {
+	res := make([]nameresolution.Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Health != nameresolution.HealthStatusUnhealthy {
+			res = append(res, c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Address < res[j].Address
+	})
+	return res
+}
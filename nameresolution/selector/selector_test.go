@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/dapr/components-contrib/nameresolution"
+)
+
+func selectN(t *testing.T, s *Selector, n int, candidates []nameresolution.Candidate) []string {
+	t.Helper()
+	res := make([]string, n)
+	for i := 0; i < n; i++ {
+		c, err := s.Select("app", candidates)
+		require.NoError(t, err)
+		res[i] = c.Address
+	}
+	return res
+}
+
+func TestSelect(t *testing.T) {
+	candidates := []nameresolution.Candidate{
+		{Address: "c:1", Zone: "z2", Weight: 3},
+		{Address: "a:1", Zone: "z1"},
+		{Address: "d:1", Zone: "z1", Health: nameresolution.HealthStatusUnhealthy},
+		{Address: "b:1", Zone: "z2", Health: nameresolution.HealthStatusHealthy},
+	}
+
+	t.Run("no healthy candidates", func(t *testing.T) {
+		s := New(Options{})
+		_, err := s.Select("app", []nameresolution.Candidate{
+			{Address: "a:1", Health: nameresolution.HealthStatusUnhealthy},
+		})
+		require.ErrorIs(t, err, ErrNoCandidates)
+
+		_, err = s.Select("app", nil)
+		require.ErrorIs(t, err, ErrNoCandidates)
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		s := New(Options{Policy: PolicyRoundRobin})
+		assert.Equal(t, []string{"a:1", "b:1", "c:1", "a:1", "b:1"}, selectN(t, s, 5, candidates))
+
+		// Keys have separate counters
+		c, err := s.Select("other", candidates)
+		require.NoError(t, err)
+		assert.Equal(t, "a:1", c.Address)
+	})
+
+	t.Run("weighted random", func(t *testing.T) {
+		s := New(Options{Policy: PolicyWeightedRandom})
+		counts := map[string]int{}
+		for _, addr := range selectN(t, s, 1000, candidates) {
+			counts[addr]++
+		}
+
+		// c:1 has weight 3 and should be picked about 600 times; the others about 200 times each
+		assert.Len(t, counts, 3)
+		assert.Greater(t, counts["c:1"], 450)
+		assert.Greater(t, counts["a:1"], 100)
+		assert.Greater(t, counts["b:1"], 100)
+	})
+
+	t.Run("least recently failed", func(t *testing.T) {
+		clk := clocktesting.NewFakeClock(time.Now())
+		s := newSelector(Options{Policy: PolicyLeastRecentlyFailed}, clk)
+
+		s.ReportFailure("a:1")
+		assert.Equal(t, []string{"b:1", "c:1", "b:1"}, selectN(t, s, 3, candidates))
+
+		// When all candidates have failed, the one that failed first is picked
+		clk.Step(time.Second)
+		s.ReportFailure("c:1")
+		clk.Step(time.Second)
+		s.ReportFailure("b:1")
+		assert.Equal(t, []string{"a:1", "a:1"}, selectN(t, s, 2, candidates))
+
+		// Failures expire
+		clk.Step(failureExpiry - time.Second)
+		assert.ElementsMatch(t, []string{"a:1", "c:1"}, selectN(t, s, 2, candidates))
+	})
+
+	t.Run("same zone first", func(t *testing.T) {
+		s := New(Options{Policy: PolicySameZoneFirst, Zone: "z2"})
+		assert.Equal(t, []string{"b:1", "c:1", "b:1"}, selectN(t, s, 3, candidates))
+
+		// d:1 is the only instance in z1 but it's unhealthy, so a:1 is picked
+		s = New(Options{Policy: PolicySameZoneFirst, Zone: "z1"})
+		assert.Equal(t, []string{"a:1", "a:1"}, selectN(t, s, 2, candidates))
+
+		// Falls back to all candidates if none is in the same zone
+		s = New(Options{Policy: PolicySameZoneFirst, Zone: "z3"})
+		assert.Equal(t, []string{"a:1", "b:1", "c:1"}, selectN(t, s, 3, candidates))
+	})
+}
+
+func TestOptionsValidate(t *testing.T) {
+	require.NoError(t, Options{}.Validate())
+	require.NoError(t, Options{Policy: PolicySameZoneFirst}.Validate())
+	require.ErrorContains(t, Options{Policy: "foo"}.Validate(), "invalid selection policy")
+}
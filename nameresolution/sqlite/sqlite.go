@@ -28,6 +28,7 @@ import (
 
 	internalsql "github.com/dapr/components-contrib/internal/component/sql"
 	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/components-contrib/nameresolution/selector"
 	"github.com/dapr/kit/logger"
 )
 
@@ -42,12 +43,18 @@ type resolver struct {
 	metadata       sqliteMetadata
 	db             *sql.DB
 	gc             internalsql.GarbageCollector
+	selector       *selector.Selector
 	registrationID string
 	closed         atomic.Bool
 	closeCh        chan struct{}
 	wg             sync.WaitGroup
 }
 
+var (
+	_ nameresolution.CandidateResolver = (*resolver)(nil)
+	_ nameresolution.FailureReporter   = (*resolver)(nil)
+)
+
 // NewResolver creates a name resolver that is based on a SQLite DB.
 func NewResolver(logger logger.Logger) nameresolution.Resolver {
 	return &resolver{
@@ -67,6 +74,8 @@ func (s *resolver) Init(ctx context.Context, md nameresolution.Metadata) error {
 		return err
 	}
 
+	s.selector = selector.New(s.metadata.Options)
+
 	connString, err := s.metadata.GetConnectionString(s.logger)
 	if err != nil {
 		// Already logged
@@ -151,8 +160,8 @@ func (s *resolver) registerHost(ctx context.Context) error {
 	// We use REPLACE to take over any previous registration for that address
 	// TODO: Add support for namespacing. See https://github.com/dapr/components-contrib/issues/3179
 	_, err = s.db.ExecContext(queryCtx,
-		fmt.Sprintf("REPLACE INTO %s (registration_id, address, app_id, namespace, last_update) VALUES (?, ?, ?, ?, unixepoch(CURRENT_TIMESTAMP))", s.metadata.TableName),
-		s.registrationID, s.metadata.GetAddress(), s.metadata.appID, "",
+		fmt.Sprintf("REPLACE INTO %s (registration_id, address, app_id, namespace, zone, weight, last_update) VALUES (?, ?, ?, ?, ?, ?, unixepoch(CURRENT_TIMESTAMP))", s.metadata.TableName),
+		s.registrationID, s.metadata.GetAddress(), s.metadata.appID, "", s.metadata.Zone, s.metadata.Weight,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to register host: %w", err)
@@ -235,37 +244,66 @@ func (s *resolver) doRenewRegistration(ctx context.Context, addr string) error {
 
 // ResolveID resolves name to address.
 func (s *resolver) ResolveID(ctx context.Context, req nameresolution.ResolveRequest) (addr string, err error) {
+	candidates, err := s.ResolveCandidates(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := s.selector.Select(req.ID, candidates)
+	if err != nil {
+		return "", ErrNoHost
+	}
+	return c.Address, nil
+}
+
+// ResolveCandidates returns all the hosts that are registered for the app.
+func (s *resolver) ResolveCandidates(ctx context.Context, req nameresolution.ResolveRequest) ([]nameresolution.Candidate, error) {
 	queryCtx, queryCancel := context.WithTimeout(ctx, s.metadata.Timeout)
 	defer queryCancel()
 
+	// Hosts that haven't renewed their registration within the update interval are not returned
 	//nolint:gosec
 	q := fmt.Sprintf(
-		// See: https://stackoverflow.com/a/24591696
-		`SELECT address
-		FROM %[1]s
+		`SELECT address, zone, weight
+		FROM %s
 		WHERE
-			ROWID = (
-				SELECT ROWID
-				FROM %[1]s
-				WHERE
-					app_id = ?
-					AND unixepoch(CURRENT_TIMESTAMP) - last_update < %[2]d
-				ORDER BY RANDOM()
-				LIMIT 1
-			)`,
+			app_id = ?
+			AND unixepoch(CURRENT_TIMESTAMP) - last_update < %d`,
 		s.metadata.TableName,
 		int(s.metadata.UpdateInterval.Seconds()),
 	)
 
-	err = s.db.QueryRowContext(queryCtx, q, req.ID).Scan(&addr)
+	rows, err := s.db.QueryContext(queryCtx, q, req.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrNoHost
+		return nil, fmt.Errorf("failed to look up address: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]nameresolution.Candidate, 0)
+	for rows.Next() {
+		c := nameresolution.Candidate{
+			Health: nameresolution.HealthStatusHealthy,
 		}
-		return "", fmt.Errorf("failed to look up address: %w", err)
+		err = rows.Scan(&c.Address, &c.Zone, &c.Weight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up address: %w", err)
+		}
+		candidates = append(candidates, c)
 	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up address: %w", err)
+	}
+
+	if len(candidates) == 0 {
+		return nil, ErrNoHost
+	}
+	return candidates, nil
+}
 
-	return addr, nil
+// ReportFailure records that a call to the host at the given address failed.
+func (s *resolver) ReportFailure(address string) {
+	s.selector.ReportFailure(address)
 }
 
 // Removes the registration for the host
@@ -22,6 +22,7 @@ import (
 
 	authSqlite "github.com/dapr/components-contrib/internal/authentication/sqlite"
 	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/components-contrib/nameresolution/selector"
 	"github.com/dapr/kit/metadata"
 )
 
@@ -30,6 +31,7 @@ const (
 	defaultMetadataTableName = "metadata"
 	defaultUpdateInterval    = 5 * time.Second
 	defaultCleanupInternal   = time.Hour
+	defaultWeight            = 1
 
 	// For a nameresolver, we want a fairly low timeout
 	defaultTimeout     = time.Second
@@ -39,11 +41,13 @@ const (
 type sqliteMetadata struct {
 	// Config options - passed by the user via the Configuration resource
 	authSqlite.SqliteAuthMetadata `mapstructure:",squash"`
+	selector.Options              `mapstructure:",squash"`
 
 	TableName         string        `mapstructure:"tableName"`
 	MetadataTableName string        `mapstructure:"metadataTableName"`
 	UpdateInterval    time.Duration `mapstructure:"updateInterval"` // Units smaller than seconds are not accepted
 	CleanupInterval   time.Duration `mapstructure:"cleanupInterval" mapstructurealiases:"cleanupIntervalInSeconds"`
+	Weight            int           `mapstructure:"weight"` // Relative weight of this host, used by the weighted random policy
 
 	// Instance properties - these are passed by the runtime
 	appID       string
@@ -82,6 +86,13 @@ func (m *sqliteMetadata) InitWithMetadata(meta nameresolution.Metadata) error {
 	if err != nil {
 		return err
 	}
+	err = m.Options.Validate()
+	if err != nil {
+		return err
+	}
+	if m.Weight < 1 {
+		return errors.New("weight must be greater than zero")
+	}
 	if !authSqlite.ValidIdentifier(m.TableName) {
 		return fmt.Errorf("invalid identifier for table name: %s", m.TableName)
 	}
@@ -117,6 +128,8 @@ func (m *sqliteMetadata) reset() {
 	m.MetadataTableName = defaultMetadataTableName
 	m.UpdateInterval = defaultUpdateInterval
 	m.CleanupInterval = defaultCleanupInternal
+	m.Options = selector.Options{}
+	m.Weight = defaultWeight
 
 	m.appID = ""
 	m.namespace = ""
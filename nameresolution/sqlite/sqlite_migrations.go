@@ -61,5 +61,21 @@ func performMigrations(ctx context.Context, db *sql.DB, logger logger.Logger, op
 			}
 			return nil
 		},
+		// Migration 1: add the zone and weight columns
+		func(ctx context.Context) error {
+			logger.Infof("Adding zone and weight columns to hosts table '%s'", opts.HostsTableName)
+			_, err := m.GetConn().ExecContext(
+				ctx,
+				fmt.Sprintf(
+					`ALTER TABLE %[1]s ADD COLUMN zone TEXT NOT NULL DEFAULT '';
+					ALTER TABLE %[1]s ADD COLUMN weight INTEGER NOT NULL DEFAULT 1;`,
+					opts.HostsTableName,
+				),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to add columns to hosts table: %w", err)
+			}
+			return nil
+		},
 	})
 }
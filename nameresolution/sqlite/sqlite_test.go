@@ -63,9 +63,12 @@ func TestSqliteNameResolver(t *testing.T) {
 			{"f77ed318", "8.8.8.8:1", "app-8", "", now - 100},
 		}
 		for i, r := range rows {
-			_, err := nr.db.Exec("INSERT INTO hosts VALUES (?, ?, ?, ?, ?)", r...)
+			_, err := nr.db.Exec("INSERT INTO hosts (registration_id, address, app_id, namespace, last_update) VALUES (?, ?, ?, ?, ?)", r...)
 			require.NoErrorf(t, err, "Failed to insert row %d", i)
 		}
+
+		_, err := nr.db.Exec("UPDATE hosts SET zone = 'z1', weight = 5 WHERE address = '1.1.1.1:2'")
+		require.NoError(t, err)
 	})
 
 	if t.Failed() {
@@ -112,6 +115,24 @@ func TestSqliteNameResolver(t *testing.T) {
 		}
 	})
 
+	t.Run("Resolve candidates", func(t *testing.T) {
+		res, err := nr.ResolveCandidates(context.Background(), nameresolution.ResolveRequest{ID: "app-1"})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []nameresolution.Candidate{
+			{Address: "1.1.1.1:1", Zone: "", Weight: 1, Health: nameresolution.HealthStatusHealthy},
+			{Address: "1.1.1.1:2", Zone: "z1", Weight: 5, Health: nameresolution.HealthStatusHealthy},
+			{Address: "1.1.1.1:3", Zone: "", Weight: 1, Health: nameresolution.HealthStatusHealthy},
+		}, res)
+
+		res, err = nr.ResolveCandidates(context.Background(), nameresolution.ResolveRequest{ID: "app-2"})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Equal(t, "2.2.2.2:1", res[0].Address)
+
+		_, err = nr.ResolveCandidates(context.Background(), nameresolution.ResolveRequest{ID: "app-4"})
+		require.ErrorIs(t, err, ErrNoHost)
+	})
+
 	// Simulate the ticker
 	t.Run("Renew registration", func(t *testing.T) {
 		t.Run("Succeess", func(t *testing.T) {
@@ -0,0 +1,96 @@
+;; Guest used in tests, which transforms messages without parsing them:
+;; - messages for the topic "drop" are dropped
+;; - messages for the topic "error" make the guest exit with code 1
+;; - the data "secret" (base64 "c2VjcmV0") is replaced with "******" (base64 "KioqKioq")
+;; - messages for the topic "fanout" are returned twice
+(module
+  (import "wasi_snapshot_preview1" "fd_read" (func $fd_read (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "proc_exit" (func $proc_exit (param i32)))
+
+  ;; 0-8 is the iovec, and 8-12 the number of bytes read or written.
+  ;; The input is read at offset 1024.
+  (memory (export "memory") 2)
+
+  (data (i32.const 16) "[],")
+  (data (i32.const 32) "\"topic\":\"drop\"")
+  (data (i32.const 64) "\"topic\":\"error\"")
+  (data (i32.const 96) "\"topic\":\"fanout\"")
+  (data (i32.const 128) "c2VjcmV0")
+  (data (i32.const 144) "KioqKioq")
+
+  ;; Length of the input
+  (global $len (mut i32) (i32.const 0))
+
+  (func $write (param $ptr i32) (param $n i32)
+    (i32.store (i32.const 0) (local.get $ptr))
+    (i32.store (i32.const 4) (local.get $n))
+    (drop (call $fd_write (i32.const 1) (i32.const 0) (i32.const 1) (i32.const 8))))
+
+  ;; Returns the offset of the first occurrence of the pattern in the input, or -1.
+  (func $find (param $pat i32) (param $patLen i32) (result i32)
+    (local $i i32) (local $j i32)
+    (local.set $i (i32.const 1024))
+    (block $notFound
+      (loop $outer
+        (br_if $notFound (i32.gt_u
+          (i32.add (local.get $i) (local.get $patLen))
+          (i32.add (i32.const 1024) (global.get $len))))
+        (local.set $j (i32.const 0))
+        (block $mismatch
+          (loop $inner
+            (if (i32.eq (local.get $j) (local.get $patLen))
+              (then (return (local.get $i))))
+            (br_if $mismatch (i32.ne
+              (i32.load8_u (i32.add (local.get $i) (local.get $j)))
+              (i32.load8_u (i32.add (local.get $pat) (local.get $j)))))
+            (local.set $j (i32.add (local.get $j) (i32.const 1)))
+            (br $inner)))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $outer)))
+    (i32.const -1))
+
+  (func $copy (param $dst i32) (param $src i32) (param $n i32)
+    (block $done
+      (loop $next
+        (br_if $done (i32.eqz (local.get $n)))
+        (i32.store8 (local.get $dst) (i32.load8_u (local.get $src)))
+        (local.set $dst (i32.add (local.get $dst) (i32.const 1)))
+        (local.set $src (i32.add (local.get $src) (i32.const 1)))
+        (local.set $n (i32.sub (local.get $n) (i32.const 1)))
+        (br $next))))
+
+  (func $main (export "_start")
+    (local $i i32)
+
+    ;; Read the input until EOF
+    (block $eof
+      (loop $read
+        (i32.store (i32.const 0) (i32.add (i32.const 1024) (global.get $len)))
+        (i32.store (i32.const 4) (i32.sub (i32.const 130048) (global.get $len)))
+        (drop (call $fd_read (i32.const 0) (i32.const 0) (i32.const 1) (i32.const 8)))
+        (br_if $eof (i32.eqz (i32.load (i32.const 8))))
+        (global.set $len (i32.add (global.get $len) (i32.load (i32.const 8))))
+        (br $read)))
+
+    (if (i32.ge_s (call $find (i32.const 32) (i32.const 14)) (i32.const 0))
+      (then
+        (call $write (i32.const 16) (i32.const 2))
+        (return)))
+    (if (i32.ge_s (call $find (i32.const 64) (i32.const 15)) (i32.const 0))
+      (then (call $proc_exit (i32.const 1))))
+
+    (block $done
+      (loop $replace
+        (local.set $i (call $find (i32.const 128) (i32.const 8)))
+        (br_if $done (i32.lt_s (local.get $i) (i32.const 0)))
+        (call $copy (local.get $i) (i32.const 144) (i32.const 8))
+        (br $replace)))
+
+    (call $write (i32.const 16) (i32.const 1))
+    (call $write (i32.const 1024) (global.get $len))
+    (if (i32.ge_s (call $find (i32.const 96) (i32.const 16)) (i32.const 0))
+      (then
+        (call $write (i32.const 18) (i32.const 1))
+        (call $write (i32.const 1024) (global.get $len))))
+    (call $write (i32.const 17) (i32.const 1))))
@@ -0,0 +1,303 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wasm contains a pubsub that transforms and filters messages with a WebAssembly guest, before they are published to or delivered from another pubsub.
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/dapr/components-contrib/health"
+	"github.com/dapr/components-contrib/internal/wasm"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+// Direction of a message passed to the guest.
+type Direction string
+
+const (
+	// DirectionPublish is used for messages that are being published.
+	DirectionPublish Direction = "publish"
+	// DirectionSubscribe is used for messages that are being delivered to a subscriber.
+	DirectionSubscribe Direction = "subscribe"
+)
+
+// Maximum number of bytes of the guest's STDERR included in errors.
+const maxStderrLen = 1024
+
+// Options contains the options for the transformer.
+type Options struct {
+	// Metadata of the guest, with the same properties as the wasm binding and HTTP middleware, such as "url" and "strictSandbox".
+	Guest map[string]string
+	// Topics whose messages are transformed; if empty, messages of all topics are transformed.
+	Topics []string
+}
+
+// Message is the message passed to the guest on STDIN, encoded as JSON.
+// The guest writes to STDOUT a JSON array of messages, which replace the input message: an empty array drops the message, and multiple messages fan it out.
+// In the messages returned by the guest, the direction is ignored and an empty topic is replaced with the topic of the input message.
+type Message struct {
+	Direction   Direction         `json:"direction,omitempty"`
+	Topic       string            `json:"topic"`
+	Data        []byte            `json:"data"`
+	ContentType *string           `json:"contentType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Transformer is a pubsub that passes messages to a WebAssembly guest before they are published to, or after they are received from, another pubsub.
+//
+// The guest is a WASI command that is invoked for each message: it receives the message on STDIN and returns the resulting messages on STDOUT (see Message).
+// If the guest exits with a non-zero code, publishing fails, or the message is not acknowledged when subscribing.
+// When a message is fanned out on the subscribe path, each resulting message is delivered to the handler in order, and delivery stops at the first error.
+//
+// The transformer doesn't implement bulk publishing and subscribing, so the runtime uses its default implementations, which call Publish and Subscribe.
+type Transformer struct {
+	pubsub pubsub.PubSub
+	logger logger.Logger
+	opts   Options
+	topics map[string]struct{}
+
+	meta            *wasm.InitMetadata
+	runtime         wazero.Runtime
+	module          wazero.CompiledModule
+	instanceCounter atomic.Uint64
+}
+
+// NewTransformer returns a pubsub that transforms the messages of ps with the guest in the options.
+// The guest is loaded, and the underlying pubsub is initialized, when Init is invoked.
+func NewTransformer(ps pubsub.PubSub, logger logger.Logger, opts Options) (*Transformer, error) {
+	if ps == nil {
+		return nil, errors.New("pubsub is required")
+	}
+	if opts.Guest["url"] == "" {
+		return nil, errors.New("url of the guest is required")
+	}
+
+	t := &Transformer{
+		pubsub: ps,
+		logger: logger,
+		opts:   opts,
+	}
+	if len(opts.Topics) > 0 {
+		t.topics = make(map[string]struct{}, len(opts.Topics))
+		for _, topic := range opts.Topics {
+			t.topics[topic] = struct{}{}
+		}
+	}
+	return t, nil
+}
+
+// Init loads and compiles the guest, then initializes the underlying pubsub.
+func (t *Transformer) Init(ctx context.Context, md pubsub.Metadata) (err error) {
+	t.meta, err = wasm.GetInitMetadata(ctx, metadata.Base{Name: md.Name, Properties: t.opts.Guest})
+	if err != nil {
+		return fmt.Errorf("wasm: failed to parse metadata: %w", err)
+	}
+
+	// The below ensures context cancels in-flight wasm functions.
+	t.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+
+	t.module, err = t.runtime.CompileModule(ctx, t.meta.Guest)
+	if err != nil {
+		_ = t.runtime.Close(context.Background())
+		return fmt.Errorf("wasm: error compiling binary: %w", err)
+	}
+	_, err = wasi_snapshot_preview1.Instantiate(ctx, t.runtime)
+	if err != nil {
+		_ = t.runtime.Close(context.Background())
+		return fmt.Errorf("wasm: error instantiating host wasi functions: %w", err)
+	}
+
+	err = t.pubsub.Init(ctx, md)
+	if err != nil {
+		_ = t.runtime.Close(context.Background())
+		return err
+	}
+	return nil
+}
+
+// Features returns the features of the underlying pubsub.
+func (t *Transformer) Features() []pubsub.Feature {
+	return t.pubsub.Features()
+}
+
+// Publish transforms the message, then publishes the resulting messages.
+// If the guest drops the message, nothing is published.
+func (t *Transformer) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+	if !t.appliesTo(req.Topic) {
+		return t.pubsub.Publish(ctx, req)
+	}
+
+	msgs, err := t.transform(ctx, &Message{
+		Direction:   DirectionPublish,
+		Topic:       req.Topic,
+		Data:        req.Data,
+		ContentType: req.ContentType,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		err = t.pubsub.Publish(ctx, &pubsub.PublishRequest{
+			Data:        msg.Data,
+			PubsubName:  req.PubsubName,
+			Topic:       msg.Topic,
+			Metadata:    msg.Metadata,
+			ContentType: msg.ContentType,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to the underlying pubsub, and transforms messages before they are delivered to the handler.
+// Messages dropped by the guest are acknowledged without invoking the handler.
+func (t *Transformer) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	if !t.appliesTo(req.Topic) {
+		return t.pubsub.Subscribe(ctx, req, handler)
+	}
+
+	return t.pubsub.Subscribe(ctx, req, func(ctx context.Context, in *pubsub.NewMessage) error {
+		msgs, err := t.transform(ctx, &Message{
+			Direction:   DirectionSubscribe,
+			Topic:       in.Topic,
+			Data:        in.Data,
+			ContentType: in.ContentType,
+			Metadata:    in.Metadata,
+		})
+		if err != nil {
+			t.logger.Errorf("Failed to transform message from topic %s: %v", in.Topic, err)
+			return err
+		}
+
+		for _, msg := range msgs {
+			err = handler(ctx, &pubsub.NewMessage{
+				Data:        msg.Data,
+				Topic:       msg.Topic,
+				Metadata:    msg.Metadata,
+				ContentType: msg.ContentType,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Returns true if messages of the topic are transformed.
+func (t *Transformer) appliesTo(topic string) bool {
+	if t.topics == nil {
+		return true
+	}
+	_, ok := t.topics[topic]
+	return ok
+}
+
+// Invokes the guest with the message, returning the resulting messages.
+func (t *Transformer) transform(ctx context.Context, in *Message) ([]*Message, error) {
+	input, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	guestName := t.meta.GuestName
+	if guestName == "" {
+		guestName = t.module.Name()
+	}
+
+	// Modules are instantiated for each message, so they need unique names.
+	instanceName := guestName + "-" + strconv.FormatUint(t.instanceCounter.Add(1), 10)
+	var stdout, stderr bytes.Buffer
+	moduleConfig := wasm.NewModuleConfig(t.meta).
+		WithName(instanceName).
+		WithArgs(guestName).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+
+	// Instantiating executes the guest's main function (exported as _start).
+	mod, err := t.runtime.InstantiateModule(ctx, t.module, moduleConfig)
+	if mod != nil {
+		// WASI typically calls proc_exit which exits the module, but just in case it doesn't, close the module manually.
+		_ = mod.Close(ctx)
+	}
+	if err != nil {
+		if stderr.Len() > 0 {
+			msg := stderr.Bytes()
+			if len(msg) > maxStderrLen {
+				msg = msg[:maxStderrLen]
+			}
+			return nil, fmt.Errorf("wasm: guest failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("wasm: guest failed: %w", err)
+	}
+
+	var out []*Message
+	err = json.Unmarshal(stdout.Bytes(), &out)
+	if err != nil {
+		return nil, fmt.Errorf("wasm: invalid output from guest: %w", err)
+	}
+	for i, msg := range out {
+		if msg == nil {
+			return nil, fmt.Errorf("wasm: invalid output from guest: message %d is null", i)
+		}
+		msg.Direction = ""
+		if msg.Topic == "" {
+			msg.Topic = in.Topic
+		}
+	}
+	return out, nil
+}
+
+// Ping the underlying pubsub.
+func (t *Transformer) Ping(ctx context.Context) error {
+	return pubsub.Ping(ctx, t.pubsub)
+}
+
+// Close the underlying pubsub and releases the resources of the guest.
+func (t *Transformer) Close() error {
+	err := t.pubsub.Close()
+	if t.runtime != nil {
+		err = errors.Join(err, t.runtime.Close(context.Background()))
+	}
+	return err
+}
+
+// GetComponentMetadata returns the metadata of the underlying pubsub.
+func (t *Transformer) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
+	if mc, ok := t.pubsub.(interface{ GetComponentMetadata() metadata.MetadataMap }); ok {
+		return mc.GetComponentMetadata()
+	}
+	return nil
+}
+
+var (
+	_ pubsub.PubSub = (*Transformer)(nil)
+	_ health.Pinger = (*Transformer)(nil)
+)
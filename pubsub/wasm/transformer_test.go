@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+const urlTransform = "file://testdata/transform/main.wasm"
+
+// fakePubSub records published messages, and delivers messages to the handlers synchronously.
+type fakePubSub struct {
+	initialized bool
+	closed      bool
+	published   []*pubsub.PublishRequest
+	handlers    map[string]pubsub.Handler
+}
+
+func (f *fakePubSub) Init(ctx context.Context, md pubsub.Metadata) error {
+	f.initialized = true
+	return nil
+}
+
+func (f *fakePubSub) Features() []pubsub.Feature {
+	return nil
+}
+
+func (f *fakePubSub) Publish(ctx context.Context, req *pubsub.PublishRequest) error {
+	f.published = append(f.published, req)
+	return nil
+}
+
+func (f *fakePubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	if f.handlers == nil {
+		f.handlers = map[string]pubsub.Handler{}
+	}
+	f.handlers[req.Topic] = handler
+	return nil
+}
+
+func (f *fakePubSub) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakePubSub) GetComponentMetadata() metadata.MetadataMap {
+	return metadata.MetadataMap{}
+}
+
+func newTestTransformer(t *testing.T, opts Options) (*Transformer, *fakePubSub) {
+	t.Helper()
+
+	inner := &fakePubSub{}
+	tr, err := NewTransformer(inner, logger.NewLogger("test"), opts)
+	require.NoError(t, err)
+	require.NoError(t, tr.Init(context.Background(), pubsub.Metadata{Base: metadata.Base{Name: "test"}}))
+	t.Cleanup(func() {
+		require.NoError(t, tr.Close())
+	})
+	require.True(t, inner.initialized)
+	return tr, inner
+}
+
+func TestNewTransformer(t *testing.T) {
+	_, err := NewTransformer(nil, logger.NewLogger("test"), Options{Guest: map[string]string{"url": urlTransform}})
+	require.ErrorContains(t, err, "pubsub is required")
+
+	_, err = NewTransformer(&fakePubSub{}, logger.NewLogger("test"), Options{})
+	require.ErrorContains(t, err, "url of the guest is required")
+
+	tr, err := NewTransformer(&fakePubSub{}, logger.NewLogger("test"), Options{Guest: map[string]string{"url": "file://testdata/missing.wasm"}})
+	require.NoError(t, err)
+	require.ErrorContains(t, tr.Init(context.Background(), pubsub.Metadata{}), "wasm: failed to parse metadata")
+}
+
+func TestPublish(t *testing.T) {
+	contentType := "text/plain"
+	tr, inner := newTestTransformer(t, Options{
+		Guest: map[string]string{"url": urlTransform},
+	})
+
+	publish := func(topic string, data string) error {
+		inner.published = nil
+		return tr.Publish(context.Background(), &pubsub.PublishRequest{
+			PubsubName:  "test",
+			Topic:       topic,
+			Data:        []byte(data),
+			ContentType: &contentType,
+			Metadata:    map[string]string{"foo": "bar"},
+		})
+	}
+
+	t.Run("modify", func(t *testing.T) {
+		require.NoError(t, publish("orders", "secret"))
+		require.Len(t, inner.published, 1)
+		assert.Equal(t, &pubsub.PublishRequest{
+			PubsubName:  "test",
+			Topic:       "orders",
+			Data:        []byte("******"),
+			ContentType: &contentType,
+			Metadata:    map[string]string{"foo": "bar"},
+		}, inner.published[0])
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		require.NoError(t, publish("drop", "secret"))
+		assert.Empty(t, inner.published)
+	})
+
+	t.Run("fan out", func(t *testing.T) {
+		require.NoError(t, publish("fanout", "hello"))
+		require.Len(t, inner.published, 2)
+		for _, req := range inner.published {
+			assert.Equal(t, "fanout", req.Topic)
+			assert.Equal(t, []byte("hello"), req.Data)
+		}
+	})
+
+	t.Run("guest error", func(t *testing.T) {
+		err := publish("error", "hello")
+		require.ErrorContains(t, err, "wasm: guest failed")
+		require.ErrorContains(t, err, "exit_code(1)")
+		assert.Empty(t, inner.published)
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	tr, inner := newTestTransformer(t, Options{
+		Guest:  map[string]string{"url": urlTransform, "strictSandbox": "true"},
+		Topics: []string{"orders", "fanout", "error"},
+	})
+
+	var received []*pubsub.NewMessage
+	var handlerErr error
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		received = append(received, msg)
+		return handlerErr
+	}
+	for _, topic := range []string{"orders", "fanout", "error", "drop"} {
+		require.NoError(t, tr.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler))
+	}
+
+	deliver := func(topic string, data string) error {
+		received = nil
+		return inner.handlers[topic](context.Background(), &pubsub.NewMessage{
+			Topic:    topic,
+			Data:     []byte(data),
+			Metadata: map[string]string{"foo": "bar"},
+		})
+	}
+
+	t.Run("modify", func(t *testing.T) {
+		require.NoError(t, deliver("orders", "secret"))
+		require.Len(t, received, 1)
+		assert.Equal(t, &pubsub.NewMessage{
+			Topic:    "orders",
+			Data:     []byte("******"),
+			Metadata: map[string]string{"foo": "bar"},
+		}, received[0])
+	})
+
+	t.Run("fan out", func(t *testing.T) {
+		require.NoError(t, deliver("fanout", "hello"))
+		assert.Len(t, received, 2)
+
+		// Delivery stops at the first error
+		handlerErr = errors.New("handler failed")
+		defer func() {
+			handlerErr = nil
+		}()
+		require.ErrorIs(t, deliver("fanout", "hello"), handlerErr)
+		assert.Len(t, received, 1)
+	})
+
+	t.Run("guest error", func(t *testing.T) {
+		require.ErrorContains(t, deliver("error", "hello"), "exit_code(1)")
+		assert.Empty(t, received)
+	})
+
+	t.Run("topic not transformed", func(t *testing.T) {
+		// The guest would drop this message
+		require.NoError(t, deliver("drop", "secret"))
+		require.Len(t, received, 1)
+		assert.Equal(t, []byte("secret"), received[0].Data)
+	})
+}
@@ -81,7 +81,7 @@ func (o *oosSecretStore) Init(_ context.Context, metadata secretstores.Metadata)
 func (o *oosSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
 	name := req.Name
 
-	parameterVersion, err := o.getVersionFromMetadata(req.Metadata)
+	parameterVersion, err := o.getVersion(req)
 	if err != nil {
 		return secretstores.GetSecretResponse{}, err
 	}
@@ -106,6 +106,9 @@ func (o *oosSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 	parameter := output.Body.Parameter
 	if parameter != nil {
 		response.Data[*parameter.Name] = *parameter.Value
+		if parameter.ParameterVersion != nil {
+			response.Version = strconv.FormatInt(int64(*parameter.ParameterVersion), 10)
+		}
 	}
 
 	return response, nil
@@ -169,9 +172,13 @@ func (o *oosSecretStore) getParameterStoreMetadata(spec secretstores.Metadata) (
 	return &meta, err
 }
 
-// getVersionFromMetadata returns the parameter version from the metadata. If not set means latest version.
-func (o *oosSecretStore) getVersionFromMetadata(metadata map[string]string) (*int32, error) {
-	if s, ok := metadata[VersionID]; ok && s != "" {
+// getVersion returns the parameter version from the request or its metadata. If not set means latest version.
+func (o *oosSecretStore) getVersion(req secretstores.GetSecretRequest) (*int32, error) {
+	s, err := req.RequestedVersion(VersionID)
+	if err != nil {
+		return nil, err
+	}
+	if s != "" {
 		val, err := strconv.ParseInt(s, 10, 32)
 		if err != nil {
 			return nil, err
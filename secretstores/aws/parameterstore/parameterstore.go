@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -76,9 +77,11 @@ func (s *ssmSecretStore) Init(_ context.Context, metadata secretstores.Metadata)
 func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
 	name := req.Name
 
-	var versionID string
-	if value, ok := req.Metadata[VersionID]; ok {
-		versionID = value
+	versionID, err := req.RequestedVersion(VersionID)
+	if err != nil {
+		return secretstores.GetSecretResponse{Data: nil}, err
+	}
+	if versionID != "" {
 		name = fmt.Sprintf("%s:%s", req.Name, versionID)
 	}
 
@@ -97,6 +100,9 @@ func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 		secretName := (*output.Parameter.Name)[len(s.prefix):]
 		resp.Data[secretName] = *output.Parameter.Value
 	}
+	if output.Parameter.Version != nil {
+		resp.Version = strconv.FormatInt(*output.Parameter.Version, 10)
+	}
 
 	return resp, nil
 }
@@ -120,6 +120,35 @@ func TestGetSecret(t *testing.T) {
 			assert.Equal(t, secretValue, output.Data[req.Name])
 		})
 
+		t.Run("with version", func(t *testing.T) {
+			s := ssmSecretStore{
+				client: &mockedSSM{
+					GetParameterFn: func(ctx context.Context, input *ssm.GetParameterInput, option ...request.Option) (*ssm.GetParameterOutput, error) {
+						secret := secretValue
+						name, version, _ := strings.Cut(*input.Name, ":")
+						assert.Equal(t, "2", version)
+
+						return &ssm.GetParameterOutput{
+							Parameter: &ssm.Parameter{
+								Name:    &name,
+								Value:   &secret,
+								Version: aws.Int64(2),
+							},
+						}, nil
+					},
+				},
+			}
+
+			req := secretstores.GetSecretRequest{
+				Name:    "/aws/dev/secret",
+				Version: "2",
+			}
+			output, e := s.GetSecret(context.Background(), req)
+			assert.NoError(t, e)
+			assert.Equal(t, secretValue, output.Data[req.Name])
+			assert.Equal(t, "2", output.Version)
+		})
+
 		t.Run("with prefix", func(t *testing.T) {
 			s := ssmSecretStore{
 				client: &mockedSSM{
@@ -71,8 +71,12 @@ func (s *smSecretStore) Init(_ context.Context, metadata secretstores.Metadata)
 // GetSecret retrieves a secret using a key and returns a map of decrypted string/string values.
 func (s *smSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
 	var versionID *string
-	if value, ok := req.Metadata[VersionID]; ok {
-		versionID = &value
+	version, err := req.RequestedVersion(VersionID)
+	if err != nil {
+		return secretstores.GetSecretResponse{Data: nil}, err
+	}
+	if version != "" {
+		versionID = &version
 	}
 	var versionStage *string
 	if value, ok := req.Metadata[VersionStage]; ok {
@@ -94,6 +98,9 @@ func (s *smSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecre
 	if output.Name != nil && output.SecretString != nil {
 		resp.Data[*output.Name] = *output.SecretString
 	}
+	if output.VersionId != nil {
+		resp.Version = *output.VersionId
+	}
 
 	return resp, nil
 }
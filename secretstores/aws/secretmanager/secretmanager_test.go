@@ -108,6 +108,39 @@ func TestGetSecret(t *testing.T) {
 			assert.Equal(t, secretValue, output.Data[req.Name])
 		})
 
+		t.Run("with version", func(t *testing.T) {
+			s := smSecretStore{
+				client: &mockedSM{
+					GetSecretValueFn: func(ctx context.Context, input *secretsmanager.GetSecretValueInput, option ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+						if assert.NotNil(t, input.VersionId) {
+							assert.Equal(t, "2", *input.VersionId)
+						}
+						secret := secretValue
+
+						return &secretsmanager.GetSecretValueOutput{
+							Name:         input.SecretId,
+							SecretString: &secret,
+							VersionId:    input.VersionId,
+						}, nil
+					},
+				},
+			}
+
+			req := secretstores.GetSecretRequest{
+				Name:    "/aws/secret/testing",
+				Version: "2",
+			}
+			output, e := s.GetSecret(context.Background(), req)
+			assert.NoError(t, e)
+			assert.Equal(t, secretValue, output.Data[req.Name])
+			assert.Equal(t, "2", output.Version)
+
+			// The version in the metadata must match
+			req.Metadata = map[string]string{VersionID: "1"}
+			_, e = s.GetSecret(context.Background(), req)
+			assert.ErrorContains(t, e, "does not match")
+		})
+
 		t.Run("with version stage", func(t *testing.T) {
 			s := smSecretStore{
 				client: &mockedSM{
@@ -112,9 +112,10 @@ func (k *keyvaultSecretStore) Init(_ context.Context, meta secretstores.Metadata
 
 // GetSecret retrieves a secret using a key and returns a map of decrypted string/string values.
 func (k *keyvaultSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
-	version := "" // empty string means latest version
-	if val, ok := req.Metadata[VersionID]; ok {
-		version = val
+	// Empty string means latest version
+	version, err := req.RequestedVersion(VersionID)
+	if err != nil {
+		return secretstores.GetSecretResponse{}, err
 	}
 
 	secretResp, err := k.vaultClient.GetSecret(ctx, req.Name, version, nil)
@@ -126,11 +127,15 @@ func (k *keyvaultSecretStore) GetSecret(ctx context.Context, req secretstores.Ge
 	if secretResp.Value != nil {
 		secretValue = *secretResp.Value
 	}
+	if secretResp.ID != nil {
+		version = secretResp.ID.Version()
+	}
 
 	return secretstores.GetSecretResponse{
 		Data: map[string]string{
 			req.Name: secretValue,
 		},
+		Version: version,
 	}, nil
 }
 
@@ -23,6 +23,10 @@ type Feature string
 const (
 	// FeatureMultipleKeyValuesPerSecret advertises that this SecretStore supports multiple keys-values under a single secret.
 	FeatureMultipleKeyValuesPerSecret Feature = "MULTIPLE_KEY_VALUES_PER_SECRET"
+	// FeatureSecretWriter advertises that this SecretStore implements the SecretWriter interface to create, update and delete secrets.
+	FeatureSecretWriter Feature = "SECRET_WRITER"
+	// FeatureSecretWatcher advertises that this SecretStore implements the SecretWatcher interface to notify of changes to secrets.
+	FeatureSecretWatcher Feature = "SECRET_WATCHER"
 )
 
 // IsPresent checks if a given feature is present in the list.
@@ -111,9 +111,12 @@ func (s *Store) GetSecret(ctx context.Context, req secretstores.GetSecretRequest
 	}
 	secretName := fmt.Sprintf("projects/%s/secrets/%s", s.ProjectID, req.Name)
 
-	versionID := "latest"
-	if value, ok := req.Metadata[VersionID]; ok {
-		versionID = value
+	versionID, err := req.RequestedVersion(VersionID)
+	if err != nil {
+		return res, err
+	}
+	if versionID == "" {
+		versionID = "latest"
 	}
 
 	secret, err := s.getSecret(ctx, secretName, versionID)
@@ -26,7 +26,11 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"golang.org/x/net/http2"
@@ -40,6 +44,7 @@ import (
 const (
 	defaultVaultAddress          string = "https://127.0.0.1:8200"
 	defaultVaultEnginePath       string = "secret"
+	defaultWatchPollInterval            = 30 * time.Second
 	componentVaultAddress        string = "vaultAddr"
 	componentCaCert              string = "caCert"
 	componentCaPath              string = "caPath"
@@ -67,7 +72,11 @@ const (
 	valueTypeText valueType = "text"
 )
 
-var _ secretstores.SecretStore = (*vaultSecretStore)(nil)
+var (
+	_ secretstores.SecretStore   = (*vaultSecretStore)(nil)
+	_ secretstores.SecretWriter  = (*vaultSecretStore)(nil)
+	_ secretstores.SecretWatcher = (*vaultSecretStore)(nil)
+)
 
 func (v valueType) isMapType() bool {
 	return v == valueTypeMap
@@ -84,10 +93,14 @@ type vaultSecretStore struct {
 	vaultKVPrefix       string
 	vaultEnginePath     string
 	vaultValueType      valueType
+	watchPollInterval   time.Duration
 
 	json jsoniter.API
 
-	logger logger.Logger
+	logger  logger.Logger
+	closed  atomic.Bool
+	closeCh chan struct{}
+	wg      sync.WaitGroup
 }
 
 type VaultMetadata struct {
@@ -103,6 +116,7 @@ type VaultMetadata struct {
 	VaultTokenMountPath string
 	EnginePath          string
 	VaultValueType      string
+	WatchPollInterval   time.Duration
 }
 
 // tlsConfig is TLS configuration to interact with HashiCorp Vault.
@@ -117,10 +131,25 @@ type tlsConfig struct {
 // vaultKVResponse is the response data from Vault KV.
 type vaultKVResponse struct {
 	Data struct {
-		Data map[string]string `json:"data"`
+		Data     map[string]string `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// vaultKVMetadataResponse is the response data from Vault KV, for the metadata of a secret.
+type vaultKVMetadataResponse struct {
+	Data struct {
+		CurrentVersion int `json:"current_version"`
 	} `json:"data"`
 }
 
+// vaultKVWriteRequest is the request body to write a secret in Vault KV.
+type vaultKVWriteRequest struct {
+	Data json.RawMessage `json:"data"`
+}
+
 // vaultListKVResponse is the response data from Vault KV.
 type vaultListKVResponse struct {
 	Data struct {
@@ -131,9 +160,10 @@ type vaultListKVResponse struct {
 // NewHashiCorpVaultSecretStore returns a new HashiCorp Vault secret store.
 func NewHashiCorpVaultSecretStore(logger logger.Logger) secretstores.SecretStore {
 	return &vaultSecretStore{
-		client: &http.Client{},
-		logger: logger,
-		json:   jsoniter.ConfigFastest,
+		client:  &http.Client{},
+		logger:  logger,
+		json:    jsoniter.ConfigFastest,
+		closeCh: make(chan struct{}),
 	}
 }
 
@@ -171,6 +201,13 @@ func (v *vaultSecretStore) Init(_ context.Context, meta secretstores.Metadata) e
 		}
 	}
 
+	v.watchPollInterval = defaultWatchPollInterval
+	if m.WatchPollInterval < 0 {
+		return fmt.Errorf("vault init error, invalid watch poll interval %v", m.WatchPollInterval)
+	} else if m.WatchPollInterval > 0 {
+		v.watchPollInterval = m.WatchPollInterval
+	}
+
 	v.vaultToken = m.VaultToken
 	v.vaultTokenMountPath = m.VaultTokenMountPath
 	initErr := v.initVaultToken()
@@ -273,6 +310,7 @@ func (v *vaultSecretStore) getSecret(ctx context.Context, secret, version string
 		d.Data.Data = map[string]string{
 			secret: res,
 		}
+		d.Data.Metadata.Version = v.json.Get(b, DataStr, "metadata", "version").ToInt()
 	}
 
 	return &d, nil
@@ -280,10 +318,13 @@ func (v *vaultSecretStore) getSecret(ctx context.Context, secret, version string
 
 // GetSecret retrieves a secret using a key and returns a map of decrypted string/string values.
 func (v *vaultSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
-	// version 0 represent for latest version
-	version := "0"
-	if value, ok := req.Metadata[versionID]; ok {
-		version = value
+	version, err := req.RequestedVersion(versionID)
+	if err != nil {
+		return secretstores.GetSecretResponse{Data: nil}, err
+	}
+	if version == "" {
+		// version 0 represent for latest version
+		version = "0"
 	}
 	d, err := v.getSecret(ctx, req.Name, version)
 	if err != nil {
@@ -293,10 +334,227 @@ func (v *vaultSecretStore) GetSecret(ctx context.Context, req secretstores.GetSe
 	resp := secretstores.GetSecretResponse{
 		Data: d.Data.Data,
 	}
+	if d.Data.Metadata.Version > 0 {
+		resp.Version = strconv.Itoa(d.Data.Metadata.Version)
+	}
 
 	return resp, nil
 }
 
+// SetSecret creates a new version of a secret.
+// If vaultValueType is "text", the request must contain a single value with the name of the secret as key, which must be a JSON object.
+func (v *vaultSecretStore) SetSecret(ctx context.Context, req secretstores.SetSecretRequest) error {
+	var body vaultKVWriteRequest
+	if v.vaultValueType.isMapType() {
+		data, err := json.Marshal(req.Data)
+		if err != nil {
+			return err
+		}
+		body.Data = data
+	} else {
+		value, ok := req.Data[req.Name]
+		if !ok || len(req.Data) != 1 {
+			return fmt.Errorf("secret %s must contain a single value with the name of the secret as key", req.Name)
+		}
+		if !json.Valid([]byte(value)) || !strings.HasPrefix(strings.TrimSpace(value), "{") {
+			return fmt.Errorf("the value of secret %s must be a JSON object", req.Name)
+		}
+		body.Data = json.RawMessage(value)
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	return v.writeRequest(ctx, http.MethodPost, v.kvPath("data", req.Name), reqBody, req.Name)
+}
+
+// DeleteSecret permanently deletes all versions of a secret.
+func (v *vaultSecretStore) DeleteSecret(ctx context.Context, req secretstores.DeleteSecretRequest) error {
+	return v.writeRequest(ctx, http.MethodDelete, v.kvPath("metadata", req.Name), nil, req.Name)
+}
+
+// WatchSecrets polls Vault for the current version of the secrets, and notifies of changes when a new version is found or a secret is deleted.
+// If no secret names are specified, the list of secrets is retrieved at every poll.
+func (v *vaultSecretStore) WatchSecrets(ctx context.Context, req secretstores.WatchSecretsRequest, handler secretstores.SecretChangeHandler) error {
+	if v.closed.Load() {
+		return errors.New("secret store is closed")
+	}
+
+	versions, err := v.currentVersions(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+
+		ticker := time.NewTicker(v.watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-v.closeCh:
+				return
+			case <-ticker.C:
+				current, err := v.currentVersions(ctx, req)
+				if err != nil {
+					v.logger.Warnf("Failed to retrieve the current version of secrets: %v", err)
+					continue
+				}
+				v.notifyChanges(ctx, versions, current, handler)
+				versions = current
+			}
+		}
+	}()
+
+	return nil
+}
+
+// currentVersions returns the current version of each watched secret.
+func (v *vaultSecretStore) currentVersions(ctx context.Context, req secretstores.WatchSecretsRequest) (map[string]int, error) {
+	names := req.Names
+	if len(names) == 0 {
+		var err error
+		names, err = v.listKeysUnderPath(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make(map[string]int, len(names))
+	for _, name := range names {
+		version, err := v.getSecretVersion(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		res[name] = version
+	}
+	return res, nil
+}
+
+// notifyChanges invokes the handler for each secret whose version differs between previous and current.
+func (v *vaultSecretStore) notifyChanges(ctx context.Context, previous, current map[string]int, handler secretstores.SecretChangeHandler) {
+	for name, version := range current {
+		if previous[name] == version {
+			continue
+		}
+		d, err := v.getSecret(ctx, name, strconv.Itoa(version))
+		if err != nil {
+			v.logger.Warnf("Failed to retrieve version %d of secret %s: %v", version, name, err)
+			continue
+		}
+		err = handler(ctx, &secretstores.SecretChangeEvent{
+			Name:    name,
+			Type:    secretstores.SecretChangeTypeUpdated,
+			Data:    d.Data.Data,
+			Version: strconv.Itoa(version),
+		})
+		if err != nil {
+			v.logger.Errorf("Error handling change to secret %s: %v", name, err)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		err := handler(ctx, &secretstores.SecretChangeEvent{
+			Name: name,
+			Type: secretstores.SecretChangeTypeDeleted,
+		})
+		if err != nil {
+			v.logger.Errorf("Error handling deletion of secret %s: %v", name, err)
+		}
+	}
+}
+
+// getSecretVersion returns the current version of a secret, from its metadata.
+func (v *vaultSecretStore) getSecretVersion(ctx context.Context, secret string) (int, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, v.kvPath("metadata", secret), nil)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't generate request: %w", err)
+	}
+	// Set vault token.
+	httpReq.Header.Set(vaultHTTPHeader, v.vaultToken)
+	// Set X-Vault-Request header
+	httpReq.Header.Set(vaultHTTPRequestHeader, "true")
+
+	httpresp, err := v.client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get secret metadata: %w", err)
+	}
+	defer httpresp.Body.Close()
+
+	if httpresp.StatusCode != http.StatusOK {
+		var b bytes.Buffer
+		io.Copy(&b, httpresp.Body)
+		if httpresp.StatusCode == http.StatusNotFound {
+			return 0, fmt.Errorf("getSecretVersion %s failed %w", secret, ErrNotFound)
+		}
+		return 0, fmt.Errorf("couldn't get successful response, status code %d, body %s",
+			httpresp.StatusCode, b.String())
+	}
+
+	var d vaultKVMetadataResponse
+	if err := json.NewDecoder(httpresp.Body).Decode(&d); err != nil {
+		return 0, fmt.Errorf("couldn't decode response body: %s", err)
+	}
+	return d.Data.CurrentVersion, nil
+}
+
+// Close stops watching secrets.
+func (v *vaultSecretStore) Close() error {
+	if v.closeCh != nil && v.closed.CompareAndSwap(false, true) {
+		close(v.closeCh)
+	}
+	v.wg.Wait()
+	return nil
+}
+
+// kvPath returns the URL of a secret in the KV engine, under the "data" or "metadata" path.
+func (v *vaultSecretStore) kvPath(kind string, secret string) string {
+	if v.vaultKVPrefix == "" {
+		return v.vaultAddress + "/v1/" + v.vaultEnginePath + "/" + kind + "/" + secret
+	}
+	return v.vaultAddress + "/v1/" + v.vaultEnginePath + "/" + kind + "/" + v.vaultKVPrefix + "/" + secret
+}
+
+// writeRequest sends a request that modifies a secret.
+func (v *vaultSecretStore) writeRequest(ctx context.Context, method string, addr string, body []byte, secret string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, method, addr, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("couldn't generate request: %w", err)
+	}
+	// Set vault token.
+	httpReq.Header.Set(vaultHTTPHeader, v.vaultToken)
+	// Set X-Vault-Request header
+	httpReq.Header.Set(vaultHTTPRequestHeader, "true")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpresp, err := v.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("couldn't write secret: %w", err)
+	}
+	defer httpresp.Body.Close()
+
+	if httpresp.StatusCode != http.StatusOK && httpresp.StatusCode != http.StatusNoContent {
+		var b bytes.Buffer
+		io.Copy(&b, httpresp.Body)
+		v.logger.Debugf("write secret %s couldn't get successful response: %#v, %s", secret, httpresp, b.String())
+		return fmt.Errorf("couldn't get successful response, status code %d, body %s",
+			httpresp.StatusCode, b.String())
+	}
+
+	return nil
+}
+
 // BulkGetSecret retrieves all secrets in the store and returns a map of decrypted string/string values.
 func (v *vaultSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
 	version := "0"
@@ -530,10 +788,10 @@ func readCertificateFolder(certPool *x509.CertPool, path string) error {
 // Features returns the features available in this secret store.
 func (v *vaultSecretStore) Features() []secretstores.Feature {
 	if v.vaultValueType == valueTypeText {
-		return []secretstores.Feature{}
+		return []secretstores.Feature{secretstores.FeatureSecretWriter, secretstores.FeatureSecretWatcher}
 	}
 
-	return []secretstores.Feature{secretstores.FeatureMultipleKeyValuesPerSecret}
+	return []secretstores.Feature{secretstores.FeatureMultipleKeyValuesPerSecret, secretstores.FeatureSecretWriter, secretstores.FeatureSecretWatcher}
 }
 
 func (v *vaultSecretStore) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
@@ -16,11 +16,18 @@ package vault
 import (
 	"context"
 	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/secretstores"
@@ -460,3 +467,185 @@ func TestGetFeatures(t *testing.T) {
 		assert.False(t, secretstores.FeatureMultipleKeyValuesPerSecret.IsPresent(f))
 	})
 }
+
+func TestSetAndDeleteSecret(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		token  string
+		body   string
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{
+			method: r.Method,
+			path:   r.URL.Path,
+			token:  r.Header.Get(vaultHTTPHeader),
+			body:   string(body),
+		})
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"data":{"version":1}}`))
+	}))
+	defer server.Close()
+
+	newStore := func(valueType valueType) *vaultSecretStore {
+		requests = nil
+		return &vaultSecretStore{
+			client:          server.Client(),
+			vaultAddress:    server.URL,
+			vaultToken:      expectedTok,
+			vaultKVPrefix:   defaultVaultKVPrefix,
+			vaultEnginePath: defaultVaultEnginePath,
+			vaultValueType:  valueType,
+			logger:          logger.NewLogger("test"),
+		}
+	}
+
+	t.Run("Vault supports SECRET_WRITER", func(t *testing.T) {
+		s := NewHashiCorpVaultSecretStore(logger.NewLogger("test"))
+		assert.True(t, secretstores.FeatureSecretWriter.IsPresent(s.Features()))
+	})
+
+	t.Run("set secret", func(t *testing.T) {
+		s := newStore(valueTypeMap)
+		err := s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "mysecret",
+			Data: map[string]string{"key": "value"},
+		})
+		require.NoError(t, err)
+		require.Len(t, requests, 1)
+		assert.Equal(t, http.MethodPost, requests[0].method)
+		assert.Equal(t, "/v1/secret/data/dapr/mysecret", requests[0].path)
+		assert.Equal(t, expectedTok, requests[0].token)
+		assert.JSONEq(t, `{"data":{"key":"value"}}`, requests[0].body)
+	})
+
+	t.Run("set secret with value type text", func(t *testing.T) {
+		s := newStore(valueTypeText)
+		err := s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "mysecret",
+			Data: map[string]string{"mysecret": `{"key":"value"}`},
+		})
+		require.NoError(t, err)
+		require.Len(t, requests, 1)
+		assert.JSONEq(t, `{"data":{"key":"value"}}`, requests[0].body)
+
+		err = s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "mysecret",
+			Data: map[string]string{"mysecret": "not an object"},
+		})
+		require.Error(t, err)
+		require.Len(t, requests, 1)
+	})
+
+	t.Run("delete secret", func(t *testing.T) {
+		s := newStore(valueTypeMap)
+		s.vaultKVPrefix = ""
+		err := s.DeleteSecret(context.Background(), secretstores.DeleteSecretRequest{Name: "mysecret"})
+		require.NoError(t, err)
+		require.Len(t, requests, 1)
+		assert.Equal(t, http.MethodDelete, requests[0].method)
+		assert.Equal(t, "/v1/secret/metadata/mysecret", requests[0].path)
+	})
+}
+
+func TestWatchSecrets(t *testing.T) {
+	// Fake KV engine, with the values of each version of the secrets
+	var lock sync.Mutex
+	kv := map[string][]string{
+		"secret1": {"a"},
+		"secret2": {"b"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/dapr/"):
+			versions, ok := kv[strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/dapr/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"data":{"current_version":` + strconv.Itoa(len(versions)) + `}}`))
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/dapr/"):
+			versions, ok := kv[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/dapr/")]
+			version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+			if version == 0 {
+				version = len(versions)
+			}
+			if !ok || version > len(versions) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"data":{"data":{"key":"` + versions[version-1] + `"},"metadata":{"version":` + strconv.Itoa(version) + `}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s := NewHashiCorpVaultSecretStore(logger.NewLogger("test")).(*vaultSecretStore)
+	s.client = server.Client()
+	s.vaultAddress = server.URL
+	s.vaultKVPrefix = defaultVaultKVPrefix
+	s.vaultEnginePath = defaultVaultEnginePath
+	s.vaultValueType = valueTypeMap
+	s.watchPollInterval = 10 * time.Millisecond
+	defer s.Close()
+	assert.True(t, secretstores.FeatureSecretWatcher.IsPresent(s.Features()))
+
+	events := make(chan *secretstores.SecretChangeEvent, 10)
+	err := s.WatchSecrets(context.Background(), secretstores.WatchSecretsRequest{
+		Names: []string{"secret1", "secret2", "secret3"},
+	}, func(ctx context.Context, e *secretstores.SecretChangeEvent) error {
+		events <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	nextEvent := func(t *testing.T) *secretstores.SecretChangeEvent {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return nil
+		}
+	}
+
+	lock.Lock()
+	kv["secret1"] = append(kv["secret1"], "a2")
+	lock.Unlock()
+	assert.Equal(t, &secretstores.SecretChangeEvent{
+		Name:    "secret1",
+		Type:    secretstores.SecretChangeTypeUpdated,
+		Data:    map[string]string{"key": "a2"},
+		Version: "2",
+	}, nextEvent(t))
+
+	lock.Lock()
+	delete(kv, "secret2")
+	lock.Unlock()
+	assert.Equal(t, &secretstores.SecretChangeEvent{
+		Name: "secret2",
+		Type: secretstores.SecretChangeTypeDeleted,
+	}, nextEvent(t))
+
+	t.Run("get secret version", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "secret1"})
+		require.NoError(t, err)
+		assert.Equal(t, "2", resp.Version)
+		assert.Equal(t, map[string]string{"key": "a2"}, resp.Data)
+
+		resp, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "secret1", Version: "1"})
+		require.NoError(t, err)
+		assert.Equal(t, "1", resp.Version)
+		assert.Equal(t, map[string]string{"key": "a"}, resp.Data)
+	})
+}
@@ -83,8 +83,12 @@ func (c *csmsSecretStore) Init(ctx context.Context, meta secretstores.Metadata)
 func (c *csmsSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
 	request := &model.ShowSecretVersionRequest{}
 	request.SecretName = req.Name
-	if value, ok := req.Metadata[versionID]; ok {
-		request.VersionId = value
+	version, err := req.RequestedVersion(versionID)
+	if err != nil {
+		return secretstores.GetSecretResponse{}, err
+	}
+	if version != "" {
+		request.VersionId = version
 	}
 
 	response, err := c.client.ShowSecretVersion(request)
@@ -18,9 +18,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	kubeclient "github.com/dapr/components-contrib/internal/authentication/kubernetes"
 	"github.com/dapr/components-contrib/metadata"
@@ -28,17 +34,27 @@ import (
 	"github.com/dapr/kit/logger"
 )
 
-var _ secretstores.SecretStore = (*kubernetesSecretStore)(nil)
+var (
+	_ secretstores.SecretStore   = (*kubernetesSecretStore)(nil)
+	_ secretstores.SecretWriter  = (*kubernetesSecretStore)(nil)
+	_ secretstores.SecretWatcher = (*kubernetesSecretStore)(nil)
+)
 
 type kubernetesSecretStore struct {
 	kubeClient kubernetes.Interface
 	md         kubernetesMetadata
 	logger     logger.Logger
+	closed     atomic.Bool
+	closeCh    chan struct{}
+	wg         sync.WaitGroup
 }
 
 // NewKubernetesSecretStore returns a new Kubernetes secret store.
 func NewKubernetesSecretStore(logger logger.Logger) secretstores.SecretStore {
-	return &kubernetesSecretStore{logger: logger}
+	return &kubernetesSecretStore{
+		logger:  logger,
+		closeCh: make(chan struct{}),
+	}
 }
 
 // Init creates a Kubernetes client.
@@ -77,9 +93,15 @@ func (k *kubernetesSecretStore) GetSecret(ctx context.Context, req secretstores.
 		return resp, err
 	}
 
+	// Kubernetes does not keep previous versions of secrets, so only the current resource version can be requested
+	if req.Version != "" && req.Version != secret.ResourceVersion {
+		return resp, fmt.Errorf("cannot get version %s of secret %s: %w", req.Version, req.Name, secretstores.ErrVersionNotFound)
+	}
+
 	for k, v := range secret.Data {
 		resp.Data[k] = string(v)
 	}
+	resp.Version = secret.ResourceVersion
 
 	return resp, nil
 }
@@ -109,6 +131,167 @@ func (k *kubernetesSecretStore) BulkGetSecret(ctx context.Context, req secretsto
 	return resp, nil
 }
 
+// SetSecret creates a secret, or replaces the data of an existing secret.
+func (k *kubernetesSecretStore) SetSecret(ctx context.Context, req secretstores.SetSecretRequest) error {
+	namespace, err := k.getNamespaceFromMetadata(req.Metadata)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string][]byte, len(req.Data))
+	for k, v := range req.Data {
+		data[k] = []byte(v)
+	}
+
+	secrets := k.kubeClient.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(ctx, req.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      req.Name,
+				Namespace: namespace,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	secret.Data = data
+	secret.StringData = nil
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// DeleteSecret deletes a secret.
+func (k *kubernetesSecretStore) DeleteSecret(ctx context.Context, req secretstores.DeleteSecretRequest) error {
+	namespace, err := k.getNamespaceFromMetadata(req.Metadata)
+	if err != nil {
+		return err
+	}
+
+	return k.kubeClient.CoreV1().Secrets(namespace).Delete(ctx, req.Name, metav1.DeleteOptions{})
+}
+
+// WatchSecrets watches secrets in the namespace with an informer, and notifies of changes to the secrets.
+func (k *kubernetesSecretStore) WatchSecrets(ctx context.Context, req secretstores.WatchSecretsRequest, handler secretstores.SecretChangeHandler) error {
+	if k.closed.Load() {
+		return errors.New("secret store is closed")
+	}
+
+	namespace, err := k.getNamespaceFromMetadata(req.Metadata)
+	if err != nil {
+		return err
+	}
+
+	// The informer notifies of all existing secrets when it starts: list the current versions, so only changes are notified
+	list, err := k.kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	initialVersions := make(map[string]string, len(list.Items))
+	for _, s := range list.Items {
+		initialVersions[s.Name] = s.ResourceVersion
+	}
+	var lock sync.Mutex
+
+	notify := func(secret *corev1.Secret, changeType secretstores.SecretChangeType) {
+		if !req.IsWatched(secret.Name) {
+			return
+		}
+		e := &secretstores.SecretChangeEvent{
+			Name:    secret.Name,
+			Type:    changeType,
+			Version: secret.ResourceVersion,
+		}
+		if changeType == secretstores.SecretChangeTypeUpdated {
+			e.Data = make(map[string]string, len(secret.Data))
+			for k, v := range secret.Data {
+				e.Data[k] = string(v)
+			}
+		}
+		err := handler(ctx, e)
+		if err != nil {
+			k.logger.Errorf("Error handling change to secret %s: %v", secret.Name, err)
+		}
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(k.kubeClient, 0, informers.WithNamespace(namespace))
+	informer := factory.Core().V1().Secrets().Informer()
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			secret, ok := obj.(*corev1.Secret)
+			if !ok {
+				return
+			}
+			lock.Lock()
+			initialVersion, isInitial := initialVersions[secret.Name]
+			delete(initialVersions, secret.Name)
+			lock.Unlock()
+			if isInitial && initialVersion == secret.ResourceVersion {
+				return
+			}
+			notify(secret, secretstores.SecretChangeTypeUpdated)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, ok := oldObj.(*corev1.Secret)
+			if !ok {
+				return
+			}
+			newSecret, ok := newObj.(*corev1.Secret)
+			if !ok || oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				return
+			}
+			notify(newSecret, secretstores.SecretChangeTypeUpdated)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			secret, ok := obj.(*corev1.Secret)
+			if !ok {
+				return
+			}
+			notify(secret, secretstores.SecretChangeTypeDeleted)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add event handler to informer: %w", err)
+	}
+
+	stopCh := make(chan struct{})
+	factory.Start(stopCh)
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		select {
+		case <-ctx.Done():
+		case <-k.closeCh:
+		}
+		close(stopCh)
+		factory.Shutdown()
+	}()
+
+	for _, synced := range factory.WaitForCacheSync(stopCh) {
+		if !synced {
+			return errors.New("failed to sync secrets informer")
+		}
+	}
+
+	return nil
+}
+
+// Close stops all informers.
+func (k *kubernetesSecretStore) Close() error {
+	if k.closeCh != nil && k.closed.CompareAndSwap(false, true) {
+		close(k.closeCh)
+	}
+	k.wg.Wait()
+	return nil
+}
+
 func (k *kubernetesSecretStore) getNamespaceFromMetadata(metadata map[string]string) (string, error) {
 	if val, ok := metadata["namespace"]; ok && val != "" {
 		return val, nil
@@ -127,7 +310,10 @@ func (k *kubernetesSecretStore) getNamespaceFromMetadata(metadata map[string]str
 
 // Features returns the features available in this secret store.
 func (k *kubernetesSecretStore) Features() []secretstores.Feature {
-	return []secretstores.Feature{}
+	return []secretstores.Feature{
+		secretstores.FeatureSecretWriter,
+		secretstores.FeatureSecretWatcher,
+	}
 }
 
 func (k *kubernetesSecretStore) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
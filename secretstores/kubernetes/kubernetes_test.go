@@ -14,11 +14,18 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 
+	"github.com/dapr/components-contrib/secretstores"
 	"github.com/dapr/kit/logger"
 )
 
@@ -67,8 +74,137 @@ func TestGetNamespace(t *testing.T) {
 func TestGetFeatures(t *testing.T) {
 	s := kubernetesSecretStore{logger: logger.NewLogger("test")}
 	// Yes, we are skipping initialization as feature retrieval doesn't depend on it.
-	t.Run("no features are advertised", func(t *testing.T) {
+	t.Run("SECRET_WRITER is advertised", func(t *testing.T) {
 		f := s.Features()
-		assert.Empty(t, f)
+		assert.True(t, secretstores.FeatureSecretWriter.IsPresent(f))
 	})
 }
+
+func TestSetAndDeleteSecret(t *testing.T) {
+	s := kubernetesSecretStore{
+		kubeClient: fake.NewSimpleClientset(),
+		md:         kubernetesMetadata{DefaultNamespace: "default"},
+		logger:     logger.NewLogger("test"),
+	}
+	ctx := context.Background()
+
+	t.Run("create secret", func(t *testing.T) {
+		err := s.SetSecret(ctx, secretstores.SetSecretRequest{
+			Name: "mysecret",
+			Data: map[string]string{"key1": "value1", "key2": "value2"},
+		})
+		require.NoError(t, err)
+
+		resp, err := s.GetSecret(ctx, secretstores.GetSecretRequest{Name: "mysecret"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"key1": "value1", "key2": "value2"}, resp.Data)
+	})
+
+	t.Run("update secret", func(t *testing.T) {
+		err := s.SetSecret(ctx, secretstores.SetSecretRequest{
+			Name: "mysecret",
+			Data: map[string]string{"key1": "value3"},
+		})
+		require.NoError(t, err)
+
+		resp, err := s.GetSecret(ctx, secretstores.GetSecretRequest{Name: "mysecret"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"key1": "value3"}, resp.Data)
+	})
+
+	t.Run("delete secret", func(t *testing.T) {
+		err := s.DeleteSecret(ctx, secretstores.DeleteSecretRequest{Name: "mysecret"})
+		require.NoError(t, err)
+
+		_, err = s.GetSecret(ctx, secretstores.GetSecretRequest{Name: "mysecret"})
+		require.Error(t, err)
+
+		err = s.DeleteSecret(ctx, secretstores.DeleteSecretRequest{Name: "mysecret"})
+		require.Error(t, err)
+	})
+}
+
+func TestWatchSecrets(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret1", Namespace: "default", ResourceVersion: "1"},
+			Data:       map[string][]byte{"key": []byte("a")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "secret2", Namespace: "default", ResourceVersion: "1"},
+			Data:       map[string][]byte{"key": []byte("b")},
+		},
+	)
+	s := NewKubernetesSecretStore(logger.NewLogger("test")).(*kubernetesSecretStore)
+	s.kubeClient = client
+	s.md.DefaultNamespace = "default"
+	defer s.Close()
+	assert.True(t, secretstores.FeatureSecretWatcher.IsPresent(s.Features()))
+
+	ctx := context.Background()
+	events := make(chan *secretstores.SecretChangeEvent, 10)
+	err := s.WatchSecrets(ctx, secretstores.WatchSecretsRequest{
+		Names: []string{"secret1", "secret3"},
+	}, func(ctx context.Context, e *secretstores.SecretChangeEvent) error {
+		events <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	nextEvent := func(t *testing.T) *secretstores.SecretChangeEvent {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return nil
+		}
+	}
+
+	secrets := client.CoreV1().Secrets("default")
+	_, err = secrets.Update(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret2", Namespace: "default", ResourceVersion: "2"},
+		Data:       map[string][]byte{"key": []byte("b2")},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = secrets.Update(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret1", Namespace: "default", ResourceVersion: "2"},
+		Data:       map[string][]byte{"key": []byte("a2")},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, &secretstores.SecretChangeEvent{
+		Name:    "secret1",
+		Type:    secretstores.SecretChangeTypeUpdated,
+		Data:    map[string]string{"key": "a2"},
+		Version: "2",
+	}, nextEvent(t))
+
+	_, err = secrets.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret3", Namespace: "default", ResourceVersion: "1"},
+		Data:       map[string][]byte{"key": []byte("c")},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	e := nextEvent(t)
+	assert.Equal(t, "secret3", e.Name)
+	assert.Equal(t, secretstores.SecretChangeTypeUpdated, e.Type)
+
+	require.NoError(t, secrets.Delete(ctx, "secret1", metav1.DeleteOptions{}))
+	e = nextEvent(t)
+	assert.Equal(t, "secret1", e.Name)
+	assert.Equal(t, secretstores.SecretChangeTypeDeleted, e.Type)
+	assert.Nil(t, e.Data)
+
+	// Only the current version can be retrieved
+	resp, err := s.GetSecret(ctx, secretstores.GetSecretRequest{Name: "secret3", Version: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", resp.Version)
+	_, err = s.GetSecret(ctx, secretstores.GetSecretRequest{Name: "secret3", Version: "0"})
+	require.ErrorIs(t, err, secretstores.ErrVersionNotFound)
+
+	select {
+	case e = <-events:
+		t.Fatalf("unexpected event: %v", e)
+	default:
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptedfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"gopkg.in/yaml.v3"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/components-contrib/secretstores/local/file"
+	"github.com/dapr/kit/logger"
+	kitmd "github.com/dapr/kit/metadata"
+)
+
+const (
+	// Environment variables used by SOPS for age keys, used when no key is set in the metadata.
+	envSOPSAgeKey     = "SOPS_AGE_KEY"
+	envSOPSAgeKeyFile = "SOPS_AGE_KEY_FILE"
+
+	// Header of age-encrypted files, in the binary and armored formats.
+	ageHeader      = "age-encryption.org/v1"
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+type encryptedFileMetadata struct {
+	SecretsFile     string `mapstructure:"secretsFile"`
+	NestedSeparator string `mapstructure:"nestedSeparator"`
+	MultiValued     bool   `mapstructure:"multiValued"`
+	AgeKey          string `mapstructure:"ageKey"`
+	AgeKeyFile      string `mapstructure:"ageKeyFile"`
+	PGPKey          string `mapstructure:"pgpKey"`
+	PGPKeyFile      string `mapstructure:"pgpKeyFile"`
+	PGPPassphrase   string `mapstructure:"pgpPassphrase"`
+}
+
+var (
+	_ secretstores.SecretStore   = (*encryptedFileSecretStore)(nil)
+	_ secretstores.SecretWatcher = (*encryptedFileSecretStore)(nil)
+)
+
+// encryptedFileSecretStore is a local file secret store that reads secrets files encrypted with SOPS or age.
+// Secrets are decrypted in memory only, and the store is read-only so secrets are never written to disk unencrypted.
+type encryptedFileSecretStore struct {
+	// Local file secret store, which reads the file with readSecretsFile
+	secretstores.SecretStore
+
+	ageIdentities []age.Identity
+	pgpKeyRing    openpgp.EntityList
+	logger        logger.Logger
+}
+
+// NewEncryptedFileSecretStore returns a new encrypted local file secret store.
+func NewEncryptedFileSecretStore(logger logger.Logger) secretstores.SecretStore {
+	s := &encryptedFileSecretStore{
+		logger: logger,
+	}
+	s.SecretStore = file.NewLocalSecretStoreWithReader(logger, s.readSecretsFile)
+	return s
+}
+
+// Init loads the decryption keys, then reads and decrypts the secrets file.
+func (s *encryptedFileSecretStore) Init(ctx context.Context, meta secretstores.Metadata) error {
+	var m encryptedFileMetadata
+	err := kitmd.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return err
+	}
+
+	err = s.loadAgeIdentities(m)
+	if err != nil {
+		return err
+	}
+	err = s.loadPGPKeyRing(m)
+	if err != nil {
+		return err
+	}
+	if len(s.ageIdentities) == 0 && len(s.pgpKeyRing) == 0 {
+		return errors.New("missing decryption keys in metadata: at least one of ageKey, ageKeyFile, pgpKey, or pgpKeyFile is required")
+	}
+
+	return s.SecretStore.Init(ctx, meta)
+}
+
+func (s *encryptedFileSecretStore) loadAgeIdentities(m encryptedFileMetadata) error {
+	key := m.AgeKey
+	keyFile := m.AgeKeyFile
+	if key == "" && keyFile == "" {
+		key = os.Getenv(envSOPSAgeKey)
+		keyFile = os.Getenv(envSOPSAgeKeyFile)
+	}
+
+	s.ageIdentities = nil
+	if key != "" {
+		identities, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("failed to parse age key: %w", err)
+		}
+		s.ageIdentities = append(s.ageIdentities, identities...)
+	}
+	if keyFile != "" {
+		f, err := os.Open(keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to open age key file: %w", err)
+		}
+		defer f.Close()
+		identities, err := age.ParseIdentities(f)
+		if err != nil {
+			return fmt.Errorf("failed to parse age key file: %w", err)
+		}
+		s.ageIdentities = append(s.ageIdentities, identities...)
+	}
+	return nil
+}
+
+func (s *encryptedFileSecretStore) loadPGPKeyRing(m encryptedFileMetadata) error {
+	s.pgpKeyRing = nil
+	keys := make([]string, 0, 2)
+	if m.PGPKey != "" {
+		keys = append(keys, m.PGPKey)
+	}
+	if m.PGPKeyFile != "" {
+		data, err := os.ReadFile(m.PGPKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read PGP key file: %w", err)
+		}
+		keys = append(keys, string(data))
+	}
+
+	for _, key := range keys {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("failed to parse PGP key: %w", err)
+		}
+		for _, e := range entities {
+			if e.PrivateKey == nil {
+				return errors.New("PGP key does not contain a private key")
+			}
+			err = e.DecryptPrivateKeys([]byte(m.PGPPassphrase))
+			if err != nil {
+				return fmt.Errorf("failed to decrypt PGP private key: %w", err)
+			}
+		}
+		s.pgpKeyRing = append(s.pgpKeyRing, entities...)
+	}
+	return nil
+}
+
+// readSecretsFile reads and decrypts the secrets file.
+func (s *encryptedFileSecretStore) readSecretsFile(secretsFile string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(secretsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte(ageHeader)) || bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorHeader)) {
+		return s.decryptAgeFile(data)
+	}
+	return s.decryptSOPSFile(data)
+}
+
+// decryptAgeFile decrypts a file encrypted with age, which contains JSON or YAML.
+// Values are returned as strings, like the values in SOPS files.
+func (s *encryptedFileSecretStore) decryptAgeFile(data []byte) (map[string]interface{}, error) {
+	if len(s.ageIdentities) == 0 {
+		return nil, errors.New("cannot decrypt age-encrypted file: no age key configured")
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if !bytes.HasPrefix(data, []byte(ageHeader)) {
+		r = armor.NewReader(r)
+	}
+	r, err := age.Decrypt(r, s.ageIdentities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt age-encrypted file: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt age-encrypted file: %w", err)
+	}
+
+	// JSON is parsed as YAML, which is a superset of JSON
+	var node yaml.Node
+	err = yaml.Unmarshal(plaintext, &node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted file as JSON or YAML: %w", err)
+	}
+	res, err := yamlToSecrets(&node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted file: %w", err)
+	}
+	return res, nil
+}
+
+// decryptSOPSFile decrypts a file encrypted with SOPS, in the JSON or YAML format.
+func (s *encryptedFileSecretStore) decryptSOPSFile(data []byte) (map[string]interface{}, error) {
+	f, err := parseSOPSFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := s.sopsDataKey(f.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.decrypt(dataKey)
+}
+
+// sopsDataKey decrypts the data key of a SOPS file with the first key that can decrypt it.
+func (s *encryptedFileSecretStore) sopsDataKey(m sopsMetadata) ([]byte, error) {
+	errs := []error{}
+	if len(s.ageIdentities) > 0 {
+		for _, entry := range m.Age {
+			r, err := age.Decrypt(armor.NewReader(strings.NewReader(entry.Enc)), s.ageIdentities...)
+			if err == nil {
+				var dataKey []byte
+				dataKey, err = io.ReadAll(r)
+				if err == nil {
+					return dataKey, nil
+				}
+			}
+			errs = append(errs, fmt.Errorf("age recipient %s: %w", entry.Recipient, err))
+		}
+	}
+	if len(s.pgpKeyRing) > 0 {
+		for _, entry := range m.PGP {
+			block, err := pgparmor.Decode(strings.NewReader(entry.Enc))
+			if err == nil {
+				var md *openpgp.MessageDetails
+				md, err = openpgp.ReadMessage(block.Body, s.pgpKeyRing, nil, nil)
+				if err == nil {
+					var dataKey []byte
+					dataKey, err = io.ReadAll(md.UnverifiedBody)
+					if err == nil {
+						return dataKey, nil
+					}
+				}
+			}
+			errs = append(errs, fmt.Errorf("PGP key %s: %w", entry.Fingerprint, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("failed to decrypt SOPS data key: the file is not encrypted for any of the configured keys")
+	}
+	return nil, fmt.Errorf("failed to decrypt SOPS data key: %w", errors.Join(errs...))
+}
+
+// yamlToSecrets converts a YAML document to the secrets object, decoding all values as strings.
+func yamlToSecrets(node *yaml.Node) (map[string]interface{}, error) {
+	value, err := yamlNodeToSOPSValue(node)
+	if err != nil {
+		return nil, err
+	}
+	branch, ok := value.(sopsBranch)
+	if !ok {
+		return nil, errors.New("the document must be an object")
+	}
+	return valueToSecret(branch).(map[string]interface{}), nil
+}
+
+func valueToSecret(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case sopsBranch:
+		res := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			res[item.key] = valueToSecret(item.value)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = valueToSecret(item)
+		}
+		return res
+	default:
+		return sopsLeafToString(v)
+	}
+}
+
+// Features returns the features of the local file secret store, except SecretWriter since the store is read-only.
+func (s *encryptedFileSecretStore) Features() []secretstores.Feature {
+	features := s.SecretStore.Features()
+	res := make([]secretstores.Feature, 0, len(features))
+	for _, f := range features {
+		if f != secretstores.FeatureSecretWriter {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// WatchSecrets watches the secrets file for changes, decrypting it again when it's modified.
+func (s *encryptedFileSecretStore) WatchSecrets(ctx context.Context, req secretstores.WatchSecretsRequest, handler secretstores.SecretChangeHandler) error {
+	return s.SecretStore.(secretstores.SecretWatcher).WatchSecrets(ctx, req, handler)
+}
+
+// Close stops watching the secrets file.
+func (s *encryptedFileSecretStore) Close() error {
+	if closer, ok := s.SecretStore.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *encryptedFileSecretStore) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
+	metadataStruct := encryptedFileMetadata{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo, metadata.SecretStoreType)
+	return
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptedfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+// The files in testdata contain the following document, encrypted with SOPS (using the keys in testdata/age.key and testdata/pgp.asc) or age:
+//
+//	secret: value
+//	nested:
+//	    user: admin
+//	    password: pa$$w0rd
+//	    port: 5432
+//	list:
+//	    - a
+//	    - b
+//	public_unencrypted: not secret
+var expectedSecrets = map[string]map[string]string{
+	"secret":             {"secret": "value"},
+	"nested:user":        {"nested:user": "admin"},
+	"nested:password":    {"nested:password": "pa$$w0rd"},
+	"nested:port":        {"nested:port": "5432"},
+	"list:0":             {"list:0": "a"},
+	"list:1":             {"list:1": "b"},
+	"public_unencrypted": {"public_unencrypted": "not secret"},
+}
+
+func newStore(t *testing.T, props map[string]string) (secretstores.SecretStore, error) {
+	t.Helper()
+	s := NewEncryptedFileSecretStore(logger.NewLogger("test"))
+	t.Cleanup(func() {
+		s.(*encryptedFileSecretStore).Close()
+	})
+	return s, s.Init(context.Background(), secretstores.Metadata{Base: metadata.Base{Properties: props}})
+}
+
+func TestEncryptedFileSecretStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		props map[string]string
+	}{
+		{name: "SOPS JSON with age", file: "secrets.sops.json", props: map[string]string{"ageKeyFile": "testdata/age.key"}},
+		{name: "SOPS YAML with PGP", file: "secrets.sops.yaml", props: map[string]string{"pgpKeyFile": "testdata/pgp.asc"}},
+		{name: "age", file: "secrets.json.age", props: map[string]string{"ageKeyFile": "testdata/age.key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.props["secretsFile"] = filepath.Join("testdata", tt.file)
+			s, err := newStore(t, tt.props)
+			require.NoError(t, err)
+
+			resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "nested:password"})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"nested:password": "pa$$w0rd"}, resp.Data)
+
+			bulk, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, expectedSecrets, bulk.Data)
+		})
+	}
+
+	t.Run("age key in metadata", func(t *testing.T) {
+		key, err := os.ReadFile("testdata/age.key")
+		require.NoError(t, err)
+		s, err := newStore(t, map[string]string{
+			"secretsFile": "testdata/secrets.sops.json",
+			"ageKey":      string(key),
+		})
+		require.NoError(t, err)
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "secret"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"secret": "value"}, resp.Data)
+	})
+
+	t.Run("multi-valued secrets", func(t *testing.T) {
+		s, err := newStore(t, map[string]string{
+			"secretsFile": "testdata/secrets.sops.yaml",
+			"pgpKeyFile":  "testdata/pgp.asc",
+			"multiValued": "true",
+		})
+		require.NoError(t, err)
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "nested"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"user": "admin", "password": "pa$$w0rd", "port": "5432"}, resp.Data)
+		assert.True(t, secretstores.FeatureMultipleKeyValuesPerSecret.IsPresent(s.Features()))
+	})
+
+	t.Run("store is read-only", func(t *testing.T) {
+		s, err := newStore(t, map[string]string{
+			"secretsFile": "testdata/secrets.sops.json",
+			"ageKeyFile":  "testdata/age.key",
+		})
+		require.NoError(t, err)
+		_, ok := s.(secretstores.SecretWriter)
+		assert.False(t, ok)
+		assert.False(t, secretstores.FeatureSecretWriter.IsPresent(s.Features()))
+		assert.True(t, secretstores.FeatureSecretWatcher.IsPresent(s.Features()))
+	})
+
+	t.Run("missing keys", func(t *testing.T) {
+		t.Setenv(envSOPSAgeKey, "")
+		t.Setenv(envSOPSAgeKeyFile, "")
+		_, err := newStore(t, map[string]string{
+			"secretsFile": "testdata/secrets.sops.json",
+		})
+		require.ErrorContains(t, err, "missing decryption keys")
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := newStore(t, map[string]string{
+			"secretsFile": "testdata/secrets.sops.json",
+			"pgpKeyFile":  "testdata/pgp.asc",
+		})
+		require.ErrorContains(t, err, "not encrypted for any of the configured keys")
+	})
+
+	t.Run("tampered file", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/secrets.sops.yaml")
+		require.NoError(t, err)
+		secretsFile := filepath.Join(t.TempDir(), "secrets.yaml")
+
+		// Changing an unencrypted value invalidates the MAC
+		tampered := strings.Replace(string(data), "public_unencrypted: not secret", "public_unencrypted: changed", 1)
+		require.NotEqual(t, string(data), tampered)
+		require.NoError(t, os.WriteFile(secretsFile, []byte(tampered), 0o600))
+		_, err = newStore(t, map[string]string{
+			"secretsFile": secretsFile,
+			"pgpKeyFile":  "testdata/pgp.asc",
+		})
+		require.ErrorContains(t, err, "MAC mismatch")
+
+		// Moving encrypted values to another key fails decryption
+		lines := strings.Split(string(data), "\n")
+		var userLine, passwordLine int
+		for i, l := range lines {
+			switch {
+			case strings.HasPrefix(l, "    user: "):
+				userLine = i
+			case strings.HasPrefix(l, "    password: "):
+				passwordLine = i
+			}
+		}
+		lines[userLine], lines[passwordLine] = "    user: "+strings.TrimPrefix(lines[passwordLine], "    password: "), "    password: "+strings.TrimPrefix(lines[userLine], "    user: ")
+		require.NoError(t, os.WriteFile(secretsFile, []byte(strings.Join(lines, "\n")), 0o600))
+		_, err = newStore(t, map[string]string{
+			"secretsFile": secretsFile,
+			"pgpKeyFile":  "testdata/pgp.asc",
+		})
+		require.ErrorContains(t, err, "failed to decrypt value at path 'nested:user'")
+	})
+
+	t.Run("plaintext file", func(t *testing.T) {
+		secretsFile := filepath.Join(t.TempDir(), "secrets.json")
+		require.NoError(t, os.WriteFile(secretsFile, []byte(`{"secret": "value"}`), 0o600))
+		_, err := newStore(t, map[string]string{
+			"secretsFile": secretsFile,
+			"ageKeyFile":  "testdata/age.key",
+		})
+		require.ErrorContains(t, err, "not encrypted with SOPS")
+	})
+}
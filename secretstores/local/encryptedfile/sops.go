@@ -0,0 +1,409 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptedfile
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Key of the SOPS metadata in encrypted files.
+	sopsMetadataKey = "sops"
+	// Suffix of the keys that are not encrypted, used when no other rule is set in the file.
+	sopsDefaultUnencryptedSuffix = "_unencrypted"
+)
+
+// Format of the values encrypted by SOPS.
+var sopsEncryptedValueRegex = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]`)
+
+// sopsMetadata contains the metadata of a SOPS-encrypted file.
+// Only the age and PGP key sources are supported.
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	PGP []struct {
+		Fingerprint string `yaml:"fp"`
+		Enc         string `yaml:"enc"`
+	} `yaml:"pgp"`
+	KeyGroups         []interface{} `yaml:"key_groups"`
+	LastModified      string        `yaml:"lastmodified"`
+	MAC               string        `yaml:"mac"`
+	MACOnlyEncrypted  bool          `yaml:"mac_only_encrypted"`
+	UnencryptedSuffix string        `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string        `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string        `yaml:"unencrypted_regex"`
+	EncryptedRegex    string        `yaml:"encrypted_regex"`
+}
+
+// sopsItem is an item in a SOPS tree branch.
+type sopsItem struct {
+	key   string
+	value interface{}
+}
+
+// sopsBranch is an object in a SOPS tree, which preserves the order of the keys.
+type sopsBranch []sopsItem
+
+// sopsFile is a parsed SOPS-encrypted file.
+type sopsFile struct {
+	branch   sopsBranch
+	metadata sopsMetadata
+
+	unencryptedRegex *regexp.Regexp
+	encryptedRegex   *regexp.Regexp
+}
+
+// parseSOPSFile parses a SOPS-encrypted JSON or YAML file.
+// JSON files are parsed as YAML, which is a superset of JSON.
+func parseSOPSFile(data []byte) (*sopsFile, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("failed to parse file: the document must be an object")
+	}
+
+	f := &sopsFile{}
+	root := doc.Content[0]
+	found := false
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == sopsMetadataKey {
+			err = root.Content[i+1].Decode(&f.metadata)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse SOPS metadata: %w", err)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New("file is not encrypted with SOPS: metadata not found")
+	}
+
+	err = f.validateMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	branch, err := yamlNodeToSOPSValue(root)
+	if err != nil {
+		return nil, err
+	}
+	f.branch = make(sopsBranch, 0, len(branch.(sopsBranch)))
+	for _, item := range branch.(sopsBranch) {
+		if item.key != sopsMetadataKey {
+			f.branch = append(f.branch, item)
+		}
+	}
+	return f, nil
+}
+
+func (f *sopsFile) validateMetadata() error {
+	m := &f.metadata
+	if len(m.KeyGroups) > 0 {
+		return errors.New("SOPS files with key groups are not supported")
+	}
+	if m.MAC == "" {
+		return errors.New("SOPS file does not contain a MAC")
+	}
+
+	rules := 0
+	for _, r := range []string{m.UnencryptedSuffix, m.EncryptedSuffix, m.UnencryptedRegex, m.EncryptedRegex} {
+		if r != "" {
+			rules++
+		}
+	}
+	switch {
+	case rules == 0:
+		m.UnencryptedSuffix = sopsDefaultUnencryptedSuffix
+	case rules > 1:
+		return errors.New("SOPS file contains more than one rule for encrypted keys")
+	}
+
+	var err error
+	if m.UnencryptedRegex != "" {
+		f.unencryptedRegex, err = regexp.Compile(m.UnencryptedRegex)
+		if err != nil {
+			return fmt.Errorf("invalid unencrypted_regex in SOPS file: %w", err)
+		}
+	}
+	if m.EncryptedRegex != "" {
+		f.encryptedRegex, err = regexp.Compile(m.EncryptedRegex)
+		if err != nil {
+			return fmt.Errorf("invalid encrypted_regex in SOPS file: %w", err)
+		}
+	}
+	return nil
+}
+
+// decrypt decrypts the values in the file with the data key, verifies the MAC, and returns the decrypted document.
+func (f *sopsFile) decrypt(dataKey []byte) (map[string]interface{}, error) {
+	mac := sha512.New()
+	res, err := f.decryptBranch(f.branch, nil, dataKey, mac)
+	if err != nil {
+		return nil, err
+	}
+
+	// The MAC is encrypted using the last modified date as associated data
+	lastModified, err := time.Parse(time.RFC3339, f.metadata.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lastmodified in SOPS file: %w", err)
+	}
+	expectedMAC, err := sopsDecryptValue(f.metadata.MAC, dataKey, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt MAC: %w", err)
+	}
+	expectedMACStr, _ := expectedMAC.(string)
+	if subtle.ConstantTimeCompare([]byte(expectedMACStr), []byte(fmt.Sprintf("%X", mac.Sum(nil)))) != 1 {
+		return nil, errors.New("MAC mismatch: the file may have been tampered with")
+	}
+
+	return res, nil
+}
+
+func (f *sopsFile) decryptBranch(branch sopsBranch, path []string, dataKey []byte, mac hash.Hash) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(branch))
+	for _, item := range branch {
+		value, err := f.decryptValue(item.value, append(path[:len(path):len(path)], item.key), dataKey, mac)
+		if err != nil {
+			return nil, err
+		}
+		res[item.key] = value
+	}
+	return res, nil
+}
+
+func (f *sopsFile) decryptValue(value interface{}, path []string, dataKey []byte, mac hash.Hash) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case sopsBranch:
+		return f.decryptBranch(v, path, dataKey, mac)
+	case []interface{}:
+		res := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			dec, err := f.decryptValue(item, path, dataKey, mac)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, dec)
+		}
+		return res, nil
+	default:
+		dec, err := f.decryptLeaf(v, path, dataKey, mac)
+		if err != nil {
+			return nil, err
+		}
+		return sopsLeafToString(dec), nil
+	}
+}
+
+// decryptLeaf decrypts a leaf value if it's encrypted, and adds it to the MAC.
+func (f *sopsFile) decryptLeaf(value interface{}, path []string, dataKey []byte, mac hash.Hash) (interface{}, error) {
+	encrypted := f.isEncrypted(path)
+	if encrypted {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value at path '%s' is not encrypted", strings.Join(path, ":"))
+		}
+		var err error
+		value, err = sopsDecryptValue(str, dataKey, strings.Join(path, ":")+":")
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt value at path '%s': %w", strings.Join(path, ":"), err)
+		}
+	}
+
+	if encrypted || !f.metadata.MACOnlyEncrypted {
+		b, err := sopsLeafToBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		mac.Write(b)
+	}
+	return value, nil
+}
+
+// isEncrypted returns true if the value at the path is encrypted, according to the rules in the metadata.
+func (f *sopsFile) isEncrypted(path []string) bool {
+	m := f.metadata
+	for _, p := range path {
+		switch {
+		case m.UnencryptedSuffix != "" && strings.HasSuffix(p, m.UnencryptedSuffix):
+			return false
+		case m.EncryptedSuffix != "" && strings.HasSuffix(p, m.EncryptedSuffix):
+			return true
+		case f.unencryptedRegex != nil && f.unencryptedRegex.MatchString(p):
+			return false
+		case f.encryptedRegex != nil && f.encryptedRegex.MatchString(p):
+			return true
+		}
+	}
+	return m.EncryptedSuffix == "" && m.EncryptedRegex == ""
+}
+
+// sopsDecryptValue decrypts a value in the format "ENC[AES256_GCM,data:...,iv:...,tag:...,type:...]".
+func sopsDecryptValue(value string, dataKey []byte, aad string) (interface{}, error) {
+	// Empty strings are not encrypted
+	if value == "" {
+		return "", nil
+	}
+
+	match := sopsEncryptedValueRegex.FindStringSubmatch(value)
+	if match == nil {
+		return nil, errors.New("value is not in the SOPS format")
+	}
+	data, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(match[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid iv: %w", err)
+	}
+	tag, err := base64.StdEncoding.DecodeString(match[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag: %w", err)
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return nil, errors.New("decryption failed")
+	}
+
+	switch match[4] {
+	case "str":
+		return string(plaintext), nil
+	case "int":
+		return strconv.Atoi(string(plaintext))
+	case "float":
+		return strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		return strconv.ParseBool(string(plaintext))
+	case "bytes":
+		return plaintext, nil
+	default:
+		return nil, fmt.Errorf("unknown type %s", match[4])
+	}
+}
+
+// sopsLeafToBytes returns the representation of a value used by SOPS to compute the MAC.
+func sopsLeafToBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		// SOPS uses "True" and "False"
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %T", value)
+	}
+}
+
+// sopsLeafToString returns the value of a secret.
+func sopsLeafToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// yamlNodeToSOPSValue converts a YAML node to a value in a SOPS tree.
+// Comments are ignored, as they are not included in the MAC.
+func yamlNodeToSOPSValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return sopsBranch{}, nil
+		}
+		return yamlNodeToSOPSValue(node.Content[0])
+	case yaml.MappingNode:
+		branch := make(sopsBranch, 0, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			if node.Content[i].Kind != yaml.ScalarNode {
+				return nil, errors.New("only string keys are supported")
+			}
+			value, err := yamlNodeToSOPSValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			branch = append(branch, sopsItem{key: node.Content[i].Value, value: value})
+		}
+		return branch, nil
+	case yaml.SequenceNode:
+		res := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlNodeToSOPSValue(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, value)
+		}
+		return res, nil
+	case yaml.ScalarNode:
+		var value interface{}
+		err := node.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	case yaml.AliasNode:
+		return yamlNodeToSOPSValue(node.Alias)
+	default:
+		return nil, fmt.Errorf("unsupported YAML node kind %d", node.Kind)
+	}
+}
@@ -16,6 +16,7 @@ package env
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"reflect"
 	"runtime"
@@ -57,6 +58,11 @@ func (s *envSecretStore) Init(_ context.Context, meta secretstores.Metadata) err
 
 // GetSecret retrieves a secret from env var using provided key.
 func (s *envSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	// Env vars are not versioned
+	if req.Version != "" {
+		return secretstores.GetSecretResponse{}, fmt.Errorf("cannot get version %s of secret %s: %w", req.Version, req.Name, secretstores.ErrVersionNotFound)
+	}
+
 	var value string
 	name := s.metadata.Prefix + req.Name
 	if s.isKeyAllowed(name) {
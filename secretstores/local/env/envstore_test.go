@@ -47,6 +47,11 @@ func TestEnvStore(t *testing.T) {
 		assert.Equal(t, "secret1", resp.Data["TEST_SECRET"])
 	})
 
+	t.Run("Get a version", func(t *testing.T) {
+		_, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "TEST_SECRET", Version: "1"})
+		require.ErrorIs(t, err, secretstores.ErrVersionNotFound)
+	})
+
 	if runtime.GOOS != "windows" {
 		t.Run("Get is case-sensitive on *nix", func(t *testing.T) {
 			resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "test_secret"})
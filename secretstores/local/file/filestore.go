@@ -20,9 +20,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/secretstores"
@@ -36,16 +41,26 @@ type localSecretStoreMetaData struct {
 	MultiValued     bool
 }
 
-var _ secretstores.SecretStore = (*localSecretStore)(nil)
+var (
+	_ secretstores.SecretStore   = (*localSecretStore)(nil)
+	_ secretstores.SecretWriter  = (*localSecretStore)(nil)
+	_ secretstores.SecretWatcher = (*localSecretStore)(nil)
+)
 
 type localSecretStore struct {
 	secretsFile     string
 	nestedSeparator string
+	multiValued     bool
 	currenContext   []string
 	currentPath     string
 	secrets         map[string]interface{}
+	jsonConfig      map[string]interface{}
 	readLocalFileFn func(secretsFile string) (map[string]interface{}, error)
 	features        []secretstores.Feature
+	lock            sync.RWMutex
+	closed          atomic.Bool
+	closeCh         chan struct{}
+	wg              sync.WaitGroup
 	logger          logger.Logger
 }
 
@@ -56,6 +71,15 @@ func NewLocalSecretStore(logger logger.Logger) secretstores.SecretStore {
 	}
 }
 
+// NewLocalSecretStoreWithReader returns a new Local secret store that uses readFn to read and parse the secrets file, for example to decrypt it.
+// The secrets returned by readFn are flattened in the same way as the secrets in a JSON file.
+func NewLocalSecretStoreWithReader(logger logger.Logger, readFn func(secretsFile string) (map[string]interface{}, error)) secretstores.SecretStore {
+	return &localSecretStore{
+		logger:          logger,
+		readLocalFileFn: readFn,
+	}
+}
+
 // Init creates a Local secret store.
 func (j *localSecretStore) Init(_ context.Context, metadata secretstores.Metadata) error {
 	meta, err := j.getLocalSecretStoreMetadata(metadata)
@@ -78,7 +102,18 @@ func (j *localSecretStore) Init(_ context.Context, metadata secretstores.Metadat
 		return err
 	}
 
-	if meta.MultiValued {
+	j.secretsFile = meta.SecretsFile
+	j.multiValued = meta.MultiValued
+	j.closeCh = make(chan struct{})
+	j.loadSecrets(jsonConfig)
+
+	return nil
+}
+
+// loadSecrets loads the secrets from the parsed secrets file.
+func (j *localSecretStore) loadSecrets(jsonConfig map[string]interface{}) {
+	j.jsonConfig = jsonConfig
+	if j.multiValued {
 		allSecrets := map[string]interface{}{}
 		for k, v := range jsonConfig {
 			switch v := v.(type) {
@@ -95,20 +130,30 @@ func (j *localSecretStore) Init(_ context.Context, metadata secretstores.Metadat
 		// key-valyes per secret.
 		j.features = []secretstores.Feature{
 			secretstores.FeatureMultipleKeyValuesPerSecret,
+			secretstores.FeatureSecretWriter,
+			secretstores.FeatureSecretWatcher,
 		}
 	} else {
 		j.secrets = map[string]interface{}{}
 		j.visitJSONObject(jsonConfig)
 		// MultiValued is not set: reset to its default single-value per
-		// secret (no extra feature) behavior.
-		j.features = []secretstores.Feature{}
+		// secret behavior.
+		j.features = []secretstores.Feature{
+			secretstores.FeatureSecretWriter,
+			secretstores.FeatureSecretWatcher,
+		}
 	}
-
-	return nil
 }
 
 // GetSecret retrieves a secret using a key and returns a map of decrypted string/string values.
 func (j *localSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	if req.Version != "" {
+		return secretstores.GetSecretResponse{}, fmt.Errorf("cannot get version %s of secret %s: %w", req.Version, req.Name, secretstores.ErrVersionNotFound)
+	}
+
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
 	secretValue, exists := j.secrets[req.Name]
 	if !exists {
 		return secretstores.GetSecretResponse{}, fmt.Errorf("secret %s not found", req.Name)
@@ -138,6 +183,9 @@ func (j *localSecretStore) GetSecret(ctx context.Context, req secretstores.GetSe
 
 // BulkGetSecret retrieves all secrets in the store and returns a map of decrypted string/string values.
 func (j *localSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
 	r := map[string]map[string]string{}
 
 	for k, v := range j.secrets {
@@ -164,6 +212,292 @@ func (j *localSecretStore) BulkGetSecret(ctx context.Context, req secretstores.B
 	}, nil
 }
 
+// SetSecret creates or updates a secret, and saves the secrets file.
+// If MultiValued is not set, the request must contain a single value with the name of the secret as key, and secrets with nested names are saved in nested objects.
+func (j *localSecretStore) SetSecret(ctx context.Context, req secretstores.SetSecretRequest) error {
+	if req.Name == "" {
+		return errors.New("secret name is required")
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	jsonConfig, err := cloneJSONObject(j.jsonConfig)
+	if err != nil {
+		return err
+	}
+
+	if j.multiValued {
+		values := make(map[string]interface{}, len(req.Data))
+		for k, v := range req.Data {
+			values[k] = v
+		}
+		jsonConfig[req.Name] = values
+	} else {
+		value, ok := req.Data[req.Name]
+		if !ok || len(req.Data) != 1 {
+			return fmt.Errorf("secret %s must contain a single value with the name of the secret as key", req.Name)
+		}
+		if _, isPrimitive := jsonConfig[req.Name].(string); isPrimitive {
+			// Secret whose name contains the separator, at the top level
+			jsonConfig[req.Name] = value
+		} else {
+			err = setNestedValue(jsonConfig, strings.Split(req.Name, j.nestedSeparator), value)
+			if err != nil {
+				return fmt.Errorf("cannot set secret %s: %w", req.Name, err)
+			}
+		}
+	}
+
+	return j.saveSecrets(jsonConfig)
+}
+
+// DeleteSecret deletes a secret, and saves the secrets file.
+func (j *localSecretStore) DeleteSecret(ctx context.Context, req secretstores.DeleteSecretRequest) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if _, exists := j.secrets[req.Name]; !exists {
+		return fmt.Errorf("secret %s not found", req.Name)
+	}
+
+	jsonConfig, err := cloneJSONObject(j.jsonConfig)
+	if err != nil {
+		return err
+	}
+
+	if _, isTopLevel := jsonConfig[req.Name]; isTopLevel {
+		delete(jsonConfig, req.Name)
+	} else if !j.multiValued {
+		deleteNestedValue(jsonConfig, strings.Split(req.Name, j.nestedSeparator))
+	}
+
+	return j.saveSecrets(jsonConfig)
+}
+
+// saveSecrets writes the secrets file and reloads the secrets.
+// The file is replaced atomically, so it's never left partially-written.
+func (j *localSecretStore) saveSecrets(jsonConfig map[string]interface{}) error {
+	data, err := json.MarshalIndent(jsonConfig, "", "    ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(j.secretsFile); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.secretsFile), "."+filepath.Base(j.secretsFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.secretsFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+
+	j.loadSecrets(jsonConfig)
+	return nil
+}
+
+// WatchSecrets watches the secrets file for changes, and notifies of changes to the secrets when the file is modified.
+// The secrets are reloaded from the file when it's modified, including by other processes.
+func (j *localSecretStore) WatchSecrets(ctx context.Context, req secretstores.WatchSecretsRequest, handler secretstores.SecretChangeHandler) error {
+	if j.closed.Load() {
+		return errors.New("secret store is closed")
+	}
+
+	// Watch the directory rather than the file, so changes are detected when the file is replaced
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	err = watcher.Add(filepath.Dir(j.secretsFile))
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch secrets file: %w", err)
+	}
+
+	previous, err := j.watchedSecrets(req)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer watcher.Close()
+
+		secretsFile := filepath.Clean(j.secretsFile)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-j.closeCh:
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				j.logger.Warnf("Error watching secrets file: %v", err)
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != secretsFile || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+
+				// The file may be written in multiple steps: if it can't be parsed, wait for the next event
+				err = j.reloadSecrets()
+				if err != nil {
+					j.logger.Warnf("Failed to reload secrets file: %v", err)
+					continue
+				}
+				current, err := j.watchedSecrets(req)
+				if err != nil {
+					j.logger.Warnf("Failed to reload secrets file: %v", err)
+					continue
+				}
+				j.notifyChanges(ctx, previous, current, handler)
+				previous = current
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reloadSecrets reads the secrets file again.
+func (j *localSecretStore) reloadSecrets() error {
+	jsonConfig, err := j.readLocalFileFn(j.secretsFile)
+	if err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.loadSecrets(jsonConfig)
+	return nil
+}
+
+// watchedSecrets returns the secrets included in the watch request.
+func (j *localSecretStore) watchedSecrets(req secretstores.WatchSecretsRequest) (map[string]map[string]string, error) {
+	all, err := j.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for name := range all.Data {
+		if !req.IsWatched(name) {
+			delete(all.Data, name)
+		}
+	}
+	return all.Data, nil
+}
+
+// notifyChanges invokes the handler for each secret that differs between previous and current.
+func (j *localSecretStore) notifyChanges(ctx context.Context, previous, current map[string]map[string]string, handler secretstores.SecretChangeHandler) {
+	for name, data := range current {
+		if reflect.DeepEqual(previous[name], data) {
+			continue
+		}
+		err := handler(ctx, &secretstores.SecretChangeEvent{
+			Name: name,
+			Type: secretstores.SecretChangeTypeUpdated,
+			Data: data,
+		})
+		if err != nil {
+			j.logger.Errorf("Error handling change to secret %s: %v", name, err)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		err := handler(ctx, &secretstores.SecretChangeEvent{
+			Name: name,
+			Type: secretstores.SecretChangeTypeDeleted,
+		})
+		if err != nil {
+			j.logger.Errorf("Error handling deletion of secret %s: %v", name, err)
+		}
+	}
+}
+
+// Close stops watching the secrets file.
+func (j *localSecretStore) Close() error {
+	if j.closeCh != nil && j.closed.CompareAndSwap(false, true) {
+		close(j.closeCh)
+	}
+	j.wg.Wait()
+	return nil
+}
+
+// setNestedValue sets the value at the path of nested objects, creating objects as needed.
+func setNestedValue(obj map[string]interface{}, path []string, value string) error {
+	existing, exists := obj[path[0]]
+	if len(path) == 1 {
+		switch existing.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("'%s' contains nested values", path[0])
+		}
+		obj[path[0]] = value
+		return nil
+	}
+
+	if !exists {
+		existing = map[string]interface{}{}
+		obj[path[0]] = existing
+	}
+	child, ok := existing.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("'%s' is not an object", path[0])
+	}
+	return setNestedValue(child, path[1:], value)
+}
+
+// deleteNestedValue deletes the value at the path of nested objects, and removes objects that are left empty.
+func deleteNestedValue(obj map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+
+	child, ok := obj[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	deleteNestedValue(child, path[1:])
+	if len(child) == 0 {
+		delete(obj, path[0])
+	}
+}
+
+func cloneJSONObject(obj map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (j *localSecretStore) visitJSONObject(jsonConfig map[string]interface{}) error {
 	for key, element := range jsonConfig {
 		j.enterContext(key)
@@ -18,7 +18,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -230,3 +233,163 @@ func TestMultiValuedSecrets(t *testing.T) {
 		}, resp.Data)
 	})
 }
+
+func TestSetAndDeleteSecret(t *testing.T) {
+	newStore := func(t *testing.T, contents string, multiValued bool) (*localSecretStore, string) {
+		secretsFile := filepath.Join(t.TempDir(), "secrets.json")
+		require.NoError(t, os.WriteFile(secretsFile, []byte(contents), 0o640))
+
+		s := &localSecretStore{
+			logger: logger.NewLogger("test"),
+		}
+		m := secretstores.Metadata{}
+		m.Properties = map[string]string{
+			"secretsFile": secretsFile,
+			"multiValued": fmt.Sprintf("%t", multiValued),
+		}
+		require.NoError(t, s.Init(context.Background(), m))
+		return s, secretsFile
+	}
+
+	t.Run("supports SECRET_WRITER", func(t *testing.T) {
+		s, _ := newStore(t, `{}`, false)
+		assert.True(t, secretstores.FeatureSecretWriter.IsPresent(s.Features()))
+	})
+
+	t.Run("set and delete nested secrets", func(t *testing.T) {
+		s, secretsFile := newStore(t, `{"parent": {"child1": "12345"}}`, false)
+
+		err := s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "parent:child2",
+			Data: map[string]string{"parent:child2": "67890"},
+		})
+		require.NoError(t, err)
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "parent:child2"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"parent:child2": "67890"}, resp.Data)
+
+		// The file is updated and keeps its permissions
+		data, err := os.ReadFile(secretsFile)
+		require.NoError(t, err)
+		assert.Equal(t, "{\n    \"parent\": {\n        \"child1\": \"12345\",\n        \"child2\": \"67890\"\n    }\n}\n", string(data))
+		info, err := os.Stat(secretsFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+		err = s.DeleteSecret(context.Background(), secretstores.DeleteSecretRequest{Name: "parent:child1"})
+		require.NoError(t, err)
+		err = s.DeleteSecret(context.Background(), secretstores.DeleteSecretRequest{Name: "parent:child2"})
+		require.NoError(t, err)
+		_, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "parent:child2"})
+		require.Error(t, err)
+
+		data, err = os.ReadFile(secretsFile)
+		require.NoError(t, err)
+		assert.Equal(t, "{}\n", string(data))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		s, secretsFile := newStore(t, `{"parent": {"child1": "12345"}}`, false)
+
+		err := s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "parent",
+			Data: map[string]string{"parent": "value"},
+		})
+		require.Error(t, err)
+		err = s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "parent:child1:child2",
+			Data: map[string]string{"parent:child1:child2": "value"},
+		})
+		require.Error(t, err)
+		err = s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "other",
+			Data: map[string]string{"a": "1", "b": "2"},
+		})
+		require.Error(t, err)
+		err = s.DeleteSecret(context.Background(), secretstores.DeleteSecretRequest{Name: "notfound"})
+		require.Error(t, err)
+
+		data, err := os.ReadFile(secretsFile)
+		require.NoError(t, err)
+		assert.Equal(t, `{"parent": {"child1": "12345"}}`, string(data))
+	})
+
+	t.Run("multi-valued secrets", func(t *testing.T) {
+		s, _ := newStore(t, `{}`, true)
+
+		err := s.SetSecret(context.Background(), secretstores.SetSecretRequest{
+			Name: "parent",
+			Data: map[string]string{"child1": "12345", "child2": "67890"},
+		})
+		require.NoError(t, err)
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "parent"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"child1": "12345", "child2": "67890"}, resp.Data)
+
+		err = s.DeleteSecret(context.Background(), secretstores.DeleteSecretRequest{Name: "parent"})
+		require.NoError(t, err)
+		_, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "parent"})
+		require.Error(t, err)
+	})
+}
+
+func TestWatchSecrets(t *testing.T) {
+	secretsFile := filepath.Join(t.TempDir(), "secrets.json")
+	require.NoError(t, os.WriteFile(secretsFile, []byte(`{"secret1": "a", "secret2": "b", "secret3": "c"}`), 0o600))
+
+	s := &localSecretStore{
+		logger: logger.NewLogger("test"),
+	}
+	m := secretstores.Metadata{}
+	m.Properties = map[string]string{
+		"secretsFile": secretsFile,
+	}
+	require.NoError(t, s.Init(context.Background(), m))
+	defer s.Close()
+	assert.True(t, secretstores.FeatureSecretWatcher.IsPresent(s.Features()))
+
+	events := make(chan *secretstores.SecretChangeEvent, 10)
+	err := s.WatchSecrets(context.Background(), secretstores.WatchSecretsRequest{
+		Names: []string{"secret1", "secret2", "secret4"},
+	}, func(ctx context.Context, e *secretstores.SecretChangeEvent) error {
+		events <- e
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Modify the file externally
+	require.NoError(t, os.WriteFile(secretsFile, []byte(`{"secret1": "a2", "secret3": "c2", "secret4": "d"}`), 0o600))
+
+	received := map[string]*secretstores.SecretChangeEvent{}
+	for len(received) < 3 {
+		select {
+		case e := <-events:
+			received[e.Name] = e
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, received: %v", received)
+		}
+	}
+	assert.Equal(t, &secretstores.SecretChangeEvent{
+		Name: "secret1",
+		Type: secretstores.SecretChangeTypeUpdated,
+		Data: map[string]string{"secret1": "a2"},
+	}, received["secret1"])
+	assert.Equal(t, &secretstores.SecretChangeEvent{
+		Name: "secret2",
+		Type: secretstores.SecretChangeTypeDeleted,
+	}, received["secret2"])
+	assert.Equal(t, &secretstores.SecretChangeEvent{
+		Name: "secret4",
+		Type: secretstores.SecretChangeTypeUpdated,
+		Data: map[string]string{"secret4": "d"},
+	}, received["secret4"])
+
+	// The store is reloaded
+	resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "secret3"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"secret3": "c2"}, resp.Data)
+
+	// Versions are not supported
+	_, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "secret3", Version: "1"})
+	require.ErrorIs(t, err, secretstores.ErrVersionNotFound)
+}
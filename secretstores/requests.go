@@ -13,13 +13,68 @@ limitations under the License.
 
 package secretstores
 
+import "fmt"
+
 // GetSecretRequest describes a get secret request from a secret store.
 type GetSecretRequest struct {
-	Name     string            `json:"name"`
+	Name string `json:"name"`
+	// Version of the secret to retrieve.
+	// If empty, the latest version is returned.
+	Version  string            `json:"version,omitempty"`
 	Metadata map[string]string `json:"metadata"`
 }
 
+// RequestedVersion returns the version set in Version or, if that's empty, in the metadata property with the given key, which secret stores used to select versions before Version was added.
+// It returns an error if both are set to different values.
+func (r GetSecretRequest) RequestedVersion(metadataKey string) (string, error) {
+	mdVersion := r.Metadata[metadataKey]
+	switch {
+	case r.Version == "":
+		return mdVersion, nil
+	case mdVersion == "" || mdVersion == r.Version:
+		return r.Version, nil
+	default:
+		return "", fmt.Errorf("version '%s' does not match metadata property %s '%s'", r.Version, metadataKey, mdVersion)
+	}
+}
+
 // BulkGetSecretRequest describes a bulk get secret request from a secret store.
 type BulkGetSecretRequest struct {
 	Metadata map[string]string `json:"metadata"`
 }
+
+// SetSecretRequest describes a request to create or update a secret in a secret store.
+type SetSecretRequest struct {
+	Name string `json:"name"`
+	// Values of the secret.
+	// Secret stores that do not support multiple key-values per secret require a single value, with the name of the secret as key.
+	Data     map[string]string `json:"data"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// DeleteSecretRequest describes a request to delete a secret from a secret store.
+type DeleteSecretRequest struct {
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// WatchSecretsRequest describes a request to watch secrets for changes.
+type WatchSecretsRequest struct {
+	// Names of the secrets to watch.
+	// If empty, all secrets in the store are watched.
+	Names    []string          `json:"names"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// IsWatched returns true if the secret with the given name is included in the request.
+func (r WatchSecretsRequest) IsWatched(name string) bool {
+	if len(r.Names) == 0 {
+		return true
+	}
+	for _, n := range r.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstores
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestedVersion(t *testing.T) {
+	tests := map[string]struct {
+		req    GetSecretRequest
+		expect string
+		err    bool
+	}{
+		"latest":           {req: GetSecretRequest{}, expect: ""},
+		"version":          {req: GetSecretRequest{Version: "2"}, expect: "2"},
+		"metadata":         {req: GetSecretRequest{Metadata: map[string]string{"version_id": "1"}}, expect: "1"},
+		"both, same value": {req: GetSecretRequest{Version: "2", Metadata: map[string]string{"version_id": "2"}}, expect: "2"},
+		"both, different":  {req: GetSecretRequest{Version: "2", Metadata: map[string]string{"version_id": "1"}}, err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			version, err := tc.req.RequestedVersion("version_id")
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, version)
+		})
+	}
+}
@@ -16,9 +16,32 @@ package secretstores
 // GetSecretResponse describes the response object for a secret returned from a secret store.
 type GetSecretResponse struct {
 	Data map[string]string `json:"data"`
+	// Version of the secret that was returned, if the secret store supports versioning.
+	Version string `json:"version,omitempty"`
 }
 
 // BulkGetSecretResponse describes the response object for all the secrets returned from a secret store.
 type BulkGetSecretResponse struct {
 	Data map[string]map[string]string `json:"data"`
 }
+
+// SecretChangeType is the type of a change to a secret.
+type SecretChangeType string
+
+const (
+	// SecretChangeTypeUpdated indicates that a secret was created or updated.
+	SecretChangeTypeUpdated SecretChangeType = "updated"
+	// SecretChangeTypeDeleted indicates that a secret was deleted.
+	SecretChangeTypeDeleted SecretChangeType = "deleted"
+)
+
+// SecretChangeEvent describes a change to a secret.
+type SecretChangeEvent struct {
+	Name string           `json:"name"`
+	Type SecretChangeType `json:"type"`
+	// Values of the secret after the change.
+	// Nil if the secret was deleted.
+	Data map[string]string `json:"data,omitempty"`
+	// Version of the secret after the change, if the secret store supports versioning.
+	Version string `json:"version,omitempty"`
+}
@@ -15,6 +15,7 @@ package secretstores
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dapr/components-contrib/health"
@@ -35,6 +36,37 @@ type SecretStore interface {
 	Features() []Feature
 }
 
+// SecretWriter is an optional interface implemented by secret stores that can create, update and delete secrets.
+// Secret stores that implement it advertise the FeatureSecretWriter feature.
+type SecretWriter interface {
+	// SetSecret creates a secret, or replaces the values of an existing secret.
+	SetSecret(ctx context.Context, req SetSecretRequest) error
+	// DeleteSecret deletes a secret.
+	DeleteSecret(ctx context.Context, req DeleteSecretRequest) error
+}
+
+// SecretWatcher is an optional interface implemented by secret stores that can notify when secrets change.
+// Secret stores that implement it advertise the FeatureSecretWatcher feature.
+type SecretWatcher interface {
+	// WatchSecrets starts watching secrets for changes, and returns once the watch is established.
+	// The handler is invoked for each change, until the context is canceled or the secret store is closed.
+	WatchSecrets(ctx context.Context, req WatchSecretsRequest, handler SecretChangeHandler) error
+}
+
+// SecretChangeHandler is the handler invoked by SecretWatcher when a secret changes.
+type SecretChangeHandler func(ctx context.Context, e *SecretChangeEvent) error
+
+// StoreConsumerMetadataKey is the metadata property with the name of the secret store used by a StoreConsumer.
+const StoreConsumerMetadataKey = "secretStore"
+
+// StoreConsumer is an optional interface for components that read secrets from another secret store, provided with SetSecretStore.
+type StoreConsumer interface {
+	SetSecretStore(store SecretStore)
+}
+
+// ErrVersionNotFound is returned when the version of a secret that was requested does not exist, or the secret store cannot retrieve it.
+var ErrVersionNotFound = errors.New("secret version not found")
+
 func Ping(ctx context.Context, secretStore SecretStore) error {
 	// checks if this secretStore has the ping option then executes
 	if secretStoreWithPing, ok := secretStore.(health.Pinger); ok {
@@ -99,7 +99,10 @@ func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 		return response, errors.New("secret name is empty")
 	}
 
-	versionID := req.Metadata[VersionID]
+	versionID, err := req.RequestedVersion(VersionID)
+	if err != nil {
+		return response, err
+	}
 	ssmReq := ssm.NewGetSecretValueRequest()
 	ssmReq.SecretName = &req.Name
 	ssmReq.VersionId = &versionID
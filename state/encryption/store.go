@@ -0,0 +1,340 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package encryption contains a state store that transparently encrypts values stored in another state store.
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/health"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/utils"
+)
+
+// ErrNotEncrypted is returned when a value read from the state store is not encrypted, and Options.AllowUnencrypted is false.
+var ErrNotEncrypted = errors.New("value is not encrypted")
+
+// Options contains the options for the encrypted state store.
+type Options struct {
+	// Name (or name/version) of the key encryption key in the crypto provider.
+	// When the crypto provider supports key management, values are tagged with the ID of the version of the key used to encrypt them, so they can be decrypted after the key is rotated.
+	KeyName string
+	// Algorithm used to wrap the data keys, for example "A256KW" or "RSA-OAEP-256".
+	Algorithm string
+	// If true, values that are not encrypted are returned as-is, which allows adding encryption to a state store that contains data already.
+	// Values that are written are always encrypted.
+	AllowUnencrypted bool
+}
+
+// Store is a state store that encrypts values before saving them in another state store, and decrypts them when they are retrieved.
+//
+// Each value is encrypted in an envelope (see crypto.SealEnvelope) with a new data key, and the key of the state is used as associated data, so values cannot be moved to another key.
+// Envelopes are stored base64-encoded, so they can be saved in state stores that support text values only.
+// ETags, TTLs and other request metadata are passed to the underlying state store unchanged.
+// Query results are decrypted, but filtering and sorting on the contents of encrypted values is not possible.
+//
+// Transactions and queries are supported only if the underlying state store supports them; see NewEncryptedStore.
+type Store struct {
+	store state.Store
+	cp    contribCrypto.SubtleCrypto
+	opts  Options
+}
+
+// NewEncryptedStore returns a state store that encrypts values stored in store, using the key encryption key in the crypto provider cp.
+// The underlying state store is initialized when Init is invoked, while the crypto provider must be initialized already.
+// The returned state store implements state.TransactionalStore and state.Querier only if store implements them.
+func NewEncryptedStore(store state.Store, cp contribCrypto.SubtleCrypto, opts Options) (state.Store, error) {
+	if store == nil || cp == nil {
+		return nil, errors.New("state store and crypto provider are required")
+	}
+	if opts.KeyName == "" {
+		return nil, errors.New("key name is required")
+	}
+	if opts.Algorithm == "" {
+		return nil, errors.New("algorithm is required")
+	}
+
+	s := &Store{
+		store: store,
+		cp:    cp,
+		opts:  opts,
+	}
+	_, transactional := store.(state.TransactionalStore)
+	_, querier := store.(state.Querier)
+	switch {
+	case transactional && querier:
+		return &transactionalQuerierStore{Store: s}, nil
+	case transactional:
+		return &transactionalStore{Store: s}, nil
+	case querier:
+		return &querierStore{Store: s}, nil
+	default:
+		return s, nil
+	}
+}
+
+// Init initializes the underlying state store.
+func (s *Store) Init(ctx context.Context, metadata state.Metadata) error {
+	return s.store.Init(ctx, metadata)
+}
+
+// Features returns the features of the underlying state store.
+// The transactional and query features are included only if the underlying state store implements the corresponding interfaces.
+func (s *Store) Features() []state.Feature {
+	_, transactional := s.store.(state.TransactionalStore)
+	_, querier := s.store.(state.Querier)
+	features := s.store.Features()
+	res := make([]state.Feature, 0, len(features))
+	for _, f := range features {
+		if (f == state.FeatureTransactional && !transactional) || (f == state.FeatureQueryAPI && !querier) {
+			continue
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// Get retrieves and decrypts a value.
+func (s *Store) Get(ctx context.Context, req *state.GetRequest) (*state.GetResponse, error) {
+	res, err := s.store.Get(ctx, req)
+	if err != nil || res == nil || len(res.Data) == 0 {
+		return res, err
+	}
+
+	res.Data, err = s.decrypt(ctx, req.Key, res.Data)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Set encrypts and saves a value.
+func (s *Store) Set(ctx context.Context, req *state.SetRequest) error {
+	encReq, err := s.encryptRequest(ctx, *req)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(ctx, &encReq)
+}
+
+// Delete deletes a value.
+func (s *Store) Delete(ctx context.Context, req *state.DeleteRequest) error {
+	return s.store.Delete(ctx, req)
+}
+
+// BulkGet retrieves and decrypts values in bulk.
+// Values that cannot be decrypted are returned with an error.
+func (s *Store) BulkGet(ctx context.Context, req []state.GetRequest, opts state.BulkGetOpts) ([]state.BulkGetResponse, error) {
+	res, err := s.store.BulkGet(ctx, req, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res {
+		if res[i].Error != "" || len(res[i].Data) == 0 {
+			continue
+		}
+		res[i].Data, err = s.decrypt(ctx, res[i].Key, res[i].Data)
+		if err != nil {
+			res[i].Data = nil
+			res[i].Error = err.Error()
+		}
+	}
+	return res, nil
+}
+
+// BulkSet encrypts and saves values in bulk.
+func (s *Store) BulkSet(ctx context.Context, req []state.SetRequest, opts state.BulkStoreOpts) error {
+	encReqs := make([]state.SetRequest, len(req))
+	for i := range req {
+		var err error
+		encReqs[i], err = s.encryptRequest(ctx, req[i])
+		if err != nil {
+			return err
+		}
+	}
+	return s.store.BulkSet(ctx, encReqs, opts)
+}
+
+// BulkDelete deletes values in bulk.
+func (s *Store) BulkDelete(ctx context.Context, req []state.DeleteRequest, opts state.BulkStoreOpts) error {
+	return s.store.BulkDelete(ctx, req, opts)
+}
+
+// Encrypts the values in the transaction and executes it in the underlying state store.
+func (s *Store) multi(ctx context.Context, tx state.TransactionalStore, request *state.TransactionalStateRequest) error {
+
+	// Do not modify the caller's request
+	encRequest := &state.TransactionalStateRequest{
+		Operations: make([]state.TransactionalStateOperation, len(request.Operations)),
+		Metadata:   request.Metadata,
+	}
+	for i, o := range request.Operations {
+		switch req := o.(type) {
+		case state.SetRequest:
+			encReq, err := s.encryptRequest(ctx, req)
+			if err != nil {
+				return err
+			}
+			encRequest.Operations[i] = encReq
+		case *state.SetRequest:
+			encReq, err := s.encryptRequest(ctx, *req)
+			if err != nil {
+				return err
+			}
+			encRequest.Operations[i] = &encReq
+		default:
+			encRequest.Operations[i] = o
+		}
+	}
+
+	return tx.Multi(ctx, encRequest)
+}
+
+// Executes a query in the underlying state store and decrypts the results.
+func (s *Store) query(ctx context.Context, querier state.Querier, req *state.QueryRequest) (*state.QueryResponse, error) {
+	res, err := querier.Query(ctx, req)
+	if err != nil || res == nil {
+		return res, err
+	}
+	for i := range res.Results {
+		item := &res.Results[i]
+		if item.Error != "" || len(item.Data) == 0 {
+			continue
+		}
+		item.Data, err = s.decrypt(ctx, item.Key, item.Data)
+		if err != nil {
+			item.Data = nil
+			item.Error = err.Error()
+		}
+	}
+	return res, nil
+}
+
+// Ping the underlying state store.
+func (s *Store) Ping(ctx context.Context) error {
+	return state.Ping(ctx, s.store)
+}
+
+// Close the underlying state store.
+func (s *Store) Close() error {
+	if closer, ok := s.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// GetComponentMetadata returns the metadata of the underlying state store.
+func (s *Store) GetComponentMetadata() (metadataInfo metadata.MetadataMap) {
+	if mc, ok := s.store.(interface{ GetComponentMetadata() metadata.MetadataMap }); ok {
+		return mc.GetComponentMetadata()
+	}
+	return nil
+}
+
+// encryptRequest returns a copy of the request with the value encrypted.
+func (s *Store) encryptRequest(ctx context.Context, req state.SetRequest) (state.SetRequest, error) {
+	plaintext, err := utils.Marshal(req.Value, json.Marshal)
+	if err != nil {
+		return req, err
+	}
+
+	envelope, err := contribCrypto.SealEnvelope(ctx, s.cp, plaintext, contribCrypto.EnvelopeEncryptOptions{
+		KeyName:        s.opts.KeyName,
+		Algorithm:      s.opts.Algorithm,
+		AssociatedData: []byte(req.Key),
+	})
+	if err != nil {
+		return req, fmt.Errorf("failed to encrypt value for key '%s': %w", req.Key, err)
+	}
+
+	enc := make([]byte, base64.StdEncoding.EncodedLen(len(envelope)))
+	base64.StdEncoding.Encode(enc, envelope)
+	req.Value = enc
+	// The stored value is not in the original format anymore
+	req.ContentType = nil
+	return req, nil
+}
+
+// decrypt decrypts a value retrieved from the underlying state store.
+func (s *Store) decrypt(ctx context.Context, key string, data []byte) ([]byte, error) {
+	envelope := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(envelope, data)
+	if err != nil || !contribCrypto.IsEnvelope(envelope[:n]) {
+		if s.opts.AllowUnencrypted {
+			return data, nil
+		}
+		return nil, fmt.Errorf("failed to decrypt value for key '%s': %w", key, ErrNotEncrypted)
+	}
+
+	plaintext, err := contribCrypto.OpenEnvelope(ctx, s.cp, envelope[:n], contribCrypto.EnvelopeDecryptOptions{
+		AssociatedData: []byte(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value for key '%s': %w", key, err)
+	}
+	return plaintext, nil
+}
+
+// Encrypted state store wrapping a transactional state store.
+type transactionalStore struct {
+	*Store
+}
+
+// Multi encrypts the values in the transaction and executes it in the underlying state store.
+func (s *transactionalStore) Multi(ctx context.Context, request *state.TransactionalStateRequest) error {
+	return s.multi(ctx, s.store.(state.TransactionalStore), request)
+}
+
+// Encrypted state store wrapping a state store that supports queries.
+type querierStore struct {
+	*Store
+}
+
+// Query executes a query in the underlying state store and decrypts the results.
+func (s *querierStore) Query(ctx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
+	return s.query(ctx, s.store.(state.Querier), req)
+}
+
+// Encrypted state store wrapping a transactional state store that supports queries.
+type transactionalQuerierStore struct {
+	*Store
+}
+
+// Multi encrypts the values in the transaction and executes it in the underlying state store.
+func (s *transactionalQuerierStore) Multi(ctx context.Context, request *state.TransactionalStateRequest) error {
+	return s.multi(ctx, s.store.(state.TransactionalStore), request)
+}
+
+// Query executes a query in the underlying state store and decrypts the results.
+func (s *transactionalQuerierStore) Query(ctx context.Context, req *state.QueryRequest) (*state.QueryResponse, error) {
+	return s.query(ctx, s.store.(state.Querier), req)
+}
+
+// Ensure the stores implement the interfaces.
+var (
+	_ state.Store              = (*Store)(nil)
+	_ health.Pinger            = (*Store)(nil)
+	_ state.TransactionalStore = (*transactionalStore)(nil)
+	_ state.Querier            = (*querierStore)(nil)
+	_ state.TransactionalStore = (*transactionalQuerierStore)(nil)
+	_ state.Querier            = (*transactionalQuerierStore)(nil)
+)
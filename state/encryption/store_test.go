@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	contribCrypto "github.com/dapr/components-contrib/crypto"
+	"github.com/dapr/components-contrib/crypto/localstorage"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	inmemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func newTestStore(t *testing.T, opts Options) (state.Store, state.Store, contribCrypto.SubtleCrypto) {
+	t.Helper()
+	ctx := context.Background()
+	log := logger.NewLogger("test")
+
+	cp := localstorage.NewLocalStorageCrypto(log)
+	err := cp.Init(ctx, contribCrypto.Metadata{Base: metadata.Base{
+		Properties: map[string]string{"path": t.TempDir()},
+	}})
+	require.NoError(t, err)
+	_, err = cp.(contribCrypto.KeyManager).CreateKey(ctx, "mykey", contribCrypto.KeyTypeAES256)
+	require.NoError(t, err)
+
+	inner := inmemory.NewInMemoryStateStore(log)
+	if opts.KeyName == "" {
+		opts.KeyName = "mykey"
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = "A256KW"
+	}
+	s, err := NewEncryptedStore(inner, cp, opts)
+	require.NoError(t, err)
+	require.NoError(t, s.Init(ctx, state.Metadata{}))
+	t.Cleanup(func() {
+		s.(io.Closer).Close()
+	})
+	return s, inner, cp
+}
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("values are encrypted at rest", func(t *testing.T) {
+		s, inner, _ := newTestStore(t, Options{})
+
+		err := s.Set(ctx, &state.SetRequest{Key: "k1", Value: map[string]string{"name": "jane"}})
+		require.NoError(t, err)
+
+		raw, err := inner.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw.Data), "jane")
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"jane"}`, string(res.Data))
+	})
+
+	t.Run("missing keys", func(t *testing.T) {
+		s, _, _ := newTestStore(t, Options{})
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "notfound"})
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+	})
+
+	t.Run("ETags and TTLs are preserved", func(t *testing.T) {
+		s, _, _ := newTestStore(t, Options{})
+
+		err := s.Set(ctx, &state.SetRequest{Key: "k1", Value: []byte("v1"), Metadata: map[string]string{"ttlInSeconds": "100"}})
+		require.NoError(t, err)
+		res, err := s.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		require.NotNil(t, res.ETag)
+		assert.Contains(t, res.Metadata, state.GetRespMetaKeyTTLExpireTime)
+
+		err = s.Set(ctx, &state.SetRequest{Key: "k1", Value: []byte("v2"), ETag: ptr.Of("bad")})
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+
+		err = s.Set(ctx, &state.SetRequest{Key: "k1", Value: []byte("v2"), ETag: res.ETag})
+		require.NoError(t, err)
+		res, err = s.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		assert.Equal(t, "v2", string(res.Data))
+	})
+
+	t.Run("values cannot be moved to another key", func(t *testing.T) {
+		s, inner, _ := newTestStore(t, Options{})
+
+		err := s.Set(ctx, &state.SetRequest{Key: "k1", Value: []byte("secret")})
+		require.NoError(t, err)
+		raw, err := inner.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		err = inner.Set(ctx, &state.SetRequest{Key: "k2", Value: raw.Data})
+		require.NoError(t, err)
+
+		_, err = s.Get(ctx, &state.GetRequest{Key: "k2"})
+		require.ErrorIs(t, err, contribCrypto.ErrEnvelopeDecryption)
+	})
+
+	t.Run("key rotation", func(t *testing.T) {
+		s, _, cp := newTestStore(t, Options{})
+
+		err := s.Set(ctx, &state.SetRequest{Key: "old", Value: []byte("v1")})
+		require.NoError(t, err)
+		_, err = cp.(contribCrypto.KeyManager).RotateKey(ctx, "mykey")
+		require.NoError(t, err)
+		err = s.Set(ctx, &state.SetRequest{Key: "new", Value: []byte("v2")})
+		require.NoError(t, err)
+
+		res, err := s.BulkGet(ctx, []state.GetRequest{{Key: "old"}, {Key: "new"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		for _, r := range res {
+			require.Empty(t, r.Error)
+		}
+		assert.Equal(t, "v1", string(res[0].Data))
+		assert.Equal(t, "v2", string(res[1].Data))
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		s, _, _ := newTestStore(t, Options{})
+
+		err := s.Set(ctx, &state.SetRequest{Key: "k2", Value: []byte("v2")})
+		require.NoError(t, err)
+
+		req := &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				state.SetRequest{Key: "k1", Value: []byte("v1")},
+				state.DeleteRequest{Key: "k2"},
+			},
+		}
+		err = s.(state.TransactionalStore).Multi(ctx, req)
+		require.NoError(t, err)
+		// The caller's request is not modified
+		assert.Equal(t, []byte("v1"), req.Operations[0].(state.SetRequest).Value)
+
+		res, err := s.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		assert.Equal(t, "v1", string(res.Data))
+		res, err = s.Get(ctx, &state.GetRequest{Key: "k2"})
+		require.NoError(t, err)
+		assert.Empty(t, res.Data)
+	})
+
+	t.Run("bulk set", func(t *testing.T) {
+		s, inner, _ := newTestStore(t, Options{})
+
+		err := s.BulkSet(ctx, []state.SetRequest{{Key: "k1", Value: "v1"}, {Key: "k2", Value: "v2"}}, state.BulkStoreOpts{})
+		require.NoError(t, err)
+
+		raw, err := inner.Get(ctx, &state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw.Data), "v1")
+		res, err := s.Get(ctx, &state.GetRequest{Key: "k2"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v2"`, string(res.Data))
+	})
+
+	t.Run("unencrypted values", func(t *testing.T) {
+		s, inner, _ := newTestStore(t, Options{})
+		err := inner.Set(ctx, &state.SetRequest{Key: "plain", Value: []byte("hello")})
+		require.NoError(t, err)
+
+		_, err = s.Get(ctx, &state.GetRequest{Key: "plain"})
+		require.ErrorIs(t, err, ErrNotEncrypted)
+
+		res, err := s.BulkGet(ctx, []state.GetRequest{{Key: "plain"}}, state.BulkGetOpts{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, res[0].Error)
+
+		s, inner, _ = newTestStore(t, Options{AllowUnencrypted: true})
+		err = inner.Set(ctx, &state.SetRequest{Key: "plain", Value: []byte("hello")})
+		require.NoError(t, err)
+		got, err := s.Get(ctx, &state.GetRequest{Key: "plain"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(got.Data))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewEncryptedStore(inmemory.NewInMemoryStateStore(logger.NewLogger("test")), localstorage.NewLocalStorageCrypto(logger.NewLogger("test")), Options{Algorithm: "A256KW"})
+		require.Error(t, err)
+	})
+}
+
+func TestEncryptedStoreCapabilities(t *testing.T) {
+	log := logger.NewLogger("test")
+	cp := localstorage.NewLocalStorageCrypto(log)
+	opts := Options{KeyName: "mykey", Algorithm: "A256KW"}
+
+	t.Run("transactional store", func(t *testing.T) {
+		s, err := NewEncryptedStore(inmemory.NewInMemoryStateStore(log), cp, opts)
+		require.NoError(t, err)
+
+		assert.Implements(t, (*state.TransactionalStore)(nil), s)
+		_, ok := s.(state.Querier)
+		assert.False(t, ok)
+		assert.Contains(t, s.Features(), state.FeatureTransactional)
+		assert.Contains(t, s.Features(), state.FeatureETag)
+	})
+
+	t.Run("store without transactions", func(t *testing.T) {
+		// Hides the methods of the in-memory store that aren't part of state.Store
+		inner := struct{ state.Store }{inmemory.NewInMemoryStateStore(log)}
+		s, err := NewEncryptedStore(inner, cp, opts)
+		require.NoError(t, err)
+
+		_, ok := s.(state.TransactionalStore)
+		assert.False(t, ok)
+		_, ok = s.(state.Querier)
+		assert.False(t, ok)
+		assert.NotContains(t, s.Features(), state.FeatureTransactional)
+		assert.Contains(t, s.Features(), state.FeatureETag)
+	})
+}
@@ -53,6 +53,15 @@ type Querier interface {
 	Query(ctx context.Context, req *QueryRequest) (*QueryResponse, error)
 }
 
+// StoreConsumerMetadataKey is the metadata property with the name of the state store used by a StoreConsumer.
+const StoreConsumerMetadataKey = "stateStore"
+
+// StoreConsumer is an optional interface for components that use a state store, named in their metadata with the StoreConsumerMetadataKey property.
+// SetStateStore provides that state store to the component.
+type StoreConsumer interface {
+	SetStateStore(store Store)
+}
+
 func Ping(ctx context.Context, store Store) error {
 	// checks if this store has the ping option then executes
 	if storeWithPing, ok := store.(health.Pinger); ok {
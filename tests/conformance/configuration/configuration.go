@@ -35,6 +35,7 @@ import (
 const (
 	keyCount               = 10
 	v1                     = "1.0.0"
+	v2                     = "2.0.0"
 	defaultMaxReadDuration = 30 * time.Second
 	defaultWaitDuration    = 5 * time.Second
 	postgresComponent      = "postgresql"
@@ -381,6 +382,106 @@ func ConformanceTests(t *testing.T, props map[string]string, store configuration
 			verifyNoMessagesReceived(t, processedC3)
 		})
 	})
+
+	if config.HasOperation("write") {
+		t.Run("write", func(t *testing.T) {
+			writer, ok := store.(configuration.StoreWriter)
+			require.True(t, ok, "store does not implement StoreWriter")
+
+			awaitingMessages := make(map[string]map[string]struct{}, keyCount*2)
+			processedC := make(chan *configuration.UpdateEvent, keyCount*2)
+			subscribeMetadata := make(map[string]string)
+			if strings.HasPrefix(component, postgresComponent) {
+				subscribeMetadata[pgNotifyChannelKey] = pgNotifyChannel
+			}
+			var subscribeID string
+
+			t.Run("subscribe", func(t *testing.T) {
+				var err error
+				subscribeID, err = store.Subscribe(context.Background(),
+					&configuration.SubscribeRequest{
+						Keys:     getKeys(initValues1),
+						Metadata: subscribeMetadata,
+					},
+					func(ctx context.Context, e *configuration.UpdateEvent) error {
+						processedC <- e
+						return nil
+					})
+				require.NoError(t, err, "expected no error on subscribe")
+				time.Sleep(defaultWaitDuration)
+			})
+
+			t.Run("set with version mismatch", func(t *testing.T) {
+				expectedVersions := make(map[string]string, len(initValues1))
+				for key := range initValues1 {
+					expectedVersions[key] = "mismatch"
+				}
+				updatedValues, _ := updateKeyValues(initValues1, runID, counter, v2)
+				err := writer.Set(context.Background(), &configuration.SetRequest{
+					Items:            updatedValues,
+					ExpectedVersions: expectedVersions,
+				})
+				require.ErrorIs(t, err, configuration.ErrVersionMismatch)
+
+				resp, err := store.Get(context.Background(), &configuration.GetRequest{
+					Keys:     getKeys(initValues1),
+					Metadata: make(map[string]string),
+				})
+				require.NoError(t, err)
+				assert.Equal(t, initValues1, resp.Items)
+			})
+
+			t.Run("set and verify messages received", func(t *testing.T) {
+				expectedVersions := make(map[string]string, len(initValues1))
+				for key, item := range initValues1 {
+					expectedVersions[key] = item.Version
+				}
+				initValues1, counter = updateKeyValues(initValues1, runID, counter, v2)
+				err := writer.Set(context.Background(), &configuration.SetRequest{
+					Items:            initValues1,
+					ExpectedVersions: expectedVersions,
+				})
+				require.NoError(t, err, "expected no error on setting keys")
+
+				resp, err := store.Get(context.Background(), &configuration.GetRequest{
+					Keys:     getKeys(initValues1),
+					Metadata: make(map[string]string),
+				})
+				require.NoError(t, err)
+				assert.Equal(t, initValues1, resp.Items)
+
+				updateAwaitingMessages(awaitingMessages, initValues1)
+				verifyMessagesReceived(t, processedC, awaitingMessages)
+			})
+
+			t.Run("delete and verify messages received", func(t *testing.T) {
+				expectedVersions := make(map[string]string, len(initValues1))
+				for key := range initValues1 {
+					expectedVersions[key] = v2
+				}
+				err := writer.Delete(context.Background(), &configuration.DeleteRequest{
+					Keys:             getKeys(initValues1),
+					ExpectedVersions: expectedVersions,
+				})
+				require.NoError(t, err, "expected no error on deleting keys")
+
+				if !strings.HasPrefix(component, postgresComponent) {
+					for k := range initValues1 {
+						initValues1[k] = &configuration.Item{}
+					}
+				}
+				updateAwaitingMessages(awaitingMessages, initValues1)
+				verifyMessagesReceived(t, processedC, awaitingMessages)
+			})
+
+			t.Run("unsubscribe", func(t *testing.T) {
+				err := store.Unsubscribe(context.Background(), &configuration.UnsubscribeRequest{
+					ID: subscribeID,
+				})
+				assert.NoError(t, err, "expected no error in unsubscribe")
+			})
+		})
+	}
 }
 
 func verifyNoMessagesReceived(t *testing.T, processedChan chan *configuration.UpdateEvent) {
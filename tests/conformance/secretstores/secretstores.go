@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/secretstores"
@@ -117,4 +118,45 @@ func ConformanceTests(t *testing.T, props map[string]string, store secretstores.
 			}
 		})
 	})
+
+	// Write
+	if config.HasOperation("write") {
+		t.Run("write", func(t *testing.T) {
+			writer, ok := store.(secretstores.SecretWriter)
+			require.True(t, ok, "expected secret store to implement SecretWriter")
+			require.True(t, secretstores.FeatureSecretWriter.IsPresent(store.Features()), "expected secret store to advertise the SECRET_WRITER feature")
+
+			const name = "conftestwritesecret"
+			get := func(t *testing.T) map[string]string {
+				resp, err := store.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: name})
+				require.NoError(t, err, "expected no error on getting secret %s", name)
+				return resp.Data
+			}
+
+			t.Run("create", func(t *testing.T) {
+				err := writer.SetSecret(context.Background(), secretstores.SetSecretRequest{
+					Name: name,
+					Data: map[string]string{name: "v1"},
+				})
+				require.NoError(t, err, "expected no error on setting secret %s", name)
+				assert.Equal(t, map[string]string{name: "v1"}, get(t))
+			})
+
+			t.Run("update", func(t *testing.T) {
+				err := writer.SetSecret(context.Background(), secretstores.SetSecretRequest{
+					Name: name,
+					Data: map[string]string{name: "v2"},
+				})
+				require.NoError(t, err, "expected no error on updating secret %s", name)
+				assert.Equal(t, map[string]string{name: "v2"}, get(t))
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				err := writer.DeleteSecret(context.Background(), secretstores.DeleteSecretRequest{Name: name})
+				require.NoError(t, err, "expected no error on deleting secret %s", name)
+				_, err = store.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: name})
+				assert.Error(t, err, "expected error on getting deleted secret %s", name)
+			})
+		})
+	}
 }
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dapr/components-contrib/configuration"
+	"github.com/dapr/components-contrib/tests/utils/configupdater"
+	"github.com/dapr/kit/logger"
+)
+
+type fileItem struct {
+	Value    string            `yaml:"value"`
+	Version  string            `yaml:"version"`
+	Metadata map[string]string `yaml:"metadata"`
+}
+
+type ConfigUpdater struct {
+	path   string
+	items  map[string]fileItem
+	logger logger.Logger
+}
+
+func NewFileConfigUpdater(logger logger.Logger) configupdater.Updater {
+	return &ConfigUpdater{
+		logger: logger,
+	}
+}
+
+func (r *ConfigUpdater) Init(props map[string]string) error {
+	r.path = props["path"]
+	if r.path == "" {
+		return fmt.Errorf("missing path of the configuration file")
+	}
+
+	// Start with an empty file
+	err := os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err != nil {
+		return err
+	}
+	r.items = make(map[string]fileItem)
+	return r.save()
+}
+
+// save writes the file atomically, so the configuration store never reads a partially-written file.
+func (r *ConfigUpdater) save() error {
+	data, err := yaml.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+func (r *ConfigUpdater) AddKey(items map[string]*configuration.Item) error {
+	if len(items) == 0 {
+		return fmt.Errorf("empty list of items")
+	}
+	for key, item := range items {
+		r.items[key] = fileItem{
+			Value:    item.Value,
+			Version:  item.Version,
+			Metadata: item.Metadata,
+		}
+	}
+	return r.save()
+}
+
+func (r *ConfigUpdater) UpdateKey(items map[string]*configuration.Item) error {
+	return r.AddKey(items)
+}
+
+func (r *ConfigUpdater) DeleteKey(keys []string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("empty list of items")
+	}
+	for _, key := range keys {
+		delete(r.items, key)
+	}
+	return r.save()
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/configuration"
+	authSqlite "github.com/dapr/components-contrib/internal/authentication/sqlite"
+	"github.com/dapr/components-contrib/tests/utils/configupdater"
+	"github.com/dapr/kit/logger"
+)
+
+type ConfigUpdater struct {
+	db          *sql.DB
+	configTable string
+	logger      logger.Logger
+}
+
+func NewSQLiteConfigUpdater(logger logger.Logger) configupdater.Updater {
+	return &ConfigUpdater{
+		logger: logger,
+	}
+}
+
+func (r *ConfigUpdater) Init(props map[string]string) error {
+	md := authSqlite.SqliteAuthMetadata{}
+	md.Reset()
+	md.ConnectionString = props["connectionString"]
+	err := md.Validate()
+	if err != nil {
+		return err
+	}
+
+	r.configTable = "configuration"
+	if tbl, ok := props["tableName"]; ok && tbl != "" {
+		r.configTable = tbl
+	}
+
+	connString, err := md.GetConnectionString(r.logger)
+	if err != nil {
+		return err
+	}
+	r.db, err = sql.Open("sqlite", connString)
+	if err != nil {
+		return fmt.Errorf("failed to create connection: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Creating the table if it doesn't exist, with the same layout used by the component
+	_, err = r.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+r.configTable+" (key TEXT NOT NULL, value TEXT NOT NULL, version TEXT NOT NULL, metadata TEXT, PRIMARY KEY (key, version))")
+	if err != nil {
+		return fmt.Errorf("error creating table : %w", err)
+	}
+
+	// Deleting existing data
+	_, err = r.db.ExecContext(ctx, "DELETE FROM "+r.configTable)
+	if err != nil {
+		return fmt.Errorf("error deleting existing data : %w", err)
+	}
+
+	return nil
+}
+
+func (r *ConfigUpdater) AddKey(items map[string]*configuration.Item) error {
+	if len(items) == 0 {
+		return fmt.Errorf("empty list of items")
+	}
+	placeholders := make([]string, 0, len(items))
+	params := make([]any, 0, 4*len(items))
+	for key, item := range items {
+		metadata, err := json.Marshal(item.Metadata)
+		if err != nil {
+			return err
+		}
+		placeholders = append(placeholders, "(?, ?, ?, ?)")
+		params = append(params, key, item.Value, item.Version, string(metadata))
+	}
+	query := "INSERT INTO " + r.configTable + " (key, value, version, metadata) VALUES " + strings.Join(placeholders, ", ")
+	_, err := r.db.Exec(query, params...)
+	return err
+}
+
+func (r *ConfigUpdater) UpdateKey(items map[string]*configuration.Item) error {
+	if len(items) == 0 {
+		return fmt.Errorf("empty list of items")
+	}
+	for key, item := range items {
+		metadata, err := json.Marshal(item.Metadata)
+		if err != nil {
+			return err
+		}
+		query := "UPDATE " + r.configTable + " SET value = ?, version = ?, metadata = ? WHERE key = ?"
+		_, err = r.db.Exec(query, item.Value, item.Version, string(metadata), key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ConfigUpdater) DeleteKey(keys []string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("empty list of items")
+	}
+	for _, key := range keys {
+		_, err := r.db.Exec("DELETE FROM "+r.configTable+" WHERE key = ?", key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}